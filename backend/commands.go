@@ -0,0 +1,60 @@
+package main
+
+import (
+        "context"
+        "encoding/json"
+        "flag"
+        "fmt"
+        "historical-events-backend/internal/config"
+        "historical-events-backend/internal/database"
+        "historical-events-backend/internal/database/repositories"
+        "historical-events-backend/internal/services"
+        "os"
+)
+
+// runCommand runs a maintenance command given on the command line instead of
+// the server, e.g. "backend lint -json"
+func runCommand(cfg *config.Config, args []string) error {
+        switch args[0] {
+        case "lint":
+                return lintCommand(cfg, args[1:])
+        }
+        return fmt.Errorf("unknown command %q (available: lint)", args[0])
+}
+
+// lintCommand prints the data quality report, as text or with -json as the
+// API returns it. It fails when the report has errors, so it can gate CI.
+func lintCommand(cfg *config.Config, args []string) error {
+        flags := flag.NewFlagSet("lint", flag.ExitOnError)
+        asJSON := flags.Bool("json", false, "print the report as JSON")
+        flags.Parse(args)
+
+        db, err := database.NewConnection(&cfg.Database)
+        if err != nil {
+                return fmt.Errorf("failed to connect to database: %w", err)
+        }
+        defer db.Close()
+
+        report, err := services.NewLinter(repositories.NewLintRepository(db.DB)).Run(context.Background())
+        if err != nil {
+                return err
+        }
+
+        if *asJSON {
+                encoder := json.NewEncoder(os.Stdout)
+                encoder.SetIndent("", "  ")
+                if err := encoder.Encode(report); err != nil {
+                        return err
+                }
+        } else {
+                for _, finding := range report.Findings {
+                        fmt.Printf("%-7s %-24s %-32s %s: %s\n", finding.Severity, finding.Code, finding.Link, finding.Name, finding.Message)
+                }
+                fmt.Printf("%d errors, %d warnings, %d notes\n", report.ErrorCount, report.WarningCount, report.InfoCount)
+        }
+
+        if report.ErrorCount > 0 {
+                return fmt.Errorf("data quality check found %d errors", report.ErrorCount)
+        }
+        return nil
+}
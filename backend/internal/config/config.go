@@ -2,12 +2,14 @@ package config
 
 import (
 	"os"
+	"strconv"
 )
 
 // Config holds all configuration for the application
 type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
+	Trash    TrashConfig
 }
 
 // ServerConfig holds server-specific configuration
@@ -27,6 +29,13 @@ type DatabaseConfig struct {
 	DatabaseURL string
 }
 
+// TrashConfig holds the trash bin configuration
+type TrashConfig struct {
+	// RetentionDays is how long deleted rows stay in the trash before they
+	// are purged; 0 or less keeps them until restored
+	RetentionDays int
+}
+
 // Load reads configuration from environment variables
 func Load() *Config {
 	return &Config{
@@ -43,6 +52,9 @@ func Load() *Config {
 			SSLMode:     getEnv("DB_SSL_MODE", "disable"),
 			DatabaseURL: getEnv("DATABASE_URL", ""),
 		},
+		Trash: TrashConfig{
+			RetentionDays: getEnvInt("TRASH_RETENTION_DAYS", 30),
+		},
 	}
 }
 
@@ -52,4 +64,15 @@ func getEnv(key, fallback string) string {
 		return value
 	}
 	return fallback
-}
\ No newline at end of file
+}
+
+// getEnvInt returns environment variable value as an integer, or default if
+// not set or not a number
+func getEnvInt(key string, fallback int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,195 @@
+package repositories
+
+import (
+        "context"
+        "database/sql"
+        "fmt"
+        "strings"
+        "historical-events-backend/internal/models"
+
+        "github.com/lib/pq"
+)
+
+// BulkEvent is an event for BulkCreateTx with the names of its tags
+type BulkEvent struct {
+        Event *models.HistoricalEvent
+        Tags  []string
+}
+
+// bulkStagingColumns are the columns of the import_staging temp table filled
+// by COPY; dates are staged as ISO text and cast on insert
+var bulkStagingColumns = []string{
+        "row_no", "name", "description", "latitude", "longitude", "event_date", "era", "lens_type", "source",
+        "name_en", "name_ru", "description_en", "description_ru", "date_precision", "date_circa",
+        "date_earliest", "date_earliest_era", "date_latest", "date_latest_era", "end_date", "end_era",
+        "calendar", "external_id", "created_by",
+}
+
+// BulkCreateTx inserts events into a dataset as part of tx with a handful of
+// set-based statements instead of several round-trips per event: rows and
+// tag names are staged with COPY into temporary tables, missing tags are
+// created (tagColor picks the colour of each), and events and event_tags are
+// inserted with INSERT ... SELECT. Tag names match existing tags ignoring
+// case. progress is called while staging with the number of rows sent. It
+// returns the IDs of the created events, in the order given.
+func (r *EventRepository) BulkCreateTx(ctx context.Context, tx *sql.Tx, datasetID int, events []BulkEvent, tagColor func(name string) string, progress func(done int)) ([]int, error) {
+        if len(events) == 0 {
+                return nil, nil
+        }
+
+        _, err := tx.ExecContext(ctx, `
+                CREATE TEMP TABLE import_staging (
+                        row_no INTEGER PRIMARY KEY,
+                        event_id INTEGER,
+                        name VARCHAR(255), description TEXT,
+                        latitude DOUBLE PRECISION, longitude DOUBLE PRECISION,
+                        event_date INTEGER, era VARCHAR(2), lens_type VARCHAR(50), source TEXT,
+                        name_en VARCHAR(255), name_ru VARCHAR(255), description_en TEXT, description_ru TEXT,
+                        date_precision VARCHAR(16), date_circa BOOLEAN,
+                        date_earliest INTEGER, date_earliest_era VARCHAR(2), date_latest INTEGER, date_latest_era VARCHAR(2),
+                        end_date INTEGER, end_era VARCHAR(2), calendar VARCHAR(16), external_id VARCHAR(255), created_by INTEGER
+                ) ON COMMIT DROP;
+                CREATE TEMP TABLE import_staging_tags (row_no INTEGER, tag_name TEXT) ON COMMIT DROP;
+                CREATE TEMP TABLE import_staging_new_tags (name TEXT, color TEXT) ON COMMIT DROP;`)
+        if err != nil {
+                return nil, fmt.Errorf("failed to create staging tables: %w", err)
+        }
+
+        // Stage events and their tag names
+        eventCopy, err := tx.PrepareContext(ctx, pq.CopyIn("import_staging", bulkStagingColumns...))
+        if err != nil {
+                return nil, fmt.Errorf("failed to start event copy: %w", err)
+        }
+        defer eventCopy.Close()
+        tagCopy, err := tx.PrepareContext(ctx, pq.CopyIn("import_staging_tags", "row_no", "tag_name"))
+        if err != nil {
+                return nil, fmt.Errorf("failed to start tag copy: %w", err)
+        }
+        defer tagCopy.Close()
+
+        tagNames := make(map[string]string) // Lower-cased name -> first spelling
+        for i, bulk := range events {
+                if i%1000 == 0 {
+                        if err := ctx.Err(); err != nil {
+                                return nil, err
+                        }
+                        progress(i)
+                }
+
+                event := bulk.Event
+                precision := event.DatePrecision
+                if precision == "" {
+                        precision = models.DatePrecisionDay
+                }
+                calendar := event.EventDate.Calendar
+                if calendar == "" {
+                        calendar = models.DefaultCalendar(event.EventDate)
+                }
+                _, err := eventCopy.ExecContext(ctx, i, event.Name, event.Description, event.Latitude, event.Longitude,
+                        event.EventDate, event.EventDate.Era, event.LensType, event.Source,
+                        event.NameEn, event.NameRu, event.DescriptionEn, event.DescriptionRu, precision, event.Circa,
+                        bulkDate(event.EarliestDate), optionalEra(event.EarliestDate), bulkDate(event.LatestDate), optionalEra(event.LatestDate),
+                        bulkDate(event.EndDate), optionalEra(event.EndDate), calendar, event.ExternalID, event.CreatedBy)
+                if err != nil {
+                        return nil, fmt.Errorf("failed to stage event: %w", err)
+                }
+
+                for _, tag := range bulk.Tags {
+                        if _, err := tagCopy.ExecContext(ctx, i, tag); err != nil {
+                                return nil, fmt.Errorf("failed to stage event tag: %w", err)
+                        }
+                        if _, seen := tagNames[strings.ToLower(tag)]; !seen {
+                                tagNames[strings.ToLower(tag)] = tag
+                        }
+                }
+        }
+        if _, err := eventCopy.ExecContext(ctx); err != nil {
+                return nil, fmt.Errorf("failed to copy events: %w", err)
+        }
+        if _, err := tagCopy.ExecContext(ctx); err != nil {
+                return nil, fmt.Errorf("failed to copy event tags: %w", err)
+        }
+        progress(len(events))
+
+        // Create the tags that do not exist yet
+        if len(tagNames) > 0 {
+                newTagCopy, err := tx.PrepareContext(ctx, pq.CopyIn("import_staging_new_tags", "name", "color"))
+                if err != nil {
+                        return nil, fmt.Errorf("failed to start new tag copy: %w", err)
+                }
+                defer newTagCopy.Close()
+                for _, name := range tagNames {
+                        if _, err := newTagCopy.ExecContext(ctx, name, tagColor(name)); err != nil {
+                                return nil, fmt.Errorf("failed to stage tag: %w", err)
+                        }
+                }
+                if _, err := newTagCopy.ExecContext(ctx); err != nil {
+                        return nil, fmt.Errorf("failed to copy tags: %w", err)
+                }
+
+                _, err = tx.ExecContext(ctx, `
+                        INSERT INTO tags (name, description, color)
+                        SELECT n.name, 'Auto-generated tag for ' || n.name, n.color
+                        FROM import_staging_new_tags n
+                        WHERE NOT EXISTS (SELECT 1 FROM tags t WHERE lower(t.name) = lower(n.name) AND t.deleted_at IS NULL)
+                        ON CONFLICT (name) WHERE deleted_at IS NULL DO NOTHING`)
+                if err != nil {
+                        return nil, fmt.Errorf("failed to create tags: %w", err)
+                }
+        }
+
+        // Assign event IDs up front so tag links can be joined by row
+        _, err = tx.ExecContext(ctx, `UPDATE import_staging SET event_id = nextval(pg_get_serial_sequence('events', 'id'))`)
+        if err != nil {
+                return nil, fmt.Errorf("failed to assign event ids: %w", err)
+        }
+
+        _, err = tx.ExecContext(ctx, `
+                INSERT INTO events (id, name, description, latitude, longitude, event_date, era, lens_type, source, dataset_id, created_by,
+                                    name_en, name_ru, description_en, description_ru, date_precision, date_circa,
+                                    date_earliest, date_earliest_era, date_latest, date_latest_era, end_date, end_era, calendar, external_id)
+                SELECT event_id, name, description, latitude, longitude, event_date, era, lens_type, source, $1, created_by,
+                       name_en, name_ru, description_en, description_ru, date_precision, date_circa,
+                       date_earliest, date_earliest_era, date_latest, date_latest_era, end_date, end_era, calendar, external_id
+                FROM import_staging
+                ORDER BY row_no`, datasetID)
+        if err != nil {
+                return nil, fmt.Errorf("failed to insert events: %w", err)
+        }
+
+        _, err = tx.ExecContext(ctx, `
+                INSERT INTO event_tags (event_id, tag_id)
+                SELECT DISTINCT s.event_id, t.id
+                FROM import_staging_tags st
+                JOIN import_staging s ON s.row_no = st.row_no
+                JOIN LATERAL (
+                        SELECT id FROM tags WHERE lower(tags.name) = lower(st.tag_name) AND tags.deleted_at IS NULL ORDER BY id LIMIT 1
+                ) t ON true`)
+        if err != nil {
+                return nil, fmt.Errorf("failed to link event tags: %w", err)
+        }
+
+        rows, err := tx.QueryContext(ctx, `SELECT event_id FROM import_staging ORDER BY row_no`)
+        if err != nil {
+                return nil, fmt.Errorf("failed to read event ids: %w", err)
+        }
+        defer rows.Close()
+
+        ids := make([]int, 0, len(events))
+        for rows.Next() {
+                var id int
+                if err := rows.Scan(&id); err != nil {
+                        return nil, fmt.Errorf("failed to scan event id: %w", err)
+                }
+                ids = append(ids, id)
+        }
+        return ids, rows.Err()
+}
+
+// bulkDate stages an optional date as its day number
+func bulkDate(d *models.HistoricDate) interface{} {
+        if d == nil {
+                return nil
+        }
+        return *d
+}
@@ -22,6 +22,7 @@ func (r *DatasetRepository) GetAll() ([]models.EventDataset, error) {
                        d.modified, d.created_at, d.updated_at, u.username
                 FROM event_datasets d
                 LEFT JOIN users u ON d.uploaded_by = u.id
+                WHERE d.deleted_at IS NULL
                 ORDER BY d.created_at DESC`
         
         rows, err := r.db.Query(query)
@@ -71,8 +72,26 @@ func (r *DatasetRepository) GetAll() ([]models.EventDataset, error) {
         return datasets, nil
 }
 
+// Begin starts a transaction for writing a dataset together with its events
+func (r *DatasetRepository) Begin() (*sql.Tx, error) {
+        tx, err := r.db.Begin()
+        if err != nil {
+                return nil, fmt.Errorf("failed to begin transaction: %w", err)
+        }
+        return tx, nil
+}
+
 // Create creates a new dataset
 func (r *DatasetRepository) Create(dataset *models.EventDataset) (*models.EventDataset, error) {
+        return createDataset(r.db, dataset)
+}
+
+// CreateTx creates a new dataset as part of the transaction tx
+func (r *DatasetRepository) CreateTx(tx *sql.Tx, dataset *models.EventDataset) (*models.EventDataset, error) {
+        return createDataset(tx, dataset)
+}
+
+func createDataset(q querier, dataset *models.EventDataset) (*models.EventDataset, error) {
         query := `
                 INSERT INTO event_datasets (filename, description, event_count, uploaded_by, created_at, updated_at)
                 VALUES ($1, $2, $3, $4, $5, $6)
@@ -82,7 +101,7 @@ func (r *DatasetRepository) Create(dataset *models.EventDataset) (*models.EventD
         dataset.CreatedAt = now
         dataset.UpdatedAt = now
         
-        err := r.db.QueryRow(query, dataset.Filename, dataset.Description, 
+        err := q.QueryRow(query, dataset.Filename, dataset.Description, 
                 dataset.EventCount, dataset.UploadedBy, dataset.CreatedAt, dataset.UpdatedAt).Scan(&dataset.ID)
         if err != nil {
                 return nil, fmt.Errorf("failed to create dataset: %w", err)
@@ -96,7 +115,7 @@ func (r *DatasetRepository) GetByID(id int) (*models.EventDataset, error) {
         query := `
                 SELECT id, filename, description, event_count, uploaded_by, modified, created_at, updated_at
                 FROM event_datasets 
-                WHERE id = $1`
+                WHERE id = $1 AND deleted_at IS NULL`
         
         var dataset models.EventDataset
         var uploadedBy sql.NullInt32
@@ -125,40 +144,101 @@ func (r *DatasetRepository) GetByID(id int) (*models.EventDataset, error) {
         return &dataset, nil
 }
 
-// Delete deletes a dataset and all its associated events
-func (r *DatasetRepository) Delete(id int) error {
-        tx, err := r.db.Begin()
+// GetEventIDsTx returns the IDs of the live events of a dataset as part of
+// the transaction tx
+func (r *DatasetRepository) GetEventIDsTx(tx *sql.Tx, id int) ([]int, error) {
+        rows, err := tx.Query("SELECT id FROM events WHERE dataset_id = $1 AND deleted_at IS NULL ORDER BY id", id)
+        if err != nil {
+                return nil, fmt.Errorf("failed to query dataset events: %w", err)
+        }
+        defer rows.Close()
+        
+        var ids []int
+        for rows.Next() {
+                var eventID int
+                if err := rows.Scan(&eventID); err != nil {
+                        return nil, fmt.Errorf("failed to scan dataset event: %w", err)
+                }
+                ids = append(ids, eventID)
+        }
+        return ids, rows.Err()
+}
+
+// DeleteTx moves a dataset and its events to the trash as part of the
+// transaction tx. The events get the dataset's deleted_at, so that restoring
+// the dataset brings back exactly these events.
+func (r *DatasetRepository) DeleteTx(tx *sql.Tx, id int) error {
+        // CURRENT_TIMESTAMP is the same for every statement of the transaction
+        result, err := tx.Exec("UPDATE event_datasets SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL", id)
         if err != nil {
-                return fmt.Errorf("failed to begin transaction: %w", err)
+                return fmt.Errorf("failed to delete dataset: %w", err)
         }
-        defer tx.Rollback()
         
-        // First delete all events associated with this dataset
-        _, err = tx.Exec("DELETE FROM event_tags WHERE event_id IN (SELECT id FROM events WHERE dataset_id = $1)", id)
+        rowsAffected, err := result.RowsAffected()
         if err != nil {
-                return fmt.Errorf("failed to delete event tags: %w", err)
+                return fmt.Errorf("failed to get affected rows: %w", err)
+        }
+        if rowsAffected == 0 {
+                return fmt.Errorf("dataset not found")
         }
         
-        // Delete events
-        _, err = tx.Exec("DELETE FROM events WHERE dataset_id = $1", id)
+        _, err = tx.Exec("UPDATE events SET deleted_at = CURRENT_TIMESTAMP WHERE dataset_id = $1 AND deleted_at IS NULL", id)
         if err != nil {
                 return fmt.Errorf("failed to delete events: %w", err)
         }
         
-        // Delete the dataset itself
-        _, err = tx.Exec("DELETE FROM event_datasets WHERE id = $1", id)
+        return nil
+}
+
+// RestoreTx takes a dataset and the events deleted with it out of the trash
+// as part of the transaction tx, returning the IDs of the restored events.
+// Returns ErrNotInTrash, or ErrRestoreConflict when a live event of the
+// dataset has taken the external ID of a restored one.
+func (r *DatasetRepository) RestoreTx(tx *sql.Tx, id int) ([]int, error) {
+        rows, err := tx.Query(`
+                UPDATE events e
+                SET deleted_at = NULL
+                FROM event_datasets d
+                WHERE d.id = $1 AND e.dataset_id = d.id AND e.deleted_at = d.deleted_at
+                RETURNING e.id`, id)
         if err != nil {
-                return fmt.Errorf("failed to delete dataset: %w", err)
+                return nil, restoreError(err)
         }
+        defer rows.Close()
         
-        return tx.Commit()
+        var eventIDs []int
+        for rows.Next() {
+                var eventID int
+                if err := rows.Scan(&eventID); err != nil {
+                        return nil, fmt.Errorf("failed to scan restored event: %w", err)
+                }
+                eventIDs = append(eventIDs, eventID)
+        }
+        if err := rows.Err(); err != nil {
+                return nil, restoreError(err)
+        }
+        
+        if err := restoreRow(tx, "event_datasets", id); err != nil {
+                return nil, err
+        }
+        return eventIDs, nil
 }
 
 // UpdateEventCount updates the event count for a dataset
 func (r *DatasetRepository) UpdateEventCount(id int, count int) error {
+        return updateDatasetEventCount(r.db, id, count)
+}
+
+// UpdateEventCountTx updates the event count for a dataset as part of the
+// transaction tx
+func (r *DatasetRepository) UpdateEventCountTx(tx *sql.Tx, id int, count int) error {
+        return updateDatasetEventCount(tx, id, count)
+}
+
+func updateDatasetEventCount(q querier, id int, count int) error {
         query := `UPDATE event_datasets SET event_count = $1, updated_at = $2 WHERE id = $3`
         
-        _, err := r.db.Exec(query, count, time.Now(), id)
+        _, err := q.Exec(query, count, time.Now(), id)
         if err != nil {
                 return fmt.Errorf("failed to update dataset event count: %w", err)
         }
@@ -166,6 +246,21 @@ func (r *DatasetRepository) UpdateEventCount(id int, count int) error {
         return nil
 }
 
+// GetUploadedBy returns the ID of the user who uploaded a dataset, or nil when
+// it is unknown
+func (r *DatasetRepository) GetUploadedBy(id int) (*int, error) {
+        var uploadedBy *int
+        err := r.db.QueryRow(`SELECT uploaded_by FROM event_datasets WHERE id = $1 AND deleted_at IS NULL`, id).Scan(&uploadedBy)
+        if err == sql.ErrNoRows {
+                return nil, fmt.Errorf("dataset not found")
+        }
+        if err != nil {
+                return nil, fmt.Errorf("failed to get dataset uploader: %w", err)
+        }
+        
+        return uploadedBy, nil
+}
+
 // MarkAsModified sets the modified flag to true for a dataset
 func (r *DatasetRepository) MarkAsModified(id int) error {
         query := `UPDATE event_datasets SET modified = TRUE, updated_at = $1 WHERE id = $2`
@@ -0,0 +1,224 @@
+package repositories
+
+import (
+        "database/sql"
+        "fmt"
+        "historical-events-backend/internal/models"
+        "strings"
+
+        "github.com/lib/pq"
+)
+
+// withSimilarityThreshold runs fn in a read-only transaction in which pg_trgm's
+// % operator matches names at least minSimilarity alike
+func (r *EventRepository) withSimilarityThreshold(minSimilarity float64, fn func(tx *sql.Tx) error) error {
+        tx, err := r.db.Begin()
+        if err != nil {
+                return fmt.Errorf("failed to begin transaction: %w", err)
+        }
+        defer tx.Rollback()
+
+        if _, err := tx.Exec(`SELECT set_config('pg_trgm.similarity_threshold', $1, true)`, fmt.Sprintf("%g", minSimilarity)); err != nil {
+                return fmt.Errorf("failed to set similarity threshold: %w", err)
+        }
+        return fn(tx)
+}
+
+// FindDuplicatePairs returns pairs of events whose names are similar in
+// either locale, whose dates are at most opts.MaxYears astronomical years
+// apart and which are at most opts.MaxDistanceKm apart, most similar names
+// first. Pairs are scored with opts.Score.
+func (r *EventRepository) FindDuplicatePairs(opts models.DuplicateOptions) ([]models.DuplicatePair, error) {
+        args := []interface{}{opts.MaxYears, opts.MaxDistanceKm * 1000, opts.Limit}
+        var conditions []string
+        if opts.DatasetID != nil {
+                args = append(args, *opts.DatasetID)
+                conditions = append(conditions, fmt.Sprintf("(a.dataset_id = $%d OR b.dataset_id = $%d)", len(args), len(args)))
+        }
+        if opts.CrossDataset {
+                conditions = append(conditions, "a.dataset_id IS DISTINCT FROM b.dataset_id")
+        }
+        extra := ""
+        if len(conditions) > 0 {
+                extra = "AND " + strings.Join(conditions, " AND ")
+        }
+
+        query := fmt.Sprintf(`
+                SELECT id, other_id, name_similarity, years_apart, distance_km
+                FROM (
+                        SELECT a.id, b.id AS other_id,
+                               GREATEST(similarity(lower(a.name_en), lower(b.name_en)), similarity(lower(a.name_ru), lower(b.name_ru)))::float8 AS name_similarity,
+                               abs(historic_astronomical_year(a.event_date) - historic_astronomical_year(b.event_date))::float8 AS years_apart,
+                               (ST_Distance(a.location, b.location) / 1000)::float8 AS distance_km
+                        FROM events a
+                        JOIN events b ON a.id < b.id
+                                     AND (lower(a.name_en) %% lower(b.name_en) OR lower(a.name_ru) %% lower(b.name_ru))
+                        WHERE ST_DWithin(a.location, b.location, $2)
+                          AND a.deleted_at IS NULL AND b.deleted_at IS NULL %s
+                ) pairs
+                WHERE years_apart <= $1
+                ORDER BY name_similarity DESC, id, other_id
+                LIMIT $3`, extra)
+
+        var pairs []models.DuplicatePair
+        err := r.withSimilarityThreshold(opts.MinSimilarity, func(tx *sql.Tx) error {
+                rows, err := tx.Query(query, args...)
+                if err != nil {
+                        return fmt.Errorf("failed to query duplicate events: %w", err)
+                }
+                defer rows.Close()
+
+                for rows.Next() {
+                        var pair models.DuplicatePair
+                        if err := rows.Scan(&pair.EventID, &pair.OtherID, &pair.NameSimilarity, &pair.YearsApart, &pair.DistanceKm); err != nil {
+                                return fmt.Errorf("failed to scan duplicate pair: %w", err)
+                        }
+                        pair.Score = opts.Score(pair.NameSimilarity, pair.YearsApart, pair.DistanceKm)
+                        pairs = append(pairs, pair)
+                }
+                return rows.Err()
+        })
+        if err != nil {
+                return nil, err
+        }
+        return pairs, nil
+}
+
+// FindSimilar finds existing events that are likely duplicates of events not
+// saved yet, with the same thresholds as FindDuplicatePairs; events of
+// excludeDatasetID (0 for none) are ignored. Matches are keyed by the index of the event in
+// events and have the existing event as EventID, best score first.
+func (r *EventRepository) FindSimilar(events []*models.HistoricalEvent, opts models.DuplicateOptions, excludeDatasetID int) (map[int][]models.DuplicatePair, error) {
+        if len(events) == 0 {
+                return nil, nil
+        }
+
+        indexes := make([]int64, len(events))
+        namesEn := make([]string, len(events))
+        namesRu := make([]string, len(events))
+        years := make([]float64, len(events))
+        lats := make([]float64, len(events))
+        lngs := make([]float64, len(events))
+        for i, event := range events {
+                indexes[i] = int64(i)
+                namesEn[i] = strings.ToLower(event.NameEn)
+                namesRu[i] = strings.ToLower(event.NameRu)
+                years[i] = event.EventDate.AstronomicalYear()
+                lats[i] = event.Latitude
+                lngs[i] = event.Longitude
+        }
+        var datasetID interface{}
+        if excludeDatasetID != 0 {
+                datasetID = excludeDatasetID
+        }
+
+        query := `
+                SELECT idx, id, name_similarity, years_apart, distance_km
+                FROM (
+                        SELECT p.idx, e.id,
+                               GREATEST(similarity(lower(e.name_en), p.name_en), similarity(lower(e.name_ru), p.name_ru))::float8 AS name_similarity,
+                               abs(historic_astronomical_year(e.event_date) - p.year)::float8 AS years_apart,
+                               (ST_Distance(e.location, p.location) / 1000)::float8 AS distance_km
+                        FROM (
+                                SELECT idx, name_en, name_ru, year,
+                                       ST_SetSRID(ST_MakePoint(lng, lat), 4326)::geography AS location
+                                FROM unnest($1::int[], $2::text[], $3::text[], $4::numeric[], $5::float8[], $6::float8[])
+                                     AS u(idx, name_en, name_ru, year, lat, lng)
+                        ) p
+                        JOIN events e ON (lower(e.name_en) % p.name_en OR lower(e.name_ru) % p.name_ru)
+                        WHERE ST_DWithin(e.location, p.location, $8)
+                          AND e.dataset_id IS DISTINCT FROM $9
+                          AND e.deleted_at IS NULL
+                ) matches
+                WHERE years_apart <= $7
+                ORDER BY idx, name_similarity DESC, id`
+
+        matches := make(map[int][]models.DuplicatePair)
+        err := r.withSimilarityThreshold(opts.MinSimilarity, func(tx *sql.Tx) error {
+                rows, err := tx.Query(query, pq.Array(indexes), pq.Array(namesEn), pq.Array(namesRu), pq.Array(years), pq.Array(lats), pq.Array(lngs),
+                        opts.MaxYears, opts.MaxDistanceKm*1000, datasetID)
+                if err != nil {
+                        return fmt.Errorf("failed to query similar events: %w", err)
+                }
+                defer rows.Close()
+
+                for rows.Next() {
+                        var index int
+                        var pair models.DuplicatePair
+                        if err := rows.Scan(&index, &pair.EventID, &pair.NameSimilarity, &pair.YearsApart, &pair.DistanceKm); err != nil {
+                                return fmt.Errorf("failed to scan similar event: %w", err)
+                        }
+                        pair.Score = opts.Score(pair.NameSimilarity, pair.YearsApart, pair.DistanceKm)
+                        matches[index] = append(matches[index], pair)
+                }
+                return rows.Err()
+        })
+        if err != nil {
+                return nil, err
+        }
+        return matches, nil
+}
+
+// GetByIDs retrieves the events with the given IDs, ordered by ID
+func (r *EventRepository) GetByIDs(ids []int) ([]models.HistoricalEvent, error) {
+        if len(ids) == 0 {
+                return nil, nil
+        }
+
+        query := `
+                SELECT ` + eventColumns + `
+                FROM events_with_display_dates
+                WHERE id = ANY($1)
+                ORDER BY id`
+
+        rows, err := r.db.Query(query, pq.Array(ids))
+        if err != nil {
+                return nil, fmt.Errorf("failed to query events by id: %w", err)
+        }
+        defer rows.Close()
+
+        var events []models.HistoricalEvent
+        for rows.Next() {
+                event, err := scanEvent(rows)
+                if err != nil {
+                        return nil, fmt.Errorf("failed to scan event: %w", err)
+                }
+                events = append(events, event)
+        }
+
+        if err = rows.Err(); err != nil {
+                return nil, fmt.Errorf("error iterating over events: %w", err)
+        }
+
+        return events, nil
+}
+
+// MergeTx merges the duplicates into the canonical event as part of tx: the
+// canonical event is saved as given, gets the tags of the duplicates and the
+// duplicates are moved to the trash
+func (r *EventRepository) MergeTx(tx *sql.Tx, canonical *models.HistoricalEvent, duplicateIDs []int) (*models.HistoricalEvent, error) {
+        merged, err := updateEvent(tx, canonical)
+        if err != nil {
+                return nil, err
+        }
+
+        _, err = tx.Exec(`
+                INSERT INTO event_tags (event_id, tag_id)
+                SELECT DISTINCT $1::integer, tag_id FROM event_tags WHERE event_id = ANY($2)
+                ON CONFLICT DO NOTHING`, canonical.ID, pq.Array(duplicateIDs))
+        if err != nil {
+                return nil, fmt.Errorf("failed to merge event tags: %w", err)
+        }
+
+        result, err := tx.Exec(`UPDATE events SET deleted_at = CURRENT_TIMESTAMP WHERE id = ANY($1) AND deleted_at IS NULL`, pq.Array(duplicateIDs))
+        if err != nil {
+                return nil, fmt.Errorf("failed to delete merged events: %w", err)
+        }
+        if deleted, err := result.RowsAffected(); err != nil {
+                return nil, fmt.Errorf("failed to get affected rows: %w", err)
+        } else if int(deleted) != len(duplicateIDs) {
+                return nil, fmt.Errorf("event not found")
+        }
+
+        return merged, nil
+}
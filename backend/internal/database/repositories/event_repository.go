@@ -3,11 +3,18 @@ package repositories
 import (
         "database/sql"
         "encoding/json"
+        "errors"
         "fmt"
         "historical-events-backend/internal/models"
         "log"
+        "strings"
+
+        "github.com/lib/pq"
 )
 
+// ErrNotPending is returned when reviewing an event that is not pending review
+var ErrNotPending = errors.New("event is not pending review")
+
 // EventRepository handles event data operations
 type EventRepository struct {
         db *sql.DB
@@ -18,14 +25,133 @@ func NewEventRepository(db *sql.DB) *EventRepository {
         return &EventRepository{db: db}
 }
 
+// Begin starts a transaction for writing an event together with its revision
+func (r *EventRepository) Begin() (*sql.Tx, error) {
+        tx, err := r.db.Begin()
+        if err != nil {
+                return nil, fmt.Errorf("failed to begin transaction: %w", err)
+        }
+        return tx, nil
+}
+
+// eventColumns is the column list read from events_with_display_dates by scanEvent
+const eventColumns = `id, name, description, latitude, longitude, event_date, lens_type, source, display_date, dataset_id, created_by, updated_by, created_at, updated_at, name_en, name_ru, description_en, description_ru, date_precision, date_circa, date_earliest, date_latest, end_date, end_display_date, calendar, external_id, tags, version, status, review_note, reviewed_by, reviewed_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+        Scan(dest ...interface{}) error
+}
+
+// scanEvent scans a row selected with eventColumns (followed by any extra
+// destinations) and decodes its aggregated tags JSON
+func scanEvent(row rowScanner, extra ...interface{}) (models.HistoricalEvent, error) {
+        var event models.HistoricalEvent
+        var calendar string
+        var tagsJSON []byte
+        
+        dest := []interface{}{&event.ID, &event.Name, &event.Description, &event.Latitude,
+                &event.Longitude, &event.EventDate, &event.LensType, &event.Source, &event.DisplayDate, &event.DatasetID, &event.CreatedBy, &event.UpdatedBy, &event.CreatedAt, &event.UpdatedAt, &event.NameEn, &event.NameRu, &event.DescriptionEn, &event.DescriptionRu,
+                &event.DatePrecision, &event.Circa, &event.EarliestDate, &event.LatestDate,
+                &event.EndDate, &event.EndDisplayDate, &calendar, &event.ExternalID, &tagsJSON, &event.Version,
+                &event.Status, &event.ReviewNote, &event.ReviewedBy, &event.ReviewedAt}
+        if err := row.Scan(append(dest, extra...)...); err != nil {
+                return event, err
+        }
+        inEventCalendar(&event, calendar)
+        
+        // Parse tags JSON
+        event.Tags = []models.Tag{}
+        if len(tagsJSON) > 0 {
+                if err := json.Unmarshal(tagsJSON, &event.Tags); err != nil {
+                        log.Printf("Error unmarshaling tags for event %d: %v", event.ID, err)
+                        event.Tags = []models.Tag{}
+                }
+        }
+        
+        return event, nil
+}
+
+// inEventCalendar converts the scanned dates of an event, which come out of
+// their day number columns as Gregorian dates, to the event's calendar; the
+// optional dates are always in the event's calendar
+func inEventCalendar(event *models.HistoricalEvent, calendar string) {
+        event.EventDate = event.EventDate.In(calendar)
+        for _, date := range []*models.HistoricDate{event.EarliestDate, event.LatestDate, event.EndDate} {
+                if date != nil {
+                        *date = date.In(calendar)
+                }
+        }
+}
+
+// optionalEra returns the era column value for an optional date (NULL when unset)
+func optionalEra(d *models.HistoricDate) interface{} {
+        if d == nil {
+                return nil
+        }
+        return d.Era
+}
+
+// buildFilterClause translates an EventFilter into a WHERE clause over
+// events_with_display_dates. Placeholders continue numbering after args.
+// Unless the filter asks for other statuses, only published events match.
+func buildFilterClause(filter models.EventFilter, args []interface{}) (string, []interface{}) {
+        var conditions []string
+        
+        // Date bounds match any event whose date range (uncertainty or duration) overlaps [From, To]
+        if filter.From != nil {
+                args = append(args, *filter.From)
+                conditions = append(conditions, fmt.Sprintf("astronomical_latest >= $%d", len(args)))
+        }
+        if filter.To != nil {
+                args = append(args, *filter.To)
+                conditions = append(conditions, fmt.Sprintf("astronomical_earliest <= $%d", len(args)))
+        }
+        if len(filter.LensTypes) > 0 {
+                args = append(args, pq.Array(filter.LensTypes))
+                conditions = append(conditions, fmt.Sprintf("lens_type = ANY($%d)", len(args)))
+        }
+        if len(filter.DatasetIDs) > 0 {
+                args = append(args, pq.Array(filter.DatasetIDs))
+                conditions = append(conditions, fmt.Sprintf("dataset_id = ANY($%d)", len(args)))
+        }
+        if len(filter.TagIDs) > 0 {
+                args = append(args, pq.Array(filter.TagIDs))
+                if filter.TagMode == models.TagModeAll {
+                        // Every requested tag must be attached to the event
+                        conditions = append(conditions, fmt.Sprintf(
+                                "id IN (SELECT event_id FROM event_tags WHERE tag_id = ANY($%d) GROUP BY event_id HAVING COUNT(DISTINCT tag_id) = cardinality($%d::int[]))",
+                                len(args), len(args)))
+                } else {
+                        conditions = append(conditions, fmt.Sprintf(
+                                "id IN (SELECT event_id FROM event_tags WHERE tag_id = ANY($%d))", len(args)))
+                }
+        }
+        
+        statuses := filter.Statuses
+        if len(statuses) == 0 {
+                statuses = []string{models.EventStatusPublished}
+        }
+        args = append(args, pq.Array(statuses))
+        conditions = append(conditions, fmt.Sprintf("status = ANY($%d)", len(args)))
+        
+        return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
 // GetAll retrieves all events from the database
 func (r *EventRepository) GetAll() ([]models.HistoricalEvent, error) {
-        query := `
-                SELECT id, name, description, latitude, longitude, event_date, era, lens_type, source, display_date, dataset_id, created_by, updated_by, created_at, updated_at, name_en, name_ru, description_en, description_ru, tags
+        return r.GetFiltered(models.EventFilter{})
+}
+
+// GetFiltered retrieves all events matching the filter, ordered by date
+func (r *EventRepository) GetFiltered(filter models.EventFilter) ([]models.HistoricalEvent, error) {
+        whereClause, args := buildFilterClause(filter, nil)
+        query := fmt.Sprintf(`
+                SELECT %s
                 FROM events_with_display_dates 
-                ORDER BY astronomical_year ASC`
+                %s
+                ORDER BY astronomical_year ASC, id ASC`, eventColumns, whereClause)
         
-        rows, err := r.db.Query(query)
+        rows, err := r.db.Query(query, args...)
         if err != nil {
                 return nil, fmt.Errorf("failed to query events: %w", err)
         }
@@ -34,28 +160,11 @@ func (r *EventRepository) GetAll() ([]models.HistoricalEvent, error) {
         var events []models.HistoricalEvent
         
         for rows.Next() {
-                var event models.HistoricalEvent
-                var tagsJSON []byte
-                
-                err := rows.Scan(&event.ID, &event.Name, &event.Description, &event.Latitude, 
-                        &event.Longitude, &event.EventDate, &event.Era, &event.LensType, &event.Source, &event.DisplayDate, &event.DatasetID, &event.CreatedBy, &event.UpdatedBy, &event.CreatedAt, &event.UpdatedAt, &event.NameEn, &event.NameRu, &event.DescriptionEn, &event.DescriptionRu, &tagsJSON)
+                event, err := scanEvent(rows)
                 if err != nil {
                         log.Printf("Error scanning event: %v", err)
                         continue
                 }
-                
-                // Parse tags JSON
-                if len(tagsJSON) > 0 {
-                        var tags []models.Tag
-                        if err := json.Unmarshal(tagsJSON, &tags); err != nil {
-                                log.Printf("Error unmarshaling tags for event %d: %v", event.ID, err)
-                                tags = []models.Tag{}
-                        }
-                        event.Tags = tags
-                } else {
-                        event.Tags = []models.Tag{}
-                }
-                
                 events = append(events, event)
         }
         
@@ -66,50 +175,50 @@ func (r *EventRepository) GetAll() ([]models.HistoricalEvent, error) {
         return events, nil
 }
 
-// GetByDatasetID retrieves all events from a specific dataset
+// GetByDatasetID retrieves all events from a specific dataset, whatever their
+// status
 func (r *EventRepository) GetByDatasetID(datasetID int) ([]models.HistoricalEvent, error) {
+        events, err := r.GetFiltered(models.EventFilter{DatasetIDs: []int{datasetID}, Statuses: models.EventStatuses})
+        if err != nil {
+                return nil, fmt.Errorf("failed to query events for dataset %d: %w", datasetID, err)
+        }
+        return events, nil
+}
+
+// FindByNames returns the events whose English or Russian name matches one
+// of the given names, ignoring case. Used to spot likely duplicates on import.
+func (r *EventRepository) FindByNames(names []string) ([]models.HistoricalEvent, error) {
+        if len(names) == 0 {
+                return nil, nil
+        }
+        
+        lowered := make([]string, len(names))
+        for i, name := range names {
+                lowered[i] = strings.ToLower(name)
+        }
+        
         query := `
-                SELECT id, name, description, latitude, longitude, event_date, era, lens_type, source, display_date, dataset_id, created_by, updated_by, created_at, updated_at, name_en, name_ru, description_en, description_ru, tags
-                FROM events_with_display_dates 
-                WHERE dataset_id = $1
-                ORDER BY astronomical_year ASC`
+                SELECT ` + eventColumns + `
+                FROM events_with_display_dates
+                WHERE lower(name_en) = ANY($1) OR lower(name_ru) = ANY($1)`
         
-        rows, err := r.db.Query(query, datasetID)
+        rows, err := r.db.Query(query, pq.Array(lowered))
         if err != nil {
-                return nil, fmt.Errorf("failed to query events for dataset %d: %w", datasetID, err)
+                return nil, fmt.Errorf("failed to query events by name: %w", err)
         }
         defer rows.Close()
         
         var events []models.HistoricalEvent
-        
         for rows.Next() {
-                var event models.HistoricalEvent
-                var tagsJSON []byte
-                
-                err := rows.Scan(&event.ID, &event.Name, &event.Description, &event.Latitude, 
-                        &event.Longitude, &event.EventDate, &event.Era, &event.LensType, &event.Source, &event.DisplayDate, &event.DatasetID, &event.CreatedBy, &event.UpdatedBy, &event.CreatedAt, &event.UpdatedAt, &event.NameEn, &event.NameRu, &event.DescriptionEn, &event.DescriptionRu, &tagsJSON)
+                event, err := scanEvent(rows)
                 if err != nil {
-                        log.Printf("Error scanning event: %v", err)
-                        continue
-                }
-                
-                // Parse tags JSON
-                if len(tagsJSON) > 0 {
-                        var tags []models.Tag
-                        if err := json.Unmarshal(tagsJSON, &tags); err != nil {
-                                log.Printf("Error unmarshaling tags for event %d: %v", event.ID, err)
-                                tags = []models.Tag{}
-                        }
-                        event.Tags = tags
-                } else {
-                        event.Tags = []models.Tag{}
+                        return nil, fmt.Errorf("failed to scan event: %w", err)
                 }
-                
                 events = append(events, event)
         }
         
         if err = rows.Err(); err != nil {
-                return nil, fmt.Errorf("error iterating over events for dataset %d: %w", datasetID, err)
+                return nil, fmt.Errorf("error iterating over events: %w", err)
         }
         
         return events, nil
@@ -118,16 +227,11 @@ func (r *EventRepository) GetByDatasetID(datasetID int) ([]models.HistoricalEven
 // GetByID retrieves a single event by ID
 func (r *EventRepository) GetByID(id int) (*models.HistoricalEvent, error) {
         query := `
-                SELECT id, name, description, latitude, longitude, event_date, era, lens_type, source, display_date, dataset_id, created_by, updated_by, created_at, updated_at, name_en, name_ru, description_en, description_ru, tags
+                SELECT ` + eventColumns + `
                 FROM events_with_display_dates 
                 WHERE id = $1`
         
-        var event models.HistoricalEvent
-        var tagsJSON []byte
-        err := r.db.QueryRow(query, id).Scan(
-                &event.ID, &event.Name, &event.Description, &event.Latitude,
-                &event.Longitude, &event.EventDate, &event.Era, &event.LensType, &event.Source, &event.DisplayDate, &event.DatasetID, &event.CreatedBy, &event.UpdatedBy, &event.CreatedAt, &event.UpdatedAt, &event.NameEn, &event.NameRu, &event.DescriptionEn, &event.DescriptionRu, &tagsJSON)
-        
+        event, err := scanEvent(r.db.QueryRow(query, id))
         if err != nil {
                 if err == sql.ErrNoRows {
                         return nil, fmt.Errorf("event with id %d not found", id)
@@ -135,33 +239,56 @@ func (r *EventRepository) GetByID(id int) (*models.HistoricalEvent, error) {
                 return nil, fmt.Errorf("failed to get event by id: %w", err)
         }
         
-        // Parse tags JSON
-        if len(tagsJSON) > 0 {
-                var tags []models.Tag
-                if err := json.Unmarshal(tagsJSON, &tags); err != nil {
-                        log.Printf("Error unmarshaling tags for event %d: %v", event.ID, err)
-                        tags = []models.Tag{}
-                }
-                event.Tags = tags
-        } else {
-                event.Tags = []models.Tag{}
+        return &event, nil
+}
+
+// GetCreatedBy returns the ID of the user who created an event, or nil when
+// it is unknown (imported before authorship was recorded, or user deleted)
+func (r *EventRepository) GetCreatedBy(id int) (*int, error) {
+        var createdBy *int
+        err := r.db.QueryRow(`SELECT created_by FROM events WHERE id = $1 AND deleted_at IS NULL`, id).Scan(&createdBy)
+        if err == sql.ErrNoRows {
+                return nil, fmt.Errorf("event with id %d not found", id)
+        }
+        if err != nil {
+                return nil, fmt.Errorf("failed to get event author: %w", err)
         }
         
-        return &event, nil
+        return createdBy, nil
 }
 
 // Create creates a new event in the database
 func (r *EventRepository) Create(event *models.HistoricalEvent) (*models.HistoricalEvent, error) {
+        return createEvent(r.db, event)
+}
+
+// CreateTx creates a new event as part of the transaction tx
+func (r *EventRepository) CreateTx(tx *sql.Tx, event *models.HistoricalEvent) (*models.HistoricalEvent, error) {
+        return createEvent(tx, event)
+}
+
+func createEvent(q querier, event *models.HistoricalEvent) (*models.HistoricalEvent, error) {
         query := `
-                INSERT INTO events (name, description, latitude, longitude, event_date, era, lens_type, source, dataset_id, created_by, name_en, name_ru, description_en, description_ru) 
-                VALUES ($1, $2, $3::double precision, $4::double precision, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) 
-                RETURNING id`
+                INSERT INTO events (name, description, latitude, longitude, event_date, era, lens_type, source, dataset_id, created_by, name_en, name_ru, description_en, description_ru,
+                                    date_precision, date_circa, date_earliest, date_earliest_era, date_latest, date_latest_era, end_date, end_era, calendar, external_id, status) 
+                VALUES ($1, $2, $3::double precision, $4::double precision, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25) 
+                RETURNING id, version`
         
         var createdEvent = *event
+        if createdEvent.DatePrecision == "" {
+                createdEvent.DatePrecision = models.DatePrecisionDay
+        }
+        if createdEvent.EventDate.Calendar == "" {
+                createdEvent.EventDate.Calendar = models.DefaultCalendar(createdEvent.EventDate)
+        }
+        if createdEvent.Status == "" {
+                createdEvent.Status = models.EventStatusPublished
+        }
         
-        err := r.db.QueryRow(query, event.Name, event.Description, event.Latitude, 
-                event.Longitude, event.EventDate, event.Era, event.LensType, event.Source, event.DatasetID, event.CreatedBy, event.NameEn, event.NameRu, event.DescriptionEn, event.DescriptionRu).
-                Scan(&createdEvent.ID)
+        err := q.QueryRow(query, event.Name, event.Description, event.Latitude, 
+                event.Longitude, createdEvent.EventDate, event.EventDate.Era, event.LensType, event.Source, event.DatasetID, event.CreatedBy, event.NameEn, event.NameRu, event.DescriptionEn, event.DescriptionRu,
+                createdEvent.DatePrecision, event.Circa, event.EarliestDate, optionalEra(event.EarliestDate), event.LatestDate, optionalEra(event.LatestDate), event.EndDate, optionalEra(event.EndDate), createdEvent.EventDate.Calendar, event.ExternalID, createdEvent.Status).
+                Scan(&createdEvent.ID, &createdEvent.Version)
         
         if err != nil {
                 return nil, fmt.Errorf("failed to create event: %w", err)
@@ -170,19 +297,22 @@ func (r *EventRepository) Create(event *models.HistoricalEvent) (*models.Histori
         return &createdEvent, nil
 }
 
-// GetInBoundingBox retrieves events within a geographical bounding box
-func (r *EventRepository) GetInBoundingBox(minLat, minLng, maxLat, maxLng float64) ([]models.HistoricalEvent, error) {
-        query := `
-                SELECT e.id, e.name, e.description, e.latitude, e.longitude, e.event_date, e.era, e.lens_type, e.source,
-                       e.display_date, e.dataset_id, e.created_by, e.updated_by, e.created_at, e.updated_at,
-                       e.name_en, e.name_ru, e.description_en, e.description_ru, e.tags,
-                       ST_X(ev.location::geometry) as lng, ST_Y(ev.location::geometry) as lat
-                FROM events_with_display_dates e
-                JOIN events ev ON e.id = ev.id
-                WHERE ev.location && ST_MakeEnvelope($1, $2, $3, $4, 4326)
-                ORDER BY e.astronomical_year DESC`
-        
-        rows, err := r.db.Query(query, minLng, minLat, maxLng, maxLat)
+// GetInBoundingBox retrieves events within a geographical bounding box that
+// match the filter
+func (r *EventRepository) GetInBoundingBox(minLat, minLng, maxLat, maxLng float64, filter models.EventFilter) ([]models.HistoricalEvent, error) {
+        whereClause, args := buildFilterClause(filter, []interface{}{minLng, minLat, maxLng, maxLat})
+        query := fmt.Sprintf(`
+                SELECT %s, lng, lat
+                FROM (
+                        SELECT e.*, ST_X(ev.location::geometry) AS lng, ST_Y(ev.location::geometry) AS lat
+                        FROM events_with_display_dates e
+                        JOIN events ev ON e.id = ev.id
+                        WHERE ev.location && ST_MakeEnvelope($1, $2, $3, $4, 4326)
+                ) inside
+                %s
+                ORDER BY astronomical_year DESC, id DESC`, eventColumns, whereClause)
+        
+        rows, err := r.db.Query(query, args...)
         if err != nil {
                 return nil, fmt.Errorf("bounding box query failed: %w", err)
         }
@@ -190,32 +320,13 @@ func (r *EventRepository) GetInBoundingBox(minLat, minLng, maxLat, maxLng float6
         
         var events []models.HistoricalEvent
         for rows.Next() {
-                var event models.HistoricalEvent
                 var lng, lat float64
-                var tagsJSON []byte
-                
-                err := rows.Scan(&event.ID, &event.Name, &event.Description, 
-                        &event.Latitude, &event.Longitude, &event.EventDate, &event.Era, &event.LensType, &event.Source,
-                        &event.DisplayDate, &event.DatasetID, &event.CreatedBy, &event.UpdatedBy, &event.CreatedAt, &event.UpdatedAt,
-                        &event.NameEn, &event.NameRu, &event.DescriptionEn, &event.DescriptionRu, &tagsJSON,
-                        &lng, &lat)
+                event, err := scanEvent(rows, &lng, &lat)
                 if err != nil {
                         log.Printf("Error scanning bounding box event: %v", err)
                         continue
                 }
                 
-                // Parse tags JSON
-                if len(tagsJSON) > 0 {
-                        var tags []models.Tag
-                        if err := json.Unmarshal(tagsJSON, &tags); err != nil {
-                                log.Printf("Error unmarshaling tags for event %d: %v", event.ID, err)
-                                tags = []models.Tag{}
-                        }
-                        event.Tags = tags
-                } else {
-                        event.Tags = []models.Tag{}
-                }
-                
                 // Update with PostGIS coordinates for accuracy
                 event.Longitude = lng
                 event.Latitude = lat
@@ -229,41 +340,313 @@ func (r *EventRepository) GetInBoundingBox(minLat, minLng, maxLat, maxLng float6
         return events, nil
 }
 
-// Update updates an existing event in the database
+// GetTile renders the events inside a web-mercator tile that match the filter
+// as a Mapbox Vector Tile with a single "events" layer. Returns an empty
+// slice when no event falls in the tile.
+func (r *EventRepository) GetTile(z, x, y int, filter models.EventFilter, locale string) ([]byte, error) {
+        whereClause, args := buildFilterClause(filter, []interface{}{z, x, y, locale})
+        query := fmt.Sprintf(`
+                SELECT COALESCE(ST_AsMVT(tile, 'events', 4096, 'geom'), ''::bytea)
+                FROM (
+                        SELECT id,
+                               COALESCE(NULLIF(CASE WHEN $4 = 'ru' THEN name_ru ELSE name_en END, ''), name) AS name,
+                               lens_type, display_date, era, astronomical_year::float8 AS astronomical_year,
+                               tags->0->>'color' AS color,
+                               (SELECT string_agg(t->>'id', ',') FROM json_array_elements(tags) t) AS tag_ids,
+                               geom
+                        FROM (
+                                SELECT e.*, ST_AsMVTGeom(ST_Transform(ev.location::geometry, 3857), ST_TileEnvelope($1, $2, $3), 4096, 64, true) AS geom
+                                FROM events_with_display_dates e
+                                JOIN events ev ON e.id = ev.id
+                                WHERE ev.location && ST_Transform(ST_TileEnvelope($1, $2, $3), 4326)
+                        ) inside
+                        %s
+                ) tile
+                WHERE geom IS NOT NULL`, whereClause)
+        
+        var tile []byte
+        if err := r.db.QueryRow(query, args...).Scan(&tile); err != nil {
+                return nil, fmt.Errorf("event tile query failed: %w", err)
+        }
+        return tile, nil
+}
+
+// GetClusters groups the events inside a bounding box that match the filter
+// into grid cells of cellSize degrees. Each cell becomes one cluster at the
+// centroid of its events, with per-lens and per-tag counts.
+func (r *EventRepository) GetClusters(minLat, minLng, maxLat, maxLng, cellSize float64, filter models.EventFilter) ([]models.EventCluster, error) {
+        whereClause, args := buildFilterClause(filter, []interface{}{minLng, minLat, maxLng, maxLat, cellSize})
+        query := fmt.Sprintf(`
+                WITH points AS (
+                        SELECT id, lens_type, tags, lng, lat,
+                               floor(lng / $5)::bigint AS cell_x, floor(lat / $5)::bigint AS cell_y
+                        FROM (
+                                SELECT e.*, ST_X(ev.location::geometry) AS lng, ST_Y(ev.location::geometry) AS lat
+                                FROM events_with_display_dates e
+                                JOIN events ev ON e.id = ev.id
+                                WHERE ev.location && ST_MakeEnvelope($1, $2, $3, $4, 4326)
+                        ) inside
+                        %s
+                ),
+                cells AS (
+                        SELECT cell_x, cell_y, COUNT(*) AS point_count, AVG(lat) AS lat, AVG(lng) AS lng,
+                               MIN(lng) AS min_lng, MIN(lat) AS min_lat, MAX(lng) AS max_lng, MAX(lat) AS max_lat,
+                               MIN(id) AS first_id
+                        FROM points
+                        GROUP BY cell_x, cell_y
+                ),
+                lens AS (
+                        SELECT cell_x, cell_y, json_object_agg(lens_type, n) AS lens_counts
+                        FROM (SELECT cell_x, cell_y, lens_type, COUNT(*) AS n FROM points GROUP BY cell_x, cell_y, lens_type) l
+                        GROUP BY cell_x, cell_y
+                ),
+                tag AS (
+                        SELECT cell_x, cell_y, json_object_agg(tag_id, n) AS tag_counts
+                        FROM (
+                                SELECT p.cell_x, p.cell_y, (t->>'id')::int AS tag_id, COUNT(*) AS n
+                                FROM points p
+                                CROSS JOIN LATERAL json_array_elements(p.tags) t
+                                GROUP BY p.cell_x, p.cell_y, tag_id
+                        ) x
+                        GROUP BY cell_x, cell_y
+                )
+                SELECT c.point_count, c.lat, c.lng, c.min_lng, c.min_lat, c.max_lng, c.max_lat, c.first_id,
+                       l.lens_counts, COALESCE(t.tag_counts, '{}'::json)
+                FROM cells c
+                JOIN lens l USING (cell_x, cell_y)
+                LEFT JOIN tag t USING (cell_x, cell_y)
+                ORDER BY c.point_count DESC`, whereClause)
+        
+        rows, err := r.db.Query(query, args...)
+        if err != nil {
+                return nil, fmt.Errorf("cluster query failed: %w", err)
+        }
+        defer rows.Close()
+        
+        clusters := []models.EventCluster{}
+        for rows.Next() {
+                var cluster models.EventCluster
+                var firstID int
+                var lensJSON, tagJSON []byte
+                
+                err := rows.Scan(&cluster.Count, &cluster.Latitude, &cluster.Longitude,
+                        &cluster.Bounds[0], &cluster.Bounds[1], &cluster.Bounds[2], &cluster.Bounds[3],
+                        &firstID, &lensJSON, &tagJSON)
+                if err != nil {
+                        return nil, fmt.Errorf("failed to scan cluster: %w", err)
+                }
+                
+                if err := json.Unmarshal(lensJSON, &cluster.LensCounts); err != nil {
+                        return nil, fmt.Errorf("failed to decode cluster lens counts: %w", err)
+                }
+                if err := json.Unmarshal(tagJSON, &cluster.TagCounts); err != nil {
+                        return nil, fmt.Errorf("failed to decode cluster tag counts: %w", err)
+                }
+                if cluster.Count == 1 {
+                        cluster.EventID = &firstID
+                }
+                clusters = append(clusters, cluster)
+        }
+        
+        if err = rows.Err(); err != nil {
+                return nil, fmt.Errorf("error iterating over clusters: %w", err)
+        }
+        
+        return clusters, nil
+}
+
+// GetInRadius retrieves published events within radiusMeters (great-circle distance)
+// of the given point, using ST_DWithin on the geography column. Each event carries its
+// distance in meters. Results are ordered by distance or, if sortByDistance is
+// false, chronologically.
+func (r *EventRepository) GetInRadius(lat, lng, radiusMeters float64, sortByDistance bool) ([]models.HistoricalEvent, error) {
+        orderByClause := "astronomical_year ASC"
+        if sortByDistance {
+                orderByClause = "distance ASC, astronomical_year ASC"
+        }
+        
+        query := fmt.Sprintf(`
+                SELECT %s, distance
+                FROM (
+                        SELECT e.*, ST_Distance(ev.location, center.point) AS distance
+                        FROM events_with_display_dates e
+                        JOIN events ev ON e.id = ev.id
+                        CROSS JOIN (SELECT ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography AS point) center
+                        WHERE ST_DWithin(ev.location, center.point, $3) AND e.status = $4
+                ) nearby
+                ORDER BY %s`, eventColumns, orderByClause)
+        
+        rows, err := r.db.Query(query, lng, lat, radiusMeters, models.EventStatusPublished)
+        if err != nil {
+                return nil, fmt.Errorf("radius query failed: %w", err)
+        }
+        defer rows.Close()
+        
+        var events []models.HistoricalEvent
+        for rows.Next() {
+                var distance float64
+                event, err := scanEvent(rows, &distance)
+                if err != nil {
+                        log.Printf("Error scanning radius event: %v", err)
+                        continue
+                }
+                event.Distance = &distance
+                events = append(events, event)
+        }
+        
+        if err = rows.Err(); err != nil {
+                return nil, fmt.Errorf("error iterating over radius events: %w", err)
+        }
+        
+        return events, nil
+}
+
+// Search runs a full-text query against the English and Russian search vectors
+// and returns the best-ranked matches that also satisfy the filter. Snippets are
+// taken from the preferred locale when it matched, otherwise from the other one.
+func (r *EventRepository) Search(text, locale string, filter models.EventFilter, limit int) ([]models.EventSearchResult, error) {
+        args := []interface{}{text, locale}
+        whereClause, args := buildFilterClause(filter, args)
+        args = append(args, limit)
+        
+        const headlineOptions = `'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10'`
+        query := fmt.Sprintf(`
+                WITH q AS (
+                        SELECT websearch_to_tsquery('english', $1) AS en,
+                               websearch_to_tsquery('russian', $1) AS ru
+                ), matches AS (
+                        SELECT e.*,
+                               CASE WHEN ev.search_en @@ q.en THEN ts_rank(ev.search_en, q.en) ELSE 0 END AS rank_en,
+                               CASE WHEN ev.search_ru @@ q.ru THEN ts_rank(ev.search_ru, q.ru) ELSE 0 END AS rank_ru
+                        FROM events_with_display_dates e
+                        JOIN events ev ON ev.id = e.id
+                        CROSS JOIN q
+                        WHERE ev.search_en @@ q.en OR ev.search_ru @@ q.ru
+                ), ranked AS (
+                        SELECT m.*,
+                               GREATEST(rank_en, rank_ru) AS rank,
+                               CASE WHEN ($2 = 'ru' AND rank_ru > 0) OR rank_en = 0 THEN 'ru' ELSE 'en' END AS matched_locale
+                        FROM matches m
+                        %s
+                        ORDER BY rank DESC, astronomical_year ASC
+                        LIMIT $%d
+                )
+                SELECT %s, rank, matched_locale,
+                       CASE WHEN matched_locale = 'ru'
+                            THEN ts_headline('russian', name_ru, q.ru, %s)
+                            ELSE ts_headline('english', name_en, q.en, %s) END,
+                       CASE WHEN matched_locale = 'ru'
+                            THEN ts_headline('russian', COALESCE(description_ru, ''), q.ru, %s)
+                            ELSE ts_headline('english', COALESCE(description_en, ''), q.en, %s) END
+                FROM ranked
+                CROSS JOIN q
+                ORDER BY rank DESC, astronomical_year ASC`,
+                whereClause, len(args), eventColumns,
+                headlineOptions, headlineOptions, headlineOptions, headlineOptions)
+        
+        rows, err := r.db.Query(query, args...)
+        if err != nil {
+                return nil, fmt.Errorf("search query failed: %w", err)
+        }
+        defer rows.Close()
+        
+        results := []models.EventSearchResult{}
+        for rows.Next() {
+                var result models.EventSearchResult
+                event, err := scanEvent(rows, &result.Rank, &result.MatchedLocale, &result.NameSnippet, &result.DescriptionSnippet)
+                if err != nil {
+                        log.Printf("Error scanning search result: %v", err)
+                        continue
+                }
+                result.Event = event
+                results = append(results, result)
+        }
+        
+        if err = rows.Err(); err != nil {
+                return nil, fmt.Errorf("error iterating over search results: %w", err)
+        }
+        
+        return results, nil
+}
+
+// Update updates an existing event in the database. An event without an
+// ExternalID keeps the one it has, since editor updates do not carry it, and
+// likewise an event without a Status.
+// When event.Version is set, the update only applies to that version of the
+// event and returns ErrVersionConflict otherwise.
 func (r *EventRepository) Update(event *models.HistoricalEvent) (*models.HistoricalEvent, error) {
+        return updateEvent(r.db, event)
+}
+
+// UpdateTx updates an existing event as part of the transaction tx
+func (r *EventRepository) UpdateTx(tx *sql.Tx, event *models.HistoricalEvent) (*models.HistoricalEvent, error) {
+        return updateEvent(tx, event)
+}
+
+func updateEvent(q querier, event *models.HistoricalEvent) (*models.HistoricalEvent, error) {
         query := `
                 UPDATE events 
                 SET name = $2, description = $3, latitude = $4::double precision, longitude = $5::double precision, 
                     event_date = $6, era = $7, lens_type = $8, source = $9, dataset_id = $10, updated_by = $11, updated_at = $12,
-                    name_en = $13, name_ru = $14, description_en = $15, description_ru = $16
-                WHERE id = $1
-                RETURNING id, name, description, latitude, longitude, event_date, era, lens_type, source, dataset_id, created_at, updated_at, created_by, updated_by, name_en, name_ru, description_en, description_ru`
+                    name_en = $13, name_ru = $14, description_en = $15, description_ru = $16,
+                    date_precision = $17, date_circa = $18, date_earliest = $19, date_earliest_era = $20, date_latest = $21, date_latest_era = $22,
+                    end_date = $23, end_era = $24, calendar = $25, external_id = COALESCE($26, external_id),
+                    status = COALESCE(NULLIF($28, ''), status), version = version + 1
+                WHERE id = $1 AND deleted_at IS NULL AND ` + versionCheck(27) + `
+                RETURNING id, name, description, latitude, longitude, event_date, lens_type, source, dataset_id, created_at, updated_at, created_by, updated_by, name_en, name_ru, description_en, description_ru,
+                          date_precision, date_circa, date_earliest, date_latest, end_date, calendar, external_id, version,
+                          status, review_note, reviewed_by, reviewed_at`
         
         var updatedEvent models.HistoricalEvent
+        var updatedCalendar string
+        precision := event.DatePrecision
+        if precision == "" {
+                precision = models.DatePrecisionDay
+        }
+        eventDate := event.EventDate
+        if eventDate.Calendar == "" {
+                eventDate.Calendar = models.DefaultCalendar(eventDate)
+        }
+        calendar := eventDate.Calendar
         
-        err := r.db.QueryRow(query, event.ID, event.Name, event.Description, 
-                event.Latitude, event.Longitude, event.EventDate, event.Era, event.LensType, event.Source, event.DatasetID,
-                event.UpdatedBy, event.UpdatedAt, event.NameEn, event.NameRu, event.DescriptionEn, event.DescriptionRu).
+        err := q.QueryRow(query, event.ID, event.Name, event.Description, 
+                event.Latitude, event.Longitude, eventDate, eventDate.Era, event.LensType, event.Source, event.DatasetID,
+                event.UpdatedBy, event.UpdatedAt, event.NameEn, event.NameRu, event.DescriptionEn, event.DescriptionRu,
+                precision, event.Circa, event.EarliestDate, optionalEra(event.EarliestDate), event.LatestDate, optionalEra(event.LatestDate), event.EndDate, optionalEra(event.EndDate), calendar, event.ExternalID, event.Version, event.Status).
                 Scan(&updatedEvent.ID, &updatedEvent.Name, &updatedEvent.Description, 
                 &updatedEvent.Latitude, &updatedEvent.Longitude, &updatedEvent.EventDate, 
-                &updatedEvent.Era, &updatedEvent.LensType, &updatedEvent.Source, &updatedEvent.DatasetID, &updatedEvent.CreatedAt,
-                &updatedEvent.UpdatedAt, &updatedEvent.CreatedBy, &updatedEvent.UpdatedBy, &updatedEvent.NameEn, &updatedEvent.NameRu, &updatedEvent.DescriptionEn, &updatedEvent.DescriptionRu)
+                &updatedEvent.LensType, &updatedEvent.Source, &updatedEvent.DatasetID, &updatedEvent.CreatedAt,
+                &updatedEvent.UpdatedAt, &updatedEvent.CreatedBy, &updatedEvent.UpdatedBy, &updatedEvent.NameEn, &updatedEvent.NameRu, &updatedEvent.DescriptionEn, &updatedEvent.DescriptionRu,
+                &updatedEvent.DatePrecision, &updatedEvent.Circa, &updatedEvent.EarliestDate, &updatedEvent.LatestDate,
+                &updatedEvent.EndDate, &updatedCalendar, &updatedEvent.ExternalID, &updatedEvent.Version,
+                &updatedEvent.Status, &updatedEvent.ReviewNote, &updatedEvent.ReviewedBy, &updatedEvent.ReviewedAt)
         
         if err != nil {
                 if err == sql.ErrNoRows {
-                        return nil, fmt.Errorf("event with id %d not found", event.ID)
+                        return nil, staleOrMissing(q, "events", "deleted_at IS NULL", event.ID, fmt.Errorf("event with id %d not found", event.ID))
                 }
                 return nil, fmt.Errorf("failed to update event: %w", err)
         }
+        inEventCalendar(&updatedEvent, updatedCalendar)
         
         return &updatedEvent, nil
 }
 
-// Delete removes an event from the database
-func (r *EventRepository) Delete(id int) error {
-        query := `DELETE FROM events WHERE id = $1`
+// Delete moves an event to the trash. A non-zero version must be the current
+// version of the event, or ErrVersionConflict is returned.
+func (r *EventRepository) Delete(id, version int) error {
+        return deleteEvent(r.db, id, version)
+}
+
+// DeleteTx moves an event to the trash as part of the transaction tx
+func (r *EventRepository) DeleteTx(tx *sql.Tx, id, version int) error {
+        return deleteEvent(tx, id, version)
+}
+
+func deleteEvent(q querier, id, version int) error {
+        query := `UPDATE events SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL AND ` + versionCheck(2)
         
-        result, err := r.db.Exec(query, id)
+        result, err := q.Exec(query, id, version)
         if err != nil {
                 return fmt.Errorf("failed to delete event: %w", err)
         }
@@ -274,12 +657,72 @@ func (r *EventRepository) Delete(id int) error {
         }
         
         if rowsAffected == 0 {
-                return fmt.Errorf("event with id %d not found", id)
+                return staleOrMissing(q, "events", "deleted_at IS NULL", id, fmt.Errorf("event with id %d not found", id))
         }
         
         return nil
 }
 
+// RestoreTx takes an event out of the trash as part of the transaction tx.
+// Returns ErrNotInTrash, ErrDatasetInTrash or ErrRestoreConflict.
+func (r *EventRepository) RestoreTx(tx *sql.Tx, id int) error {
+        var datasetDeleted bool
+        err := tx.QueryRow(`
+                SELECT d.deleted_at IS NOT NULL
+                FROM events e
+                LEFT JOIN event_datasets d ON d.id = e.dataset_id
+                WHERE e.id = $1 AND e.deleted_at IS NOT NULL`, id).Scan(&datasetDeleted)
+        if err == sql.ErrNoRows {
+                return ErrNotInTrash
+        }
+        if err != nil {
+                return fmt.Errorf("failed to get deleted event: %w", err)
+        }
+        if datasetDeleted {
+                return ErrDatasetInTrash
+        }
+        
+        return restoreRow(tx, "events", id)
+}
+
+// ReviewTx approves or rejects a pending event as part of the transaction tx:
+// it gets the status (published or rejected), the reviewer and the note. A
+// non-zero version must be the current version of the event. Returns
+// ErrNotPending or ErrVersionConflict.
+func (r *EventRepository) ReviewTx(tx *sql.Tx, id, version int, status string, note *string, reviewerID *int) error {
+        query := `
+                UPDATE events
+                SET status = $3, review_note = $4, reviewed_by = $5, reviewed_at = CURRENT_TIMESTAMP, version = version + 1
+                WHERE id = $1 AND deleted_at IS NULL AND status = $6 AND ` + versionCheck(2)
+        
+        result, err := tx.Exec(query, id, version, status, note, reviewerID, models.EventStatusPending)
+        if err != nil {
+                return fmt.Errorf("failed to review event: %w", err)
+        }
+        
+        rowsAffected, err := result.RowsAffected()
+        if err != nil {
+                return fmt.Errorf("failed to get affected rows: %w", err)
+        }
+        if rowsAffected > 0 {
+                return nil
+        }
+        
+        // Another reviewer may have been first: report that before a stale version
+        var current string
+        err = tx.QueryRow(`SELECT status FROM events WHERE id = $1 AND deleted_at IS NULL`, id).Scan(&current)
+        if err == sql.ErrNoRows {
+                return fmt.Errorf("event with id %d not found", id)
+        }
+        if err != nil {
+                return fmt.Errorf("failed to get event status: %w", err)
+        }
+        if current != models.EventStatusPending {
+                return ErrNotPending
+        }
+        return ErrVersionConflict
+}
+
 // ValidateCoordinates validates latitude and longitude values
 func (r *EventRepository) ValidateCoordinates(lat, lng float64) error {
         if lat < -90 || lat > 90 {
@@ -293,47 +736,38 @@ func (r *EventRepository) ValidateCoordinates(lat, lng float64) error {
 
 // GetPaginated retrieves events with pagination support (kept for backward compatibility)
 func (r *EventRepository) GetPaginated(page, limit int) ([]models.HistoricalEvent, int, error) {
-        return r.GetPaginatedWithSort(page, limit, "date", "asc")
+        return r.GetPaginatedWithSort(page, limit, "date", "asc", models.EventFilter{})
 }
 
-// GetPaginatedWithSort retrieves events with pagination and sorting support
-func (r *EventRepository) GetPaginatedWithSort(page, limit int, sortField, sortDirection string) ([]models.HistoricalEvent, int, error) {
+// GetPaginatedWithSort retrieves filtered events with pagination and sorting support
+func (r *EventRepository) GetPaginatedWithSort(page, limit int, sortField, sortDirection string, filter models.EventFilter) ([]models.HistoricalEvent, int, error) {
         // Calculate offset
         offset := (page - 1) * limit
         
+        whereClause, args := buildFilterClause(filter, nil)
+        
         // Get total count first
-        countQuery := `SELECT COUNT(*) FROM events_with_display_dates`
+        countQuery := `SELECT COUNT(*) FROM events_with_display_dates ` + whereClause
         var total int
-        err := r.db.QueryRow(countQuery).Scan(&total)
+        err := r.db.QueryRow(countQuery, args...).Scan(&total)
         if err != nil {
                 return nil, 0, fmt.Errorf("failed to count events: %w", err)
         }
         
         // Build ORDER BY clause based on sort parameters
-        var orderByClause string
-        switch sortField {
-        case "name":
-                orderByClause = "name"
-        case "date":
-                orderByClause = "astronomical_year"
-        case "type":
-                orderByClause = "lens_type"
-        default:
-                orderByClause = "astronomical_year" // Default to date sorting
-        }
-        
-        if sortDirection != "asc" && sortDirection != "desc" {
-                sortDirection = "asc" // Default to ascending
-        }
+        orderByClause, _ := sortColumn(sortField)
+        sortDirection = normalizeSortDirection(sortDirection)
         
         // Get paginated events with dynamic sorting
+        args = append(args, limit, offset)
         query := fmt.Sprintf(`
-                SELECT id, name, description, latitude, longitude, event_date, era, lens_type, display_date, created_by, updated_by, created_at, updated_at, name_en, name_ru, description_en, description_ru, tags
+                SELECT %s
                 FROM events_with_display_dates 
-                ORDER BY %s %s
-                LIMIT $1 OFFSET $2`, orderByClause, sortDirection)
+                %s
+                ORDER BY %s %s, id %s
+                LIMIT $%d OFFSET $%d`, eventColumns, whereClause, orderByClause, sortDirection, sortDirection, len(args)-1, len(args))
         
-        rows, err := r.db.Query(query, limit, offset)
+        rows, err := r.db.Query(query, args...)
         if err != nil {
                 return nil, 0, fmt.Errorf("failed to query paginated events: %w", err)
         }
@@ -342,28 +776,11 @@ func (r *EventRepository) GetPaginatedWithSort(page, limit int, sortField, sortD
         var events []models.HistoricalEvent
         
         for rows.Next() {
-                var event models.HistoricalEvent
-                var tagsJSON []byte
-                
-                err := rows.Scan(&event.ID, &event.Name, &event.Description, &event.Latitude, 
-                        &event.Longitude, &event.EventDate, &event.Era, &event.LensType, &event.DisplayDate, &event.CreatedBy, &event.UpdatedBy, &event.CreatedAt, &event.UpdatedAt, &event.NameEn, &event.NameRu, &event.DescriptionEn, &event.DescriptionRu, &tagsJSON)
+                event, err := scanEvent(rows)
                 if err != nil {
                         log.Printf("Error scanning paginated event: %v", err)
                         continue
                 }
-                
-                // Parse tags JSON
-                if len(tagsJSON) > 0 {
-                        var tags []models.Tag
-                        if err := json.Unmarshal(tagsJSON, &tags); err != nil {
-                                log.Printf("Error unmarshaling tags for event %d: %v", event.ID, err)
-                                tags = []models.Tag{}
-                        }
-                        event.Tags = tags
-                } else {
-                        event.Tags = []models.Tag{}
-                }
-                
                 events = append(events, event)
         }
         
@@ -372,4 +789,141 @@ func (r *EventRepository) GetPaginatedWithSort(page, limit int, sortField, sortD
         }
         
         return events, total, nil
-}
\ No newline at end of file
+}
+
+// GetByStatus retrieves a page of the events with the given status, least
+// recently changed first, along with their total count. Serves the
+// moderation queue.
+func (r *EventRepository) GetByStatus(status string, page, limit int) ([]models.HistoricalEvent, int, error) {
+        var total int
+        err := r.db.QueryRow(`SELECT COUNT(*) FROM events WHERE status = $1 AND deleted_at IS NULL`, status).Scan(&total)
+        if err != nil {
+                return nil, 0, fmt.Errorf("failed to count events: %w", err)
+        }
+        
+        query := `
+                SELECT ` + eventColumns + `
+                FROM events_with_display_dates
+                WHERE status = $1
+                ORDER BY updated_at ASC, id ASC
+                LIMIT $2 OFFSET $3`
+        
+        rows, err := r.db.Query(query, status, limit, (page-1)*limit)
+        if err != nil {
+                return nil, 0, fmt.Errorf("failed to query events by status: %w", err)
+        }
+        defer rows.Close()
+        
+        events := []models.HistoricalEvent{}
+        for rows.Next() {
+                event, err := scanEvent(rows)
+                if err != nil {
+                        return nil, 0, fmt.Errorf("failed to scan event: %w", err)
+                }
+                events = append(events, event)
+        }
+        
+        if err = rows.Err(); err != nil {
+                return nil, 0, fmt.Errorf("error iterating over events: %w", err)
+        }
+        
+        return events, total, nil
+}
+
+// NormalizeSortField maps a requested sort field to one of "date", "name" or "type"
+func NormalizeSortField(sortField string) string {
+        switch sortField {
+        case "name", "type":
+                return sortField
+        default:
+                return "date" // Default to date sorting
+        }
+}
+
+// sortColumn returns the view column and SQL type backing a sort field
+func sortColumn(sortField string) (column, sqlType string) {
+        switch NormalizeSortField(sortField) {
+        case "name":
+                return "name", "text"
+        case "type":
+                return "lens_type", "text"
+        default:
+                return "astronomical_year", "numeric"
+        }
+}
+
+// normalizeSortDirection defaults anything other than "desc" to ascending
+func normalizeSortDirection(sortDirection string) string {
+        if sortDirection != "desc" {
+                return "asc"
+        }
+        return sortDirection
+}
+
+// GetPageAfter retrieves filtered events using keyset pagination on
+// (sort key, id). Unlike GetPaginatedWithSort it does not count the whole
+// result set and is stable while rows are inserted or edited between pages.
+// A nil cursor starts from the beginning. The returned cursor is nil when
+// there are no further rows.
+func (r *EventRepository) GetPageAfter(cursor *models.EventCursor, limit int, sortField, sortDirection string, filter models.EventFilter) ([]models.HistoricalEvent, *models.EventCursor, error) {
+        sortField = NormalizeSortField(sortField)
+        sortDirection = normalizeSortDirection(sortDirection)
+        column, sqlType := sortColumn(sortField)
+        
+        whereClause, args := buildFilterClause(filter, nil)
+        
+        if cursor != nil {
+                comparison := ">"
+                if sortDirection == "desc" {
+                        comparison = "<"
+                }
+                args = append(args, cursor.Key, cursor.ID)
+                whereClause += fmt.Sprintf(" AND (%s, id) %s ($%d::%s, $%d)", column, comparison, len(args)-1, sqlType, len(args))
+        }
+        
+        // Fetch one extra row to learn whether another page exists
+        args = append(args, limit+1)
+        query := fmt.Sprintf(`
+                SELECT %s, %s::text
+                FROM events_with_display_dates 
+                %s
+                ORDER BY %s %s, id %s
+                LIMIT $%d`, eventColumns, column, whereClause, column, sortDirection, sortDirection, len(args))
+        
+        rows, err := r.db.Query(query, args...)
+        if err != nil {
+                return nil, nil, fmt.Errorf("failed to query events page: %w", err)
+        }
+        defer rows.Close()
+        
+        var events []models.HistoricalEvent
+        var keys []string
+        
+        for rows.Next() {
+                var key string
+                event, err := scanEvent(rows, &key)
+                if err != nil {
+                        return nil, nil, fmt.Errorf("error scanning events page: %w", err)
+                }
+                events = append(events, event)
+                keys = append(keys, key)
+        }
+        
+        if err = rows.Err(); err != nil {
+                return nil, nil, fmt.Errorf("error iterating over events page: %w", err)
+        }
+        
+        if len(events) <= limit {
+                return events, nil, nil
+        }
+        
+        events = events[:limit]
+        next := &models.EventCursor{
+                SortField:     sortField,
+                SortDirection: sortDirection,
+                Filter:        filter.CacheKey(),
+                Key:           keys[limit-1],
+                ID:            events[limit-1].ID,
+        }
+        return events, next, nil
+}
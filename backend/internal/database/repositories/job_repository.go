@@ -0,0 +1,141 @@
+package repositories
+
+import (
+        "database/sql"
+        "encoding/json"
+        "fmt"
+        "historical-events-backend/internal/models"
+)
+
+// JobRepository handles import job records
+type JobRepository struct {
+        db *sql.DB
+}
+
+// NewJobRepository creates a new job repository
+func NewJobRepository(db *sql.DB) *JobRepository {
+        return &JobRepository{db: db}
+}
+
+const jobColumns = `id, kind, status, dataset_id, COALESCE(filename, ''), total_rows, progress, progress_total, result, error, created_by, created_at, started_at, finished_at, updated_at`
+
+func scanJob(row rowScanner) (*models.ImportJob, error) {
+        var job models.ImportJob
+        var result []byte
+        err := row.Scan(&job.ID, &job.Kind, &job.Status, &job.DatasetID, &job.Filename, &job.TotalRows, &job.Progress, &job.ProgressTotal,
+                &result, &job.Error, &job.CreatedBy, &job.CreatedAt, &job.StartedAt, &job.FinishedAt, &job.UpdatedAt)
+        if err != nil {
+                return nil, err
+        }
+        if len(result) > 0 {
+                job.Result = json.RawMessage(result)
+        }
+        return &job, nil
+}
+
+// Create records a new queued job
+func (r *JobRepository) Create(job *models.ImportJob) (*models.ImportJob, error) {
+        query := `
+                INSERT INTO import_jobs (kind, status, dataset_id, filename, total_rows, created_by)
+                VALUES ($1, $2, $3, $4, $5, $6)
+                RETURNING ` + jobColumns
+        
+        created, err := scanJob(r.db.QueryRow(query, job.Kind, models.ImportJobQueued, job.DatasetID, job.Filename, job.TotalRows, job.CreatedBy))
+        if err != nil {
+                return nil, fmt.Errorf("failed to create import job: %w", err)
+        }
+        return created, nil
+}
+
+// GetByID retrieves a job by ID
+func (r *JobRepository) GetByID(id int) (*models.ImportJob, error) {
+        query := `SELECT ` + jobColumns + ` FROM import_jobs WHERE id = $1`
+        
+        job, err := scanJob(r.db.QueryRow(query, id))
+        if err != nil {
+                if err == sql.ErrNoRows {
+                        return nil, fmt.Errorf("import job not found")
+                }
+                return nil, fmt.Errorf("failed to get import job: %w", err)
+        }
+        return job, nil
+}
+
+// GetRecent retrieves the most recent jobs, newest first. A non-nil createdBy
+// keeps only the jobs that user started.
+func (r *JobRepository) GetRecent(limit int, createdBy *int) ([]models.ImportJob, error) {
+        query := `SELECT ` + jobColumns + ` FROM import_jobs
+                WHERE $2::INTEGER IS NULL OR created_by = $2
+                ORDER BY created_at DESC, id DESC LIMIT $1`
+        
+        rows, err := r.db.Query(query, limit, createdBy)
+        if err != nil {
+                return nil, fmt.Errorf("failed to query import jobs: %w", err)
+        }
+        defer rows.Close()
+        
+        jobs := []models.ImportJob{}
+        for rows.Next() {
+                job, err := scanJob(rows)
+                if err != nil {
+                        return nil, fmt.Errorf("failed to scan import job: %w", err)
+                }
+                jobs = append(jobs, *job)
+        }
+        return jobs, rows.Err()
+}
+
+// MarkRunning records that a job has started
+func (r *JobRepository) MarkRunning(id int) error {
+        _, err := r.db.Exec(`
+                UPDATE import_jobs SET status = $2, started_at = NOW(), updated_at = NOW()
+                WHERE id = $1`, id, models.ImportJobRunning)
+        if err != nil {
+                return fmt.Errorf("failed to start import job: %w", err)
+        }
+        return nil
+}
+
+// UpdateProgress records how many events a running job has written
+func (r *JobRepository) UpdateProgress(id, progress, total int) error {
+        _, err := r.db.Exec(`
+                UPDATE import_jobs SET progress = $2, progress_total = $3, updated_at = NOW()
+                WHERE id = $1`, id, progress, total)
+        if err != nil {
+                return fmt.Errorf("failed to update import job progress: %w", err)
+        }
+        return nil
+}
+
+// Finish records the final status of a job with its result and error message
+func (r *JobRepository) Finish(id int, status string, datasetID *int, progress, total int, result interface{}, errMessage *string) error {
+        var resultJSON []byte
+        if result != nil {
+                var err error
+                if resultJSON, err = json.Marshal(result); err != nil {
+                        return fmt.Errorf("failed to encode import job result: %w", err)
+                }
+        }
+        
+        _, err := r.db.Exec(`
+                UPDATE import_jobs
+                SET status = $2, dataset_id = COALESCE($3, dataset_id), progress = $4, progress_total = $5,
+                    result = $6, error = $7, finished_at = NOW(), updated_at = NOW()
+                WHERE id = $1`, id, status, datasetID, progress, total, resultJSON, errMessage)
+        if err != nil {
+                return fmt.Errorf("failed to finish import job: %w", err)
+        }
+        return nil
+}
+
+// FailUnfinished marks every queued or running job as failed. It is called on
+// startup for jobs that were interrupted by a crash or restart.
+func (r *JobRepository) FailUnfinished(message string) (int64, error) {
+        result, err := r.db.Exec(`
+                UPDATE import_jobs SET status = $1, error = $2, finished_at = NOW(), updated_at = NOW()
+                WHERE status IN ($3, $4)`, models.ImportJobFailed, message, models.ImportJobQueued, models.ImportJobRunning)
+        if err != nil {
+                return 0, fmt.Errorf("failed to fail unfinished import jobs: %w", err)
+        }
+        return result.RowsAffected()
+}
@@ -0,0 +1,176 @@
+package repositories
+
+import (
+        "context"
+        "database/sql"
+        "fmt"
+        "historical-events-backend/internal/models"
+        "strings"
+)
+
+// LintRepository runs the data quality checks that are done in SQL
+type LintRepository struct {
+        db *sql.DB
+}
+
+// NewLintRepository creates a new lint repository
+func NewLintRepository(db *sql.DB) *LintRepository {
+        return &LintRepository{db: db}
+}
+
+// lintCheck is a data quality check. The query returns the id, name and a
+// detail of each offending record; a message with a %s verb shows the detail.
+type lintCheck struct {
+        code     string
+        severity string
+        kind     string
+        message  string
+        query    string
+}
+
+// eventLintName is the display name of an event in lint queries
+const eventLintName = `COALESCE(NULLIF(e.name_en, ''), e.name)`
+
+var lintChecks = []lintCheck{
+        {
+                code: models.LintNullIsland, severity: models.LintSeverityError, kind: models.LintEvent,
+                message: "event is at latitude 0, longitude 0; its coordinates are probably missing",
+                query: `SELECT e.id, ` + eventLintName + `, '' FROM events e
+                        WHERE e.deleted_at IS NULL AND e.latitude = 0 AND e.longitude = 0 ORDER BY e.id`,
+        },
+        {
+                // A tag with the name of a region declares that the event happened there
+                code: models.LintOutsideRegion, severity: models.LintSeverityWarning, kind: models.LintEvent,
+                message: "event is outside region %s, named by one of its tags",
+                query: `SELECT DISTINCT e.id, ` + eventLintName + `, COALESCE(NULLIF(r.name_en, ''), r.name) || ' (#' || r.id || ')'
+                        FROM events e
+                        JOIN event_tags et ON et.event_id = e.id
+                        JOIN tags t ON t.id = et.tag_id AND t.deleted_at IS NULL
+                        JOIN regions r ON lower(t.name) IN (lower(r.name), lower(r.name_en), lower(r.name_ru)) AND r.deleted_at IS NULL
+                        WHERE e.deleted_at IS NULL AND r.geom IS NOT NULL
+                          AND CASE WHEN ST_IsValid(r.geom) THEN NOT ST_Covers(r.geom, e.location::geometry) ELSE false END
+                        ORDER BY e.id`,
+        },
+        {
+                code: models.LintMissingTranslation, severity: models.LintSeverityWarning, kind: models.LintEvent,
+                message: "Russian %s missing",
+                query: `SELECT e.id, ` + eventLintName + `,
+                               CASE
+                                   WHEN NOT missing_name THEN 'description is'
+                                   WHEN missing_description THEN 'name and description are'
+                                   ELSE 'name is'
+                               END
+                        FROM events e
+                        CROSS JOIN LATERAL (
+                                SELECT COALESCE(e.name_ru, '') = '' AS missing_name,
+                                       COALESCE(e.description_en, '') <> '' AND COALESCE(e.description_ru, '') = '' AS missing_description
+                        ) m
+                        WHERE e.deleted_at IS NULL AND (missing_name OR missing_description)
+                        ORDER BY e.id`,
+        },
+        {
+                code: models.LintIdenticalDescriptions, severity: models.LintSeverityWarning, kind: models.LintEvent,
+                message: "English and Russian descriptions are identical; one of them is probably untranslated",
+                query: `SELECT e.id, ` + eventLintName + `, '' FROM events e
+                        WHERE e.deleted_at IS NULL AND COALESCE(e.description_en, '') <> '' AND e.description_en = e.description_ru
+                        ORDER BY e.id`,
+        },
+        {
+                code: models.LintUnusedTag, severity: models.LintSeverityInfo, kind: models.LintTag,
+                message: "tag is not attached to any event",
+                query: `SELECT t.id, t.name, '' FROM tags t
+                        WHERE t.deleted_at IS NULL
+                          AND NOT EXISTS (
+                                SELECT 1 FROM event_tags et
+                                JOIN events e ON e.id = et.event_id AND e.deleted_at IS NULL
+                                WHERE et.tag_id = t.id
+                          )
+                        ORDER BY t.id`,
+        },
+        {
+                code: models.LintTemplateRange, severity: models.LintSeverityError, kind: models.LintTemplate,
+                message: "template starts after it ends (%s)",
+                query: `SELECT id, name, start_display_date || ' – ' || end_display_date
+                        FROM date_templates_with_display
+                        WHERE start_date > end_date
+                        ORDER BY id`,
+        },
+        {
+                code: models.LintInvalidRegionGeometry, severity: models.LintSeverityError, kind: models.LintRegion,
+                message: "region geometry is invalid: %s",
+                query: `SELECT id, COALESCE(NULLIF(name_en, ''), name),
+                               CASE
+                                   WHEN geom IS NULL THEN COALESCE('unreadable GeoJSON: ' || region_geometry_error(geojson), 'no geometry')
+                                   WHEN ST_IsEmpty(geom) THEN 'no geometry'
+                                   WHEN NOT ST_IsValid(geom) THEN ST_IsValidReason(geom)
+                                   ELSE 'not a polygon (' || GeometryType(geom) || ')'
+                               END
+                        FROM regions
+                        WHERE deleted_at IS NULL
+                          AND (geom IS NULL OR ST_IsEmpty(geom) OR NOT ST_IsValid(geom) OR ST_Dimension(geom) < 2)
+                        ORDER BY id`,
+        },
+}
+
+// Check runs the SQL data quality checks and adds their findings to report
+func (r *LintRepository) Check(ctx context.Context, report *models.LintReport) error {
+        for _, check := range lintChecks {
+                if err := r.runCheck(ctx, check, report); err != nil {
+                        return err
+                }
+        }
+        return nil
+}
+
+func (r *LintRepository) runCheck(ctx context.Context, check lintCheck, report *models.LintReport) error {
+        rows, err := r.db.QueryContext(ctx, check.query)
+        if err != nil {
+                return fmt.Errorf("failed to run %s check: %w", check.code, err)
+        }
+        defer rows.Close()
+
+        for rows.Next() {
+                var id int
+                var name, detail sql.NullString
+                if err := rows.Scan(&id, &name, &detail); err != nil {
+                        return fmt.Errorf("failed to scan %s finding: %w", check.code, err)
+                }
+                message := check.message
+                if strings.Contains(message, "%s") {
+                        message = fmt.Sprintf(message, detail.String)
+                }
+                report.Add(models.LintFinding{Severity: check.severity, Code: check.code, Kind: check.kind, ID: id, Name: name.String, Message: message})
+        }
+        return rows.Err()
+}
+
+// LintSource is the source link of an event
+type LintSource struct {
+        EventID int
+        Name    string
+        Source  string
+}
+
+// GetEventSources returns the events that have a source, ordered by ID
+func (r *LintRepository) GetEventSources(ctx context.Context) ([]LintSource, error) {
+        rows, err := r.db.QueryContext(ctx, `
+                SELECT e.id, `+eventLintName+`, e.source FROM events e
+                WHERE e.deleted_at IS NULL AND COALESCE(e.source, '') <> ''
+                ORDER BY e.id`)
+        if err != nil {
+                return nil, fmt.Errorf("failed to query event sources: %w", err)
+        }
+        defer rows.Close()
+
+        var sources []LintSource
+        for rows.Next() {
+                var source LintSource
+                var name sql.NullString
+                if err := rows.Scan(&source.EventID, &name, &source.Source); err != nil {
+                        return nil, fmt.Errorf("failed to scan event source: %w", err)
+                }
+                source.Name = name.String
+                sources = append(sources, source)
+        }
+        return sources, rows.Err()
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"historical-events-backend/internal/models"
 )
@@ -18,8 +19,9 @@ func (r *RegionRepository) GetAll() ([]models.Region, error) {
 	query := `
 		SELECT id, name, name_en, name_ru, description, description_en, description_ru,
 		       geojson, color, fill_opacity, border_color, border_width,
-		       created_at, updated_at
+		       created_at, updated_at, version
 		FROM regions
+		WHERE deleted_at IS NULL
 		ORDER BY name ASC`
 
 	rows, err := r.db.Query(query)
@@ -36,7 +38,7 @@ func (r *RegionRepository) GetAll() ([]models.Region, error) {
 			&region.Description, &region.DescriptionEn, &region.DescriptionRu,
 			&region.GeoJSON, &region.Color, &region.FillOpacity,
 			&region.BorderColor, &region.BorderWidth,
-			&region.CreatedAt, &region.UpdatedAt,
+			&region.CreatedAt, &region.UpdatedAt, &region.Version,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan region: %w", err)
@@ -55,9 +57,9 @@ func (r *RegionRepository) GetByID(id int) (*models.Region, error) {
 	query := `
 		SELECT id, name, name_en, name_ru, description, description_en, description_ru,
 		       geojson, color, fill_opacity, border_color, border_width,
-		       created_at, updated_at
+		       created_at, updated_at, version
 		FROM regions
-		WHERE id = $1`
+		WHERE id = $1 AND deleted_at IS NULL`
 
 	var region models.Region
 	err := r.db.QueryRow(query, id).Scan(
@@ -65,7 +67,7 @@ func (r *RegionRepository) GetByID(id int) (*models.Region, error) {
 		&region.Description, &region.DescriptionEn, &region.DescriptionRu,
 		&region.GeoJSON, &region.Color, &region.FillOpacity,
 		&region.BorderColor, &region.BorderWidth,
-		&region.CreatedAt, &region.UpdatedAt,
+		&region.CreatedAt, &region.UpdatedAt, &region.Version,
 	)
 
 	if err == sql.ErrNoRows {
@@ -82,10 +84,10 @@ func (r *RegionRepository) GetByTemplateID(templateID int) ([]models.Region, err
 	query := `
 		SELECT r.id, r.name, r.name_en, r.name_ru, r.description, r.description_en, r.description_ru,
 		       r.geojson, r.color, r.fill_opacity, r.border_color, r.border_width,
-		       r.created_at, r.updated_at
+		       r.created_at, r.updated_at, r.version
 		FROM regions r
 		JOIN template_regions tr ON r.id = tr.region_id
-		WHERE tr.template_id = $1
+		WHERE tr.template_id = $1 AND r.deleted_at IS NULL
 		ORDER BY r.name ASC`
 
 	rows, err := r.db.Query(query, templateID)
@@ -102,7 +104,7 @@ func (r *RegionRepository) GetByTemplateID(templateID int) ([]models.Region, err
 			&region.Description, &region.DescriptionEn, &region.DescriptionRu,
 			&region.GeoJSON, &region.Color, &region.FillOpacity,
 			&region.BorderColor, &region.BorderWidth,
-			&region.CreatedAt, &region.UpdatedAt,
+			&region.CreatedAt, &region.UpdatedAt, &region.Version,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan region: %w", err)
@@ -146,7 +148,7 @@ func (r *RegionRepository) Create(region *models.RegionCreate) (*models.Region,
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id, name, name_en, name_ru, description, description_en, description_ru,
 		          geojson, color, fill_opacity, border_color, border_width,
-		          created_at, updated_at`
+		          created_at, updated_at, version`
 
 	var created models.Region
 	err := r.db.QueryRow(query,
@@ -158,7 +160,7 @@ func (r *RegionRepository) Create(region *models.RegionCreate) (*models.Region,
 		&created.Description, &created.DescriptionEn, &created.DescriptionRu,
 		&created.GeoJSON, &created.Color, &created.FillOpacity,
 		&created.BorderColor, &created.BorderWidth,
-		&created.CreatedAt, &created.UpdatedAt,
+		&created.CreatedAt, &created.UpdatedAt, &created.Version,
 	)
 
 	if err != nil {
@@ -177,11 +179,17 @@ func (r *RegionRepository) Create(region *models.RegionCreate) (*models.Region,
 	return &created, nil
 }
 
-func (r *RegionRepository) Update(id int, region *models.RegionUpdate) (*models.Region, error) {
+// Update applies the set fields of region to a region. A non-zero version
+// must be the current version of the region, or ErrVersionConflict is
+// returned; so is a concurrent update between reading and writing the region.
+func (r *RegionRepository) Update(id, version int, region *models.RegionUpdate) (*models.Region, error) {
 	existing, err := r.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
+	if version != 0 && existing.Version != version {
+		return nil, ErrVersionConflict
+	}
 
 	if region.NameEn != nil {
 		existing.NameEn = *region.NameEn
@@ -219,26 +227,29 @@ func (r *RegionRepository) Update(id int, region *models.RegionUpdate) (*models.
 		    description = $5, description_en = $6, description_ru = $7,
 		    geojson = $8, color = $9, fill_opacity = $10,
 		    border_color = $11, border_width = $12,
-		    updated_at = CURRENT_TIMESTAMP
-		WHERE id = $1
+		    updated_at = CURRENT_TIMESTAMP, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND version = $13
 		RETURNING id, name, name_en, name_ru, description, description_en, description_ru,
 		          geojson, color, fill_opacity, border_color, border_width,
-		          created_at, updated_at`
+		          created_at, updated_at, version`
 
 	var updated models.Region
 	err = r.db.QueryRow(query, id,
 		existing.Name, existing.NameEn, existing.NameRu,
 		existing.Description, existing.DescriptionEn, existing.DescriptionRu,
 		existing.GeoJSON, existing.Color, existing.FillOpacity,
-		existing.BorderColor, existing.BorderWidth,
+		existing.BorderColor, existing.BorderWidth, existing.Version,
 	).Scan(
 		&updated.ID, &updated.Name, &updated.NameEn, &updated.NameRu,
 		&updated.Description, &updated.DescriptionEn, &updated.DescriptionRu,
 		&updated.GeoJSON, &updated.Color, &updated.FillOpacity,
 		&updated.BorderColor, &updated.BorderWidth,
-		&updated.CreatedAt, &updated.UpdatedAt,
+		&updated.CreatedAt, &updated.UpdatedAt, &updated.Version,
 	)
 
+	if err == sql.ErrNoRows {
+		return nil, staleOrMissing(r.db, "regions", "deleted_at IS NULL", id, fmt.Errorf("region not found"))
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to update region: %w", err)
 	}
@@ -259,10 +270,13 @@ func (r *RegionRepository) Update(id int, region *models.RegionUpdate) (*models.
 	return &updated, nil
 }
 
-func (r *RegionRepository) Delete(id int) error {
-	query := `DELETE FROM regions WHERE id = $1`
+// Delete moves a region to the trash. Its template links are kept, hidden
+// until the region is restored. A non-zero version must be the current
+// version of the region, or ErrVersionConflict is returned.
+func (r *RegionRepository) Delete(id, version int) error {
+	query := `UPDATE regions SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL AND ` + versionCheck(2)
 
-	result, err := r.db.Exec(query, id)
+	result, err := r.db.Exec(query, id, version)
 	if err != nil {
 		return fmt.Errorf("failed to delete region: %w", err)
 	}
@@ -273,12 +287,18 @@ func (r *RegionRepository) Delete(id int) error {
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("region not found")
+		return staleOrMissing(r.db, "regions", "deleted_at IS NULL", id, fmt.Errorf("region not found"))
 	}
 
 	return nil
 }
 
+// Restore takes a region out of the trash. Returns ErrNotInTrash when the
+// region is missing or not deleted.
+func (r *RegionRepository) Restore(id int) error {
+	return restoreRow(r.db, "regions", id)
+}
+
 func (r *RegionRepository) LinkToTemplate(regionID, templateID int) error {
 	query := `
 		INSERT INTO template_regions (region_id, template_id)
@@ -337,3 +357,75 @@ func (r *RegionRepository) GetTemplateIDsByRegion(regionID int) ([]int, error) {
 
 	return templateIDs, nil
 }
+
+// GeoJSONError returns why PostGIS cannot read geojson as a geometry, or ""
+// when it can. Such GeoJSON would be stored without geom and never drawn.
+func (r *RegionRepository) GeoJSONError(geojson json.RawMessage) (string, error) {
+	var problem sql.NullString
+	if err := r.db.QueryRow(`SELECT region_geometry_error($1::jsonb)`, string(geojson)).Scan(&problem); err != nil {
+		return "", fmt.Errorf("failed to check region geometry: %w", err)
+	}
+	return problem.String, nil
+}
+
+// GetTile renders the regions intersecting a web-mercator tile as a Mapbox
+// Vector Tile with a single "regions" layer. When templateID is set, only the
+// regions linked to that template are included. Geometry is simplified to
+// about one pixel at the tile's zoom before clipping. Returns an empty slice
+// when no region falls in the tile.
+func (r *RegionRepository) GetTile(z, x, y int, templateID *int, locale string) ([]byte, error) {
+	query := `
+		SELECT COALESCE(ST_AsMVT(tile, 'regions', 4096, 'geom'), ''::bytea)
+		FROM (
+			SELECT r.id,
+			       COALESCE(NULLIF(CASE WHEN $5 = 'ru' THEN r.name_ru ELSE r.name_en END, ''), r.name) AS name,
+			       r.color, r.fill_opacity, r.border_color, r.border_width,
+			       ST_AsMVTGeom(
+			           ST_SimplifyPreserveTopology(ST_Transform(r.geom, 3857), 40075016.686 / (256 * 2 ^ $1)),
+			           ST_TileEnvelope($1, $2, $3), 4096, 64, true
+			       ) AS geom
+			FROM regions r
+			WHERE r.geom && ST_Transform(ST_TileEnvelope($1, $2, $3), 4326)
+			  AND r.deleted_at IS NULL
+			  AND ($4::int IS NULL OR r.id IN (SELECT region_id FROM template_regions WHERE template_id = $4))
+		) tile
+		WHERE geom IS NOT NULL`
+
+	var tile []byte
+	if err := r.db.QueryRow(query, z, x, y, templateID, locale).Scan(&tile); err != nil {
+		return nil, fmt.Errorf("failed to render region tile %d/%d/%d: %w", z, x, y, err)
+	}
+	return tile, nil
+}
+
+// GetKMLGeometries returns the geometry of every region linked to a template,
+// rendered as KML by PostGIS, keyed by region ID
+func (r *RegionRepository) GetKMLGeometries(templateID int) (map[int]string, error) {
+	query := `
+		SELECT r.id, ST_AsKML(r.geom)
+		FROM regions r
+		JOIN template_regions tr ON r.id = tr.region_id
+		WHERE tr.template_id = $1 AND r.geom IS NOT NULL AND r.deleted_at IS NULL`
+
+	rows, err := r.db.Query(query, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query region geometries for template %d: %w", templateID, err)
+	}
+	defer rows.Close()
+
+	geometries := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var kml string
+		if err := rows.Scan(&id, &kml); err != nil {
+			return nil, fmt.Errorf("failed to scan region geometry: %w", err)
+		}
+		geometries[id] = kml
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over region geometries: %w", err)
+	}
+
+	return geometries, nil
+}
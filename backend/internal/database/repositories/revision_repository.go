@@ -0,0 +1,293 @@
+package repositories
+
+import (
+        "database/sql"
+        "encoding/json"
+        "errors"
+        "fmt"
+        "historical-events-backend/internal/models"
+
+        "github.com/lib/pq"
+)
+
+// RevisionRepository handles event revision history
+type RevisionRepository struct {
+        db *sql.DB
+}
+
+// NewRevisionRepository creates a new revision repository
+func NewRevisionRepository(db *sql.DB) *RevisionRepository {
+        return &RevisionRepository{db: db}
+}
+
+// RecordTx records the current state of an event as a revision, as part of
+// tx. Record a delete before deleting the event.
+func (r *RevisionRepository) RecordTx(tx *sql.Tx, eventID int, action string, authorID *int) error {
+        return recordRevisions(tx, []int{eventID}, action, authorID, nil)
+}
+
+// RecordManyTx records the current state of several events with the same
+// action and author, as part of tx
+func (r *RevisionRepository) RecordManyTx(tx *sql.Tx, eventIDs []int, action string, authorID *int) error {
+        if len(eventIDs) == 0 {
+                return nil
+        }
+        return recordRevisions(tx, eventIDs, action, authorID, nil)
+}
+
+// RecordRevertTx records the state of an event after it was reverted to the
+// revision revertedTo, as part of tx
+func (r *RevisionRepository) RecordRevertTx(tx *sql.Tx, eventID int, authorID *int, revertedTo int) error {
+        return recordRevisions(tx, []int{eventID}, models.RevisionRevert, authorID, &revertedTo)
+}
+
+// recordRevisions snapshots the events from events_with_display_dates and
+// stores the snapshots with COPY
+func recordRevisions(tx *sql.Tx, eventIDs []int, action string, authorID *int, revertedTo *int) error {
+        rows, err := tx.Query(`SELECT `+eventColumns+` FROM events_with_display_dates WHERE id = ANY($1) ORDER BY id`, pq.Array(eventIDs))
+        if err != nil {
+                return fmt.Errorf("failed to read events for revision: %w", err)
+        }
+        var snapshots []string
+        var ids []int
+        for rows.Next() {
+                event, err := scanEvent(rows)
+                if err != nil {
+                        rows.Close()
+                        return fmt.Errorf("failed to scan event for revision: %w", err)
+                }
+                snapshot, err := json.Marshal(models.NewEventSnapshot(&event))
+                if err != nil {
+                        rows.Close()
+                        return fmt.Errorf("failed to encode event snapshot: %w", err)
+                }
+                ids = append(ids, event.ID)
+                snapshots = append(snapshots, string(snapshot))
+        }
+        rows.Close()
+        if err := rows.Err(); err != nil {
+                return fmt.Errorf("error iterating over events: %w", err)
+        }
+        if len(ids) != len(eventIDs) {
+                return fmt.Errorf("event not found")
+        }
+
+        stmt, err := tx.Prepare(pq.CopyIn("event_revisions", "event_id", "action", "snapshot", "author_id", "reverted_to"))
+        if err != nil {
+                return fmt.Errorf("failed to start revision copy: %w", err)
+        }
+        defer stmt.Close()
+        for i, id := range ids {
+                if _, err := stmt.Exec(id, action, snapshots[i], authorID, revertedTo); err != nil {
+                        return fmt.Errorf("failed to record revision: %w", err)
+                }
+        }
+        if _, err := stmt.Exec(); err != nil {
+                return fmt.Errorf("failed to record revisions: %w", err)
+        }
+        return nil
+}
+
+// revisionColumns is the column list read by scanRevision
+const revisionColumns = `rv.id, rv.event_id, rv.action, rv.snapshot, rv.author_id, u.username, rv.reverted_to, rv.created_at`
+
+func scanRevision(row rowScanner) (models.EventRevision, error) {
+        var revision models.EventRevision
+        var snapshot []byte
+        var author sql.NullString
+        if err := row.Scan(&revision.ID, &revision.EventID, &revision.Action, &snapshot, &revision.AuthorID, &author, &revision.RevertedTo, &revision.CreatedAt); err != nil {
+                return revision, err
+        }
+        if err := json.Unmarshal(snapshot, &revision.Snapshot); err != nil {
+                return revision, fmt.Errorf("failed to decode event snapshot: %w", err)
+        }
+        revision.AuthorName = author.String
+        return revision, nil
+}
+
+// GetByEventID returns the revisions of an event, oldest first
+func (r *RevisionRepository) GetByEventID(eventID int) ([]models.EventRevision, error) {
+        query := `
+                SELECT ` + revisionColumns + `
+                FROM event_revisions rv
+                LEFT JOIN users u ON u.id = rv.author_id
+                WHERE rv.event_id = $1
+                ORDER BY rv.id`
+
+        rows, err := r.db.Query(query, eventID)
+        if err != nil {
+                return nil, fmt.Errorf("failed to query event revisions: %w", err)
+        }
+        defer rows.Close()
+
+        var revisions []models.EventRevision
+        for rows.Next() {
+                revision, err := scanRevision(rows)
+                if err != nil {
+                        return nil, fmt.Errorf("failed to scan event revision: %w", err)
+                }
+                revisions = append(revisions, revision)
+        }
+
+        if err = rows.Err(); err != nil {
+                return nil, fmt.Errorf("error iterating over event revisions: %w", err)
+        }
+
+        return revisions, nil
+}
+
+// GetByID returns a revision of an event
+func (r *RevisionRepository) GetByID(eventID, revisionID int) (*models.EventRevision, error) {
+        query := `
+                SELECT ` + revisionColumns + `
+                FROM event_revisions rv
+                LEFT JOIN users u ON u.id = rv.author_id
+                WHERE rv.event_id = $1 AND rv.id = $2`
+
+        revision, err := scanRevision(r.db.QueryRow(query, eventID, revisionID))
+        if err != nil {
+                if err == sql.ErrNoRows {
+                        return nil, fmt.Errorf("revision not found")
+                }
+                return nil, fmt.Errorf("failed to get event revision: %w", err)
+        }
+        return &revision, nil
+}
+
+// ErrEditNotFound is returned for an event edit that does not exist
+var ErrEditNotFound = errors.New("edit not found")
+
+// ErrEditPending is returned when another author's edit of the event is
+// already waiting for review
+var ErrEditPending = errors.New("another edit is pending")
+
+// SubmitEdit queues an edit of a published event, made against version
+// baseVersion of it, for review. The author's own pending edit of the event
+// is replaced; another author's pending edit is kept and ErrEditPending
+// returned.
+func (r *RevisionRepository) SubmitEdit(eventID, baseVersion int, snapshot models.EventSnapshot, authorID *int) (*models.EventEdit, error) {
+        data, err := json.Marshal(snapshot)
+        if err != nil {
+                return nil, fmt.Errorf("failed to encode event snapshot: %w", err)
+        }
+
+        query := `
+                INSERT INTO event_edits (event_id, base_version, snapshot, author_id)
+                VALUES ($1, $2, $3, $4)
+                ON CONFLICT (event_id) WHERE status = 'pending'
+                DO UPDATE SET base_version = EXCLUDED.base_version, snapshot = EXCLUDED.snapshot,
+                        created_at = CURRENT_TIMESTAMP
+                WHERE event_edits.author_id = EXCLUDED.author_id
+                RETURNING id, status, created_at`
+
+        edit := models.EventEdit{EventID: eventID, BaseVersion: baseVersion, Snapshot: snapshot, AuthorID: authorID}
+        if err := r.db.QueryRow(query, eventID, baseVersion, string(data), authorID).Scan(&edit.ID, &edit.Status, &edit.CreatedAt); err != nil {
+                if err == sql.ErrNoRows {
+                        return nil, ErrEditPending
+                }
+                return nil, fmt.Errorf("failed to submit event edit: %w", err)
+        }
+        return &edit, nil
+}
+
+// editColumns is the column list read by scanEdit
+const editColumns = `ed.id, ed.event_id, ed.base_version, ed.snapshot, ed.author_id, u.username, ed.status, ed.review_note, ed.reviewed_by, ed.reviewed_at, ed.created_at`
+
+func scanEdit(row rowScanner) (models.EventEdit, error) {
+        var edit models.EventEdit
+        var snapshot []byte
+        var author sql.NullString
+        if err := row.Scan(&edit.ID, &edit.EventID, &edit.BaseVersion, &snapshot, &edit.AuthorID, &author, &edit.Status, &edit.ReviewNote, &edit.ReviewedBy, &edit.ReviewedAt, &edit.CreatedAt); err != nil {
+                return edit, err
+        }
+        if err := json.Unmarshal(snapshot, &edit.Snapshot); err != nil {
+                return edit, fmt.Errorf("failed to decode event snapshot: %w", err)
+        }
+        edit.AuthorName = author.String
+        return edit, nil
+}
+
+// GetPendingEdits returns a page of the pending edits of live events, least
+// recently submitted first, and their total count
+func (r *RevisionRepository) GetPendingEdits(page, limit int) ([]models.EventEdit, int, error) {
+        var total int
+        err := r.db.QueryRow(`
+                SELECT COUNT(*)
+                FROM event_edits ed
+                JOIN events e ON e.id = ed.event_id AND e.deleted_at IS NULL
+                WHERE ed.status = 'pending'`).Scan(&total)
+        if err != nil {
+                return nil, 0, fmt.Errorf("failed to count event edits: %w", err)
+        }
+
+        query := `
+                SELECT ` + editColumns + `
+                FROM event_edits ed
+                JOIN events e ON e.id = ed.event_id AND e.deleted_at IS NULL
+                LEFT JOIN users u ON u.id = ed.author_id
+                WHERE ed.status = 'pending'
+                ORDER BY ed.created_at ASC, ed.id ASC
+                LIMIT $1 OFFSET $2`
+
+        rows, err := r.db.Query(query, limit, (page-1)*limit)
+        if err != nil {
+                return nil, 0, fmt.Errorf("failed to query event edits: %w", err)
+        }
+        defer rows.Close()
+
+        edits := []models.EventEdit{}
+        for rows.Next() {
+                edit, err := scanEdit(rows)
+                if err != nil {
+                        return nil, 0, fmt.Errorf("failed to scan event edit: %w", err)
+                }
+                edits = append(edits, edit)
+        }
+
+        if err = rows.Err(); err != nil {
+                return nil, 0, fmt.Errorf("error iterating over event edits: %w", err)
+        }
+
+        return edits, total, nil
+}
+
+// GetEdit returns an event edit, or ErrEditNotFound
+func (r *RevisionRepository) GetEdit(id int) (*models.EventEdit, error) {
+        query := `
+                SELECT ` + editColumns + `
+                FROM event_edits ed
+                LEFT JOIN users u ON u.id = ed.author_id
+                WHERE ed.id = $1`
+
+        edit, err := scanEdit(r.db.QueryRow(query, id))
+        if err != nil {
+                if err == sql.ErrNoRows {
+                        return nil, ErrEditNotFound
+                }
+                return nil, fmt.Errorf("failed to get event edit: %w", err)
+        }
+        return &edit, nil
+}
+
+// ReviewEditTx publishes or rejects a pending edit as part of the transaction
+// tx. Returns ErrNotPending when it was reviewed already.
+func (r *RevisionRepository) ReviewEditTx(tx *sql.Tx, id int, status string, note *string, reviewerID *int) error {
+        query := `
+                UPDATE event_edits
+                SET status = $2, review_note = $3, reviewed_by = $4, reviewed_at = CURRENT_TIMESTAMP
+                WHERE id = $1 AND status = 'pending'`
+
+        result, err := tx.Exec(query, id, status, note, reviewerID)
+        if err != nil {
+                return fmt.Errorf("failed to review event edit: %w", err)
+        }
+
+        rowsAffected, err := result.RowsAffected()
+        if err != nil {
+                return fmt.Errorf("failed to get affected rows: %w", err)
+        }
+        if rowsAffected == 0 {
+                return ErrNotPending
+        }
+        return nil
+}
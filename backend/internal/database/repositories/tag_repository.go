@@ -2,6 +2,7 @@ package repositories
 
 import (
         "database/sql"
+        "fmt"
         "historical-events-backend/internal/models"
 )
 
@@ -15,16 +16,30 @@ func NewTagRepository(db *sql.DB) *TagRepository {
         return &TagRepository{db: db}
 }
 
+// Begin starts a transaction for changing the tags of events together with
+// their revisions
+func (r *TagRepository) Begin() (*sql.Tx, error) {
+        tx, err := r.db.Begin()
+        if err != nil {
+                return nil, fmt.Errorf("failed to begin transaction: %w", err)
+        }
+        return tx, nil
+}
+
 // GetAllTags retrieves all tags from the database
 func (r *TagRepository) GetAllTags() ([]models.Tag, error) {
         query := `
                 SELECT t.id, t.name, t.description, t.color, t.border_color, t.key_color, t.emoji, t.weight,
                         COALESCE(et.cnt, 0) AS event_count,
-                        t.created_at, t.updated_at
+                        t.created_at, t.updated_at, t.version
                 FROM tags t
                 LEFT JOIN (
-                        SELECT tag_id, COUNT(*) AS cnt FROM event_tags GROUP BY tag_id
+                        SELECT et.tag_id, COUNT(*) AS cnt
+                        FROM event_tags et
+                        JOIN events e ON e.id = et.event_id AND e.deleted_at IS NULL AND e.status = 'published'
+                        GROUP BY et.tag_id
                 ) et ON et.tag_id = t.id
+                WHERE t.deleted_at IS NULL
                 ORDER BY name ASC`
 
         rows, err := r.db.Query(query)
@@ -48,6 +63,7 @@ func (r *TagRepository) GetAllTags() ([]models.Tag, error) {
                         &tag.EventCount,
                         &tag.CreatedAt,
                         &tag.UpdatedAt,
+                        &tag.Version,
                 )
                 if err != nil {
                         return nil, err
@@ -61,9 +77,9 @@ func (r *TagRepository) GetAllTags() ([]models.Tag, error) {
 // GetTagByID retrieves a tag by its ID
 func (r *TagRepository) GetTagByID(id int) (*models.Tag, error) {
         query := `
-                SELECT id, name, description, color, border_color, key_color, emoji, weight, created_at, updated_at
+                SELECT id, name, description, color, border_color, key_color, emoji, weight, created_at, updated_at, version
                 FROM tags
-                WHERE id = $1`
+                WHERE id = $1 AND deleted_at IS NULL`
 
         var tag models.Tag
         err := r.db.QueryRow(query, id).Scan(
@@ -77,6 +93,7 @@ func (r *TagRepository) GetTagByID(id int) (*models.Tag, error) {
                 &tag.Weight,
                 &tag.CreatedAt,
                 &tag.UpdatedAt,
+                &tag.Version,
         )
 
         if err != nil {
@@ -88,15 +105,25 @@ func (r *TagRepository) GetTagByID(id int) (*models.Tag, error) {
 
 // CreateTag creates a new tag
 func (r *TagRepository) CreateTag(tag *models.Tag) (*models.Tag, error) {
+        return createTag(r.db, tag)
+}
+
+// CreateTagTx creates a new tag as part of the transaction tx
+func (r *TagRepository) CreateTagTx(tx *sql.Tx, tag *models.Tag) (*models.Tag, error) {
+        return createTag(tx, tag)
+}
+
+func createTag(q querier, tag *models.Tag) (*models.Tag, error) {
         query := `
                 INSERT INTO tags (name, description, color, border_color, key_color, emoji, weight)
                 VALUES ($1, $2, $3, $4, $5, $6, $7)
-                RETURNING id, created_at, updated_at`
+                RETURNING id, created_at, updated_at, version`
 
-        err := r.db.QueryRow(query, tag.Name, tag.Description, tag.Color, tag.BorderColor, tag.KeyColor, tag.Emoji, tag.Weight).Scan(
+        err := q.QueryRow(query, tag.Name, tag.Description, tag.Color, tag.BorderColor, tag.KeyColor, tag.Emoji, tag.Weight).Scan(
                 &tag.ID,
                 &tag.CreatedAt,
                 &tag.UpdatedAt,
+                &tag.Version,
         )
 
         if err != nil {
@@ -106,15 +133,17 @@ func (r *TagRepository) CreateTag(tag *models.Tag) (*models.Tag, error) {
         return tag, nil
 }
 
-// UpdateTag updates an existing tag
+// UpdateTag updates an existing tag. When tag.Version is set, the update only
+// applies to that version of the tag and returns ErrVersionConflict otherwise.
 func (r *TagRepository) UpdateTag(id int, tag *models.Tag) (*models.Tag, error) {
         query := `
                 UPDATE tags 
-                SET name = $2, description = $3, color = $4, border_color = $5, key_color = $6, emoji = $7, weight = $8, updated_at = CURRENT_TIMESTAMP
-                WHERE id = $1
-                RETURNING id, name, description, color, border_color, key_color, emoji, weight, created_at, updated_at`
+                SET name = $2, description = $3, color = $4, border_color = $5, key_color = $6, emoji = $7, weight = $8, updated_at = CURRENT_TIMESTAMP,
+                    version = version + 1
+                WHERE id = $1 AND deleted_at IS NULL AND ` + versionCheck(9) + `
+                RETURNING id, name, description, color, border_color, key_color, emoji, weight, created_at, updated_at, version`
 
-        err := r.db.QueryRow(query, id, tag.Name, tag.Description, tag.Color, tag.BorderColor, tag.KeyColor, tag.Emoji, tag.Weight).Scan(
+        err := r.db.QueryRow(query, id, tag.Name, tag.Description, tag.Color, tag.BorderColor, tag.KeyColor, tag.Emoji, tag.Weight, tag.Version).Scan(
                 &tag.ID,
                 &tag.Name,
                 &tag.Description,
@@ -125,8 +154,12 @@ func (r *TagRepository) UpdateTag(id int, tag *models.Tag) (*models.Tag, error)
                 &tag.Weight,
                 &tag.CreatedAt,
                 &tag.UpdatedAt,
+                &tag.Version,
         )
 
+        if err == sql.ErrNoRows {
+                return nil, staleOrMissing(r.db, "tags", "deleted_at IS NULL", id, sql.ErrNoRows)
+        }
         if err != nil {
                 return nil, err
         }
@@ -134,11 +167,22 @@ func (r *TagRepository) UpdateTag(id int, tag *models.Tag) (*models.Tag, error)
         return tag, nil
 }
 
-// DeleteTag removes a tag from the database
-func (r *TagRepository) DeleteTag(id int) error {
-        query := `DELETE FROM tags WHERE id = $1`
+// DeleteTag moves a tag to the trash. Its events keep the link, hidden until
+// the tag is restored. A non-zero version must be the current version of the
+// tag, or ErrVersionConflict is returned.
+func (r *TagRepository) DeleteTag(id, version int) error {
+        return deleteTag(r.db, id, version)
+}
+
+// DeleteTagTx moves a tag to the trash as part of the transaction tx
+func (r *TagRepository) DeleteTagTx(tx *sql.Tx, id, version int) error {
+        return deleteTag(tx, id, version)
+}
+
+func deleteTag(q querier, id, version int) error {
+        query := `UPDATE tags SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL AND ` + versionCheck(2)
         
-        result, err := r.db.Exec(query, id)
+        result, err := q.Exec(query, id, version)
         if err != nil {
                 return err
         }
@@ -149,19 +193,25 @@ func (r *TagRepository) DeleteTag(id int) error {
         }
 
         if rowsAffected == 0 {
-                return sql.ErrNoRows
+                return staleOrMissing(q, "tags", "deleted_at IS NULL", id, sql.ErrNoRows)
         }
 
         return nil
 }
 
+// RestoreTagTx takes a tag out of the trash as part of the transaction tx.
+// Returns ErrNotInTrash, or ErrRestoreConflict when a live tag has its name.
+func (r *TagRepository) RestoreTagTx(tx *sql.Tx, id int) error {
+        return restoreRow(tx, "tags", id)
+}
+
 // GetTagsByEventID retrieves all tags for a specific event
 func (r *TagRepository) GetTagsByEventID(eventID int) ([]models.Tag, error) {
         query := `
-                SELECT t.id, t.name, t.description, t.color, t.border_color, t.key_color, t.weight, t.created_at, t.updated_at
+                SELECT t.id, t.name, t.description, t.color, t.border_color, t.key_color, t.weight, t.created_at, t.updated_at, t.version
                 FROM tags t
                 JOIN event_tags et ON t.id = et.tag_id
-                WHERE et.event_id = $1
+                WHERE et.event_id = $1 AND t.deleted_at IS NULL
                 ORDER BY t.weight DESC, t.name ASC`
 
         rows, err := r.db.Query(query, eventID)
@@ -183,6 +233,7 @@ func (r *TagRepository) GetTagsByEventID(eventID int) ([]models.Tag, error) {
                         &tag.Weight,
                         &tag.CreatedAt,
                         &tag.UpdatedAt,
+                        &tag.Version,
                 )
                 if err != nil {
                         return nil, err
@@ -193,22 +244,66 @@ func (r *TagRepository) GetTagsByEventID(eventID int) ([]models.Tag, error) {
         return tags, nil
 }
 
-// AddTagToEvent associates a tag with an event
+// GetEventIDsByTagTx returns the IDs of the live events a tag is attached
+// to, as part of the transaction tx
+func (r *TagRepository) GetEventIDsByTagTx(tx *sql.Tx, tagID int) ([]int, error) {
+        rows, err := tx.Query(`
+                SELECT et.event_id
+                FROM event_tags et
+                JOIN events e ON e.id = et.event_id AND e.deleted_at IS NULL
+                WHERE et.tag_id = $1
+                ORDER BY et.event_id`, tagID)
+        if err != nil {
+                return nil, err
+        }
+        defer rows.Close()
+
+        var ids []int
+        for rows.Next() {
+                var id int
+                if err := rows.Scan(&id); err != nil {
+                        return nil, err
+                }
+                ids = append(ids, id)
+        }
+        return ids, rows.Err()
+}
+
+// AddTagToEvent associates a tag with an event. Deleted tags are ignored.
 func (r *TagRepository) AddTagToEvent(eventID, tagID int) error {
+        return addTagToEvent(r.db, eventID, tagID)
+}
+
+// AddTagToEventTx associates a tag with an event as part of the transaction tx
+func (r *TagRepository) AddTagToEventTx(tx *sql.Tx, eventID, tagID int) error {
+        return addTagToEvent(tx, eventID, tagID)
+}
+
+func addTagToEvent(q querier, eventID, tagID int) error {
         query := `
                 INSERT INTO event_tags (event_id, tag_id)
-                VALUES ($1, $2)
+                SELECT $1, id FROM tags WHERE id = $2 AND deleted_at IS NULL
                 ON CONFLICT (event_id, tag_id) DO NOTHING`
 
-        _, err := r.db.Exec(query, eventID, tagID)
+        _, err := q.Exec(query, eventID, tagID)
         return err
 }
 
 // RemoveTagFromEvent removes the association between a tag and an event
 func (r *TagRepository) RemoveTagFromEvent(eventID, tagID int) error {
+        return removeTagFromEvent(r.db, eventID, tagID)
+}
+
+// RemoveTagFromEventTx removes the association between a tag and an event as
+// part of the transaction tx
+func (r *TagRepository) RemoveTagFromEventTx(tx *sql.Tx, eventID, tagID int) error {
+        return removeTagFromEvent(tx, eventID, tagID)
+}
+
+func removeTagFromEvent(q querier, eventID, tagID int) error {
         query := `DELETE FROM event_tags WHERE event_id = $1 AND tag_id = $2`
         
-        _, err := r.db.Exec(query, eventID, tagID)
+        _, err := q.Exec(query, eventID, tagID)
         return err
 }
 
@@ -220,19 +315,28 @@ func (r *TagRepository) SetEventTags(eventID int, tagIDs []int) error {
         }
         defer tx.Rollback()
 
+        if err := r.SetEventTagsTx(tx, eventID, tagIDs); err != nil {
+                return err
+        }
+
+        return tx.Commit()
+}
+
+// SetEventTagsTx replaces all tags for an event as part of the transaction
+// tx. Links to deleted tags are kept for when they are restored.
+func (r *TagRepository) SetEventTagsTx(tx *sql.Tx, eventID int, tagIDs []int) error {
         // Remove all existing tags for the event
-        _, err = tx.Exec("DELETE FROM event_tags WHERE event_id = $1", eventID)
+        _, err := tx.Exec("DELETE FROM event_tags WHERE event_id = $1 AND tag_id IN (SELECT id FROM tags WHERE deleted_at IS NULL)", eventID)
         if err != nil {
                 return err
         }
 
         // Add new tags
         for _, tagID := range tagIDs {
-                _, err = tx.Exec("INSERT INTO event_tags (event_id, tag_id) VALUES ($1, $2)", eventID, tagID)
-                if err != nil {
+                if err := addTagToEvent(tx, eventID, tagID); err != nil {
                         return err
                 }
         }
 
-        return tx.Commit()
+        return nil
 }
\ No newline at end of file
@@ -17,6 +17,36 @@ func NewTemplateRepository(db *sql.DB) *TemplateRepository {
         return &TemplateRepository{db: db}
 }
 
+// templateColumns is the column list read from date_templates_with_display
+// by scanTemplate
+const templateColumns = `id, group_id, group_name, name, description,
+                       name_en, name_ru, description_en, description_ru,
+                       group_name_en, group_name_ru,
+                       start_date, end_date, calendar, display_order,
+                       start_display_date, end_display_date, version`
+
+// scanTemplate scans a row selected with templateColumns. The day number
+// columns come out as Gregorian dates and are converted to the template's
+// calendar.
+func scanTemplate(row rowScanner) (models.DateTemplate, error) {
+        var template models.DateTemplate
+        var calendar string
+        
+        err := row.Scan(&template.ID, &template.GroupID, &template.GroupName,
+                &template.Name, &template.Description,
+                &template.NameEn, &template.NameRu, &template.DescriptionEn, &template.DescriptionRu,
+                &template.GroupNameEn, &template.GroupNameRu,
+                &template.StartDate, &template.EndDate, &calendar, &template.DisplayOrder,
+                &template.StartDisplayDate, &template.EndDisplayDate, &template.Version)
+        if err != nil {
+                return template, err
+        }
+        
+        template.StartDate = template.StartDate.In(calendar)
+        template.EndDate = template.EndDate.In(calendar)
+        return template, nil
+}
+
 // GetAllGroups retrieves all date template groups with localized fields
 func (r *TemplateRepository) GetAllGroups() ([]models.DateTemplateGroup, error) {
         query := `
@@ -51,14 +81,10 @@ func (r *TemplateRepository) GetAllGroups() ([]models.DateTemplateGroup, error)
 // GetTemplatesByGroup retrieves templates for a specific group with localized fields
 func (r *TemplateRepository) GetTemplatesByGroup(groupID int) ([]models.DateTemplate, error) {
         query := `
-                SELECT id, group_id, group_name, name, description,
-                       name_en, name_ru, description_en, description_ru,
-                       group_name_en, group_name_ru,
-                       start_date, start_era, end_date, end_era, display_order,
-                       start_display_date, end_display_date
+                SELECT ` + templateColumns + `
                 FROM date_templates_with_display 
                 WHERE group_id = $1
-                ORDER BY start_astronomical_year ASC`
+                ORDER BY start_date ASC`
         
         rows, err := r.db.Query(query, groupID)
         if err != nil {
@@ -68,14 +94,7 @@ func (r *TemplateRepository) GetTemplatesByGroup(groupID int) ([]models.DateTemp
         
         var templates []models.DateTemplate
         for rows.Next() {
-                var template models.DateTemplate
-                err := rows.Scan(&template.ID, &template.GroupID, &template.GroupName, 
-                        &template.Name, &template.Description,
-                        &template.NameEn, &template.NameRu, &template.DescriptionEn, &template.DescriptionRu,
-                        &template.GroupNameEn, &template.GroupNameRu,
-                        &template.StartDate, &template.StartEra,
-                        &template.EndDate, &template.EndEra, &template.DisplayOrder,
-                        &template.StartDisplayDate, &template.EndDisplayDate)
+                template, err := scanTemplate(rows)
                 if err != nil {
                         log.Printf("Error scanning date template: %v", err)
                         continue
@@ -93,13 +112,9 @@ func (r *TemplateRepository) GetTemplatesByGroup(groupID int) ([]models.DateTemp
 // GetAllTemplates retrieves all date templates across all groups with localized fields
 func (r *TemplateRepository) GetAllTemplates() ([]models.DateTemplate, error) {
         query := `
-                SELECT id, group_id, group_name, name, description,
-                       name_en, name_ru, description_en, description_ru,
-                       group_name_en, group_name_ru,
-                       start_date, start_era, end_date, end_era, display_order,
-                       start_display_date, end_display_date
+                SELECT ` + templateColumns + `
                 FROM date_templates_with_display 
-                ORDER BY group_id, start_astronomical_year ASC`
+                ORDER BY group_id, start_date ASC`
         
         rows, err := r.db.Query(query)
         if err != nil {
@@ -109,14 +124,7 @@ func (r *TemplateRepository) GetAllTemplates() ([]models.DateTemplate, error) {
         
         var templates []models.DateTemplate
         for rows.Next() {
-                var template models.DateTemplate
-                err := rows.Scan(&template.ID, &template.GroupID, &template.GroupName, 
-                        &template.Name, &template.Description,
-                        &template.NameEn, &template.NameRu, &template.DescriptionEn, &template.DescriptionRu,
-                        &template.GroupNameEn, &template.GroupNameRu,
-                        &template.StartDate, &template.StartEra,
-                        &template.EndDate, &template.EndEra, &template.DisplayOrder,
-                        &template.StartDisplayDate, &template.EndDisplayDate)
+                template, err := scanTemplate(rows)
                 if err != nil {
                         log.Printf("Error scanning date template: %v", err)
                         continue
@@ -247,19 +255,19 @@ func (r *TemplateRepository) GetTemplateCount(groupID int) (int, error) {
 func (r *TemplateRepository) CreateTemplate(template *models.DateTemplate) (*models.DateTemplate, error) {
         query := `
                 INSERT INTO date_templates (group_id, name, description, name_en, name_ru, description_en, description_ru, 
-                                            start_date, start_era, end_date, end_era, display_order)
-                VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-                RETURNING id`
+                                            start_date, start_era, end_date, end_era, calendar, display_order)
+                VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+                RETURNING id, version`
         
         err := r.db.QueryRow(query, 
                 template.GroupID,
                 template.NameEn, template.DescriptionEn,
                 template.NameEn, template.NameRu, 
                 template.DescriptionEn, template.DescriptionRu,
-                template.StartDate, template.StartEra,
-                template.EndDate, template.EndEra,
+                template.StartDate, template.StartDate.Era,
+                template.EndDate, template.EndDate.Era, template.StartDate.Calendar,
                 template.DisplayOrder,
-        ).Scan(&template.ID)
+        ).Scan(&template.ID, &template.Version)
         
         if err != nil {
                 return nil, fmt.Errorf("failed to create template: %w", err)
@@ -268,47 +276,46 @@ func (r *TemplateRepository) CreateTemplate(template *models.DateTemplate) (*mod
         return template, nil
 }
 
-// UpdateTemplate updates an existing date template
+// UpdateTemplate updates an existing date template and sets its new version.
+// When template.Version is set, the update only applies to that version of
+// the template and returns ErrVersionConflict otherwise.
 func (r *TemplateRepository) UpdateTemplate(template *models.DateTemplate) error {
         query := `
                 UPDATE date_templates 
                 SET group_id = $2, name = $3, description = $4, name_en = $5, name_ru = $6, 
                     description_en = $7, description_ru = $8, start_date = $9, start_era = $10, 
-                    end_date = $11, end_era = $12, display_order = $13
-                WHERE id = $1`
+                    end_date = $11, end_era = $12, calendar = $13, display_order = $14, version = version + 1
+                WHERE id = $1 AND ` + versionCheck(15) + `
+                RETURNING version`
         
-        result, err := r.db.Exec(query, 
+        err := r.db.QueryRow(query, 
                 template.ID,
                 template.GroupID,
                 template.NameEn, template.DescriptionEn,
                 template.NameEn, template.NameRu, 
                 template.DescriptionEn, template.DescriptionRu,
-                template.StartDate, template.StartEra,
-                template.EndDate, template.EndEra,
+                template.StartDate, template.StartDate.Era,
+                template.EndDate, template.EndDate.Era, template.StartDate.Calendar,
                 template.DisplayOrder,
-        )
+                template.Version,
+        ).Scan(&template.Version)
         
-        if err != nil {
-                return fmt.Errorf("failed to update template: %w", err)
+        if err == sql.ErrNoRows {
+                return staleOrMissing(r.db, "date_templates", "TRUE", template.ID, fmt.Errorf("template not found"))
         }
-        
-        rows, err := result.RowsAffected()
         if err != nil {
-                return fmt.Errorf("failed to check affected rows: %w", err)
-        }
-        
-        if rows == 0 {
-                return fmt.Errorf("template not found")
+                return fmt.Errorf("failed to update template: %w", err)
         }
         
         return nil
 }
 
-// DeleteTemplate deletes a date template
-func (r *TemplateRepository) DeleteTemplate(id int) error {
-        query := `DELETE FROM date_templates WHERE id = $1`
+// DeleteTemplate deletes a date template. A non-zero version must be the
+// current version of the template, or ErrVersionConflict is returned.
+func (r *TemplateRepository) DeleteTemplate(id, version int) error {
+        query := `DELETE FROM date_templates WHERE id = $1 AND ` + versionCheck(2)
         
-        result, err := r.db.Exec(query, id)
+        result, err := r.db.Exec(query, id, version)
         if err != nil {
                 return fmt.Errorf("failed to delete template: %w", err)
         }
@@ -319,7 +326,7 @@ func (r *TemplateRepository) DeleteTemplate(id int) error {
         }
         
         if rows == 0 {
-                return fmt.Errorf("template not found")
+                return staleOrMissing(r.db, "date_templates", "TRUE", id, fmt.Errorf("template not found"))
         }
         
         return nil
@@ -328,24 +335,11 @@ func (r *TemplateRepository) DeleteTemplate(id int) error {
 // GetTemplateByID retrieves a date template by ID
 func (r *TemplateRepository) GetTemplateByID(id int) (*models.DateTemplate, error) {
         query := `
-                SELECT id, group_id, group_name, name, description,
-                       name_en, name_ru, description_en, description_ru,
-                       group_name_en, group_name_ru,
-                       start_date, start_era, end_date, end_era, display_order,
-                       start_display_date, end_display_date
+                SELECT ` + templateColumns + `
                 FROM date_templates_with_display 
                 WHERE id = $1`
         
-        var template models.DateTemplate
-        err := r.db.QueryRow(query, id).Scan(
-                &template.ID, &template.GroupID, &template.GroupName,
-                &template.Name, &template.Description,
-                &template.NameEn, &template.NameRu, &template.DescriptionEn, &template.DescriptionRu,
-                &template.GroupNameEn, &template.GroupNameRu,
-                &template.StartDate, &template.StartEra,
-                &template.EndDate, &template.EndEra, &template.DisplayOrder,
-                &template.StartDisplayDate, &template.EndDisplayDate,
-        )
+        template, err := scanTemplate(r.db.QueryRow(query, id))
         
         if err == sql.ErrNoRows {
                 return nil, fmt.Errorf("template not found")
@@ -0,0 +1,172 @@
+package repositories
+
+import (
+        "database/sql"
+        "errors"
+        "fmt"
+        "historical-events-backend/internal/models"
+        "time"
+
+        "github.com/lib/pq"
+)
+
+// ErrNotInTrash is returned when restoring a row that is not soft-deleted
+var ErrNotInTrash = errors.New("not found in trash")
+
+// ErrRestoreConflict is returned when a live row has taken the name (tags) or
+// external ID (events) of the row being restored
+var ErrRestoreConflict = errors.New("conflicts with an existing row")
+
+// ErrDatasetInTrash is returned when restoring an event whose dataset is
+// deleted; the dataset has to be restored first
+var ErrDatasetInTrash = errors.New("dataset is in the trash")
+
+// restoreError maps a unique violation on restore to ErrRestoreConflict
+func restoreError(err error) error {
+        var pqErr *pq.Error
+        if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+                return ErrRestoreConflict
+        }
+        return err
+}
+
+// restoreRow clears deleted_at of one row of table, returning ErrNotInTrash
+// when the row is missing or not deleted
+func restoreRow(q querier, table string, id int) error {
+        result, err := q.Exec(`UPDATE `+table+` SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+        if err != nil {
+                return restoreError(err)
+        }
+
+        rowsAffected, err := result.RowsAffected()
+        if err != nil {
+                return err
+        }
+        if rowsAffected == 0 {
+                return ErrNotInTrash
+        }
+        return nil
+}
+
+// TrashRepository lists and purges soft-deleted events, tags, regions and
+// datasets. Deleting and restoring is done by the repository of each table.
+type TrashRepository struct {
+        db *sql.DB
+}
+
+// NewTrashRepository creates a new TrashRepository
+func NewTrashRepository(db *sql.DB) *TrashRepository {
+        return &TrashRepository{db: db}
+}
+
+// trashQueries select id, name, dataset_id, event_count and deleted_at of the
+// deleted rows of each kind, most recently deleted first
+var trashQueries = struct {
+        events, tags, regions, datasets string
+}{
+        events: `
+                SELECT e.id, COALESCE(NULLIF(e.name_en, ''), e.name), e.dataset_id, 0, e.deleted_at
+                FROM events e
+                LEFT JOIN event_datasets d ON d.id = e.dataset_id
+                WHERE e.deleted_at IS NOT NULL
+                  AND d.deleted_at IS DISTINCT FROM e.deleted_at
+                ORDER BY e.deleted_at DESC, e.id`,
+        tags: `
+                SELECT id, name, NULL::int, 0, deleted_at
+                FROM tags
+                WHERE deleted_at IS NOT NULL
+                ORDER BY deleted_at DESC, id`,
+        regions: `
+                SELECT id, name, NULL::int, 0, deleted_at
+                FROM regions
+                WHERE deleted_at IS NOT NULL
+                ORDER BY deleted_at DESC, id`,
+        datasets: `
+                SELECT d.id, d.filename, NULL::int,
+                       (SELECT COUNT(*) FROM events e WHERE e.dataset_id = d.id AND e.deleted_at = d.deleted_at),
+                       d.deleted_at
+                FROM event_datasets d
+                WHERE d.deleted_at IS NOT NULL
+                ORDER BY d.deleted_at DESC, d.id`,
+}
+
+// List returns the contents of the trash. Purge dates are left to the caller,
+// which knows the retention period.
+func (r *TrashRepository) List() (*models.Trash, error) {
+        trash := &models.Trash{}
+        for _, kind := range []struct {
+                query string
+                items *[]models.TrashItem
+        }{
+                {trashQueries.events, &trash.Events},
+                {trashQueries.tags, &trash.Tags},
+                {trashQueries.regions, &trash.Regions},
+                {trashQueries.datasets, &trash.Datasets},
+        } {
+                items, err := r.listItems(kind.query)
+                if err != nil {
+                        return nil, err
+                }
+                *kind.items = items
+        }
+        return trash, nil
+}
+
+func (r *TrashRepository) listItems(query string) ([]models.TrashItem, error) {
+        rows, err := r.db.Query(query)
+        if err != nil {
+                return nil, fmt.Errorf("failed to query trash: %w", err)
+        }
+        defer rows.Close()
+
+        items := []models.TrashItem{}
+        for rows.Next() {
+                var item models.TrashItem
+                if err := rows.Scan(&item.ID, &item.Name, &item.DatasetID, &item.EventCount, &item.DeletedAt); err != nil {
+                        return nil, fmt.Errorf("failed to scan trash item: %w", err)
+                }
+                items = append(items, item)
+        }
+
+        if err = rows.Err(); err != nil {
+                return nil, fmt.Errorf("error iterating over trash: %w", err)
+        }
+        return items, nil
+}
+
+// Purge permanently removes the rows that have been in the trash longer than
+// retention, in one transaction. Event tags and template links go with them;
+// event revisions are kept.
+func (r *TrashRepository) Purge(retention time.Duration) (models.PurgeResult, error) {
+        var result models.PurgeResult
+
+        tx, err := r.db.Begin()
+        if err != nil {
+                return result, fmt.Errorf("failed to begin transaction: %w", err)
+        }
+        defer tx.Rollback()
+
+        // Events first, so a dataset's events are gone before the dataset
+        for _, table := range []struct {
+                name  string
+                count *int64
+        }{
+                {"events", &result.Events},
+                {"tags", &result.Tags},
+                {"regions", &result.Regions},
+                {"event_datasets", &result.Datasets},
+        } {
+                res, err := tx.Exec(`DELETE FROM `+table.name+` WHERE deleted_at < CURRENT_TIMESTAMP - make_interval(secs => $1)`, retention.Seconds())
+                if err != nil {
+                        return result, fmt.Errorf("failed to purge %s: %w", table.name, err)
+                }
+                if *table.count, err = res.RowsAffected(); err != nil {
+                        return result, err
+                }
+        }
+
+        if err := tx.Commit(); err != nil {
+                return result, fmt.Errorf("failed to commit purge: %w", err)
+        }
+        return result, nil
+}
@@ -0,0 +1,40 @@
+package repositories
+
+import (
+        "database/sql"
+        "errors"
+        "fmt"
+)
+
+// ErrTransactionAborted is returned by WithSavepoint when the savepoint itself
+// could not be managed, which leaves the transaction unusable
+var ErrTransactionAborted = errors.New("transaction aborted")
+
+// querier is satisfied by both *sql.DB and *sql.Tx, so a repository method
+// can run on its own or as part of a caller's transaction
+type querier interface {
+        Exec(query string, args ...interface{}) (sql.Result, error)
+        Query(query string, args ...interface{}) (*sql.Rows, error)
+        QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// WithSavepoint runs fn inside a savepoint of tx. When fn fails, the work done
+// since the savepoint is rolled back and the transaction stays usable;
+// otherwise the savepoint is released. Errors from fn are returned unchanged;
+// savepoint failures wrap ErrTransactionAborted. name must be a plain SQL
+// identifier.
+func WithSavepoint(tx *sql.Tx, name string, fn func() error) error {
+        if _, err := tx.Exec("SAVEPOINT " + name); err != nil {
+                return fmt.Errorf("%w: failed to create savepoint: %v", ErrTransactionAborted, err)
+        }
+        if err := fn(); err != nil {
+                if _, rbErr := tx.Exec("ROLLBACK TO SAVEPOINT " + name); rbErr != nil {
+                        return fmt.Errorf("%w: rollback to savepoint failed: %v (after: %v)", ErrTransactionAborted, rbErr, err)
+                }
+                return err
+        }
+        if _, err := tx.Exec("RELEASE SAVEPOINT " + name); err != nil {
+                return fmt.Errorf("%w: failed to release savepoint: %v", ErrTransactionAborted, err)
+        }
+        return nil
+}
@@ -0,0 +1,32 @@
+package repositories
+
+import (
+        "errors"
+        "fmt"
+)
+
+// ErrVersionConflict is returned when an update or delete expects a version
+// of the row that is no longer current
+var ErrVersionConflict = errors.New("version conflict")
+
+// versionCheck is the WHERE condition of a versioned write, with the expected
+// version as parameter $n. An expected version of 0 matches any version, for
+// callers that do not take part in optimistic concurrency (imports, merges).
+func versionCheck(n int) string {
+        return fmt.Sprintf("($%d::int = 0 OR version = $%d)", n, n)
+}
+
+// staleOrMissing tells why a versioned write on table matched no row: the live
+// row exists at another version (ErrVersionConflict) or it does not exist
+// (notFound). live is the condition a row must meet to count as existing.
+func staleOrMissing(q querier, table, live string, id int, notFound error) error {
+        var exists bool
+        query := `SELECT EXISTS (SELECT 1 FROM ` + table + ` WHERE id = $1 AND ` + live + `)`
+        if err := q.QueryRow(query, id).Scan(&exists); err != nil {
+                return fmt.Errorf("failed to check %s %d: %w", table, id, err)
+        }
+        if exists {
+                return ErrVersionConflict
+        }
+        return notFound
+}
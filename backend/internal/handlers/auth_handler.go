@@ -271,22 +271,7 @@ func (h *AuthHandler) getCurrentUser(r *http.Request) *models.User {
 
 // hasAccessLevel checks if user's access level is sufficient
 func (h *AuthHandler) hasAccessLevel(userLevel, requiredLevel models.AccessLevel) bool {
-        accessLevels := map[models.AccessLevel]int{
-                models.AccessLevelGuest:  0,
-                models.AccessLevelUser:   1,
-                models.AccessLevelEditor: 2,
-                models.AccessLevelAdmin:  3,
-                models.AccessLevelSuper:  4,
-        }
-
-        userLevelNum, ok1 := accessLevels[userLevel]
-        requiredLevelNum, ok2 := accessLevels[requiredLevel]
-
-        if !ok1 || !ok2 {
-                return false
-        }
-
-        return userLevelNum >= requiredLevelNum
+        return userLevel.AtLeast(requiredLevel)
 }
 
 // User Management Methods (for admin interfaces)
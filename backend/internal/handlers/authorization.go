@@ -0,0 +1,169 @@
+package handlers
+
+import (
+        "historical-events-backend/internal/database/repositories"
+        "historical-events-backend/internal/models"
+        "historical-events-backend/pkg/response"
+        "log"
+        "net/http"
+        "strings"
+)
+
+// Permission is an operation on events, event tags or datasets
+type Permission string
+
+const (
+        PermissionCreateEvent     Permission = "create_event"
+        PermissionEditEvent       Permission = "edit_event"
+        PermissionDeleteEvent     Permission = "delete_event"
+        PermissionTagEvent        Permission = "tag_event" // Add, remove and set the tags of an event
+        PermissionRestoreEvent    Permission = "restore_event"
+        PermissionEventHistory    Permission = "event_history"    // Read the revisions of an event and revert to one
+        PermissionMergeEvents     Permission = "merge_events"     // Find and merge duplicates
+        PermissionViewUnpublished Permission = "view_unpublished" // Read draft, pending and rejected events
+        PermissionReviewEvents    Permission = "review_events"    // Publish without review, read the moderation queue, approve and reject
+        PermissionImportDataset   Permission = "import_dataset"   // Import files, create datasets
+        PermissionManageJob       Permission = "manage_job"       // Follow and cancel import jobs
+        PermissionViewDatasets    Permission = "view_datasets"    // List, read and export datasets
+        PermissionChangeDataset   Permission = "change_dataset"   // Re-import, reset the modified flag, add and remove events
+        PermissionDeleteDataset   Permission = "delete_dataset"
+        PermissionRestoreDataset  Permission = "restore_dataset"
+)
+
+// permissionRule grants a permission to users of access level Any (or above)
+// on every resource, and to users of access level Own (or above) on the
+// resources they created. Own is empty when ownership grants nothing extra.
+type permissionRule struct {
+        Any models.AccessLevel
+        Own models.AccessLevel
+}
+
+// permissions is the policy for events, event tags, datasets and import jobs.
+// Events and jobs are owned by created_by, datasets by uploaded_by. docs/access-levels.md lists
+// the same table; keep the two in sync.
+var permissions = map[Permission]permissionRule{
+        PermissionCreateEvent:     {Any: models.AccessLevelUser},
+        PermissionEditEvent:       {Any: models.AccessLevelEditor, Own: models.AccessLevelUser},
+        PermissionDeleteEvent:     {Any: models.AccessLevelEditor, Own: models.AccessLevelUser},
+        PermissionTagEvent:        {Any: models.AccessLevelEditor, Own: models.AccessLevelUser},
+        PermissionRestoreEvent:    {Any: models.AccessLevelAdmin},
+        PermissionEventHistory:    {Any: models.AccessLevelAdmin},
+        PermissionMergeEvents:     {Any: models.AccessLevelAdmin},
+        PermissionViewUnpublished: {Any: models.AccessLevelEditor, Own: models.AccessLevelUser},
+        PermissionReviewEvents:    {Any: models.AccessLevelEditor},
+        PermissionImportDataset:   {Any: models.AccessLevelEditor},
+        PermissionManageJob:       {Any: models.AccessLevelAdmin, Own: models.AccessLevelEditor},
+        PermissionViewDatasets:    {Any: models.AccessLevelEditor},
+        PermissionChangeDataset:   {Any: models.AccessLevelAdmin, Own: models.AccessLevelEditor},
+        PermissionDeleteDataset:   {Any: models.AccessLevelAdmin, Own: models.AccessLevelEditor},
+        PermissionRestoreDataset:  {Any: models.AccessLevelAdmin},
+}
+
+// minimum is the lowest access level the rule grants anything to
+func (p permissionRule) minimum() models.AccessLevel {
+        if p.Own != "" && !p.Own.AtLeast(p.Any) {
+                return p.Own
+        }
+        return p.Any
+}
+
+// can checks if user has permission p on a resource created by ownerID. A nil
+// ownerID means the owner is unknown, so only the Any level counts.
+func can(user *models.User, p Permission, ownerID *int) bool {
+        rule, ok := permissions[p]
+        if !ok || user == nil || !user.IsActive {
+                return false
+        }
+        if user.AccessLevel.AtLeast(rule.Any) {
+                return true
+        }
+        return rule.Own != "" && ownerID != nil && *ownerID == user.ID && user.AccessLevel.AtLeast(rule.Own)
+}
+
+// authorize checks permission p on a resource created by ownerID for the user
+// of the request, responding with 403 when it is not granted
+func authorize(w http.ResponseWriter, r *http.Request, p Permission, ownerID *int) bool {
+        if can(getUserFromContext(r.Context()), p, ownerID) {
+                return true
+        }
+        response.Error(w, http.StatusForbidden, "Insufficient permissions", "You can only change what you created")
+        return false
+}
+
+// RequirePermission authenticates the request and requires the lowest access
+// level permission p is granted to. For permissions with an owner rule, the
+// handler then checks the resource itself with the Authorizer.
+func (h *AuthHandler) RequirePermission(p Permission) func(http.HandlerFunc) http.HandlerFunc {
+        return h.RequireAccessLevel(permissions[p].minimum())
+}
+
+// Authorizer checks permissions on a particular event or dataset, looking up
+// its owner only when the access level alone does not grant the permission
+type Authorizer struct {
+        eventRepo   *repositories.EventRepository
+        datasetRepo *repositories.DatasetRepository
+}
+
+// NewAuthorizer creates a new Authorizer
+func NewAuthorizer(eventRepo *repositories.EventRepository, datasetRepo *repositories.DatasetRepository) *Authorizer {
+        return &Authorizer{
+                eventRepo:   eventRepo,
+                datasetRepo: datasetRepo,
+        }
+}
+
+// Event checks permission p on event id, responding with 403, or 404 when the
+// event does not exist, when it is not granted
+func (a *Authorizer) Event(w http.ResponseWriter, r *http.Request, p Permission, id int) bool {
+        if can(getUserFromContext(r.Context()), p, nil) {
+                return true
+        }
+
+        ownerID, err := a.eventRepo.GetCreatedBy(id)
+        if err != nil {
+                if strings.Contains(err.Error(), "not found") {
+                        response.NotFound(w, "Event not found")
+                        return false
+                }
+                log.Printf("Error checking permissions on event %d: %v", id, err)
+                response.InternalError(w, "Failed to check permissions")
+                return false
+        }
+        return authorize(w, r, p, ownerID)
+}
+
+// Dataset checks permission p on dataset id, responding with 403, or 404 when
+// the dataset does not exist, when it is not granted
+func (a *Authorizer) Dataset(w http.ResponseWriter, r *http.Request, p Permission, id int) bool {
+        if can(getUserFromContext(r.Context()), p, nil) {
+                return true
+        }
+
+        ownerID, err := a.datasetRepo.GetUploadedBy(id)
+        if err != nil {
+                if err.Error() == "dataset not found" {
+                        response.NotFound(w, "Dataset not found")
+                        return false
+                }
+                log.Printf("Error checking permissions on dataset %d: %v", id, err)
+                response.InternalError(w, "Failed to check permissions")
+                return false
+        }
+        return authorize(w, r, p, ownerID)
+}
+
+// DatasetChange checks that the user may move an event from dataset from to
+// dataset to (nil for none), responding like Dataset when not: adding events
+// to a dataset or taking them out of it changes the dataset, so it needs
+// PermissionChangeDataset on both. Keeping the dataset needs nothing.
+func (a *Authorizer) DatasetChange(w http.ResponseWriter, r *http.Request, from, to *int) bool {
+        if (from == nil && to == nil) || (from != nil && to != nil && *from == *to) {
+                return true
+        }
+        for _, id := range []*int{from, to} {
+                if id != nil && !a.Dataset(w, r, PermissionChangeDataset, *id) {
+                        return false
+                }
+        }
+        return true
+}
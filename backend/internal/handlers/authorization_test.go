@@ -0,0 +1,135 @@
+package handlers
+
+import (
+        "historical-events-backend/internal/models"
+        "testing"
+)
+
+// accessTable is the table in docs/access-levels.md: the lowest level allowed
+// on any event or dataset and on the user's own one ("" when owning it grants
+// nothing extra)
+var accessTable = map[Permission][2]models.AccessLevel{
+        PermissionCreateEvent:     {models.AccessLevelUser, ""},
+        PermissionEditEvent:       {models.AccessLevelEditor, models.AccessLevelUser},
+        PermissionDeleteEvent:     {models.AccessLevelEditor, models.AccessLevelUser},
+        PermissionTagEvent:        {models.AccessLevelEditor, models.AccessLevelUser},
+        PermissionRestoreEvent:    {models.AccessLevelAdmin, ""},
+        PermissionEventHistory:    {models.AccessLevelAdmin, ""},
+        PermissionMergeEvents:     {models.AccessLevelAdmin, ""},
+        PermissionViewUnpublished: {models.AccessLevelEditor, models.AccessLevelUser},
+        PermissionReviewEvents:    {models.AccessLevelEditor, ""},
+        PermissionImportDataset:   {models.AccessLevelEditor, ""},
+        PermissionManageJob:       {models.AccessLevelAdmin, models.AccessLevelEditor},
+        PermissionViewDatasets:    {models.AccessLevelEditor, ""},
+        PermissionChangeDataset:   {models.AccessLevelAdmin, models.AccessLevelEditor},
+        PermissionDeleteDataset:   {models.AccessLevelAdmin, models.AccessLevelEditor},
+        PermissionRestoreDataset:  {models.AccessLevelAdmin, ""},
+}
+
+var accessLevels = []models.AccessLevel{
+        models.AccessLevelGuest,
+        models.AccessLevelUser,
+        models.AccessLevelEditor,
+        models.AccessLevelAdmin,
+        models.AccessLevelSuper,
+}
+
+func TestPermissionsMatchDocs(t *testing.T) {
+        for p := range permissions {
+                if _, ok := accessTable[p]; !ok {
+                        t.Errorf("permission %q is missing from the access table", p)
+                }
+        }
+
+        const userID, otherID = 7, 8
+        owners := []struct {
+                name    string
+                ownerID *int
+                own     bool
+        }{
+                {"own", intPtr(userID), true},
+                {"other's", intPtr(otherID), false},
+                {"ownerless", nil, false},
+        }
+
+        for p, levels := range accessTable {
+                anyLevel, ownLevel := levels[0], levels[1]
+                for _, level := range accessLevels {
+                        user := &models.User{ID: userID, AccessLevel: level, IsActive: true}
+                        for _, owner := range owners {
+                                want := level.AtLeast(anyLevel) ||
+                                        (owner.own && ownLevel != "" && level.AtLeast(ownLevel))
+                                if got := can(user, p, owner.ownerID); got != want {
+                                        t.Errorf("can(%s, %s, %s) = %v, want %v", level, p, owner.name, got, want)
+                                }
+                        }
+                }
+        }
+}
+
+func TestCanSpotChecks(t *testing.T) {
+        own := intPtr(1)
+        other := intPtr(2)
+
+        tests := []struct {
+                level   models.AccessLevel
+                p       Permission
+                ownerID *int
+                want    bool
+        }{
+                {models.AccessLevelGuest, PermissionCreateEvent, nil, false},
+                {models.AccessLevelUser, PermissionCreateEvent, nil, true},
+                {models.AccessLevelUser, PermissionEditEvent, own, true},
+                {models.AccessLevelUser, PermissionEditEvent, other, false},
+                {models.AccessLevelUser, PermissionEditEvent, nil, false},
+                {models.AccessLevelEditor, PermissionEditEvent, other, true},
+                {models.AccessLevelUser, PermissionReviewEvents, own, false},
+                {models.AccessLevelEditor, PermissionChangeDataset, own, true},
+                {models.AccessLevelEditor, PermissionChangeDataset, other, false},
+                {models.AccessLevelEditor, PermissionChangeDataset, nil, false},
+                {models.AccessLevelAdmin, PermissionChangeDataset, other, true},
+                {models.AccessLevelEditor, PermissionDeleteDataset, own, true},
+                {models.AccessLevelEditor, PermissionRestoreDataset, own, false},
+                {models.AccessLevelSuper, PermissionRestoreDataset, nil, true},
+                {models.AccessLevelEditor, PermissionManageJob, own, true},
+                {models.AccessLevelEditor, PermissionManageJob, other, false},
+                {models.AccessLevelUser, PermissionManageJob, own, false},
+                {models.AccessLevelAdmin, PermissionManageJob, other, true},
+                {models.AccessLevelSuper, Permission("unknown"), nil, false},
+        }
+
+        for _, tt := range tests {
+                user := &models.User{ID: 1, AccessLevel: tt.level, IsActive: true}
+                if got := can(user, tt.p, tt.ownerID); got != tt.want {
+                        t.Errorf("can(%s, %s, %v) = %v, want %v", tt.level, tt.p, tt.ownerID, got, tt.want)
+                }
+        }
+}
+
+func TestCanInactiveOrAnonymous(t *testing.T) {
+        inactive := &models.User{ID: 1, AccessLevel: models.AccessLevelSuper, IsActive: false}
+        for p := range permissions {
+                if can(nil, p, nil) {
+                        t.Errorf("can(nil, %s) = true", p)
+                }
+                if can(inactive, p, intPtr(1)) {
+                        t.Errorf("can(inactive super, %s, own) = true", p)
+                }
+        }
+}
+
+func TestPermissionMinimum(t *testing.T) {
+        for p, levels := range accessTable {
+                want := levels[0]
+                if levels[1] != "" {
+                        want = levels[1]
+                }
+                if got := permissions[p].minimum(); got != want {
+                        t.Errorf("permissions[%s].minimum() = %s, want %s", p, got, want)
+                }
+        }
+}
+
+func intPtr(n int) *int {
+        return &n
+}
@@ -23,4 +23,12 @@ func getUserFromContext(ctx context.Context) *models.User {
 		return nil
 	}
 	return user
-}
\ No newline at end of file
+}
+// userIDFromContext returns the ID of the user in the request context, or
+// nil for anonymous requests
+func userIDFromContext(ctx context.Context) *int {
+	if user := getUserFromContext(ctx); user != nil {
+		return &user.ID
+	}
+	return nil
+}
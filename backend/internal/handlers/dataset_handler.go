@@ -6,25 +6,33 @@ import (
         "log"
         "net/http"
         "strconv"
-        "time"
 
         "historical-events-backend/internal/models"
         "historical-events-backend/internal/database/repositories"
+        "historical-events-backend/pkg/cache"
         "historical-events-backend/pkg/response"
 
         "github.com/gorilla/mux"
 )
 
 type DatasetHandler struct {
-        datasetRepo *repositories.DatasetRepository
-        eventRepo   *repositories.EventRepository
+        datasetRepo  *repositories.DatasetRepository
+        eventRepo    *repositories.EventRepository
+        regionRepo   *repositories.RegionRepository
+        revisionRepo *repositories.RevisionRepository
+        eventCache   *cache.EventCache
+        authorizer   *Authorizer
 }
 
 // NewDatasetHandler creates a new dataset handler
-func NewDatasetHandler(datasetRepo *repositories.DatasetRepository, eventRepo *repositories.EventRepository) *DatasetHandler {
+func NewDatasetHandler(datasetRepo *repositories.DatasetRepository, eventRepo *repositories.EventRepository, regionRepo *repositories.RegionRepository, revisionRepo *repositories.RevisionRepository, eventCache *cache.EventCache, authorizer *Authorizer) *DatasetHandler {
         return &DatasetHandler{
-                datasetRepo: datasetRepo,
-                eventRepo:   eventRepo,
+                datasetRepo:  datasetRepo,
+                eventRepo:    eventRepo,
+                regionRepo:   regionRepo,
+                revisionRepo: revisionRepo,
+                eventCache:   eventCache,
+                authorizer:   authorizer,
         }
 }
 
@@ -92,7 +100,7 @@ func (h *DatasetHandler) CreateDataset(w http.ResponseWriter, r *http.Request) {
 }
 
 // DeleteDataset handles DELETE /api/datasets/{id}
-// This will cascade delete all events associated with the dataset
+// The dataset and all its events are moved to the trash together
 func (h *DatasetHandler) DeleteDataset(w http.ResponseWriter, r *http.Request) {
         vars := mux.Vars(r)
         idStr, exists := vars["id"]
@@ -106,33 +114,92 @@ func (h *DatasetHandler) DeleteDataset(w http.ResponseWriter, r *http.Request) {
                 response.BadRequest(w, "Invalid dataset ID")
                 return
         }
+        if !h.authorizer.Dataset(w, r, PermissionDeleteDataset, id) {
+                return
+        }
 
-        // Check if dataset exists
-        _, err = h.datasetRepo.GetByID(id)
+        tx, err := h.datasetRepo.Begin()
         if err != nil {
-                if err.Error() == "dataset not found" {
-                        response.NotFound(w, "Dataset not found")
-                        return
-                }
-                log.Printf("Error checking dataset %d: %v", id, err)
-                response.InternalError(w, "Failed to check dataset")
+                log.Printf("Error deleting dataset %d: %v", id, err)
+                response.InternalError(w, "Failed to delete dataset")
                 return
         }
+        defer tx.Rollback()
 
-        // Delete dataset and all associated events
-        err = h.datasetRepo.Delete(id)
+        // Record the events as they were before they go to the trash
+        eventIDs, err := h.datasetRepo.GetEventIDsTx(tx, id)
+        if err == nil {
+                err = h.revisionRepo.RecordManyTx(tx, eventIDs, models.RevisionDelete, userIDFromContext(r.Context()))
+        }
+        if err == nil {
+                err = h.datasetRepo.DeleteTx(tx, id)
+        }
+        if err == nil {
+                err = tx.Commit()
+        }
         if err != nil {
+                if err.Error() == "dataset not found" {
+                        response.NotFound(w, "Dataset not found")
+                        return
+                }
                 log.Printf("Error deleting dataset %d: %v", id, err)
                 response.InternalError(w, "Failed to delete dataset")
                 return
         }
 
+        h.eventCache.Invalidate()
+
         response.Success(w, map[string]interface{}{
                 "id": id,
-                "message": "Dataset and all associated events deleted successfully",
+                "event_count": len(eventIDs),
+                "message": "Dataset and all associated events moved to the trash",
         }, "Dataset deleted successfully")
 }
 
+// RestoreDataset handles POST /api/datasets/{id}/restore
+// The dataset comes back with the events that were deleted together with it
+func (h *DatasetHandler) RestoreDataset(w http.ResponseWriter, r *http.Request) {
+        id, err := strconv.Atoi(mux.Vars(r)["id"])
+        if err != nil {
+                response.BadRequest(w, "Invalid dataset ID")
+                return
+        }
+
+        tx, err := h.datasetRepo.Begin()
+        if err != nil {
+                log.Printf("Error restoring dataset %d: %v", id, err)
+                response.InternalError(w, "Failed to restore dataset")
+                return
+        }
+        defer tx.Rollback()
+
+        eventIDs, err := h.datasetRepo.RestoreTx(tx, id)
+        if err == nil {
+                err = h.revisionRepo.RecordManyTx(tx, eventIDs, models.RevisionRestore, userIDFromContext(r.Context()))
+        }
+        if err == nil {
+                err = tx.Commit()
+        }
+        if err != nil {
+                writeRestoreError(w, err, "Dataset")
+                return
+        }
+
+        h.eventCache.Invalidate()
+
+        dataset, err := h.datasetRepo.GetByID(id)
+        if err != nil {
+                log.Printf("Error retrieving restored dataset %d: %v", id, err)
+                response.InternalError(w, "Dataset was restored but could not be read")
+                return
+        }
+
+        response.Success(w, map[string]interface{}{
+                "dataset": dataset,
+                "event_count": len(eventIDs),
+        }, "Dataset restored successfully")
+}
+
 // ExportDataset handles GET /api/datasets/{id}/export
 func (h *DatasetHandler) ExportDataset(w http.ResponseWriter, r *http.Request) {
         vars := mux.Vars(r)
@@ -168,64 +235,58 @@ func (h *DatasetHandler) ExportDataset(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
-        // Convert events to export format (matching import format)
-        exportEvents := make([]map[string]interface{}, len(events))
-        
-        for i, event := range events {
-                // Format date as DD.MM.YYYY
-                eventDate, err := time.Parse(time.RFC3339, event.EventDate.Format(time.RFC3339))
-                if err != nil {
-                        log.Printf("Error parsing event date for event %d: %v", event.ID, err)
-                        continue
-                }
-                
-                dateStr := fmt.Sprintf("%02d.%02d.%04d", 
-                        eventDate.Day(), eventDate.Month(), eventDate.Year())
-
-                // Extract tag names only (not IDs)
-                tagNames := make([]string, len(event.Tags))
-                for j, tag := range event.Tags {
-                        tagNames[j] = tag.Name
-                }
+        format, err := responseFormat(r, exportFormats...)
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
 
-                exportEvent := map[string]interface{}{
-                        "date":        dateStr,
-                        "era":         event.Era,
-                        "latitude":    event.Latitude,
-                        "longitude":   event.Longitude,
-                        "type":        event.LensType, // Export as "type" for compatibility
-                        "tags":        tagNames,
-                }
+        templateID, err := parseOptionalID(r.URL.Query(), "template_id")
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
 
-                // Export optimized format: "name" contains English, "name_ru" contains Russian
-                // This eliminates duplication of "name_en" when it's identical to "name"
-                if event.NameEn != "" {
-                        exportEvent["name"] = event.NameEn
-                } else if event.Name != "" {
-                        exportEvent["name"] = event.Name
-                }
-                
-                if event.NameRu != "" {
-                        exportEvent["name_ru"] = event.NameRu
-                }
+        locale := r.URL.Query().Get("locale")
+        if locale == "" {
+                locale = "en"
+        }
 
-                // Same approach for descriptions
-                if event.DescriptionEn != nil && *event.DescriptionEn != "" {
-                        exportEvent["description"] = *event.DescriptionEn
-                } else if event.Description != "" {
-                        exportEvent["description"] = event.Description
+        // GeoJSON and KML exports are for GIS tools and Google Earth and are not
+        // re-importable; CSV and XLSX use the import columns and round-trip
+        switch format {
+        case formatGeoJSON:
+                w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.geojson\"", exportBaseName(dataset.Filename)))
+                w.Header().Set("Cache-Control", "no-cache")
+                response.GeoJSON(w, models.NewEventFeatureCollection(events, locale))
+                return
+        case formatCSV, formatXLSX:
+                if err := writeTabular(w, format, exportBaseName(dataset.Filename), events); err != nil {
+                        log.Printf("Error exporting dataset %d as %s: %v", id, format, err)
+                        response.InternalError(w, "Failed to export dataset")
                 }
-                
-                if event.DescriptionRu != nil && *event.DescriptionRu != "" {
-                        exportEvent["description_ru"] = *event.DescriptionRu
+                return
+        case formatKML, formatKMZ:
+                doc := models.NewKML(exportBaseName(dataset.Filename), dataset.Description)
+                doc.AddEvents("Events", events, locale)
+                if templateID != nil {
+                        if err := addTemplateRegions(doc, h.regionRepo, *templateID, locale); err != nil {
+                                log.Printf("Error fetching regions for template %d: %v", *templateID, err)
+                                response.InternalError(w, "Failed to fetch regions for template")
+                                return
+                        }
                 }
-
-                // Include source if available
-                if event.Source != nil {
-                        exportEvent["source"] = *event.Source
+                if err := writeKML(w, doc, exportBaseName(dataset.Filename), format == formatKMZ); err != nil {
+                        log.Printf("Error exporting dataset %d as %s: %v", id, format, err)
+                        response.InternalError(w, "Failed to export dataset")
                 }
+                return
+        }
 
-                exportEvents[i] = exportEvent
+        // Convert events to export format (matching import format)
+        exportEvents := make([]map[string]interface{}, len(events))
+        for i, event := range events {
+                exportEvents[i] = datasetExportEvent(event)
         }
 
         // Create export format matching the import structure
@@ -248,6 +309,96 @@ func (h *DatasetHandler) ExportDataset(w http.ResponseWriter, r *http.Request) {
         }
 }
 
+// datasetExportEvent converts an event to the dataset import format. The
+// precision is always written, so an exported dataset never goes through the
+// 01.01.YYYY year heuristic on import (see importRequest.padsYears); other
+// optional fields are only written when re-importing would not restore them
+// anyway.
+func datasetExportEvent(event models.HistoricalEvent) map[string]interface{} {
+        precision := event.DatePrecision
+        if precision == "" {
+                precision = models.DatePrecisionDay
+        }
+
+        // Format date as DD.MM.YYYY, MM.YYYY or YYYY depending on precision
+        dateStr := event.EventDate.DatasetString(precision)
+
+        // Extract tag names only (not IDs)
+        tagNames := make([]string, len(event.Tags))
+        for j, tag := range event.Tags {
+                tagNames[j] = tag.Name
+        }
+
+        exportEvent := map[string]interface{}{
+                "date":        dateStr,
+                "era":         event.EventDate.Era,
+                "precision":   precision,
+                "latitude":    event.Latitude,
+                "longitude":   event.Longitude,
+                "type":        event.LensType, // Export as "type" for compatibility
+                "tags":        tagNames,
+        }
+
+        if event.Circa {
+                exportEvent["circa"] = true
+        }
+        if event.EventDate.Calendar != models.DefaultCalendar(event.EventDate) {
+                exportEvent["calendar"] = event.EventDate.Calendar
+        }
+        if event.EndDate != nil {
+                exportEvent["end_date"] = event.EndDate.DatasetString(precision)
+                if event.EndDate.Era != event.EventDate.Era {
+                        exportEvent["end_era"] = event.EndDate.Era
+                }
+        }
+        if event.EarliestDate != nil {
+                exportEvent["earliest"] = event.EarliestDate.DatasetString(models.DatePrecisionDay)
+                if event.EarliestDate.Era != event.EventDate.Era {
+                        exportEvent["earliest_era"] = event.EarliestDate.Era
+                }
+        }
+        if event.LatestDate != nil {
+                exportEvent["latest"] = event.LatestDate.DatasetString(models.DatePrecisionDay)
+                if event.LatestDate.Era != event.EventDate.Era {
+                        exportEvent["latest_era"] = event.LatestDate.Era
+                }
+        }
+
+        // Export optimized format: "name" contains English, "name_ru" contains Russian
+        // This eliminates duplication of "name_en" when it's identical to "name"
+        if event.NameEn != "" {
+                exportEvent["name"] = event.NameEn
+        } else if event.Name != "" {
+                exportEvent["name"] = event.Name
+        }
+        
+        if event.NameRu != "" {
+                exportEvent["name_ru"] = event.NameRu
+        }
+
+        // Same approach for descriptions
+        if event.DescriptionEn != nil && *event.DescriptionEn != "" {
+                exportEvent["description"] = *event.DescriptionEn
+        } else if event.Description != "" {
+                exportEvent["description"] = event.Description
+        }
+        
+        if event.DescriptionRu != nil && *event.DescriptionRu != "" {
+                exportEvent["description_ru"] = *event.DescriptionRu
+        }
+
+        // Include source if available
+        if event.Source != nil {
+                exportEvent["source"] = *event.Source
+        }
+
+        if event.ExternalID != nil {
+                exportEvent["external_id"] = *event.ExternalID
+        }
+
+        return exportEvent
+}
+
 // ResetModifiedFlag handles POST /api/datasets/{id}/reset-modified
 // This resets the modified flag to false for a dataset
 func (h *DatasetHandler) ResetModifiedFlag(w http.ResponseWriter, r *http.Request) {
@@ -263,6 +414,9 @@ func (h *DatasetHandler) ResetModifiedFlag(w http.ResponseWriter, r *http.Reques
                 response.BadRequest(w, "Invalid dataset ID")
                 return
         }
+        if !h.authorizer.Dataset(w, r, PermissionChangeDataset, id) {
+                return
+        }
 
         // Check if dataset exists
         _, err = h.datasetRepo.GetByID(id)
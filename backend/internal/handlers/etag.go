@@ -0,0 +1,52 @@
+package handlers
+
+import (
+        "historical-events-backend/pkg/response"
+        "net/http"
+        "strconv"
+        "strings"
+)
+
+// etag formats a row version as a strong entity tag
+func etag(version int) string {
+        return `"` + strconv.Itoa(version) + `"`
+}
+
+// setETag sets the ETag header to the version of the resource in the response
+func setETag(w http.ResponseWriter, version int) {
+        w.Header().Set("ETag", etag(version))
+}
+
+// ifMatchVersion returns the version named by the If-Match header of a write,
+// or 0 for "If-Match: *", which matches any current version. A tag this API
+// did not issue, such as a weak tag, yields -1 and matches no version. When
+// the header is missing it answers 428 Precondition Required and returns false.
+func ifMatchVersion(w http.ResponseWriter, r *http.Request) (int, bool) {
+        header := strings.TrimSpace(r.Header.Get("If-Match"))
+        if header == "" {
+                response.Error(w, http.StatusPreconditionRequired, "Precondition required",
+                        "Send the ETag of the resource in the If-Match header")
+                return 0, false
+        }
+        if header == "*" {
+                return 0, true
+        }
+
+        unquoted, err := strconv.Unquote(header)
+        if err != nil {
+                return -1, true
+        }
+        version, err := strconv.Atoi(unquoted)
+        if err != nil || version <= 0 {
+                return -1, true
+        }
+        return version, true
+}
+
+// writePreconditionFailed answers a stale write with 412, the current state of
+// the resource in the body and its version as the ETag
+func writePreconditionFailed(w http.ResponseWriter, current interface{}, version int) {
+        setETag(w, version)
+        response.PreconditionFailed(w, "Precondition failed", current,
+                "The resource has changed since it was read; the current version is included")
+}
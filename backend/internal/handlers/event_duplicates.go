@@ -0,0 +1,308 @@
+package handlers
+
+import (
+        "encoding/json"
+        "errors"
+        "fmt"
+        "historical-events-backend/internal/database/repositories"
+        "historical-events-backend/internal/models"
+        "historical-events-backend/pkg/response"
+        "log"
+        "net/http"
+        "net/url"
+        "strconv"
+        "strings"
+        "time"
+)
+
+// GetDuplicates handles GET /api/events/duplicates: groups of events that
+// probably describe the same thing, best score first
+func (h *EventHandler) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+        query := r.URL.Query()
+
+        // Get locale parameter (default to "en")
+        locale := query.Get("locale")
+        if locale == "" {
+                locale = "en"
+        }
+
+        opts, err := parseDuplicateOptions(query)
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+
+        limit, err := strconv.Atoi(query.Get("limit"))
+        if err != nil || limit < 1 {
+                limit = 50
+        }
+        if limit > 200 {
+                limit = 200
+        }
+
+        pairs, err := h.eventRepo.FindDuplicatePairs(opts)
+        if err != nil {
+                log.Printf("Error finding duplicate events: %v", err)
+                response.InternalError(w, "Failed to find duplicate events")
+                return
+        }
+
+        groups := models.GroupDuplicates(pairs)
+        if len(groups) > limit {
+                groups = groups[:limit]
+        }
+
+        var ids []int
+        for _, group := range groups {
+                ids = append(ids, group.EventIDs...)
+        }
+        events, err := h.eventRepo.GetByIDs(ids)
+        if err != nil {
+                log.Printf("Error getting duplicate events: %v", err)
+                response.InternalError(w, "Failed to find duplicate events")
+                return
+        }
+        byID := make(map[int]models.HistoricalEvent, len(events))
+        for _, event := range events {
+                event.PopulateLegacyFields(locale)
+                byID[event.ID] = event
+        }
+        for i := range groups {
+                for _, id := range groups[i].EventIDs {
+                        if event, ok := byID[id]; ok {
+                                groups[i].Events = append(groups[i].Events, event)
+                        }
+                }
+        }
+
+        response.Success(w, groups)
+}
+
+// parseDuplicateOptions reads the duplicate detection thresholds from the
+// query string. Supported parameters:
+//   min_similarity - name similarity in either locale, 0..1 (default 0.6)
+//   max_years      - largest date difference in years (default 1)
+//   max_km         - largest distance in km (default 50)
+//   dataset        - only groups with an event of this dataset
+//   cross_dataset  - only pairs of events from different datasets
+func parseDuplicateOptions(query url.Values) (models.DuplicateOptions, error) {
+        opts := models.DefaultDuplicateOptions()
+
+        for name, value := range map[string]*float64{"min_similarity": &opts.MinSimilarity, "max_years": &opts.MaxYears, "max_km": &opts.MaxDistanceKm} {
+                if param := query.Get(name); param != "" {
+                        parsed, err := strconv.ParseFloat(param, 64)
+                        if err != nil || parsed < 0 {
+                                return opts, fmt.Errorf("Invalid %s parameter", name)
+                        }
+                        *value = parsed
+                }
+        }
+        if opts.MinSimilarity <= 0 || opts.MinSimilarity > 1 {
+                return opts, fmt.Errorf("Invalid min_similarity parameter (must be > 0 and <= 1)")
+        }
+
+        if dataset := query.Get("dataset"); dataset != "" {
+                id, err := strconv.Atoi(dataset)
+                if err != nil || id < 1 {
+                        return opts, fmt.Errorf("Invalid dataset parameter")
+                }
+                opts.DatasetID = &id
+        }
+
+        if crossDataset := query.Get("cross_dataset"); crossDataset != "" {
+                var err error
+                if opts.CrossDataset, err = strconv.ParseBool(crossDataset); err != nil {
+                        return opts, fmt.Errorf("Invalid cross_dataset parameter")
+                }
+        }
+
+        return opts, nil
+}
+
+// MergeEvents handles POST /api/events/merge: the duplicates are merged into
+// the canonical event, which keeps its ID, and then deleted
+func (h *EventHandler) MergeEvents(w http.ResponseWriter, r *http.Request) {
+        var req models.MergeEventsRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+                response.BadRequest(w, "Invalid JSON format")
+                return
+        }
+
+        if req.CanonicalID < 1 {
+                response.BadRequest(w, "canonical_id is required")
+                return
+        }
+        var duplicateIDs []int
+        seen := map[int]bool{req.CanonicalID: true}
+        for _, id := range req.DuplicateIDs {
+                if !seen[id] {
+                        seen[id] = true
+                        duplicateIDs = append(duplicateIDs, id)
+                }
+        }
+        if len(duplicateIDs) == 0 {
+                response.BadRequest(w, "duplicate_ids must list at least one event other than the canonical one")
+                return
+        }
+
+        events, err := h.eventRepo.GetByIDs(append([]int{req.CanonicalID}, duplicateIDs...))
+        if err != nil {
+                log.Printf("Error getting events to merge: %v", err)
+                response.InternalError(w, "Failed to merge events")
+                return
+        }
+        if len(events) != len(duplicateIDs)+1 {
+                response.NotFound(w, "Event not found")
+                return
+        }
+
+        var canonical *models.HistoricalEvent
+        var duplicates []models.HistoricalEvent
+        for i := range events {
+                if events[i].ID == req.CanonicalID {
+                        canonical = &events[i]
+                } else {
+                        duplicates = append(duplicates, events[i])
+                }
+        }
+
+        userID := 1 // Default to admin user
+        if user := getUserFromContext(r.Context()); user != nil {
+                userID = user.ID
+        }
+        otherSources := mergeEventFields(canonical, duplicates)
+        canonical.UpdatedBy = &userID
+        canonical.UpdatedAt = time.Now()
+
+        tx, err := h.datasetRepo.Begin()
+        if err != nil {
+                log.Printf("Failed to start merge: %v", err)
+                response.InternalError(w, "Failed to merge events")
+                return
+        }
+        defer tx.Rollback()
+
+        // The duplicates' last state is recorded as a delete, the merged
+        // canonical event as an update
+        err = h.revisionRepo.RecordManyTx(tx, duplicateIDs, models.RevisionDelete, &userID)
+        if err == nil {
+                _, err = h.eventRepo.MergeTx(tx, canonical, duplicateIDs)
+        }
+        if err == nil {
+                err = h.revisionRepo.RecordTx(tx, canonical.ID, models.RevisionUpdate, &userID)
+        }
+        if errors.Is(err, repositories.ErrVersionConflict) {
+                response.Error(w, http.StatusConflict, "Canonical event was changed while merging", "reload the duplicates and try again")
+                return
+        }
+        if err != nil {
+                log.Printf("Error merging events: %v", err)
+                if err.Error() == "event not found" {
+                        response.NotFound(w, "Event not found")
+                        return
+                }
+                response.InternalError(w, "Failed to merge events")
+                return
+        }
+        if err := tx.Commit(); err != nil {
+                log.Printf("Failed to commit merge: %v", err)
+                response.InternalError(w, "Failed to merge events")
+                return
+        }
+
+        // Mark the datasets of the merged events as modified
+        datasets := make(map[int]bool)
+        for _, event := range events {
+                if event.DatasetID != nil && *event.DatasetID > 0 && !datasets[*event.DatasetID] {
+                        datasets[*event.DatasetID] = true
+                        if err := h.datasetRepo.MarkAsModified(*event.DatasetID); err != nil {
+                                log.Printf("Warning: failed to mark dataset as modified: %v", err)
+                        }
+                }
+        }
+
+        h.eventCache.Invalidate()
+
+        merged, err := h.eventRepo.GetByID(req.CanonicalID)
+        if err != nil {
+                log.Printf("Error getting merged event: %v", err)
+                response.InternalError(w, "Events were merged but the result could not be read")
+                return
+        }
+
+        locale := r.URL.Query().Get("locale")
+        if locale == "" {
+                locale = "en"
+        }
+        merged.PopulateLegacyFields(locale)
+
+        response.Success(w, models.MergeEventsResult{Event: merged, MergedIDs: duplicateIDs, OtherSources: otherSources})
+}
+
+// mergeEventFields fills the names, descriptions and source the canonical
+// event lacks from the duplicates, in order. An event has a single source
+// link, so the other distinct sources of the duplicates are appended to its
+// descriptions, where they outlive the deleted duplicates, and returned.
+func mergeEventFields(canonical *models.HistoricalEvent, duplicates []models.HistoricalEvent) []string {
+        var otherSources []string
+        seenSources := make(map[string]bool)
+        if canonical.Source != nil && *canonical.Source != "" {
+                seenSources[*canonical.Source] = true
+        }
+
+        for _, duplicate := range duplicates {
+                if canonical.NameEn == "" {
+                        canonical.NameEn = duplicate.NameEn
+                }
+                if canonical.NameRu == "" {
+                        canonical.NameRu = duplicate.NameRu
+                }
+                if isBlank(canonical.DescriptionEn) {
+                        canonical.DescriptionEn = duplicate.DescriptionEn
+                }
+                if isBlank(canonical.DescriptionRu) {
+                        canonical.DescriptionRu = duplicate.DescriptionRu
+                }
+
+                if isBlank(duplicate.Source) || seenSources[*duplicate.Source] {
+                        continue
+                }
+                seenSources[*duplicate.Source] = true
+                if isBlank(canonical.Source) {
+                        canonical.Source = duplicate.Source
+                } else {
+                        otherSources = append(otherSources, *duplicate.Source)
+                }
+        }
+
+        if len(otherSources) > 0 {
+                canonical.Description = withSources(canonical.Description, "Other sources", otherSources)
+                en := withSources(deref(canonical.DescriptionEn), "Other sources", otherSources)
+                canonical.DescriptionEn = &en
+                ru := withSources(deref(canonical.DescriptionRu), "Другие источники", otherSources)
+                canonical.DescriptionRu = &ru
+        }
+        return otherSources
+}
+
+// withSources appends a line listing the sources under label to a description
+func withSources(description, label string, sources []string) string {
+        line := label + ": " + strings.Join(sources, ", ")
+        if strings.TrimSpace(description) == "" {
+                return line
+        }
+        return description + "\n\n" + line
+}
+
+// deref returns the value of an optional text field, or ""
+func deref(s *string) string {
+        if s == nil {
+                return ""
+        }
+        return *s
+}
+
+// isBlank reports whether an optional text field is unset or empty
+func isBlank(s *string) bool {
+        return s == nil || *s == ""
+}
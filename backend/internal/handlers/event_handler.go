@@ -2,37 +2,48 @@ package handlers
 
 import (
         "encoding/json"
+        "errors"
         "fmt"
         "historical-events-backend/internal/database/repositories"
         "historical-events-backend/internal/models"
+        "historical-events-backend/internal/services"
         "historical-events-backend/pkg/cache"
         "historical-events-backend/pkg/metrics"
         "historical-events-backend/pkg/response"
+        "historical-events-backend/pkg/xlsx"
         "log"
-        "math/rand"
+        "math"
         "net/http"
+        "net/url"
         "strconv"
         "strings"
-        "time"
 
         "github.com/gorilla/mux"
 )
 
 // EventHandler handles HTTP requests for events
 type EventHandler struct {
-        eventRepo   *repositories.EventRepository
-        tagRepo     *repositories.TagRepository
-        datasetRepo *repositories.DatasetRepository
-        eventCache  *cache.EventCache
+        eventRepo    *repositories.EventRepository
+        tagRepo      *repositories.TagRepository
+        datasetRepo  *repositories.DatasetRepository
+        regionRepo   *repositories.RegionRepository
+        revisionRepo *repositories.RevisionRepository
+        eventCache   *cache.EventCache
+        jobRunner    *services.JobRunner
+        authorizer   *Authorizer
 }
 
 // NewEventHandler creates a new event handler
-func NewEventHandler(eventRepo *repositories.EventRepository, tagRepo *repositories.TagRepository, datasetRepo *repositories.DatasetRepository, eventCache *cache.EventCache) *EventHandler {
+func NewEventHandler(eventRepo *repositories.EventRepository, tagRepo *repositories.TagRepository, datasetRepo *repositories.DatasetRepository, regionRepo *repositories.RegionRepository, revisionRepo *repositories.RevisionRepository, eventCache *cache.EventCache, jobRunner *services.JobRunner, authorizer *Authorizer) *EventHandler {
         return &EventHandler{
-                eventRepo:   eventRepo,
-                tagRepo:     tagRepo,
-                datasetRepo: datasetRepo,
-                eventCache:  eventCache,
+                eventRepo:    eventRepo,
+                tagRepo:      tagRepo,
+                datasetRepo:  datasetRepo,
+                regionRepo:   regionRepo,
+                revisionRepo: revisionRepo,
+                eventCache:   eventCache,
+                jobRunner:    jobRunner,
+                authorizer:   authorizer,
         }
 }
 
@@ -46,12 +57,37 @@ func (h *EventHandler) GetAllEvents(w http.ResponseWriter, r *http.Request) {
                 locale = "en"
         }
         
+        // Parse server-side filters (date range, tags, lens types, dataset)
+        filter, err := parseEventFilter(query)
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+        
+        format, err := responseFormat(r, exportFormats...)
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+        
+        // Export formats always return the whole filtered set, ignoring pagination
+        if format != formatJSON {
+                h.exportEvents(w, r, format, locale, filter)
+                return
+        }
+        
         // Check if pagination parameters are provided
         pageStr := query.Get("page")
         limitStr := query.Get("limit")
         sortField := query.Get("sort")
         sortDir := query.Get("order")
         
+        // Cursor (keyset) pagination: "cursor=" with an empty value requests the first page
+        if query.Has("cursor") {
+                h.getEventsByCursor(w, query, locale, filter)
+                return
+        }
+        
         // If pagination parameters exist, use paginated query
         if pageStr != "" || limitStr != "" {
                 page, err := strconv.Atoi(pageStr)
@@ -77,7 +113,7 @@ func (h *EventHandler) GetAllEvents(w http.ResponseWriter, r *http.Request) {
                         sortDir = "asc"
                 }
                 
-                events, total, err := h.eventRepo.GetPaginatedWithSort(page, limit, sortField, sortDir)
+                events, total, err := h.eventRepo.GetPaginatedWithSort(page, limit, sortField, sortDir, filter)
                 if err != nil {
                         log.Printf("Error fetching paginated events: %v", err)
                         response.InternalError(w, "Failed to fetch events")
@@ -111,7 +147,13 @@ func (h *EventHandler) GetAllEvents(w http.ResponseWriter, r *http.Request) {
         // Allowing the browser to cache would cause stale data after admin edits.
         w.Header().Set("Cache-Control", "no-store")
 
-        if cached, ok := h.eventCache.Get(locale); ok {
+        // Filtered responses are cached separately from the full list
+        cacheKey := locale
+        if !filter.IsEmpty() {
+                cacheKey = locale + "?" + filter.CacheKey()
+        }
+
+        if cached, ok := h.eventCache.Get(cacheKey); ok {
                 w.Header().Set("Content-Type", "application/json")
                 w.Header().Set("X-Cache", "HIT")
                 w.WriteHeader(http.StatusOK)
@@ -119,7 +161,7 @@ func (h *EventHandler) GetAllEvents(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
-        events, err := h.eventRepo.GetAll()
+        events, err := h.eventRepo.GetFiltered(filter)
         if err != nil {
                 log.Printf("Error fetching events: %v", err)
                 response.InternalError(w, "Failed to fetch events")
@@ -141,7 +183,7 @@ func (h *EventHandler) GetAllEvents(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
-        h.eventCache.Set(locale, encoded)
+        h.eventCache.Set(cacheKey, encoded)
 
         w.Header().Set("Content-Type", "application/json")
         w.Header().Set("X-Cache", "MISS")
@@ -149,6 +191,109 @@ func (h *EventHandler) GetAllEvents(w http.ResponseWriter, r *http.Request) {
         w.Write(encoded)
 }
 
+// exportEvents serves GET /api/events as GeoJSON, KML, KMZ, CSV or XLSX. KML exports
+// can include the regions of a template (template_id) as polygons.
+func (h *EventHandler) exportEvents(w http.ResponseWriter, r *http.Request, format, locale string, filter models.EventFilter) {
+        templateID, err := parseOptionalID(r.URL.Query(), "template_id")
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+        
+        events, err := h.eventRepo.GetFiltered(filter)
+        if err != nil {
+                log.Printf("Error fetching events: %v", err)
+                response.InternalError(w, "Failed to fetch events")
+                return
+        }
+        
+        switch format {
+        case formatGeoJSON:
+                response.GeoJSON(w, models.NewEventFeatureCollection(events, locale))
+                return
+        case formatCSV, formatXLSX:
+                if err := writeTabular(w, format, "events", events); err != nil {
+                        log.Printf("Error exporting events as %s: %v", format, err)
+                        response.InternalError(w, "Failed to export events")
+                }
+                return
+        }
+        
+        doc := models.NewKML("Historical events", "")
+        doc.AddEvents("Events", events, locale)
+        if templateID != nil {
+                if err := addTemplateRegions(doc, h.regionRepo, *templateID, locale); err != nil {
+                        log.Printf("Error fetching regions for template %d: %v", *templateID, err)
+                        response.InternalError(w, "Failed to fetch regions for template")
+                        return
+                }
+        }
+        if err := writeKML(w, doc, "events", format == formatKMZ); err != nil {
+                log.Printf("Error exporting events as %s: %v", format, err)
+                response.InternalError(w, "Failed to export events")
+        }
+}
+
+// getEventsByCursor serves the keyset-paginated variant of GET /api/events.
+// The response carries next_cursor instead of page numbers and totals.
+func (h *EventHandler) getEventsByCursor(w http.ResponseWriter, query url.Values, locale string, filter models.EventFilter) {
+        limit, err := strconv.Atoi(query.Get("limit"))
+        if err != nil || limit < 1 {
+                limit = 10 // Default page size
+        }
+        
+        // Validate limit (max 100 to prevent abuse)
+        if limit > 100 {
+                limit = 100
+        }
+        
+        sortField := repositories.NormalizeSortField(query.Get("sort"))
+        sortDir := query.Get("order")
+        if sortDir != "desc" {
+                sortDir = "asc"
+        }
+        
+        var cursor *models.EventCursor
+        if cursorStr := query.Get("cursor"); cursorStr != "" {
+                cursor, err = models.DecodeEventCursor(cursorStr)
+                if err != nil || !cursor.Matches(sortField, sortDir, filter) {
+                        response.BadRequest(w, "Invalid cursor", "The cursor is malformed or was issued for a different sort order or filter")
+                        return
+                }
+        }
+        
+        events, next, err := h.eventRepo.GetPageAfter(cursor, limit, sortField, sortDir, filter)
+        if err != nil {
+                log.Printf("Error fetching events page: %v", err)
+                response.InternalError(w, "Failed to fetch events")
+                return
+        }
+        
+        // Populate legacy fields based on locale
+        for i := range events {
+                events[i].PopulateLegacyFields(locale)
+        }
+        
+        var nextCursor *string
+        if next != nil {
+                encoded := next.Encode()
+                nextCursor = &encoded
+        }
+        
+        if events == nil {
+                events = []models.HistoricalEvent{}
+        }
+        
+        response.Success(w, map[string]interface{}{
+                "events": events,
+                "pagination": map[string]interface{}{
+                        "page_size":   limit,
+                        "next_cursor": nextCursor,
+                        "has_more":    next != nil,
+                },
+        })
+}
+
 // GetEventByID handles GET /api/events/{id} with locale support
 func (h *EventHandler) GetEventByID(w http.ResponseWriter, r *http.Request) {
         vars := mux.Vars(r)
@@ -173,9 +318,16 @@ func (h *EventHandler) GetEventByID(w http.ResponseWriter, r *http.Request) {
                 return
         }
         
+        // Unpublished events are only shown to their author and reviewers
+        if event.Status != models.EventStatusPublished && !can(getUserFromContext(r.Context()), PermissionViewUnpublished, event.CreatedBy) {
+                response.NotFound(w, "Event not found")
+                return
+        }
+        
         // Populate legacy fields based on locale
         event.PopulateLegacyFields(locale)
         
+        setETag(w, event.Version)
         response.Success(w, event)
 }
 
@@ -207,8 +359,34 @@ func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
                 return
         }
         
-        // Create event
-        createdEvent, err := h.eventRepo.Create(event)
+        // Only users who may change the dataset can add events to it
+        if !h.authorizer.DatasetChange(w, r, nil, event.DatasetID) {
+                return
+        }
+        
+        // Submissions by users who cannot publish go to the moderation queue
+        event.Status, err = submissionStatus(user, req.Status, "")
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+        
+        // Create event and record its first revision
+        tx, err := h.eventRepo.Begin()
+        if err != nil {
+                log.Printf("Error creating event: %v", err)
+                response.InternalError(w, "Failed to create event")
+                return
+        }
+        defer tx.Rollback()
+        
+        createdEvent, err := h.eventRepo.CreateTx(tx, event)
+        if err == nil {
+                err = h.revisionRepo.RecordTx(tx, createdEvent.ID, models.RevisionCreate, &user.ID)
+        }
+        if err == nil {
+                err = tx.Commit()
+        }
         if err != nil {
                 log.Printf("Error creating event: %v", err)
                 response.InternalError(w, "Failed to create event")
@@ -236,7 +414,11 @@ func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
         // Increment Prometheus metrics
         metrics.EventsCreated.Inc()
 
-        response.Created(w, createdEvent, "Event created successfully")
+        message := "Event created successfully"
+        if createdEvent.Status == models.EventStatusPending {
+                message = "Event submitted for review"
+        }
+        response.Created(w, createdEvent, message)
 }
 
 // UpdateEvent handles PUT /api/events/{id}
@@ -250,12 +432,27 @@ func (h *EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
                 return
         }
         
+        // Users may edit the events they created, editors any event
+        if !h.authorizer.Event(w, r, PermissionEditEvent, id) {
+                return
+        }
+        
+        version, ok := ifMatchVersion(w, r)
+        if !ok {
+                return
+        }
+        
         var req models.CreateEventRequest
         if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
                 response.BadRequest(w, "Invalid JSON format")
                 return
         }
         
+        // Get locale parameter for response (default to "en")
+        locale := r.URL.Query().Get("locale")
+        if locale == "" {
+                locale = "en"
+        }
         
         // Validate coordinates
         if err := h.eventRepo.ValidateCoordinates(req.Latitude, req.Longitude); err != nil {
@@ -277,10 +474,53 @@ func (h *EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
                 return
         }
         event.ID = id
+        event.Version = version
         
+        // Moving the event between datasets needs permission on both, and
+        // edits by users who cannot publish go to the moderation queue
+        current, err := h.eventRepo.GetByID(id)
+        if err != nil {
+                log.Printf("Error getting event for update: %v", err)
+                if strings.Contains(err.Error(), "not found") {
+                        response.NotFound(w, "Event not found")
+                        return
+                }
+                response.InternalError(w, "Failed to get event")
+                return
+        }
+        if !h.authorizer.DatasetChange(w, r, current.DatasetID, event.DatasetID) {
+                return
+        }
+        event.Status, err = submissionStatus(user, req.Status, current.Status)
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+        if current.Status == models.EventStatusPublished && event.Status == models.EventStatusPending {
+                h.submitEdit(w, current, event, version, user, locale)
+                return
+        }
         
-        // Update event
-        updatedEvent, err := h.eventRepo.Update(event)
+        // Update event and record the revision
+        tx, err := h.eventRepo.Begin()
+        if err != nil {
+                log.Printf("Error updating event: %v", err)
+                response.InternalError(w, "Failed to update event")
+                return
+        }
+        defer tx.Rollback()
+        
+        updatedEvent, err := h.eventRepo.UpdateTx(tx, event)
+        if err == nil {
+                err = h.revisionRepo.RecordTx(tx, id, models.RevisionUpdate, &user.ID)
+        }
+        if err == nil {
+                err = tx.Commit()
+        }
+        if errors.Is(err, repositories.ErrVersionConflict) {
+                h.writeEventConflict(w, id, locale)
+                return
+        }
         if err != nil {
                 log.Printf("Error updating event: %v", err)
                 if strings.Contains(err.Error(), "not found") {
@@ -298,12 +538,6 @@ func (h *EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
                 }
         }
         
-        // Get locale parameter for response (default to "en")
-        locale := r.URL.Query().Get("locale")
-        if locale == "" {
-                locale = "en"
-        }
-        
         // Populate legacy fields based on locale for consistent response
         updatedEvent.PopulateLegacyFields(locale)
         
@@ -312,9 +546,23 @@ func (h *EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
         // Increment Prometheus metrics
         metrics.EventsUpdated.Inc()
 
+        setETag(w, updatedEvent.Version)
         response.Success(w, updatedEvent)
 }
 
+// writeEventConflict answers a stale write of event id with 412 and the event
+// as it is now
+func (h *EventHandler) writeEventConflict(w http.ResponseWriter, id int, locale string) {
+        current, err := h.eventRepo.GetByID(id)
+        if err != nil {
+                log.Printf("Error fetching event %d after version conflict: %v", id, err)
+                response.NotFound(w, "Event not found")
+                return
+        }
+        current.PopulateLegacyFields(locale)
+        writePreconditionFailed(w, current, current.Version)
+}
+
 // DeleteEvent handles DELETE /api/events/{id}
 func (h *EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
         vars := mux.Vars(r)
@@ -326,6 +574,11 @@ func (h *EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
                 return
         }
         
+        version, ok := ifMatchVersion(w, r)
+        if !ok {
+                return
+        }
+        
         // Get the event first to find its dataset
         event, err := h.eventRepo.GetByID(id)
         if err != nil {
@@ -337,11 +590,33 @@ func (h *EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
                 response.InternalError(w, "Failed to get event")
                 return
         }
+        if !authorize(w, r, PermissionDeleteEvent, event.CreatedBy) {
+                return
+        }
         
         // Store dataset ID before deleting
         datasetID := event.DatasetID
         
-        err = h.eventRepo.Delete(id)
+        // Record the last state of the event, then delete it
+        tx, err := h.eventRepo.Begin()
+        if err != nil {
+                log.Printf("Error deleting event: %v", err)
+                response.InternalError(w, "Failed to delete event")
+                return
+        }
+        defer tx.Rollback()
+        
+        err = h.revisionRepo.RecordTx(tx, id, models.RevisionDelete, userIDFromContext(r.Context()))
+        if err == nil {
+                err = h.eventRepo.DeleteTx(tx, id, version)
+        }
+        if err == nil {
+                err = tx.Commit()
+        }
+        if errors.Is(err, repositories.ErrVersionConflict) {
+                h.writeEventConflict(w, id, "en")
+                return
+        }
         if err != nil {
                 log.Printf("Error deleting event: %v", err)
                 if strings.Contains(err.Error(), "not found") {
@@ -367,6 +642,59 @@ func (h *EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
         response.Success(w, map[string]string{"message": "Event deleted successfully"})
 }
 
+// RestoreEvent handles POST /api/events/{id}/restore: the event comes back
+// from the trash with its tags
+func (h *EventHandler) RestoreEvent(w http.ResponseWriter, r *http.Request) {
+        id, err := strconv.Atoi(mux.Vars(r)["id"])
+        if err != nil {
+                response.BadRequest(w, "Invalid event ID")
+                return
+        }
+        
+        tx, err := h.eventRepo.Begin()
+        if err != nil {
+                log.Printf("Error restoring event: %v", err)
+                response.InternalError(w, "Failed to restore event")
+                return
+        }
+        defer tx.Rollback()
+        
+        err = h.eventRepo.RestoreTx(tx, id)
+        if err == nil {
+                err = h.revisionRepo.RecordTx(tx, id, models.RevisionRestore, userIDFromContext(r.Context()))
+        }
+        if err == nil {
+                err = tx.Commit()
+        }
+        if err != nil {
+                writeRestoreError(w, err, "Event")
+                return
+        }
+        
+        event, err := h.eventRepo.GetByID(id)
+        if err != nil {
+                log.Printf("Error getting restored event %d: %v", id, err)
+                response.InternalError(w, "Event was restored but could not be read")
+                return
+        }
+        
+        if event.DatasetID != nil && *event.DatasetID > 0 {
+                if err := h.datasetRepo.MarkAsModified(*event.DatasetID); err != nil {
+                        log.Printf("Warning: failed to mark dataset as modified: %v", err)
+                }
+        }
+        
+        h.eventCache.Invalidate()
+        
+        locale := r.URL.Query().Get("locale")
+        if locale == "" {
+                locale = "en"
+        }
+        event.PopulateLegacyFields(locale)
+        
+        response.Success(w, event, "Event restored successfully")
+}
+
 // GetEventsInBBox handles GET /api/events/bbox with locale support
 func (h *EventHandler) GetEventsInBBox(w http.ResponseWriter, r *http.Request) {
         query := r.URL.Query()
@@ -401,13 +729,30 @@ func (h *EventHandler) GetEventsInBBox(w http.ResponseWriter, r *http.Request) {
                 return
         }
         
-        events, err := h.eventRepo.GetInBoundingBox(minLat, minLng, maxLat, maxLng)
+        filter, err := parseEventFilter(query)
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+        
+        format, err := responseFormat(r, formatGeoJSON)
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+        
+        events, err := h.eventRepo.GetInBoundingBox(minLat, minLng, maxLat, maxLng, filter)
         if err != nil {
                 log.Printf("Bounding box query error: %v", err)
                 response.InternalError(w, "Failed to fetch events in bounding box")
                 return
         }
         
+        if format == formatGeoJSON {
+                response.GeoJSON(w, models.NewEventFeatureCollection(events, locale))
+                return
+        }
+        
         // Populate legacy fields based on locale
         for i := range events {
                 events[i].PopulateLegacyFields(locale)
@@ -416,6 +761,104 @@ func (h *EventHandler) GetEventsInBBox(w http.ResponseWriter, r *http.Request) {
         response.Success(w, events)
 }
 
+// Server-side clustering: grid cells are clusterRadiusPixels wide at the
+// requested zoom (256px tiles), and from clusterMaxZoom on individual events
+// are returned instead of clusters.
+const (
+        clusterRadiusPixels = 60
+        clusterMaxZoom      = 16
+        maxZoom             = 22
+)
+
+// GetEventClusters handles GET /api/events/clusters?bbox=minLng,minLat,maxLng,maxLat&zoom=Z
+// plus the list filters. Below clusterMaxZoom it returns clusters; at or above
+// it, the individual events inside the bounding box.
+func (h *EventHandler) GetEventClusters(w http.ResponseWriter, r *http.Request) {
+        query := r.URL.Query()
+        
+        // Get locale parameter (default to "en")
+        locale := query.Get("locale")
+        if locale == "" {
+                locale = "en"
+        }
+        
+        minLat, minLng, maxLat, maxLng, err := parseBBox(query.Get("bbox"))
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+        
+        zoom, err := strconv.Atoi(query.Get("zoom"))
+        if err != nil || zoom < 0 || zoom > maxZoom {
+                response.BadRequest(w, fmt.Sprintf("Invalid zoom parameter (0-%d)", maxZoom))
+                return
+        }
+        
+        filter, err := parseEventFilter(query)
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+        
+        result := map[string]interface{}{
+                "zoom":     zoom,
+                "clusters": []models.EventCluster{},
+                "events":   []models.HistoricalEvent{},
+        }
+        
+        if zoom >= clusterMaxZoom {
+                events, err := h.eventRepo.GetInBoundingBox(minLat, minLng, maxLat, maxLng, filter)
+                if err != nil {
+                        log.Printf("Cluster events query error: %v", err)
+                        response.InternalError(w, "Failed to fetch events")
+                        return
+                }
+                for i := range events {
+                        events[i].PopulateLegacyFields(locale)
+                }
+                if events != nil {
+                        result["events"] = events
+                }
+                response.Success(w, result)
+                return
+        }
+        
+        cellSize := clusterRadiusPixels * 360.0 / (256 * math.Exp2(float64(zoom)))
+        clusters, err := h.eventRepo.GetClusters(minLat, minLng, maxLat, maxLng, cellSize, filter)
+        if err != nil {
+                log.Printf("Cluster query error: %v", err)
+                response.InternalError(w, "Failed to fetch event clusters")
+                return
+        }
+        result["clusters"] = clusters
+        
+        response.Success(w, result)
+}
+
+// parseBBox parses "minLng,minLat,maxLng,maxLat" (west, south, east, north).
+// Map viewports can extend past the valid range, so coordinates are clamped.
+func parseBBox(value string) (minLat, minLng, maxLat, maxLng float64, err error) {
+        parts := strings.Split(value, ",")
+        if len(parts) != 4 {
+                return 0, 0, 0, 0, fmt.Errorf("Invalid bbox parameter, expected minLng,minLat,maxLng,maxLat")
+        }
+        
+        var coords [4]float64
+        for i, part := range parts {
+                coords[i], err = parseCoordinate(strings.TrimSpace(part))
+                if err != nil {
+                        return 0, 0, 0, 0, fmt.Errorf("Invalid bbox parameter, expected minLng,minLat,maxLng,maxLat")
+                }
+        }
+        
+        minLng, maxLng = math.Max(coords[0], -180), math.Min(coords[2], 180)
+        minLat, maxLat = math.Max(coords[1], -90), math.Min(coords[3], 90)
+        if minLng > maxLng || minLat > maxLat {
+                return 0, 0, 0, 0, fmt.Errorf("Invalid bbox parameter, min must not exceed max")
+        }
+        return minLat, minLng, maxLat, maxLng, nil
+}
+
 // GetEventsInRadius handles GET /api/events/radius with locale support
 func (h *EventHandler) GetEventsInRadius(w http.ResponseWriter, r *http.Request) {
         query := r.URL.Query()
@@ -444,19 +887,40 @@ func (h *EventHandler) GetEventsInRadius(w http.ResponseWriter, r *http.Request)
                 return
         }
         
-        // Convert radius to rough bounding box for demo
-        // TODO: Implement proper radius query with PostGIS ST_DWithin
-        degreeRadius := radius / 111000 // Rough conversion from meters to degrees
-        events, err := h.eventRepo.GetInBoundingBox(
-                centerLat-degreeRadius, centerLng-degreeRadius,
-                centerLat+degreeRadius, centerLng+degreeRadius)
+        if err := h.eventRepo.ValidateCoordinates(centerLat, centerLng); err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+        
+        // Sort by distance (default) or chronologically
+        sortByDistance := true
+        switch query.Get("sort") {
+        case "", "distance":
+        case "date":
+                sortByDistance = false
+        default:
+                response.BadRequest(w, "Invalid sort parameter (valid: distance, date)")
+                return
+        }
         
+        format, err := responseFormat(r, formatGeoJSON)
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+        
+        events, err := h.eventRepo.GetInRadius(centerLat, centerLng, radius, sortByDistance)
         if err != nil {
                 log.Printf("Radius query error: %v", err)
                 response.InternalError(w, "Failed to fetch events within radius")
                 return
         }
         
+        if format == formatGeoJSON {
+                response.GeoJSON(w, models.NewEventFeatureCollection(events, locale))
+                return
+        }
+        
         // Populate legacy fields based on locale
         for i := range events {
                 events[i].PopulateLegacyFields(locale)
@@ -465,286 +929,199 @@ func (h *EventHandler) GetEventsInRadius(w http.ResponseWriter, r *http.Request)
         response.Success(w, events)
 }
 
-// ImportEvents handles bulk importing of events from dataset
-func (h *EventHandler) ImportEvents(w http.ResponseWriter, r *http.Request) {
-        type ImportRequest struct {
-                Filename string `json:"filename,omitempty"`
-                Events []struct {
-                        // Legacy fields for backward compatibility
-                        Name        string   `json:"name,omitempty"`
-                        Description string   `json:"description,omitempty"`
-                        // New locale-specific fields
-                        NameEN         string   `json:"name_en,omitempty"`
-                        NameRU         string   `json:"name_ru,omitempty"`
-                        DescriptionEN  string   `json:"description_en,omitempty"`
-                        DescriptionRU  string   `json:"description_ru,omitempty"`
-                        Date           string   `json:"date"`
-                        Era            string   `json:"era"`
-                        Latitude       float64  `json:"latitude"`
-                        Longitude      float64  `json:"longitude"`
-                        Type           string   `json:"type"`
-                        Tags           []string `json:"tags"`
-                        Source         string   `json:"source,omitempty"`
-                } `json:"events"`
-        }
-
-        var req ImportRequest
-        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-                response.BadRequest(w, "Invalid JSON format")
-                return
-        }
-
-        if len(req.Events) == 0 {
-                response.BadRequest(w, "No events provided for import")
+// SearchEvents handles GET /api/events/search with full-text search across
+// English and Russian names and descriptions. Accepts the same filters as
+// GET /api/events plus q (required), locale and limit.
+func (h *EventHandler) SearchEvents(w http.ResponseWriter, r *http.Request) {
+        query := r.URL.Query()
+        
+        text := strings.TrimSpace(query.Get("q"))
+        if text == "" {
+                response.BadRequest(w, "Missing q parameter")
                 return
         }
-
-        // Get user ID from request context (set by auth middleware)
-        userID := 1 // Default to admin user for now - in real app get from context
-        if userCtx := r.Context().Value("user_id"); userCtx != nil {
-                if uid, ok := userCtx.(int); ok {
-                        userID = uid
-                }
+        
+        // Get locale parameter (default to "en")
+        locale := query.Get("locale")
+        if locale == "" {
+                locale = "en"
         }
-
-        // Create dataset record
-        filename := req.Filename
-        if filename == "" {
-                filename = "imported_dataset.json"
+        
+        filter, err := parseEventFilter(query)
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
         }
         
-        dataset := &models.EventDataset{
-                Filename:    filename,
-                Description: fmt.Sprintf("Dataset imported with %d events", len(req.Events)),
-                EventCount:  0, // Will be updated as events are created
-                UploadedBy:  userID,
+        limit, err := strconv.Atoi(query.Get("limit"))
+        if err != nil || limit < 1 {
+                limit = 50
+        }
+        if limit > 100 {
+                limit = 100
         }
         
-        createdDataset, err := h.datasetRepo.Create(dataset)
+        results, err := h.eventRepo.Search(text, locale, filter, limit)
         if err != nil {
-                log.Printf("Failed to create dataset record: %v", err)
-                response.InternalError(w, "Failed to create dataset record")
+                log.Printf("Search query error: %v", err)
+                response.InternalError(w, "Failed to search events")
                 return
         }
-
-        validLensTypes := map[string]bool{
-                "historic":   true,
-                "political":  true,
-                "military":   true,
-                "cultural":   true,
-                "religious":  true,
-                "scientific": true,
-                "battle":     true,
+        
+        // Populate legacy fields based on locale
+        for i := range results {
+                results[i].Event.PopulateLegacyFields(locale)
         }
+        
+        response.Success(w, results)
+}
 
-        importedCount := 0
-        skippedEvents := []string{}
-        for i, eventData := range req.Events {
-                if eventData.Type == "" {
-                        eventName := eventData.NameEN
-                        if eventName == "" {
-                                eventName = eventData.Name
-                        }
-                        if eventName == "" {
-                                eventName = fmt.Sprintf("event #%d", i+1)
-                        }
-                        log.Printf("Skipping event '%s': missing required field 'type'", eventName)
-                        skippedEvents = append(skippedEvents, fmt.Sprintf("'%s': missing type", eventName))
-                        continue
-                }
-
-                if !validLensTypes[eventData.Type] {
-                        eventName := eventData.NameEN
-                        if eventName == "" {
-                                eventName = eventData.Name
-                        }
-                        if eventName == "" {
-                                eventName = fmt.Sprintf("event #%d", i+1)
-                        }
-                        log.Printf("Skipping event '%s': invalid type '%s' (valid: historic, political, military, cultural, religious, scientific, battle)", eventName, eventData.Type)
-                        skippedEvents = append(skippedEvents, fmt.Sprintf("'%s': invalid type '%s'", eventName, eventData.Type))
-                        continue
-                }
-
-                // Parse date with DD.MM.YYYY format
-                parsedDate, err := time.Parse("02.01.2006", eventData.Date)
+// parseEventFilter reads the era-aware date range, tag, lens and dataset
+// filters from the query string. Supported parameters:
+//   from, to  - range bounds such as "0500-BC" or "1453-05-29-AD"
+//   tags      - comma-separated tag IDs
+//   tag_mode  - "any" (default) or "all"
+//   lens      - comma-separated lens types (alias: lens_type)
+//   dataset   - comma-separated dataset IDs (alias: dataset_id)
+func parseEventFilter(query url.Values) (models.EventFilter, error) {
+        filter := models.EventFilter{TagMode: models.TagModeAny}
+        
+        if from := query.Get("from"); from != "" {
+                bound, err := models.ParseDateBound(from, false)
                 if err != nil {
-                        log.Printf("Failed to parse date %s: %v", eventData.Date, err)
-                        continue
+                        return filter, fmt.Errorf("invalid from parameter: %v", err)
                 }
-
-                // Store BC dates as positive dates (same as predefined events)
-                // The era field indicates BC/AD, astronomical conversion happens in views
-                eventDate := parsedDate
-
-                // Create event with dataset reference
-                event := &models.HistoricalEvent{
-                        Latitude:    eventData.Latitude,
-                        Longitude:   eventData.Longitude,
-                        EventDate:   eventDate,
-                        Era:         eventData.Era,
-                        LensType:    eventData.Type,
-                        DisplayDate: formatDisplayDate(eventDate, eventData.Era),
-                        DatasetID:   &createdDataset.ID,
-                }
-
-                // Handle locale-specific fields with fallbacks
-                if eventData.NameEN != "" {
-                        event.NameEn = eventData.NameEN
-                } else if eventData.Name != "" {
-                        // Legacy fallback: use legacy name as English
-                        event.NameEn = eventData.Name
-                }
-
-                if eventData.NameRU != "" {
-                        event.NameRu = eventData.NameRU
-                }
-
-                if eventData.DescriptionEN != "" {
-                        event.DescriptionEn = &eventData.DescriptionEN
-                } else if eventData.Description != "" {
-                        // Legacy fallback: use legacy description as English
-                        event.DescriptionEn = &eventData.Description
+                filter.From = &bound
+        }
+        
+        if to := query.Get("to"); to != "" {
+                bound, err := models.ParseDateBound(to, true)
+                if err != nil {
+                        return filter, fmt.Errorf("invalid to parameter: %v", err)
                 }
-
-                if eventData.DescriptionRU != "" {
-                        event.DescriptionRu = &eventData.DescriptionRU
+                filter.To = &bound
+        }
+        
+        if filter.From != nil && filter.To != nil && *filter.From > *filter.To {
+                return filter, fmt.Errorf("from must not be later than to")
+        }
+        
+        tagIDs, err := parseIDList(query.Get("tags"))
+        if err != nil {
+                return filter, fmt.Errorf("invalid tags parameter: %v", err)
+        }
+        filter.TagIDs = tagIDs
+        
+        switch mode := query.Get("tag_mode"); mode {
+        case "", models.TagModeAny:
+                filter.TagMode = models.TagModeAny
+        case models.TagModeAll:
+                filter.TagMode = models.TagModeAll
+        default:
+                return filter, fmt.Errorf("invalid tag_mode parameter %q (valid: any, all)", mode)
+        }
+        
+        lens := query.Get("lens")
+        if lens == "" {
+                lens = query.Get("lens_type")
+        }
+        for _, lensType := range strings.Split(lens, ",") {
+                if lensType = strings.TrimSpace(lensType); lensType != "" {
+                        filter.LensTypes = append(filter.LensTypes, lensType)
                 }
+        }
+        
+        dataset := query.Get("dataset")
+        if dataset == "" {
+                dataset = query.Get("dataset_id")
+        }
+        datasetIDs, err := parseIDList(dataset)
+        if err != nil {
+                return filter, fmt.Errorf("invalid dataset parameter: %v", err)
+        }
+        filter.DatasetIDs = datasetIDs
+        
+        return filter, nil
+}
 
-                // Set legacy fields for backward compatibility
-                if eventData.Name != "" {
-                        event.Name = eventData.Name
-                } else if eventData.NameEN != "" {
-                        event.Name = eventData.NameEN
-                }
+// Response formats selectable with ?format= or the Accept header
+const (
+        formatJSON    = "json"
+        formatGeoJSON = "geojson"
+        formatKML     = "kml"
+        formatKMZ     = "kmz"
+)
 
-                if eventData.Description != "" {
-                        event.Description = eventData.Description
-                } else if eventData.DescriptionEN != "" {
-                        event.Description = eventData.DescriptionEN
-                }
+// exportFormats are the formats of whole-set exports (GET /api/events and
+// dataset export)
+var exportFormats = []string{formatGeoJSON, formatKML, formatKMZ, formatCSV, formatXLSX}
 
-                // Set source if provided (handle optional field)
-                if eventData.Source != "" {
-                        event.Source = &eventData.Source
-                }
+// formatContentTypes maps alternative formats to the media type that selects
+// them in an Accept header
+var formatContentTypes = map[string]string{
+        formatGeoJSON: response.GeoJSONContentType,
+        formatKML:     kmlContentType,
+        formatKMZ:     kmzContentType,
+        formatCSV:     csvContentType,
+        formatXLSX:    xlsx.ContentType,
+}
 
-                // Save event
-                createdEvent, err := h.eventRepo.Create(event)
-                if err != nil {
-                        log.Printf("Failed to create event %s: %v", eventData.Name, err)
-                        continue
+// responseFormat returns the representation requested by the client among
+// the endpoint's supported formats: an explicit format query parameter wins,
+// otherwise a matching Accept header. The default is the regular JSON envelope.
+func responseFormat(r *http.Request, supported ...string) (string, error) {
+        if format := r.URL.Query().Get("format"); format != "" {
+                if format == formatJSON {
+                        return format, nil
                 }
-
-                // Handle tags - find or create and associate with event
-                if len(eventData.Tags) > 0 {
-                        var tagIDs []int
-                        
-                        // Get all existing tags to check for duplicates
-                        existingTags, err := h.tagRepo.GetAllTags()
-                        if err != nil {
-                                log.Printf("Failed to get existing tags: %v", err)
-                                existingTags = []models.Tag{} // Continue with empty list
-                        }
-                        
-                        for _, tagName := range eventData.Tags {
-                                // Check if tag already exists
-                                var foundTag *models.Tag
-                                for _, existing := range existingTags {
-                                        if strings.EqualFold(existing.Name, tagName) {
-                                                foundTag = &existing
-                                                break
-                                        }
-                                }
-                                
-                                if foundTag != nil {
-                                        // Use existing tag
-                                        tagIDs = append(tagIDs, foundTag.ID)
-                                } else {
-                                        // Create new tag with random color
-                                        tag := &models.Tag{
-                                                Name:        tagName,
-                                                Description: fmt.Sprintf("Auto-generated tag for %s", tagName),
-                                                Color:       h.generateRandomColor(),
-                                        }
-                                        
-                                        createdTag, err := h.tagRepo.CreateTag(tag)
-                                        if err != nil {
-                                                log.Printf("Failed to create tag %s: %v", tagName, err)
-                                                continue
-                                        }
-                                        
-                                        tagIDs = append(tagIDs, createdTag.ID)
-                                        // Add to existing tags list for next iteration
-                                        existingTags = append(existingTags, *createdTag)
-                                }
-                        }
-                        
-                        // Associate all tags with the event
-                        if len(tagIDs) > 0 {
-                                err = h.tagRepo.SetEventTags(createdEvent.ID, tagIDs)
-                                if err != nil {
-                                        log.Printf("Failed to associate tags with event %s: %v", eventData.Name, err)
-                                }
+                for _, s := range supported {
+                        if format == s {
+                                return format, nil
                         }
                 }
-
-                importedCount++
+                return "", fmt.Errorf("Invalid format parameter (valid: %s)", strings.Join(append([]string{formatJSON}, supported...), ", "))
         }
-
-        // Update dataset with final event count
-        err = h.datasetRepo.UpdateEventCount(createdDataset.ID, importedCount)
-        if err != nil {
-                log.Printf("Failed to update dataset event count: %v", err)
+        
+        accept := r.Header.Get("Accept")
+        for _, s := range supported {
+                if strings.Contains(accept, formatContentTypes[s]) {
+                        return s, nil
+                }
         }
+        return formatJSON, nil
+}
 
-        h.eventCache.Invalidate()
-
-        result := map[string]interface{}{
-                "success":        true,
-                "imported_count": importedCount,
-                "total_count":    len(req.Events),
-                "dataset_id":     createdDataset.ID,
-                "dataset_name":   createdDataset.Filename,
-                "message":        fmt.Sprintf("Successfully imported %d out of %d events", importedCount, len(req.Events)),
+// parseOptionalID parses an optional positive integer ID query parameter
+func parseOptionalID(query url.Values, name string) (*int, error) {
+        value := query.Get(name)
+        if value == "" {
+                return nil, nil
         }
-        if len(skippedEvents) > 0 {
-                result["skipped_count"] = len(skippedEvents)
-                result["skipped_events"] = skippedEvents
+        id, err := strconv.Atoi(value)
+        if err != nil || id <= 0 {
+                return nil, fmt.Errorf("Invalid %s parameter", name)
         }
-        response.Success(w, result)
+        return &id, nil
 }
 
-// formatDisplayDate formats a date for display with era
-func formatDisplayDate(date time.Time, era string) string {
-        if era == "BC" {
-                // BC dates are stored as positive dates, use them directly
-                return fmt.Sprintf("%02d.%02d.%d BC", date.Day(), date.Month(), date.Year())
+// parseIDList parses a comma-separated list of positive integer IDs
+func parseIDList(value string) ([]int, error) {
+        var ids []int
+        for _, part := range strings.Split(value, ",") {
+                part = strings.TrimSpace(part)
+                if part == "" {
+                        continue
+                }
+                id, err := strconv.Atoi(part)
+                if err != nil || id < 1 {
+                        return nil, fmt.Errorf("%q is not a valid ID", part)
+                }
+                ids = append(ids, id)
         }
-        return fmt.Sprintf("%02d.%02d.%d AD", date.Day(), date.Month(), date.Year())
+        return ids, nil
 }
 
 // parseCoordinate parses a coordinate string to float64
 func parseCoordinate(coord string) (float64, error) {
         return strconv.ParseFloat(coord, 64)
 }
-
-// generateRandomColor generates a random hex color for tags
-func (h *EventHandler) generateRandomColor() string {
-        // Predefined vibrant colors that work well for tags
-        colors := []string{
-                "#3B82F6", // Blue
-                "#EF4444", // Red
-                "#10B981", // Green
-                "#F59E0B", // Orange
-                "#8B5CF6", // Purple
-                "#06B6D4", // Cyan
-                "#EC4899", // Pink
-                "#84CC16", // Lime
-                "#F97316", // Orange
-                "#6366F1", // Indigo
-                "#14B8A6", // Teal
-                "#F43F5E", // Rose
-        }
-        return colors[rand.Intn(len(colors))]
-}
\ No newline at end of file
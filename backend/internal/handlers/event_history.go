@@ -0,0 +1,215 @@
+package handlers
+
+import (
+        "errors"
+        "fmt"
+        "historical-events-backend/internal/database/repositories"
+        "historical-events-backend/internal/models"
+        "historical-events-backend/pkg/response"
+        "log"
+        "net/http"
+        "strconv"
+        "strings"
+        "time"
+
+        "github.com/gorilla/mux"
+)
+
+// GetEventHistory handles GET /api/events/{id}/history: the revisions of an
+// event, newest first, each with the fields it changed
+func (h *EventHandler) GetEventHistory(w http.ResponseWriter, r *http.Request) {
+        id, err := strconv.Atoi(mux.Vars(r)["id"])
+        if err != nil {
+                response.BadRequest(w, "Invalid event ID")
+                return
+        }
+
+        revisions, err := h.revisionRepo.GetByEventID(id)
+        if err != nil {
+                log.Printf("Error getting history of event %d: %v", id, err)
+                response.InternalError(w, "Failed to get event history")
+                return
+        }
+        if len(revisions) == 0 {
+                // Events from before revisions were recorded have no history yet
+                if _, err := h.eventRepo.GetByID(id); err != nil {
+                        response.NotFound(w, "Event not found")
+                        return
+                }
+        }
+
+        for i := range revisions {
+                var prev *models.EventSnapshot
+                if i > 0 {
+                        prev = &revisions[i-1].Snapshot
+                }
+                changes, err := revisions[i].Snapshot.Diff(prev)
+                if err != nil {
+                        log.Printf("Error comparing revisions of event %d: %v", id, err)
+                        response.InternalError(w, "Failed to get event history")
+                        return
+                }
+                revisions[i].Changes = changes
+        }
+
+        // Newest first
+        for i, j := 0, len(revisions)-1; i < j; i, j = i+1, j-1 {
+                revisions[i], revisions[j] = revisions[j], revisions[i]
+        }
+
+        response.Success(w, revisions)
+}
+
+// RevertEvent handles POST /api/events/{id}/revert/{rev}: the event gets the
+// fields and tags of revision rev back, recorded as a new revision. Like any
+// other write of the event it needs the event's ETag in If-Match.
+func (h *EventHandler) RevertEvent(w http.ResponseWriter, r *http.Request) {
+        vars := mux.Vars(r)
+        id, err := strconv.Atoi(vars["id"])
+        if err != nil {
+                response.BadRequest(w, "Invalid event ID")
+                return
+        }
+        revisionID, err := strconv.Atoi(vars["rev"])
+        if err != nil {
+                response.BadRequest(w, "Invalid revision ID")
+                return
+        }
+
+        version, ok := ifMatchVersion(w, r)
+        if !ok {
+                return
+        }
+
+        locale := r.URL.Query().Get("locale")
+        if locale == "" {
+                locale = "en"
+        }
+
+        revision, err := h.revisionRepo.GetByID(id, revisionID)
+        if err != nil {
+                if err.Error() == "revision not found" {
+                        response.NotFound(w, "Revision not found")
+                        return
+                }
+                log.Printf("Error getting revision %d of event %d: %v", revisionID, id, err)
+                response.InternalError(w, "Failed to revert event")
+                return
+        }
+
+        event, err := h.eventRepo.GetByID(id)
+        if err != nil {
+                if strings.Contains(err.Error(), "not found") {
+                        response.NotFound(w, "Event not found")
+                        return
+                }
+                log.Printf("Error getting event %d: %v", id, err)
+                response.InternalError(w, "Failed to revert event")
+                return
+        }
+        if version != 0 && version != event.Version {
+                h.writeEventConflict(w, id, locale)
+                return
+        }
+        previousDatasetID := event.DatasetID
+
+        // The revision's dataset may have been deleted (or purged) since
+        if datasetID := revision.Snapshot.DatasetID; datasetID != nil && *datasetID > 0 {
+                if _, err := h.datasetRepo.GetByID(*datasetID); err != nil {
+                        if err.Error() == "dataset not found" {
+                                response.Error(w, http.StatusConflict, "The revision's dataset no longer exists", "restore the dataset from the trash first")
+                                return
+                        }
+                        log.Printf("Error getting dataset %d: %v", *datasetID, err)
+                        response.InternalError(w, "Failed to revert event")
+                        return
+                }
+        }
+
+        userID := 1 // Default to admin user
+        if user := getUserFromContext(r.Context()); user != nil {
+                userID = user.ID
+        }
+        revision.Snapshot.Apply(event)
+        event.UpdatedBy = &userID
+        event.UpdatedAt = time.Now()
+
+        // Tags deleted since the revision cannot come back
+        tagIDs, err := h.existingTagIDs(revision.Snapshot.TagIDs())
+        if err != nil {
+                log.Printf("Error getting tags: %v", err)
+                response.InternalError(w, "Failed to revert event")
+                return
+        }
+
+        tx, err := h.eventRepo.Begin()
+        if err != nil {
+                log.Printf("Error reverting event %d: %v", id, err)
+                response.InternalError(w, "Failed to revert event")
+                return
+        }
+        defer tx.Rollback()
+
+        _, err = h.eventRepo.UpdateTx(tx, event)
+        if err == nil {
+                err = h.tagRepo.SetEventTagsTx(tx, id, tagIDs)
+        }
+        if err == nil {
+                err = h.revisionRepo.RecordRevertTx(tx, id, &userID, revisionID)
+        }
+        if err == nil {
+                err = tx.Commit()
+        }
+        if errors.Is(err, repositories.ErrVersionConflict) {
+                h.writeEventConflict(w, id, locale)
+                return
+        }
+        if err != nil {
+                log.Printf("Error reverting event %d: %v", id, err)
+                response.InternalError(w, "Failed to revert event")
+                return
+        }
+
+        // Mark the datasets the event was and is in as modified
+        for _, datasetID := range []*int{previousDatasetID, event.DatasetID} {
+                if datasetID != nil && *datasetID > 0 {
+                        if err := h.datasetRepo.MarkAsModified(*datasetID); err != nil {
+                                log.Printf("Warning: failed to mark dataset as modified: %v", err)
+                        }
+                }
+        }
+
+        h.eventCache.Invalidate()
+
+        reverted, err := h.eventRepo.GetByID(id)
+        if err != nil {
+                log.Printf("Error getting reverted event %d: %v", id, err)
+                response.InternalError(w, "Event was reverted but could not be read")
+                return
+        }
+
+        reverted.PopulateLegacyFields(locale)
+
+        setETag(w, reverted.Version)
+        response.JSON(w, http.StatusOK, response.SuccessResponse{Data: reverted, Message: fmt.Sprintf("Event reverted to revision %d", revisionID)})
+}
+
+// existingTagIDs keeps the tag IDs that still exist
+func (h *EventHandler) existingTagIDs(ids []int) ([]int, error) {
+        tags, err := h.tagRepo.GetAllTags()
+        if err != nil {
+                return nil, err
+        }
+        exists := make(map[int]bool, len(tags))
+        for _, tag := range tags {
+                exists[tag.ID] = true
+        }
+
+        var kept []int
+        for _, id := range ids {
+                if exists[id] {
+                        kept = append(kept, id)
+                }
+        }
+        return kept, nil
+}
@@ -0,0 +1,956 @@
+package handlers
+
+import (
+        "context"
+        "database/sql"
+        "encoding/json"
+        "errors"
+        "fmt"
+        "historical-events-backend/internal/database/repositories"
+        "historical-events-backend/internal/models"
+        "historical-events-backend/internal/services"
+        "historical-events-backend/pkg/response"
+        "log"
+        "math"
+        "math/rand"
+        "net/http"
+        "strconv"
+        "strings"
+        "time"
+)
+
+// importEvent is one event of a dataset import, in the dataset file format.
+// CSV and XLSX rows are converted to the same shape.
+type importEvent struct {
+        // Legacy fields for backward compatibility
+        Name        string   `json:"name,omitempty"`
+        Description string   `json:"description,omitempty"`
+        // New locale-specific fields
+        NameEN         string   `json:"name_en,omitempty"`
+        NameRU         string   `json:"name_ru,omitempty"`
+        DescriptionEN  string   `json:"description_en,omitempty"`
+        DescriptionRU  string   `json:"description_ru,omitempty"`
+        Date           string   `json:"date"`
+        Era            string   `json:"era"`
+        Calendar       string   `json:"calendar,omitempty"`  // julian or gregorian; defaults to Julian before 1582
+        Precision      string   `json:"precision,omitempty"` // day, month, year, decade, century, millennium
+        Circa          bool     `json:"circa,omitempty"`
+        Earliest       string   `json:"earliest,omitempty"`     // Optional lower bound, same formats as date
+        EarliestEra    string   `json:"earliest_era,omitempty"` // Defaults to era
+        Latest         string   `json:"latest,omitempty"`       // Optional upper bound, same formats as date
+        LatestEra      string   `json:"latest_era,omitempty"`   // Defaults to era
+        EndDate        string   `json:"end_date,omitempty"` // Optional end of an event with a duration
+        EndEra         string   `json:"end_era,omitempty"`  // Defaults to era
+        Latitude       float64  `json:"latitude"`
+        Longitude      float64  `json:"longitude"`
+        Type           string   `json:"type"`
+        Tags           []string `json:"tags"`
+        Source         string   `json:"source,omitempty"`
+        ExternalID     string   `json:"external_id,omitempty"` // Stable key used to match the event on re-import
+        
+        // Row is the 1-based position of the event in the request, or its
+        // spreadsheet row number for CSV/XLSX uploads
+        Row int `json:"-"`
+}
+
+// importRequest is the body of POST /api/events/import
+type importRequest struct {
+        Filename string        `json:"filename,omitempty"`
+        Events   []importEvent `json:"events"`
+
+        precisionColumn bool // The CSV/XLSX header has a precision column
+}
+
+// padsYears reports whether full dates on 1 January without a precision are
+// year-only dates padded to a day, as older datasets wrote them. Only a
+// dataset with no precision column or field at all can be such a dataset; in
+// any other, 01.01.YYYY is the day it says.
+func (req importRequest) padsYears() bool {
+        if req.precisionColumn {
+                return false
+        }
+        for _, event := range req.Events {
+                if event.Precision != "" {
+                        return false
+                }
+        }
+        return true
+}
+
+// importLensTypes are the lens types an imported event may have
+var importLensTypes = map[string]bool{
+        "historic":   true,
+        "political":  true,
+        "military":   true,
+        "cultural":   true,
+        "religious":  true,
+        "scientific": true,
+        "battle":     true,
+}
+
+// duplicateDistanceKm is how close an existing event with the same name must
+// be to count as a likely duplicate when the dates differ
+const duplicateDistanceKm = 10.0
+
+// plannedEvent is a validated import row, ready to be written
+type plannedEvent struct {
+        row        int
+        event      *models.HistoricalEvent
+        tags       []string
+        existingID int  // Event the row updates on re-import; 0 creates a new one
+        saved      bool // Set by writeImport once the row is written
+}
+
+// Import error policies. A strict import is rejected as a whole when any row
+// has an error; a lenient import skips failing rows and imports the rest.
+const (
+        importModeStrict  = "strict"
+        importModeLenient = "lenient"
+)
+
+// errImportRejected aborts a strict import on the first row that fails to save
+var errImportRejected = errors.New("import rejected")
+
+// importOptions are the query parameters shared by imports and re-imports
+type importOptions struct {
+        dryRun bool
+        mode   string
+        async  bool // Run as a background job
+        bulk   bool // Write with COPY and set-based SQL instead of row by row
+        fuzzy  bool // Also flag rows similar to existing events (fuzzy_duplicates)
+}
+
+// importProgress is called with the number of events written so far and the
+// number to write
+type importProgress func(done, total int)
+
+func noImportProgress(done, total int) {}
+
+// importResult is the outcome of an import: the status and body of the
+// synchronous response, which an import job stores as its result
+type importResult struct {
+        status    int
+        body      interface{}
+        message   string // Error message when status is not 200
+        datasetID *int
+}
+
+func importSucceeded(data map[string]interface{}, datasetID int) importResult {
+        return importResult{status: http.StatusOK, body: response.SuccessResponse{Data: data}, datasetID: &datasetID}
+}
+
+// importFailed is an error response, carrying the report when there is one
+func importFailed(status int, message string, report *models.ImportReport) importResult {
+        if report == nil {
+                return importResult{status: status, body: response.ErrorResponse{Error: message, Code: status}, message: message}
+        }
+        return importResult{
+                status:  status,
+                body:    map[string]interface{}{"error": message, "code": status, "report": report},
+                message: message,
+        }
+}
+
+// err returns the error message of a failed import
+func (res importResult) err() error {
+        if res.status == http.StatusOK {
+                return nil
+        }
+        return errors.New(res.message)
+}
+
+func (res importResult) write(w http.ResponseWriter) {
+        response.JSON(w, res.status, res.body)
+}
+
+// ImportEvents handles bulk importing of events from dataset. Besides the JSON
+// dataset format it accepts CSV and XLSX uploads as multipart/form-data.
+//
+// Every row is validated before anything is written, and the response carries
+// a report with per-row issues, the tags that get created and likely
+// duplicates. With ?dry_run=true only the report is returned and nothing is
+// written.
+//
+// The dataset, its events, new tags and tag links are written in a single
+// transaction that is only committed when the error policy (?mode=strict or
+// lenient, the default) passes, so a failed import leaves nothing behind.
+// With ?async=true the import runs as a background job and the response is
+// the job to poll.
+func (h *EventHandler) ImportEvents(w http.ResponseWriter, r *http.Request) {
+        opts, err := parseImportOptions(r)
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+
+        req, readIssues, err := readImportRequest(r)
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+
+        totalRows := len(req.Events) + len(readIssues)
+        if totalRows == 0 {
+                response.BadRequest(w, "No events provided for import")
+                return
+        }
+
+        // Get user ID from request context (set by auth middleware)
+        userID := 1 // Default to admin user
+        if user := getUserFromContext(r.Context()); user != nil {
+                userID = user.ID
+        }
+
+        run := func(ctx context.Context, progress importProgress) importResult {
+                return h.runImport(ctx, req, readIssues, opts, userID, progress)
+        }
+        if opts.async {
+                h.submitImportJob(w, &models.ImportJob{Kind: models.ImportJobImport, Filename: req.Filename, TotalRows: totalRows, CreatedBy: &userID}, run)
+                return
+        }
+        run(r.Context(), noImportProgress).write(w)
+}
+
+// runImport validates the rows and, unless it is a dry run, writes them as a
+// new dataset
+func (h *EventHandler) runImport(ctx context.Context, req importRequest, readIssues []models.ImportIssue, opts importOptions, userID int, progress importProgress) importResult {
+        totalRows := len(req.Events) + len(readIssues)
+        report := models.NewImportReport(totalRows, opts.dryRun)
+        report.Mode = opts.mode
+        for _, issue := range readIssues {
+                report.AddError(issue.Row, issue.Field, issue.Code, issue.Message)
+        }
+
+        planned, tagIDs, err := h.planImport(req, report, 0, opts.fuzzy)
+        if err != nil {
+                log.Printf("Failed to validate import: %v", err)
+                return importFailed(http.StatusInternalServerError, "Failed to validate import", nil)
+        }
+
+        if opts.dryRun {
+                message := fmt.Sprintf("%d out of %d events would be imported", len(planned), totalRows)
+                if opts.mode == importModeStrict && report.ErrorCount > 0 {
+                        message = fmt.Sprintf("The import would be rejected: %d errors in strict mode", report.ErrorCount)
+                }
+                return importResult{status: http.StatusOK, body: response.SuccessResponse{Data: map[string]interface{}{
+                        "success":     true,
+                        "dry_run":     true,
+                        "total_count": totalRows,
+                        "valid_count": len(planned),
+                        "report":      report,
+                        "message":     message,
+                }}}
+        }
+
+        if len(planned) == 0 {
+                return importFailed(http.StatusBadRequest, "No valid events to import", report)
+        }
+        if opts.mode == importModeStrict && report.ErrorCount > 0 {
+                return importFailed(http.StatusBadRequest, fmt.Sprintf("Import rejected: %d errors in strict mode", report.ErrorCount), report)
+        }
+
+        // Create dataset record
+        filename := req.Filename
+        if filename == "" {
+                filename = "imported_dataset.json"
+        }
+        
+        dataset := &models.EventDataset{
+                Filename:    filename,
+                Description: fmt.Sprintf("Dataset imported with %d events", len(planned)),
+                EventCount:  0, // Will be updated once the events are written
+                UploadedBy:  userID,
+        }
+
+        tx, err := h.datasetRepo.Begin()
+        if err != nil {
+                log.Printf("Failed to start import: %v", err)
+                return importFailed(http.StatusInternalServerError, "Failed to start import", nil)
+        }
+        defer tx.Rollback()
+        
+        createdDataset, err := h.datasetRepo.CreateTx(tx, dataset)
+        if err != nil {
+                log.Printf("Failed to create dataset record: %v", err)
+                return importFailed(http.StatusInternalServerError, "Failed to create dataset record", nil)
+        }
+
+        var importedCount int
+        if opts.bulk {
+                importedCount, err = h.writeBulkImport(ctx, tx, createdDataset.ID, planned, report, userID, progress)
+        } else {
+                importedCount, err = h.writeImport(ctx, tx, createdDataset.ID, planned, tagIDs, report, opts.mode == importModeStrict, userID, progress)
+        }
+        if err != nil {
+                return writeImportFailed(ctx, err, report)
+        }
+        if importedCount == 0 {
+                return importFailed(http.StatusBadRequest, "No events could be saved, nothing was imported", report)
+        }
+
+        // Update dataset with final event count
+        if err := h.datasetRepo.UpdateEventCountTx(tx, createdDataset.ID, importedCount); err != nil {
+                log.Printf("Failed to update dataset event count: %v", err)
+                return importFailed(http.StatusInternalServerError, "Failed to import events, nothing was imported", report)
+        }
+
+        if err := tx.Commit(); err != nil {
+                log.Printf("Failed to commit import: %v", err)
+                return importFailed(http.StatusInternalServerError, "Failed to import events, nothing was imported", report)
+        }
+
+        h.eventCache.Invalidate()
+
+        result := map[string]interface{}{
+                "success":        true,
+                "imported_count": importedCount,
+                "total_count":    totalRows,
+                "dataset_id":     createdDataset.ID,
+                "dataset_name":   createdDataset.Filename,
+                "report":         report,
+                "message":        fmt.Sprintf("Successfully imported %d out of %d events", importedCount, totalRows),
+        }
+        if skipped := skippedRowMessages(report); len(skipped) > 0 {
+                result["skipped_count"] = len(skipped)
+                result["skipped_events"] = skipped
+        }
+        return importSucceeded(result, createdDataset.ID)
+}
+
+// writeImportFailed turns a writeImport error into the import result. The
+// caller's transaction is rolled back, so nothing was written.
+func writeImportFailed(ctx context.Context, err error, report *models.ImportReport) importResult {
+        report.ImportedCount = 0
+        switch {
+        case errors.Is(err, errImportRejected):
+                return importFailed(http.StatusBadRequest, "Import rejected: an event could not be saved, nothing was imported", report)
+        case ctx.Err() != nil:
+                return importFailed(http.StatusServiceUnavailable, "Import cancelled, nothing was imported", report)
+        }
+        log.Printf("Failed to import events: %v", err)
+        return importFailed(http.StatusInternalServerError, "Failed to import events, nothing was imported", report)
+}
+
+// submitImportJob starts an import in the background and responds with the
+// job, 202 Accepted
+func (h *EventHandler) submitImportJob(w http.ResponseWriter, job *models.ImportJob, run func(ctx context.Context, progress importProgress) importResult) {
+        created, err := h.jobRunner.Submit(job, func(ctx context.Context, progress func(done, total int)) (interface{}, *int, error) {
+                res := run(ctx, progress)
+                return res.body, res.datasetID, res.err()
+        })
+        if err != nil {
+                if errors.Is(err, services.ErrShuttingDown) {
+                        response.Error(w, http.StatusServiceUnavailable, "Server is shutting down, try again later")
+                        return
+                }
+                log.Printf("Failed to start import job: %v", err)
+                response.InternalError(w, "Failed to start import job")
+                return
+        }
+
+        w.Header().Set("Location", fmt.Sprintf("/api/jobs/%d", created.ID))
+        response.JSON(w, http.StatusAccepted, response.SuccessResponse{Data: created, Message: "Import started"})
+}
+
+// parseImportOptions reads the dry_run, mode, async, bulk and
+// fuzzy_duplicates query parameters of an import
+func parseImportOptions(r *http.Request) (importOptions, error) {
+        opts := importOptions{mode: r.URL.Query().Get("mode")}
+        for name, flag := range map[string]*bool{"dry_run": &opts.dryRun, "async": &opts.async, "bulk": &opts.bulk, "fuzzy_duplicates": &opts.fuzzy} {
+                if value := r.URL.Query().Get(name); value != "" {
+                        var err error
+                        if *flag, err = strconv.ParseBool(value); err != nil {
+                                return importOptions{}, fmt.Errorf("Invalid %s parameter", name)
+                        }
+                }
+        }
+
+        if opts.mode == "" {
+                opts.mode = importModeLenient
+        }
+        if opts.mode != importModeStrict && opts.mode != importModeLenient {
+                return importOptions{}, fmt.Errorf("Invalid mode parameter (valid: strict, lenient)")
+        }
+        return opts, nil
+}
+
+// readImportRequest reads a JSON dataset body or a CSV/XLSX upload. Rows of
+// an upload that cannot be read are returned as import errors.
+func readImportRequest(r *http.Request) (importRequest, []models.ImportIssue, error) {
+        if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+                return parseTabularImport(r)
+        }
+
+        var req importRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+                return importRequest{}, nil, fmt.Errorf("Invalid JSON format")
+        }
+        for i := range req.Events {
+                req.Events[i].Row = i + 1
+        }
+        return req, nil, nil
+}
+
+// planImport validates every row and converts the valid ones into events. It
+// records issues, the tags that would be created and likely duplicates in the
+// report, and returns the IDs of existing tags by lower-cased name. Events of
+// datasetID (the dataset being re-imported, or 0) are not reported as
+// duplicates. With fuzzy, rows similar to existing events are flagged too.
+func (h *EventHandler) planImport(req importRequest, report *models.ImportReport, datasetID int, fuzzy bool) ([]plannedEvent, map[string]int, error) {
+        existingTags, err := h.tagRepo.GetAllTags()
+        if err != nil {
+                return nil, nil, fmt.Errorf("failed to get existing tags: %w", err)
+        }
+        tagIDs := make(map[string]int, len(existingTags))
+        for _, tag := range existingTags {
+                tagIDs[strings.ToLower(tag.Name)] = tag.ID
+        }
+
+        var planned []plannedEvent
+        newTags := make(map[string]bool)
+        externalIDs := make(map[string]int)
+        padsYears := req.padsYears()
+        for _, data := range req.Events {
+                event, ok := validateImportEvent(data, padsYears, report)
+                if !ok {
+                        continue
+                }
+                if event.ExternalID != nil {
+                        if row, seen := externalIDs[*event.ExternalID]; seen {
+                                report.AddError(data.Row, "external_id", models.ImportDuplicateExternalID, fmt.Sprintf("external_id '%s' is already used by row %d", *event.ExternalID, row))
+                                continue
+                        }
+                        externalIDs[*event.ExternalID] = data.Row
+                }
+
+                var tags []string
+                seen := make(map[string]bool)
+                for _, name := range data.Tags {
+                        name = strings.TrimSpace(name)
+                        key := strings.ToLower(name)
+                        if name == "" || seen[key] {
+                                continue
+                        }
+                        seen[key] = true
+                        tags = append(tags, name)
+                        if _, exists := tagIDs[key]; !exists && !newTags[key] {
+                                newTags[key] = true
+                                report.NewTags = append(report.NewTags, name)
+                        }
+                }
+
+                planned = append(planned, plannedEvent{row: data.Row, event: event, tags: tags})
+        }
+        report.ValidRows = len(planned)
+
+        if err := h.findImportDuplicates(planned, report, datasetID); err != nil {
+                return nil, nil, err
+        }
+        if fuzzy {
+                if err := h.findSimilarImportDuplicates(planned, report, datasetID); err != nil {
+                        return nil, nil, err
+                }
+        }
+        return planned, tagIDs, nil
+}
+
+// validateImportEvent converts one import row into an event, recording every
+// problem in the report. It returns false when the row cannot be imported.
+// padsYears is importRequest.padsYears of the row's dataset.
+func validateImportEvent(data importEvent, padsYears bool, report *models.ImportReport) (*models.HistoricalEvent, bool) {
+        row := data.Row
+        valid := true
+        fail := func(field, code, message string) {
+                report.AddError(row, field, code, message)
+                valid = false
+        }
+
+        if data.NameEN == "" && data.Name == "" && data.NameRU == "" {
+                fail("name", models.ImportMissingName, "missing name")
+        }
+
+        if data.Type == "" {
+                fail("type", models.ImportMissingType, "missing required field 'type'")
+        } else if !importLensTypes[data.Type] {
+                fail("type", models.ImportInvalidType, fmt.Sprintf("invalid type '%s' (valid: historic, political, military, cultural, religious, scientific, battle)", data.Type))
+        }
+
+        if data.Calendar != "" && !models.IsValidCalendar(data.Calendar) {
+                fail("calendar", models.ImportInvalidValue, fmt.Sprintf("invalid calendar '%s' (valid: julian, gregorian)", data.Calendar))
+        }
+        if data.Precision != "" && !models.IsValidDatePrecision(data.Precision) {
+                fail("precision", models.ImportInvalidValue, fmt.Sprintf("invalid precision '%s'", data.Precision))
+        }
+        if data.Era != "" && data.Era != models.EraBC && data.Era != models.EraAD {
+                fail("era", models.ImportInvalidValue, fmt.Sprintf("invalid era '%s' (valid: BC, AD)", data.Era))
+        }
+
+        if data.Latitude < -90 || data.Latitude > 90 {
+                fail("latitude", models.ImportInvalidLatitude, fmt.Sprintf("latitude must be between -90 and 90 degrees, got %g", data.Latitude))
+        }
+        if data.Longitude < -180 || data.Longitude > 180 {
+                fail("longitude", models.ImportInvalidLongitude, fmt.Sprintf("longitude must be between -180 and 180 degrees, got %g", data.Longitude))
+        }
+
+        // Parse date (DD.MM.YYYY, MM.YYYY or YYYY) and its precision
+        var eventDate models.HistoricDate
+        var precision string
+        if valid {
+                var err error
+                eventDate, precision, err = parseImportDate(data.Date, data.Era, data.Calendar, data.Precision, padsYears)
+                if err != nil {
+                        fail("date", models.ImportInvalidDate, err.Error())
+                }
+        } else if _, _, err := models.ParseDatasetDate(data.Date, data.Era); err != nil {
+                // Still report a bad date on a row that already failed
+                fail("date", models.ImportInvalidDate, err.Error())
+        }
+        if !valid {
+                return nil, false
+        }
+
+        // Store BC dates as positive dates (same as predefined events)
+        // The era field indicates BC/AD, astronomical conversion happens in views
+        event := &models.HistoricalEvent{
+                Latitude:      data.Latitude,
+                Longitude:     data.Longitude,
+                EventDate:     eventDate,
+                DatePrecision: precision,
+                Circa:         data.Circa,
+                LensType:      data.Type,
+                DisplayDate:   eventDate.Display(precision, data.Circa),
+        }
+
+        // Optional end date, read with the event's precision
+        if endDate, err := parseImportOptionalDate(data.EndDate, data.EndEra, eventDate); err != nil {
+                report.AddWarning(row, "end_date", models.ImportInvalidOptional, fmt.Sprintf("end date ignored: %v", err))
+        } else if endDate != nil && endDate.Before(eventDate) {
+                report.AddWarning(row, "end_date", models.ImportEndBeforeStart, fmt.Sprintf("end date %s ignored: earlier than the event date", endDate))
+        } else {
+                event.EndDate = endDate
+        }
+
+        // Optional uncertainty bounds, in the same formats as the date
+        var err error
+        if event.EarliestDate, err = parseImportOptionalDate(data.Earliest, data.EarliestEra, eventDate); err != nil {
+                report.AddWarning(row, "earliest", models.ImportInvalidOptional, fmt.Sprintf("earliest date ignored: %v", err))
+        }
+        if event.LatestDate, err = parseImportOptionalDate(data.Latest, data.LatestEra, eventDate); err != nil {
+                report.AddWarning(row, "latest", models.ImportInvalidOptional, fmt.Sprintf("latest date ignored: %v", err))
+        }
+        if event.EarliestDate != nil && event.LatestDate != nil && event.EarliestDate.After(*event.LatestDate) {
+                report.AddWarning(row, "earliest", models.ImportInvalidOptional, "earliest and latest dates ignored: earliest is after latest")
+                event.EarliestDate, event.LatestDate = nil, nil
+        }
+
+        // Handle locale-specific fields with fallbacks
+        if data.NameEN != "" {
+                event.NameEn = data.NameEN
+        } else if data.Name != "" {
+                // Legacy fallback: use legacy name as English
+                event.NameEn = data.Name
+        }
+
+        if data.NameRU != "" {
+                event.NameRu = data.NameRU
+        }
+
+        if data.DescriptionEN != "" {
+                event.DescriptionEn = &data.DescriptionEN
+        } else if data.Description != "" {
+                // Legacy fallback: use legacy description as English
+                event.DescriptionEn = &data.Description
+        }
+
+        if data.DescriptionRU != "" {
+                event.DescriptionRu = &data.DescriptionRU
+        }
+
+        // Set legacy fields for backward compatibility
+        if data.Name != "" {
+                event.Name = data.Name
+        } else if data.NameEN != "" {
+                event.Name = data.NameEN
+        } else {
+                event.Name = data.NameRU
+        }
+
+        if data.Description != "" {
+                event.Description = data.Description
+        } else if data.DescriptionEN != "" {
+                event.Description = data.DescriptionEN
+        }
+
+        // Set source if provided (handle optional field)
+        if data.Source != "" {
+                event.Source = &data.Source
+        }
+
+        if externalID := strings.TrimSpace(data.ExternalID); externalID != "" {
+                event.ExternalID = &externalID
+        }
+
+        return event, true
+}
+
+// findImportDuplicates flags planned events that probably already exist: an
+// existing event outside datasetID with the same name (English or Russian,
+// ignoring case) on the same date or within duplicateDistanceKm, or an
+// earlier row of the same import with the same name and date
+func (h *EventHandler) findImportDuplicates(planned []plannedEvent, report *models.ImportReport, datasetID int) error {
+        var names []string
+        for _, p := range planned {
+                for _, name := range []string{p.event.NameEn, p.event.NameRu} {
+                        if name != "" {
+                                names = append(names, name)
+                        }
+                }
+        }
+
+        existing, err := h.eventRepo.FindByNames(names)
+        if err != nil {
+                return err
+        }
+        byName := make(map[string][]models.HistoricalEvent)
+        for _, event := range existing {
+                if datasetID != 0 && event.DatasetID != nil && *event.DatasetID == datasetID {
+                        continue
+                }
+                for _, name := range []string{event.NameEn, event.NameRu} {
+                        if name != "" {
+                                key := strings.ToLower(name)
+                                byName[key] = append(byName[key], event)
+                        }
+                }
+        }
+
+        firstRow := make(map[string]int)
+        for _, p := range planned {
+                event := p.event
+                name := strings.ToLower(event.NameEn)
+                if name == "" {
+                        name = strings.ToLower(event.NameRu)
+                }
+                duplicate := models.ImportDuplicate{Row: p.row, Name: event.GetNameForLocale("en"), DisplayDate: event.DisplayDate}
+                if duplicate.Name == "" {
+                        duplicate.Name = event.NameRu
+                }
+
+                key := name + "|" + event.EventDate.String()
+                if row, seen := firstRow[key]; seen {
+                        duplicate.DuplicateOf = row
+                        duplicate.Reason = fmt.Sprintf("same name and date as row %d", row)
+                        report.Duplicates = append(report.Duplicates, duplicate)
+                        continue
+                }
+                firstRow[key] = p.row
+
+                candidates := append(byName[strings.ToLower(event.NameEn)], byName[strings.ToLower(event.NameRu)]...)
+                for _, candidate := range candidates {
+                        if candidate.EventDate.Compare(event.EventDate) == 0 {
+                                duplicate.EventID = candidate.ID
+                                duplicate.Reason = "same name and date as an existing event"
+                        } else if km := distanceKm(candidate.Latitude, candidate.Longitude, event.Latitude, event.Longitude); km <= duplicateDistanceKm {
+                                duplicate.EventID = candidate.ID
+                                duplicate.Reason = fmt.Sprintf("same name as an existing event %.1f km away (%s)", km, candidate.DisplayDate)
+                        } else {
+                                continue
+                        }
+                        report.Duplicates = append(report.Duplicates, duplicate)
+                        break
+                }
+        }
+        return nil
+}
+
+// findSimilarImportDuplicates flags planned events that are similar to an
+// existing event by the default duplicate detection thresholds (see GET
+// /api/events/duplicates), unless findImportDuplicates already flagged the
+// row. Events of datasetID are ignored.
+func (h *EventHandler) findSimilarImportDuplicates(planned []plannedEvent, report *models.ImportReport, datasetID int) error {
+        flagged := make(map[int]bool)
+        for _, duplicate := range report.Duplicates {
+                flagged[duplicate.Row] = true
+        }
+
+        var probes []*models.HistoricalEvent
+        var probeRows []plannedEvent
+        for _, p := range planned {
+                if !flagged[p.row] {
+                        probes = append(probes, p.event)
+                        probeRows = append(probeRows, p)
+                }
+        }
+
+        matches, err := h.eventRepo.FindSimilar(probes, models.DefaultDuplicateOptions(), datasetID)
+        if err != nil {
+                return err
+        }
+
+        for i, p := range probeRows {
+                best, ok := matches[i]
+                if !ok {
+                        continue
+                }
+                match := best[0]
+                duplicate := models.ImportDuplicate{Row: p.row, EventID: match.EventID, Name: p.event.GetNameForLocale("en"), Score: match.Score,
+                        Reason: fmt.Sprintf("similar to an existing event: names %.0f%% alike, %.1f years and %.1f km apart", match.NameSimilarity*100, match.YearsApart, match.DistanceKm)}
+                if duplicate.Name == "" {
+                        duplicate.Name = p.event.NameRu
+                }
+                report.Duplicates = append(report.Duplicates, duplicate)
+        }
+        return nil
+}
+
+// writeImport saves the planned events in the dataset as part of tx,
+// creating missing tags. Rows with an existingID update that event, the others
+// create new ones. In lenient mode every row is written in its own savepoint,
+// so a row that fails is rolled back and recorded in the report while the
+// rest goes on; in strict mode the first failure returns errImportRejected.
+// It reports progress after every row, stops when ctx is cancelled, and
+// returns the number of events saved.
+func (h *EventHandler) writeImport(ctx context.Context, tx *sql.Tx, datasetID int, planned []plannedEvent, tagIDs map[string]int, report *models.ImportReport, strict bool, userID int, progress importProgress) (int, error) {
+        importedCount := 0
+        progress(0, len(planned))
+        for i := range planned {
+                if err := ctx.Err(); err != nil {
+                        return 0, err
+                }
+                p := &planned[i]
+                progress(i, len(planned))
+                p.event.DatasetID = &datasetID
+
+                // Tags created for this row, kept only if the row is saved
+                rowTags := make(map[string]int)
+                err := repositories.WithSavepoint(tx, "import_row", func() error {
+                        // Save event
+                        savedEvent, err := h.saveImportEvent(tx, p, userID)
+                        if err != nil {
+                                log.Printf("Failed to save event %s: %v", p.event.Name, err)
+                                report.AddError(p.row, "", models.ImportCreateFailed, "failed to save event")
+                                return err
+                        }
+
+                        // Find or create the tags and associate them with the event
+                        var eventTagIDs []int
+                        for _, tagName := range p.tags {
+                                key := strings.ToLower(tagName)
+                                if id, exists := tagIDs[key]; exists {
+                                        eventTagIDs = append(eventTagIDs, id)
+                                        continue
+                                }
+                                if id, exists := rowTags[key]; exists {
+                                        eventTagIDs = append(eventTagIDs, id)
+                                        continue
+                                }
+
+                                // Create new tag with random color
+                                tag := &models.Tag{
+                                        Name:        tagName,
+                                        Description: fmt.Sprintf("Auto-generated tag for %s", tagName),
+                                        Color:       h.generateRandomColor(),
+                                }
+                                var createdTag *models.Tag
+                                err := repositories.WithSavepoint(tx, "import_tag", func() error {
+                                        var err error
+                                        createdTag, err = h.tagRepo.CreateTagTx(tx, tag)
+                                        return err
+                                })
+                                if errors.Is(err, repositories.ErrTransactionAborted) {
+                                        return err
+                                }
+                                if err != nil {
+                                        log.Printf("Failed to create tag %s: %v", tagName, err)
+                                        if strict {
+                                                report.AddError(p.row, "tags", models.ImportTagFailed, fmt.Sprintf("failed to create tag '%s'", tagName))
+                                                return err
+                                        }
+                                        report.AddWarning(p.row, "tags", models.ImportTagFailed, fmt.Sprintf("failed to create tag '%s'", tagName))
+                                        continue
+                                }
+                                rowTags[key] = createdTag.ID
+                                eventTagIDs = append(eventTagIDs, createdTag.ID)
+                        }
+
+                        // Updated events drop tags that are no longer listed
+                        if len(eventTagIDs) > 0 || p.existingID != 0 {
+                                if err := h.tagRepo.SetEventTagsTx(tx, savedEvent.ID, eventTagIDs); err != nil {
+                                        log.Printf("Failed to associate tags with event %s: %v", p.event.Name, err)
+                                        report.AddError(p.row, "tags", models.ImportTagFailed, "failed to attach tags")
+                                        return err
+                                }
+                        }
+                        action := models.RevisionCreate
+                        if p.existingID != 0 {
+                                action = models.RevisionUpdate
+                        }
+                        if err := h.revisionRepo.RecordTx(tx, savedEvent.ID, action, &userID); err != nil {
+                                log.Printf("Failed to record revision of event %s: %v", p.event.Name, err)
+                                report.AddError(p.row, "", models.ImportCreateFailed, "failed to save event")
+                                return err
+                        }
+                        p.event.ID = savedEvent.ID
+                        return nil
+                })
+                if errors.Is(err, repositories.ErrTransactionAborted) {
+                        return 0, err
+                }
+                if err != nil {
+                        if strict {
+                                return 0, errImportRejected
+                        }
+                        continue
+                }
+
+                for key, id := range rowTags {
+                        tagIDs[key] = id
+                }
+                p.saved = true
+                importedCount++
+        }
+        progress(len(planned), len(planned))
+        report.ImportedCount = importedCount
+        return importedCount, nil
+}
+
+// writeBulkImport creates the planned events in datasetID with
+// EventRepository.BulkCreateTx. Validation is the same as for writeImport,
+// but the events are written all at once: any database error fails the
+// whole import rather than a single row.
+func (h *EventHandler) writeBulkImport(ctx context.Context, tx *sql.Tx, datasetID int, planned []plannedEvent, report *models.ImportReport, userID int, progress importProgress) (int, error) {
+        events := make([]repositories.BulkEvent, len(planned))
+        for i := range planned {
+                planned[i].event.DatasetID = &datasetID
+                events[i] = repositories.BulkEvent{Event: planned[i].event, Tags: planned[i].tags}
+        }
+
+        progress(0, len(planned))
+        ids, err := h.eventRepo.BulkCreateTx(ctx, tx, datasetID, events, func(string) string { return h.generateRandomColor() }, func(done int) {
+                progress(done, len(planned))
+        })
+        if err != nil {
+                return 0, err
+        }
+        if err := h.revisionRepo.RecordManyTx(tx, ids, models.RevisionCreate, &userID); err != nil {
+                return 0, err
+        }
+
+        for i, id := range ids {
+                planned[i].event.ID = id
+                planned[i].saved = true
+        }
+        report.ImportedCount = len(ids)
+        return len(ids), nil
+}
+
+// saveImportEvent creates the planned event, or updates the existing event
+// it was matched with on re-import
+func (h *EventHandler) saveImportEvent(tx *sql.Tx, p *plannedEvent, userID int) (*models.HistoricalEvent, error) {
+        if p.existingID == 0 {
+                return h.eventRepo.CreateTx(tx, p.event)
+        }
+        p.event.ID = p.existingID
+        p.event.UpdatedBy = &userID
+        p.event.UpdatedAt = time.Now()
+        return h.eventRepo.UpdateTx(tx, p.event)
+}
+
+// skippedRowMessages lists the rows that were not imported, one message per
+// error, for the legacy skipped_events field
+func skippedRowMessages(report *models.ImportReport) []string {
+        var messages []string
+        for _, issue := range report.Issues {
+                if issue.Severity == models.ImportSeverityError {
+                        messages = append(messages, fmt.Sprintf("row %d: %s", issue.Row, issue.Message))
+                }
+        }
+        return messages
+}
+
+// distanceKm returns the great-circle distance between two points
+func distanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+        const earthRadiusKm = 6371.0
+        toRad := math.Pi / 180
+        dLat := (lat2 - lat1) * toRad
+        dLng := (lng2 - lng1) * toRad
+        a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+                math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+        return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// parseImportDate parses a dataset date and resolves its calendar and
+// precision. Without an explicit calendar, dates before 1582 are Julian. An
+// explicit precision wins; otherwise it follows from the date format. With
+// padsYears (see importRequest.padsYears), a full date on 1 January is read as
+// year precision.
+func parseImportDate(value, era, calendar, precision string, padsYears bool) (models.HistoricDate, string, error) {
+        date, implied, err := models.ParseDatasetDate(value, era)
+        if err != nil {
+                return models.HistoricDate{}, "", err
+        }
+        
+        date.Calendar = calendar
+        if date.Calendar == "" {
+                date.Calendar = models.DefaultCalendar(date)
+        }
+        if !models.IsValidCalendar(date.Calendar) {
+                return models.HistoricDate{}, "", fmt.Errorf("invalid calendar '%s'", calendar)
+        }
+        if err := date.Validate(); err != nil {
+                return models.HistoricDate{}, "", fmt.Errorf("invalid date %q: %v", value, err)
+        }
+        
+        if precision != "" {
+                if !models.IsValidDatePrecision(precision) {
+                        return models.HistoricDate{}, "", fmt.Errorf("invalid precision '%s'", precision)
+                }
+                return date, precision, nil
+        }
+        
+        if padsYears && implied == models.DatePrecisionDay && date.Month == 1 && date.Day == 1 {
+                implied = models.DatePrecisionYear
+        }
+        return date, implied, nil
+}
+
+// parseImportOptionalDate parses an optional dataset date (end date or
+// uncertainty bound) whose era defaults to the event era. It is always in
+// the event's calendar.
+func parseImportOptionalDate(value, era string, event models.HistoricDate) (*models.HistoricDate, error) {
+        if value == "" {
+                return nil, nil
+        }
+        if era == "" {
+                era = event.Era
+        }
+        date, _, err := models.ParseDatasetDate(value, era)
+        if err != nil {
+                return nil, err
+        }
+        date.Calendar = event.Calendar
+        if err := date.Validate(); err != nil {
+                return nil, fmt.Errorf("invalid date %q: %v", value, err)
+        }
+        return &date, nil
+}
+
+// generateRandomColor generates a random hex color for tags
+func (h *EventHandler) generateRandomColor() string {
+        // Predefined vibrant colors that work well for tags
+        colors := []string{
+                "#3B82F6", // Blue
+                "#EF4444", // Red
+                "#10B981", // Green
+                "#F59E0B", // Orange
+                "#8B5CF6", // Purple
+                "#06B6D4", // Cyan
+                "#EC4899", // Pink
+                "#84CC16", // Lime
+                "#F97316", // Orange
+                "#6366F1", // Indigo
+                "#14B8A6", // Teal
+                "#F43F5E", // Rose
+        }
+        return colors[rand.Intn(len(colors))]
+}
\ No newline at end of file
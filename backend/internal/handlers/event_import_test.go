@@ -0,0 +1,245 @@
+package handlers
+
+import (
+        "context"
+        "encoding/json"
+        "fmt"
+        "historical-events-backend/internal/config"
+        "historical-events-backend/internal/database"
+        "historical-events-backend/internal/database/repositories"
+        "historical-events-backend/internal/models"
+        "os"
+        "testing"
+)
+
+func TestParseImportDate(t *testing.T) {
+        tests := []struct {
+                value, era, calendar, precision string
+                padsYears                       bool
+                want                            models.HistoricDate
+                wantPrecision                   string
+                wantErr                         bool
+        }{
+                {value: "15.03.0044", era: "BC", want: models.HistoricDate{Year: 44, Month: 3, Day: 15, Era: models.EraBC, Calendar: models.CalendarJulian}, wantPrecision: models.DatePrecisionDay},
+                {value: "03.0044", era: "BC", want: models.HistoricDate{Year: 44, Month: 3, Day: 1, Era: models.EraBC, Calendar: models.CalendarJulian}, wantPrecision: models.DatePrecisionMonth},
+                {value: "1969", want: models.HistoricDate{Year: 1969, Month: 1, Day: 1, Era: models.EraAD, Calendar: models.CalendarGregorian}, wantPrecision: models.DatePrecisionYear},
+
+                // 01.01.YYYY is a padded year only in datasets without precisions
+                {value: "01.01.1700", padsYears: true, want: models.HistoricDate{Year: 1700, Month: 1, Day: 1, Era: models.EraAD, Calendar: models.CalendarGregorian}, wantPrecision: models.DatePrecisionYear},
+                {value: "01.01.1700", want: models.HistoricDate{Year: 1700, Month: 1, Day: 1, Era: models.EraAD, Calendar: models.CalendarGregorian}, wantPrecision: models.DatePrecisionDay},
+                {value: "01.01.1700", precision: "day", padsYears: true, want: models.HistoricDate{Year: 1700, Month: 1, Day: 1, Era: models.EraAD, Calendar: models.CalendarGregorian}, wantPrecision: models.DatePrecisionDay},
+                {value: "02.01.1700", padsYears: true, want: models.HistoricDate{Year: 1700, Month: 1, Day: 2, Era: models.EraAD, Calendar: models.CalendarGregorian}, wantPrecision: models.DatePrecisionDay},
+                {value: "01.1700", padsYears: true, want: models.HistoricDate{Year: 1700, Month: 1, Day: 1, Era: models.EraAD, Calendar: models.CalendarGregorian}, wantPrecision: models.DatePrecisionMonth},
+                {value: "01.01.1700", precision: "century", want: models.HistoricDate{Year: 1700, Month: 1, Day: 1, Era: models.EraAD, Calendar: models.CalendarGregorian}, wantPrecision: models.DatePrecisionCentury},
+
+                // Calendars
+                {value: "29.02.1300", want: models.HistoricDate{Year: 1300, Month: 2, Day: 29, Era: models.EraAD, Calendar: models.CalendarJulian}, wantPrecision: models.DatePrecisionDay},
+                {value: "29.02.1300", calendar: "gregorian", wantErr: true},
+                {value: "25.10.1917", calendar: "julian", want: models.HistoricDate{Year: 1917, Month: 10, Day: 25, Era: models.EraAD, Calendar: models.CalendarJulian}, wantPrecision: models.DatePrecisionDay},
+                {value: "25.10.1917", calendar: "coptic", wantErr: true},
+
+                {value: "1700", precision: "week", wantErr: true},
+                {value: "32.01.1700", wantErr: true},
+                {value: "", wantErr: true},
+        }
+
+        for _, tt := range tests {
+                got, precision, err := parseImportDate(tt.value, tt.era, tt.calendar, tt.precision, tt.padsYears)
+                if tt.wantErr {
+                        if err == nil {
+                                t.Errorf("parseImportDate(%q, %q, %q, %q) = %v, want error", tt.value, tt.era, tt.calendar, tt.precision, got)
+                        }
+                        continue
+                }
+                if err != nil {
+                        t.Errorf("parseImportDate(%q, %q, %q, %q): %v", tt.value, tt.era, tt.calendar, tt.precision, err)
+                        continue
+                }
+                if got != tt.want || precision != tt.wantPrecision {
+                        t.Errorf("parseImportDate(%q, %q, %q, %q, %v) = %+v, %q, want %+v, %q", tt.value, tt.era, tt.calendar, tt.precision, tt.padsYears, got, precision, tt.want, tt.wantPrecision)
+                }
+        }
+}
+
+func TestImportRequestPadsYears(t *testing.T) {
+        tests := []struct {
+                name string
+                req  importRequest
+                want bool
+        }{
+                {"no precisions", importRequest{Events: []importEvent{{Date: "01.01.1700"}, {Date: "1800"}}}, true},
+                {"one row with a precision", importRequest{Events: []importEvent{{Date: "01.01.1700"}, {Date: "1800", Precision: "year"}}}, false},
+                {"empty precision column", importRequest{Events: []importEvent{{Date: "01.01.1700"}}, precisionColumn: true}, false},
+                {"no rows", importRequest{}, true},
+        }
+
+        for _, tt := range tests {
+                if got := tt.req.padsYears(); got != tt.want {
+                        t.Errorf("%s: padsYears() = %v, want %v", tt.name, got, tt.want)
+                }
+        }
+}
+
+func TestDatasetExportRoundTrip(t *testing.T) {
+        date := func(year, month, day int, era, calendar string) models.HistoricDate {
+                return models.HistoricDate{Year: year, Month: month, Day: day, Era: era, Calendar: calendar}
+        }
+        end := date(1815, 1, 1, models.EraAD, models.CalendarGregorian)
+
+        events := []models.HistoricalEvent{
+                // A day that looks like a padded year
+                {NameEn: "New Year 1700", EventDate: date(1700, 1, 1, models.EraAD, models.CalendarGregorian), DatePrecision: models.DatePrecisionDay},
+                {NameEn: "Founding of Rome", EventDate: date(753, 4, 21, models.EraBC, models.CalendarJulian), DatePrecision: models.DatePrecisionDay, Circa: true},
+                {NameEn: "Year of the Four Emperors", EventDate: date(69, 1, 1, models.EraAD, models.CalendarJulian), DatePrecision: models.DatePrecisionYear},
+                {NameEn: "Napoleonic Wars", EventDate: date(1803, 5, 1, models.EraAD, models.CalendarGregorian), DatePrecision: models.DatePrecisionMonth, EndDate: &end},
+                {NameEn: "Hellenistic period", EventDate: date(301, 1, 1, models.EraBC, models.CalendarJulian), DatePrecision: models.DatePrecisionCentury},
+                {NameEn: "October Revolution", EventDate: date(1917, 10, 25, models.EraAD, models.CalendarJulian), DatePrecision: models.DatePrecisionDay},
+                // Events saved without a precision are days
+                {NameEn: "Unspecified", EventDate: date(1500, 1, 1, models.EraAD, models.CalendarJulian)},
+        }
+
+        req := importRequest{}
+        for i, event := range events {
+                event.Latitude, event.Longitude, event.LensType = 41.9, 12.5, "historic"
+
+                exported := datasetExportEvent(event)
+                if _, ok := exported["precision"]; !ok {
+                        t.Errorf("%s: export has no precision", event.NameEn)
+                }
+
+                data, err := json.Marshal(exported)
+                if err != nil {
+                        t.Fatal(err)
+                }
+                var row importEvent
+                if err := json.Unmarshal(data, &row); err != nil {
+                        t.Fatalf("%s: %v", event.NameEn, err)
+                }
+                row.Row = i + 1
+                req.Events = append(req.Events, row)
+        }
+        if req.padsYears() {
+                t.Fatal("an exported dataset pads years")
+        }
+
+        report := models.NewImportReport(len(req.Events), true)
+        for i, row := range req.Events {
+                got, ok := validateImportEvent(row, req.padsYears(), report)
+                if !ok {
+                        t.Fatalf("%s: row rejected: %+v", events[i].NameEn, report)
+                }
+
+                want := events[i]
+                wantPrecision := want.DatePrecision
+                if wantPrecision == "" {
+                        wantPrecision = models.DatePrecisionDay
+                }
+                if got.EventDate != want.EventDate || got.DatePrecision != wantPrecision || got.Circa != want.Circa {
+                        t.Errorf("%s: imported as %v (%s, circa %v), want %v (%s, circa %v)",
+                                want.NameEn, got.EventDate, got.DatePrecision, got.Circa, want.EventDate, wantPrecision, want.Circa)
+                }
+                if (got.EndDate == nil) != (want.EndDate == nil) || (got.EndDate != nil && *got.EndDate != *want.EndDate) {
+                        t.Errorf("%s: end date %v, want %v", want.NameEn, got.EndDate, want.EndDate)
+                }
+        }
+}
+
+// benchmarkImportEvents is the number of synthetic events of one import in
+// the import benchmarks, spread over benchmarkTagCount distinct tags
+const (
+        benchmarkImportEvents = 10000
+        benchmarkTagCount     = 50
+)
+
+// BenchmarkImport times the row-by-row import. Like BenchmarkBulkImport it
+// needs a database (DATABASE_URL or DB_HOST), e.g.
+// DATABASE_URL=... go test -run '^$' -bench Import ./internal/handlers
+func BenchmarkImport(b *testing.B) {
+        benchmarkImport(b, false)
+}
+
+// BenchmarkBulkImport times the COPY bulk import of the same events
+func BenchmarkBulkImport(b *testing.B) {
+        benchmarkImport(b, true)
+}
+
+// benchmarkImport plans the synthetic events and times writing them into a
+// new dataset. Each import happens in a transaction that is rolled back, so
+// the database is left unchanged.
+func benchmarkImport(b *testing.B, bulk bool) {
+        if os.Getenv("DATABASE_URL") == "" && os.Getenv("DB_HOST") == "" {
+                b.Skip("set DATABASE_URL or DB_HOST to run the import benchmarks")
+        }
+        cfg := config.Load()
+        db, err := database.NewConnection(&cfg.Database)
+        if err != nil {
+                b.Fatalf("failed to connect to database: %v", err)
+        }
+        defer db.Close()
+        
+        h := &EventHandler{
+                eventRepo:    repositories.NewEventRepository(db.DB),
+                tagRepo:      repositories.NewTagRepository(db.DB),
+                datasetRepo:  repositories.NewDatasetRepository(db.DB),
+                revisionRepo: repositories.NewRevisionRepository(db.DB),
+        }
+        rows := benchmarkImportRows(benchmarkImportEvents)
+        ctx := context.Background()
+        
+        b.ResetTimer()
+        for i := 0; i < b.N; i++ {
+                b.StopTimer()
+                report := models.NewImportReport(len(rows), false)
+                planned, tagIDs, err := h.planImport(importRequest{Events: rows}, report, 0, false)
+                if err != nil {
+                        b.Fatal(err)
+                }
+                if report.ErrorCount > 0 {
+                        b.Fatalf("benchmark rows are invalid: %d errors", report.ErrorCount)
+                }
+                tx, err := h.datasetRepo.Begin()
+                if err != nil {
+                        b.Fatal(err)
+                }
+                dataset, err := h.datasetRepo.CreateTx(tx, &models.EventDataset{Filename: "import_benchmark.json", Description: "Import benchmark", UploadedBy: 1})
+                if err != nil {
+                        tx.Rollback()
+                        b.Fatal(err)
+                }
+                
+                b.StartTimer()
+                if bulk {
+                        _, err = h.writeBulkImport(ctx, tx, dataset.ID, planned, report, 1, noImportProgress)
+                } else {
+                        _, err = h.writeImport(ctx, tx, dataset.ID, planned, tagIDs, report, true, 1, noImportProgress)
+                }
+                b.StopTimer()
+                tx.Rollback()
+                if err != nil {
+                        b.Fatalf("import failed: %v", err)
+                }
+        }
+        b.ReportMetric(float64(b.N*len(rows))/b.Elapsed().Seconds(), "events/s")
+}
+
+// benchmarkImportRows generates count valid import rows with two tags each,
+// named so that the tags have to be created
+func benchmarkImportRows(count int) []importEvent {
+        rows := make([]importEvent, count)
+        for i := range rows {
+                rows[i] = importEvent{
+                        NameEN:        fmt.Sprintf("Benchmark event %d", i+1),
+                        NameRU:        fmt.Sprintf("Тестовое событие %d", i+1),
+                        DescriptionEN: "Generated by the import benchmark",
+                        Date:          fmt.Sprintf("%04d-%02d-%02d", 1+i%2000, 1+i%12, 1+i%28),
+                        Era:           "AD",
+                        Latitude:      float64(i%180) - 89.5,
+                        Longitude:     float64(i%360) - 179.5,
+                        Type:          "historic",
+                        Tags:          []string{fmt.Sprintf("benchmark-%d", i%benchmarkTagCount), fmt.Sprintf("benchmark-%d", (i+1)%benchmarkTagCount)},
+                        ExternalID:    fmt.Sprintf("bench-%d", i+1),
+                        Row:           i + 1,
+                }
+        }
+        return rows
+}
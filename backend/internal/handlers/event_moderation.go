@@ -0,0 +1,439 @@
+package handlers
+
+import (
+        "encoding/json"
+        "errors"
+        "fmt"
+        "historical-events-backend/internal/database/repositories"
+        "historical-events-backend/internal/models"
+        "historical-events-backend/pkg/response"
+        "io"
+        "log"
+        "net/http"
+        "strconv"
+        "strings"
+        "time"
+
+        "github.com/gorilla/mux"
+)
+
+// submissionStatus decides the status of an event that user creates (current
+// is "") or edits, given the status the request asked for ("" for none).
+// Users who may publish get the status they ask for, and new events are
+// published. Everyone else's events stay drafts or go to the moderation
+// queue; for a published event, pending means the edit is queued (see
+// submitEdit) and the event stays published. Events are only rejected from
+// the queue.
+func submissionStatus(user *models.User, requested, current string) (string, error) {
+        if requested != "" && !models.IsValidEventStatus(requested) {
+                return "", fmt.Errorf("invalid status %q (valid: draft, pending, published)", requested)
+        }
+        if requested == models.EventStatusRejected {
+                return "", fmt.Errorf("events are rejected from the moderation queue")
+        }
+
+        if can(user, PermissionReviewEvents, nil) {
+                switch {
+                case requested != "":
+                        return requested, nil
+                case current != "":
+                        return current, nil
+                }
+                return models.EventStatusPublished, nil
+        }
+
+        if requested == models.EventStatusDraft || (requested == "" && current == models.EventStatusDraft) {
+                return models.EventStatusDraft, nil
+        }
+        return models.EventStatusPending, nil
+}
+
+// GetModerationQueue handles GET /api/moderation/queue: the pending events,
+// least recently submitted first. ?status= lists the events with another
+// status instead, such as the rejected ones.
+func (h *EventHandler) GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+        query := r.URL.Query()
+
+        locale := query.Get("locale")
+        if locale == "" {
+                locale = "en"
+        }
+
+        status := query.Get("status")
+        if status == "" {
+                status = models.EventStatusPending
+        }
+        if !models.IsValidEventStatus(status) {
+                response.BadRequest(w, fmt.Sprintf("Invalid status parameter %q (valid: draft, pending, published, rejected)", status))
+                return
+        }
+
+        page, err := strconv.Atoi(query.Get("page"))
+        if err != nil || page < 1 {
+                page = 1
+        }
+        limit, err := strconv.Atoi(query.Get("limit"))
+        if err != nil || limit < 1 {
+                limit = 10 // Default page size
+        }
+        if limit > 100 {
+                limit = 100
+        }
+
+        events, total, err := h.eventRepo.GetByStatus(status, page, limit)
+        if err != nil {
+                log.Printf("Error fetching moderation queue: %v", err)
+                response.InternalError(w, "Failed to fetch moderation queue")
+                return
+        }
+
+        for i := range events {
+                events[i].PopulateLegacyFields(locale)
+        }
+
+        response.Success(w, map[string]interface{}{
+                "events": events,
+                "pagination": map[string]interface{}{
+                        "current_page": page,
+                        "page_size":    limit,
+                        "total_items":  total,
+                        "total_pages":  (total + limit - 1) / limit,
+                },
+        })
+}
+
+// ApproveEvent handles POST /api/moderation/events/{id}/approve: the pending
+// event is published, with an optional note for its author
+func (h *EventHandler) ApproveEvent(w http.ResponseWriter, r *http.Request) {
+        h.reviewEvent(w, r, models.EventStatusPublished)
+}
+
+// RejectEvent handles POST /api/moderation/events/{id}/reject: the pending
+// event is rejected, with a note telling its author why
+func (h *EventHandler) RejectEvent(w http.ResponseWriter, r *http.Request) {
+        h.reviewEvent(w, r, models.EventStatusRejected)
+}
+
+// reviewEvent gives a pending event its review status and records the review
+// as a revision
+func (h *EventHandler) reviewEvent(w http.ResponseWriter, r *http.Request, status string) {
+        id, err := strconv.Atoi(mux.Vars(r)["id"])
+        if err != nil {
+                response.BadRequest(w, "Invalid event ID")
+                return
+        }
+
+        version, ok := ifMatchVersion(w, r)
+        if !ok {
+                return
+        }
+
+        // The body is optional when approving
+        var req models.ReviewRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+                response.BadRequest(w, "Invalid JSON payload")
+                return
+        }
+        var note *string
+        if trimmed := strings.TrimSpace(req.Note); trimmed != "" {
+                note = &trimmed
+        }
+
+        action, message := models.RevisionApprove, "Event approved and published"
+        if status == models.EventStatusRejected {
+                if note == nil {
+                        response.BadRequest(w, "A note is required to reject an event")
+                        return
+                }
+                action, message = models.RevisionReject, "Event rejected"
+        }
+
+        locale := r.URL.Query().Get("locale")
+        if locale == "" {
+                locale = "en"
+        }
+
+        reviewerID := userIDFromContext(r.Context())
+        tx, err := h.eventRepo.Begin()
+        if err != nil {
+                log.Printf("Error reviewing event %d: %v", id, err)
+                response.InternalError(w, "Failed to review event")
+                return
+        }
+        defer tx.Rollback()
+
+        err = h.eventRepo.ReviewTx(tx, id, version, status, note, reviewerID)
+        if err == nil {
+                err = h.revisionRepo.RecordTx(tx, id, action, reviewerID)
+        }
+        if err == nil {
+                err = tx.Commit()
+        }
+        if errors.Is(err, repositories.ErrVersionConflict) {
+                h.writeEventConflict(w, id, locale)
+                return
+        }
+        if errors.Is(err, repositories.ErrNotPending) {
+                response.Error(w, http.StatusConflict, "Event is not pending review", "it was reviewed already or is a draft")
+                return
+        }
+        if err != nil {
+                if strings.Contains(err.Error(), "not found") {
+                        response.NotFound(w, "Event not found")
+                        return
+                }
+                log.Printf("Error reviewing event %d: %v", id, err)
+                response.InternalError(w, "Failed to review event")
+                return
+        }
+
+        h.eventCache.Invalidate()
+
+        event, err := h.eventRepo.GetByID(id)
+        if err != nil {
+                log.Printf("Error getting reviewed event %d: %v", id, err)
+                response.InternalError(w, "Event was reviewed but could not be read")
+                return
+        }
+        event.PopulateLegacyFields(locale)
+
+        setETag(w, event.Version)
+        response.Success(w, event, message)
+}
+
+// submitEdit queues the edit of a published event by a user who cannot
+// publish, answering 202 with the edit. The published event stays as it is
+// until a reviewer approves the edit.
+func (h *EventHandler) submitEdit(w http.ResponseWriter, current, edited *models.HistoricalEvent, version int, user *models.User, locale string) {
+        if version != 0 && version != current.Version {
+                h.writeEventConflict(w, current.ID, locale)
+                return
+        }
+
+        // PUT /events/{id} leaves the tags alone
+        edited.Tags = current.Tags
+        edit, err := h.revisionRepo.SubmitEdit(current.ID, current.Version, models.NewEventSnapshot(edited), &user.ID)
+        if errors.Is(err, repositories.ErrEditPending) {
+                response.Error(w, http.StatusConflict, "Another user's edit of this event is pending review", "try again once an editor has reviewed it")
+                return
+        }
+        if err != nil {
+                log.Printf("Error submitting edit of event %d: %v", current.ID, err)
+                response.InternalError(w, "Failed to submit edit")
+                return
+        }
+        if err := h.diffEdit(edit, current); err != nil {
+                log.Printf("Error comparing edit of event %d: %v", current.ID, err)
+        }
+
+        setETag(w, current.Version)
+        response.JSON(w, http.StatusAccepted, response.SuccessResponse{Data: edit, Message: "Edit submitted for review; the published event is unchanged until it is approved"})
+}
+
+// diffEdit sets the changes of an edit against the event as it is now
+func (h *EventHandler) diffEdit(edit *models.EventEdit, current *models.HistoricalEvent) error {
+        published := models.NewEventSnapshot(current)
+        changes, err := edit.Snapshot.Diff(&published)
+        if err != nil {
+                return err
+        }
+        edit.Changes = changes
+        return nil
+}
+
+// GetPendingEdits handles GET /api/moderation/edits: the pending edits of
+// published events, least recently submitted first, each with its changes
+func (h *EventHandler) GetPendingEdits(w http.ResponseWriter, r *http.Request) {
+        query := r.URL.Query()
+
+        page, err := strconv.Atoi(query.Get("page"))
+        if err != nil || page < 1 {
+                page = 1
+        }
+        limit, err := strconv.Atoi(query.Get("limit"))
+        if err != nil || limit < 1 {
+                limit = 10 // Default page size
+        }
+        if limit > 100 {
+                limit = 100
+        }
+
+        edits, total, err := h.revisionRepo.GetPendingEdits(page, limit)
+        if err != nil {
+                log.Printf("Error fetching pending edits: %v", err)
+                response.InternalError(w, "Failed to fetch pending edits")
+                return
+        }
+
+        for i := range edits {
+                current, err := h.eventRepo.GetByID(edits[i].EventID)
+                if err != nil {
+                        log.Printf("Error getting event %d of edit %d: %v", edits[i].EventID, edits[i].ID, err)
+                        response.InternalError(w, "Failed to fetch pending edits")
+                        return
+                }
+                if err := h.diffEdit(&edits[i], current); err != nil {
+                        log.Printf("Error comparing edit %d: %v", edits[i].ID, err)
+                        response.InternalError(w, "Failed to fetch pending edits")
+                        return
+                }
+        }
+
+        response.Success(w, map[string]interface{}{
+                "edits": edits,
+                "pagination": map[string]interface{}{
+                        "current_page": page,
+                        "page_size":    limit,
+                        "total_items":  total,
+                        "total_pages":  (total + limit - 1) / limit,
+                },
+        })
+}
+
+// ApproveEdit handles POST /api/moderation/edits/{id}/approve: the edit is
+// applied to the published event and recorded as a revision by its author
+func (h *EventHandler) ApproveEdit(w http.ResponseWriter, r *http.Request) {
+        h.reviewEdit(w, r, models.EventStatusPublished)
+}
+
+// RejectEdit handles POST /api/moderation/edits/{id}/reject: the edit is
+// dropped, with a note telling its author why, and the event is unchanged
+func (h *EventHandler) RejectEdit(w http.ResponseWriter, r *http.Request) {
+        h.reviewEdit(w, r, models.EventStatusRejected)
+}
+
+// reviewEdit publishes or rejects a pending edit
+func (h *EventHandler) reviewEdit(w http.ResponseWriter, r *http.Request, status string) {
+        id, err := strconv.Atoi(mux.Vars(r)["id"])
+        if err != nil {
+                response.BadRequest(w, "Invalid edit ID")
+                return
+        }
+
+        // The body is optional when approving
+        var req models.ReviewRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+                response.BadRequest(w, "Invalid JSON payload")
+                return
+        }
+        var note *string
+        if trimmed := strings.TrimSpace(req.Note); trimmed != "" {
+                note = &trimmed
+        }
+        if status == models.EventStatusRejected && note == nil {
+                response.BadRequest(w, "A note is required to reject an edit")
+                return
+        }
+
+        locale := r.URL.Query().Get("locale")
+        if locale == "" {
+                locale = "en"
+        }
+
+        edit, err := h.revisionRepo.GetEdit(id)
+        if errors.Is(err, repositories.ErrEditNotFound) {
+                response.NotFound(w, "Edit not found")
+                return
+        }
+        if err != nil {
+                log.Printf("Error getting edit %d: %v", id, err)
+                response.InternalError(w, "Failed to review edit")
+                return
+        }
+        if edit.Status != models.EventStatusPending {
+                response.Error(w, http.StatusConflict, "Edit is not pending review", "it was reviewed already")
+                return
+        }
+
+        event, err := h.eventRepo.GetByID(edit.EventID)
+        if err != nil {
+                if strings.Contains(err.Error(), "not found") {
+                        response.NotFound(w, "Event not found")
+                        return
+                }
+                log.Printf("Error getting event %d of edit %d: %v", edit.EventID, id, err)
+                response.InternalError(w, "Failed to review edit")
+                return
+        }
+        previousDatasetID := event.DatasetID
+
+        // An edit made against an older version would overwrite the saves
+        // since; the reviewer sees what it would change now instead
+        if status == models.EventStatusPublished && edit.BaseVersion != event.Version {
+                if err := h.diffEdit(edit, event); err != nil {
+                        log.Printf("Error comparing edit %d: %v", id, err)
+                }
+                event.PopulateLegacyFields(locale)
+                setETag(w, event.Version)
+                response.Conflict(w, "Event was changed after the edit was submitted",
+                        map[string]interface{}{"event": event, "edit": edit},
+                        fmt.Sprintf("the edit was made against version %d, the event is at version %d; its changes are compared with the current event", edit.BaseVersion, event.Version))
+                return
+        }
+
+        reviewerID := userIDFromContext(r.Context())
+        tx, err := h.eventRepo.Begin()
+        if err != nil {
+                log.Printf("Error reviewing edit %d: %v", id, err)
+                response.InternalError(w, "Failed to review edit")
+                return
+        }
+        defer tx.Rollback()
+
+        if status == models.EventStatusPublished {
+                edit.Snapshot.Apply(event)
+                event.UpdatedBy = edit.AuthorID
+                event.UpdatedAt = time.Now()
+                _, err = h.eventRepo.UpdateTx(tx, event)
+                if err == nil {
+                        err = h.revisionRepo.RecordTx(tx, event.ID, models.RevisionUpdate, edit.AuthorID)
+                }
+        }
+        if err == nil {
+                err = h.revisionRepo.ReviewEditTx(tx, id, status, note, reviewerID)
+        }
+        if err == nil {
+                err = tx.Commit()
+        }
+        if errors.Is(err, repositories.ErrVersionConflict) {
+                response.Error(w, http.StatusConflict, "Event was changed while reviewing the edit", "reload the edit and try again")
+                return
+        }
+        if errors.Is(err, repositories.ErrNotPending) {
+                response.Error(w, http.StatusConflict, "Edit is not pending review", "it was reviewed already")
+                return
+        }
+        if err != nil {
+                log.Printf("Error reviewing edit %d: %v", id, err)
+                response.InternalError(w, "Failed to review edit")
+                return
+        }
+
+        if status == models.EventStatusRejected {
+                edit.Status, edit.ReviewNote, edit.ReviewedBy = status, note, reviewerID
+                response.Success(w, edit, "Edit rejected")
+                return
+        }
+
+        // Mark the datasets the event was and is in as modified
+        for _, datasetID := range []*int{previousDatasetID, event.DatasetID} {
+                if datasetID != nil && *datasetID > 0 {
+                        if err := h.datasetRepo.MarkAsModified(*datasetID); err != nil {
+                                log.Printf("Warning: failed to mark dataset as modified: %v", err)
+                        }
+                }
+        }
+
+        h.eventCache.Invalidate()
+
+        updated, err := h.eventRepo.GetByID(edit.EventID)
+        if err != nil {
+                log.Printf("Error getting event %d after approving edit %d: %v", edit.EventID, id, err)
+                response.InternalError(w, "Edit was approved but the event could not be read")
+                return
+        }
+        updated.PopulateLegacyFields(locale)
+
+        setETag(w, updated.Version)
+        response.Success(w, updated, "Edit approved and published")
+}
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+        "context"
+        "encoding/json"
+        "fmt"
+        "historical-events-backend/internal/models"
+        "historical-events-backend/pkg/response"
+        "log"
+        "net/http"
+        "sort"
+        "strconv"
+        "strings"
+
+        "github.com/gorilla/mux"
+)
+
+// ReimportDataset handles POST /api/datasets/{id}/reimport. The request body
+// is the same as for ImportEvents. Rows are matched with the dataset's events
+// by external_id, or by name and date for rows and events without one:
+// matched events are updated in place (keeping their IDs and URLs) when
+// something changed, unmatched rows create new events, and with
+// ?delete_missing=true events no longer in the file are removed. dry_run, mode
+// and async work as for ImportEvents, and everything is written in one
+// transaction.
+func (h *EventHandler) ReimportDataset(w http.ResponseWriter, r *http.Request) {
+        vars := mux.Vars(r)
+        id, err := strconv.Atoi(vars["id"])
+        if err != nil {
+                response.BadRequest(w, "Invalid dataset ID")
+                return
+        }
+        if !h.authorizer.Dataset(w, r, PermissionChangeDataset, id) {
+                return
+        }
+
+        opts, err := parseImportOptions(r)
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+        if opts.bulk {
+                response.BadRequest(w, "Bulk mode is only supported for new imports")
+                return
+        }
+
+        deleteMissing := false
+        if value := r.URL.Query().Get("delete_missing"); value != "" {
+                if deleteMissing, err = strconv.ParseBool(value); err != nil {
+                        response.BadRequest(w, "Invalid delete_missing parameter")
+                        return
+                }
+        }
+
+        dataset, err := h.datasetRepo.GetByID(id)
+        if err != nil {
+                if err.Error() == "dataset not found" {
+                        response.NotFound(w, "Dataset not found")
+                        return
+                }
+                log.Printf("Error retrieving dataset %d: %v", id, err)
+                response.InternalError(w, "Failed to retrieve dataset")
+                return
+        }
+
+        req, readIssues, err := readImportRequest(r)
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+
+        totalRows := len(req.Events) + len(readIssues)
+        if totalRows == 0 {
+                response.BadRequest(w, "No events provided for import")
+                return
+        }
+
+        user := getUserFromContext(r.Context())
+        if user == nil {
+                response.BadRequest(w, "User not found in context")
+                return
+        }
+
+        run := func(ctx context.Context, progress importProgress) importResult {
+                return h.runReimport(ctx, dataset, req, readIssues, opts, deleteMissing, user.ID, progress)
+        }
+        if opts.async {
+                h.submitImportJob(w, &models.ImportJob{Kind: models.ImportJobReimport, DatasetID: &id, Filename: req.Filename, TotalRows: totalRows, CreatedBy: &user.ID}, run)
+                return
+        }
+        run(r.Context(), noImportProgress).write(w)
+}
+
+// runReimport validates the rows, matches them with the dataset's events and,
+// unless it is a dry run, applies the changes
+func (h *EventHandler) runReimport(ctx context.Context, dataset *models.EventDataset, req importRequest, readIssues []models.ImportIssue, opts importOptions, deleteMissing bool, userID int, progress importProgress) importResult {
+        id := dataset.ID
+        report := models.NewImportReport(len(req.Events)+len(readIssues), opts.dryRun)
+        report.Mode = opts.mode
+        for _, issue := range readIssues {
+                report.AddError(issue.Row, issue.Field, issue.Code, issue.Message)
+        }
+
+        planned, tagIDs, err := h.planImport(req, report, id, opts.fuzzy)
+        if err != nil {
+                log.Printf("Failed to validate re-import: %v", err)
+                return importFailed(http.StatusInternalServerError, "Failed to validate import", nil)
+        }
+
+        existing, err := h.eventRepo.GetByDatasetID(id)
+        if err != nil {
+                log.Printf("Error retrieving events for dataset %d: %v", id, err)
+                return importFailed(http.StatusInternalServerError, "Failed to retrieve events for dataset", nil)
+        }
+
+        writes, missing, summary := matchReimport(planned, existing)
+        if !deleteMissing {
+                missing = nil
+        }
+
+        // A row that failed validation may stand for an existing event, which
+        // must not be removed as missing
+        if len(missing) > 0 && report.ErrorCount > 0 {
+                return importFailed(http.StatusBadRequest, "delete_missing requires every row to be valid", report)
+        }
+
+        if opts.dryRun {
+                summary.Removed = len(missing)
+                for _, event := range missing {
+                        summary.Changes = append(summary.Changes, models.ReimportChange{EventID: event.ID, Action: models.ReimportRemoved, Name: event.GetNameForLocale("en")})
+                }
+                message := fmt.Sprintf("%d created, %d updated, %d unchanged, %d removed", summary.Created, summary.Updated, summary.Unchanged, summary.Removed)
+                if opts.mode == importModeStrict && report.ErrorCount > 0 {
+                        message = fmt.Sprintf("The re-import would be rejected: %d errors in strict mode", report.ErrorCount)
+                }
+                return importResult{status: http.StatusOK, body: response.SuccessResponse{Data: map[string]interface{}{
+                        "success":    true,
+                        "dry_run":    true,
+                        "dataset_id": id,
+                        "summary":    summary,
+                        "report":     report,
+                        "message":    message,
+                }}}
+        }
+
+        if opts.mode == importModeStrict && report.ErrorCount > 0 {
+                return importFailed(http.StatusBadRequest, fmt.Sprintf("Import rejected: %d errors in strict mode", report.ErrorCount), report)
+        }
+
+        tx, err := h.datasetRepo.Begin()
+        if err != nil {
+                log.Printf("Failed to start re-import: %v", err)
+                return importFailed(http.StatusInternalServerError, "Failed to start import", nil)
+        }
+        defer tx.Rollback()
+
+        if _, err := h.writeImport(ctx, tx, id, writes, tagIDs, report, opts.mode == importModeStrict, userID, progress); err != nil {
+                return writeImportFailed(ctx, err, report)
+        }
+
+        // Count what was actually written; rows that failed in lenient mode
+        // leave their event as it was
+        final := models.ReimportSummary{Unchanged: summary.Unchanged, Changes: []models.ReimportChange{}}
+        for _, p := range writes {
+                if !p.saved {
+                        if p.existingID != 0 {
+                                final.Unchanged++
+                        }
+                        continue
+                }
+                action := models.ReimportCreated
+                if p.existingID != 0 {
+                        action = models.ReimportUpdated
+                        final.Updated++
+                } else {
+                        final.Created++
+                }
+                final.Changes = append(final.Changes, models.ReimportChange{Row: p.row, EventID: p.event.ID, Action: action, Name: p.event.GetNameForLocale("en")})
+        }
+
+        for _, event := range missing {
+                err := h.revisionRepo.RecordTx(tx, event.ID, models.RevisionDelete, &userID)
+                if err == nil {
+                        err = h.eventRepo.DeleteTx(tx, event.ID, 0)
+                }
+                if err != nil {
+                        log.Printf("Failed to remove event %d from dataset %d: %v", event.ID, id, err)
+                        return importFailed(http.StatusInternalServerError, "Failed to remove missing events, nothing was imported", report)
+                }
+                final.Removed++
+                final.Changes = append(final.Changes, models.ReimportChange{EventID: event.ID, Action: models.ReimportRemoved, Name: event.GetNameForLocale("en")})
+        }
+
+        eventCount := len(existing) + final.Created - final.Removed
+        if err := h.datasetRepo.UpdateEventCountTx(tx, id, eventCount); err != nil {
+                log.Printf("Failed to update dataset event count: %v", err)
+                return importFailed(http.StatusInternalServerError, "Failed to import events, nothing was imported", report)
+        }
+
+        if err := tx.Commit(); err != nil {
+                log.Printf("Failed to commit re-import: %v", err)
+                return importFailed(http.StatusInternalServerError, "Failed to import events, nothing was imported", report)
+        }
+
+        h.eventCache.Invalidate()
+
+        return importSucceeded(map[string]interface{}{
+                "success":      true,
+                "dataset_id":   id,
+                "dataset_name": dataset.Filename,
+                "event_count":  eventCount,
+                "summary":      final,
+                "report":       report,
+                "message":      fmt.Sprintf("%d created, %d updated, %d unchanged, %d removed", final.Created, final.Updated, final.Unchanged, final.Removed),
+        }, id)
+}
+
+// matchReimport pairs planned rows with the dataset's existing events. Rows
+// are matched by external_id first, then by name and date among the events
+// left. It returns the rows to write (new events, and matched events whose
+// content changed, with existingID set), the events no row matched, and a
+// summary of the planned changes.
+func matchReimport(planned []plannedEvent, existing []models.HistoricalEvent) ([]plannedEvent, []models.HistoricalEvent, models.ReimportSummary) {
+        byExternalID := make(map[string]int)
+        byKey := make(map[string][]int)
+        for i, event := range existing {
+                if event.ExternalID != nil {
+                        byExternalID[*event.ExternalID] = i
+                }
+                key := reimportKey(event)
+                byKey[key] = append(byKey[key], i)
+        }
+
+        matches := make([]int, len(planned))
+        used := make(map[int]bool)
+        for i, p := range planned {
+                matches[i] = -1
+                if p.event.ExternalID == nil {
+                        continue
+                }
+                if j, ok := byExternalID[*p.event.ExternalID]; ok {
+                        matches[i] = j
+                        used[j] = true
+                }
+        }
+        for i, p := range planned {
+                if matches[i] >= 0 {
+                        continue
+                }
+                for _, j := range byKey[reimportKey(*p.event)] {
+                        // An event keyed by another external_id is a different event
+                        if used[j] || (p.event.ExternalID != nil && existing[j].ExternalID != nil) {
+                                continue
+                        }
+                        matches[i] = j
+                        used[j] = true
+                        break
+                }
+        }
+
+        summary := models.ReimportSummary{Changes: []models.ReimportChange{}}
+        var writes []plannedEvent
+        for i, p := range planned {
+                name := p.event.GetNameForLocale("en")
+                j := matches[i]
+                if j < 0 {
+                        summary.Created++
+                        summary.Changes = append(summary.Changes, models.ReimportChange{Row: p.row, Action: models.ReimportCreated, Name: name})
+                        writes = append(writes, p)
+                        continue
+                }
+
+                match := existing[j]
+                if p.event.ExternalID == nil {
+                        p.event.ExternalID = match.ExternalID
+                }
+                if reimportSignature(*p.event, p.tags) == reimportSignature(match, eventTagNames(match)) {
+                        summary.Unchanged++
+                        continue
+                }
+                p.existingID = match.ID
+                summary.Updated++
+                summary.Changes = append(summary.Changes, models.ReimportChange{Row: p.row, EventID: match.ID, Action: models.ReimportUpdated, Name: name})
+                writes = append(writes, p)
+        }
+
+        var missing []models.HistoricalEvent
+        for j, event := range existing {
+                if !used[j] {
+                        missing = append(missing, event)
+                }
+        }
+        return writes, missing, summary
+}
+
+// reimportKey is the content-derived key of an event without an external_id:
+// its English (or else Russian) name, ignoring case, and its date
+func reimportKey(event models.HistoricalEvent) string {
+        name := event.NameEn
+        if name == "" {
+                name = event.NameRu
+        }
+        return strings.ToLower(strings.TrimSpace(name)) + "|" + event.EventDate.String()
+}
+
+// reimportSignature renders the imported content of an event in the dataset
+// export format, with tags sorted, so that two events compare equal when a
+// re-import would not change anything
+func reimportSignature(event models.HistoricalEvent, tags []string) string {
+        sorted := make([]string, len(tags))
+        for i, tag := range tags {
+                sorted[i] = strings.ToLower(tag)
+        }
+        sort.Strings(sorted)
+
+        event.Tags = make([]models.Tag, len(sorted))
+        for i, tag := range sorted {
+                event.Tags[i] = models.Tag{Name: tag}
+        }
+        signature, _ := json.Marshal(datasetExportEvent(event))
+        return string(signature)
+}
+
+func eventTagNames(event models.HistoricalEvent) []string {
+        names := make([]string, len(event.Tags))
+        for i, tag := range event.Tags {
+                names[i] = tag.Name
+        }
+        return names
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+        "historical-events-backend/internal/models"
+        "testing"
+)
+
+func reimportEvent(id int, externalID, name string, day int, tags ...string) models.HistoricalEvent {
+        event := models.HistoricalEvent{
+                ID:            id,
+                NameEn:        name,
+                EventDate:     models.HistoricDate{Year: 1805, Month: 12, Day: day, Era: models.EraAD, Calendar: models.CalendarGregorian},
+                DatePrecision: models.DatePrecisionDay,
+                Latitude:      49.13,
+                Longitude:     16.76,
+                LensType:      "military",
+        }
+        if externalID != "" {
+                event.ExternalID = &externalID
+        }
+        for _, tag := range tags {
+                event.Tags = append(event.Tags, models.Tag{Name: tag})
+        }
+        return event
+}
+
+func reimportRow(row int, event models.HistoricalEvent) plannedEvent {
+        event.ID = 0
+        return plannedEvent{row: row, event: &event, tags: eventTagNames(event)}
+}
+
+func TestMatchReimport(t *testing.T) {
+        tests := []struct {
+                name     string
+                existing []models.HistoricalEvent
+                planned  []plannedEvent
+                // Existing event each row is written to: 0 creates it, -1
+                // means the row is unchanged and not written at all
+                wantWrites  map[int]int
+                wantMissing []int
+                wantSummary [3]int // created, updated, unchanged
+        }{
+                {
+                        name: "external_id is matched before name and date",
+                        existing: []models.HistoricalEvent{
+                                reimportEvent(1, "a", "Austerlitz", 2),
+                                reimportEvent(2, "", "Battle of Austerlitz", 2),
+                        },
+                        planned: []plannedEvent{
+                                reimportRow(1, reimportEvent(0, "a", "Battle of Austerlitz", 2)),
+                        },
+                        wantWrites:  map[int]int{1: 1},
+                        wantMissing: []int{2},
+                        wantSummary: [3]int{0, 1, 0},
+                },
+                {
+                        name: "name and date match rows without external_id",
+                        existing: []models.HistoricalEvent{
+                                reimportEvent(1, "", "Austerlitz", 2),
+                                reimportEvent(2, "b", "Trafalgar", 21),
+                        },
+                        planned: []plannedEvent{
+                                reimportRow(1, reimportEvent(0, "", "  AUSTERLITZ ", 2)),
+                                // Keeps the external_id of the event it matched
+                                reimportRow(2, reimportEvent(0, "", "Trafalgar", 21)),
+                                // Same name, another day
+                                reimportRow(3, reimportEvent(0, "", "Austerlitz", 3)),
+                        },
+                        wantWrites:  map[int]int{1: 1, 2: -1, 3: 0},
+                        wantSummary: [3]int{1, 1, 1},
+                },
+                {
+                        name: "different external_ids never match by name and date",
+                        existing: []models.HistoricalEvent{
+                                reimportEvent(1, "a", "Austerlitz", 2),
+                                reimportEvent(2, "", "Trafalgar", 21),
+                        },
+                        planned: []plannedEvent{
+                                reimportRow(1, reimportEvent(0, "z", "Austerlitz", 2)),
+                                // An event without an external_id can take one
+                                reimportRow(2, reimportEvent(0, "t", "Trafalgar", 21)),
+                        },
+                        wantWrites:  map[int]int{1: 0, 2: 2},
+                        wantMissing: []int{1},
+                        wantSummary: [3]int{1, 1, 0},
+                },
+                {
+                        name: "unchanged rows are not written",
+                        existing: []models.HistoricalEvent{
+                                reimportEvent(1, "a", "Austerlitz", 2, "Napoleonic Wars", "battle"),
+                                reimportEvent(2, "b", "Trafalgar", 21, "naval"),
+                                reimportEvent(3, "c", "Ulm", 20),
+                        },
+                        planned: []plannedEvent{
+                                // Tags compare without order or case
+                                reimportRow(1, reimportEvent(0, "a", "Austerlitz", 2, "Battle", "napoleonic wars")),
+                                reimportRow(2, reimportEvent(0, "b", "Trafalgar", 21, "naval", "battle")),
+                                func() plannedEvent {
+                                        event := reimportEvent(0, "c", "Ulm", 20)
+                                        event.Latitude = 48.4
+                                        return reimportRow(3, event)
+                                }(),
+                        },
+                        wantWrites:  map[int]int{1: -1, 2: 2, 3: 3},
+                        wantSummary: [3]int{0, 2, 1},
+                },
+                {
+                        name: "unmatched events are missing",
+                        existing: []models.HistoricalEvent{
+                                reimportEvent(1, "", "Austerlitz", 2),
+                                reimportEvent(2, "", "Austerlitz", 2),
+                                reimportEvent(3, "c", "Ulm", 20),
+                                reimportEvent(4, "", "Trafalgar", 21),
+                        },
+                        planned: []plannedEvent{
+                                // Matches only one of the two identical events
+                                reimportRow(1, reimportEvent(0, "", "Austerlitz", 2)),
+                                reimportRow(2, reimportEvent(0, "c", "Ulm", 20)),
+                        },
+                        wantWrites:  map[int]int{1: -1, 2: -1},
+                        wantMissing: []int{2, 4},
+                        wantSummary: [3]int{0, 0, 2},
+                },
+        }
+
+        for _, tt := range tests {
+                t.Run(tt.name, func(t *testing.T) {
+                        writes, missing, summary := matchReimport(tt.planned, tt.existing)
+
+                        written := make(map[int]int)
+                        for _, p := range writes {
+                                written[p.row] = p.existingID
+                        }
+                        for row, want := range tt.wantWrites {
+                                got, ok := written[row]
+                                switch {
+                                case want < 0 && ok:
+                                        t.Errorf("row %d is written to %d, want it unchanged", row, got)
+                                case want >= 0 && !ok:
+                                        t.Errorf("row %d is not written, want it written to %d", row, want)
+                                case want >= 0 && got != want:
+                                        t.Errorf("row %d is written to %d, want %d", row, got, want)
+                                }
+                        }
+                        if len(writes) != len(written) {
+                                t.Errorf("%d rows written, %d distinct", len(writes), len(written))
+                        }
+
+                        var missingIDs []int
+                        for _, event := range missing {
+                                missingIDs = append(missingIDs, event.ID)
+                        }
+                        if !equalInts(missingIDs, tt.wantMissing) {
+                                t.Errorf("missing = %v, want %v", missingIDs, tt.wantMissing)
+                        }
+
+                        got := [3]int{summary.Created, summary.Updated, summary.Unchanged}
+                        if got != tt.wantSummary {
+                                t.Errorf("summary created/updated/unchanged = %v, want %v", got, tt.wantSummary)
+                        }
+                        if len(summary.Changes) != summary.Created+summary.Updated {
+                                t.Errorf("%d changes listed for %d created and %d updated", len(summary.Changes), summary.Created, summary.Updated)
+                        }
+                })
+        }
+}
+
+func TestMatchReimportKeepsExternalID(t *testing.T) {
+        existing := []models.HistoricalEvent{reimportEvent(1, "a", "Austerlitz", 2)}
+        row := reimportEvent(0, "", "Austerlitz", 2)
+        row.LensType = "political"
+
+        writes, _, _ := matchReimport([]plannedEvent{reimportRow(1, row)}, existing)
+        if len(writes) != 1 {
+                t.Fatalf("%d rows written, want 1", len(writes))
+        }
+        if id := writes[0].event.ExternalID; id == nil || *id != "a" {
+                t.Errorf("external_id = %v, want the matched event's %q", id, "a")
+        }
+}
+
+func TestReimportSignature(t *testing.T) {
+        base := reimportEvent(1, "a", "Austerlitz", 2)
+        description := "Battle of the Three Emperors"
+
+        tests := []struct {
+                name   string
+                change func(*models.HistoricalEvent)
+                tags   []string
+                same   bool
+        }{
+                {name: "identical", change: func(*models.HistoricalEvent) {}, tags: []string{"battle", "war"}, same: true},
+                {name: "tag order and case", change: func(*models.HistoricalEvent) {}, tags: []string{"War", "BATTLE"}, same: true},
+                {name: "database fields", change: func(e *models.HistoricalEvent) {
+                        e.ID, e.Version, e.Status = 99, 4, models.EventStatusPending
+                }, tags: []string{"battle", "war"}, same: true},
+                {name: "tags", change: func(*models.HistoricalEvent) {}, tags: []string{"battle"}},
+                {name: "name", change: func(e *models.HistoricalEvent) { e.NameEn = "Slavkov" }, tags: []string{"battle", "war"}},
+                {name: "description", change: func(e *models.HistoricalEvent) { e.DescriptionEn = &description }, tags: []string{"battle", "war"}},
+                {name: "date", change: func(e *models.HistoricalEvent) { e.EventDate.Day = 3 }, tags: []string{"battle", "war"}},
+                {name: "precision", change: func(e *models.HistoricalEvent) { e.DatePrecision = models.DatePrecisionYear }, tags: []string{"battle", "war"}},
+                {name: "circa", change: func(e *models.HistoricalEvent) { e.Circa = true }, tags: []string{"battle", "war"}},
+                {name: "location", change: func(e *models.HistoricalEvent) { e.Longitude = 17 }, tags: []string{"battle", "war"}},
+        }
+
+        want := reimportSignature(base, []string{"battle", "war"})
+        for _, tt := range tests {
+                event := base
+                tt.change(&event)
+                if got := reimportSignature(event, tt.tags); (got == want) != tt.same {
+                        t.Errorf("%s: signatures equal = %v, want %v", tt.name, got == want, tt.same)
+                }
+        }
+}
+
+func equalInts(a, b []int) bool {
+        if len(a) != len(b) {
+                return false
+        }
+        for i := range a {
+                if a[i] != b[i] {
+                        return false
+                }
+        }
+        return true
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+        "historical-events-backend/internal/database/repositories"
+        "historical-events-backend/internal/services"
+        "historical-events-backend/pkg/response"
+        "log"
+        "net/http"
+        "strconv"
+
+        "github.com/gorilla/mux"
+)
+
+// recentJobsLimit is how many jobs GET /api/jobs lists
+const recentJobsLimit = 50
+
+// JobHandler handles background import job requests
+type JobHandler struct {
+        jobRepo   *repositories.JobRepository
+        jobRunner *services.JobRunner
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(jobRepo *repositories.JobRepository, jobRunner *services.JobRunner) *JobHandler {
+        return &JobHandler{
+                jobRepo:   jobRepo,
+                jobRunner: jobRunner,
+        }
+}
+
+// GetJobs handles GET /api/jobs, the most recent import jobs the user
+// started, or anyone's for admins
+func (h *JobHandler) GetJobs(w http.ResponseWriter, r *http.Request) {
+        var createdBy *int
+        if user := getUserFromContext(r.Context()); !can(user, PermissionManageJob, nil) {
+                createdBy = &user.ID
+        }
+        
+        jobs, err := h.jobRepo.GetRecent(recentJobsLimit, createdBy)
+        if err != nil {
+                log.Printf("Error retrieving import jobs: %v", err)
+                response.InternalError(w, "Failed to retrieve jobs")
+                return
+        }
+
+        response.Success(w, jobs)
+}
+
+// GetJob handles GET /api/jobs/{id}: status, progress and, once finished, the
+// import result with its report. Only the user who started the job and
+// admins may read it.
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+        id, err := strconv.Atoi(mux.Vars(r)["id"])
+        if err != nil {
+                response.BadRequest(w, "Invalid job ID")
+                return
+        }
+
+        job, err := h.jobRepo.GetByID(id)
+        if err != nil {
+                if err.Error() == "import job not found" {
+                        response.NotFound(w, "Job not found")
+                        return
+                }
+                log.Printf("Error retrieving import job %d: %v", id, err)
+                response.InternalError(w, "Failed to retrieve job")
+                return
+        }
+        if !authorize(w, r, PermissionManageJob, job.CreatedBy) {
+                return
+        }
+
+        response.Success(w, job)
+}
+
+// CancelJob handles POST /api/jobs/{id}/cancel for the user who started the
+// job or an admin. The job's transaction is rolled back, so a cancelled
+// import writes nothing.
+func (h *JobHandler) CancelJob(w http.ResponseWriter, r *http.Request) {
+        id, err := strconv.Atoi(mux.Vars(r)["id"])
+        if err != nil {
+                response.BadRequest(w, "Invalid job ID")
+                return
+        }
+
+        job, err := h.jobRepo.GetByID(id)
+        if err != nil {
+                if err.Error() == "import job not found" {
+                        response.NotFound(w, "Job not found")
+                        return
+                }
+                log.Printf("Error retrieving import job %d: %v", id, err)
+                response.InternalError(w, "Failed to retrieve job")
+                return
+        }
+        if !authorize(w, r, PermissionManageJob, job.CreatedBy) {
+                return
+        }
+
+        if job.Finished() || !h.jobRunner.Cancel(id) {
+                response.Error(w, http.StatusConflict, "Job is not running", "status: "+job.Status)
+                return
+        }
+
+        response.Success(w, job, "Cancellation requested")
+}
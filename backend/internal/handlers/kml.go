@@ -0,0 +1,77 @@
+package handlers
+
+import (
+        "archive/zip"
+        "bytes"
+        "encoding/xml"
+        "fmt"
+        "historical-events-backend/internal/database/repositories"
+        "historical-events-backend/internal/models"
+        "net/http"
+        "path/filepath"
+        "strings"
+)
+
+// Media types of KML documents and zipped KMZ archives
+const (
+        kmlContentType = "application/vnd.google-earth.kml+xml"
+        kmzContentType = "application/vnd.google-earth.kmz"
+)
+
+// addTemplateRegions adds the regions linked to a template to the document as
+// a "Regions" folder of polygons
+func addTemplateRegions(doc *models.KML, regionRepo *repositories.RegionRepository, templateID int, locale string) error {
+        regions, err := regionRepo.GetByTemplateID(templateID)
+        if err != nil {
+                return err
+        }
+        geometries, err := regionRepo.GetKMLGeometries(templateID)
+        if err != nil {
+                return err
+        }
+        doc.AddRegions("Regions", regions, geometries, locale)
+        return nil
+}
+
+// writeKML sends the document as a KML download, or as a KMZ archive holding
+// doc.kml when zipped is set. name is the download file name without extension.
+func writeKML(w http.ResponseWriter, doc *models.KML, name string, zipped bool) error {
+        var buf bytes.Buffer
+        buf.WriteString(xml.Header)
+        encoder := xml.NewEncoder(&buf)
+        encoder.Indent("", "  ")
+        if err := encoder.Encode(doc); err != nil {
+                return fmt.Errorf("failed to encode KML: %w", err)
+        }
+
+        contentType, extension := kmlContentType, ".kml"
+        body := buf.Bytes()
+        if zipped {
+                // Google Earth opens the first .kml file in the archive, conventionally doc.kml
+                var archive bytes.Buffer
+                zw := zip.NewWriter(&archive)
+                f, err := zw.Create("doc.kml")
+                if err != nil {
+                        return fmt.Errorf("failed to create KMZ entry: %w", err)
+                }
+                if _, err := f.Write(body); err != nil {
+                        return fmt.Errorf("failed to write KMZ entry: %w", err)
+                }
+                if err := zw.Close(); err != nil {
+                        return fmt.Errorf("failed to finish KMZ archive: %w", err)
+                }
+                contentType, extension, body = kmzContentType, ".kmz", archive.Bytes()
+        }
+
+        w.Header().Set("Content-Type", contentType)
+        w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s%s\"", name, extension))
+        w.Header().Set("Cache-Control", "no-cache")
+        w.WriteHeader(http.StatusOK)
+        w.Write(body)
+        return nil
+}
+
+// exportBaseName strips the extension from a dataset file name
+func exportBaseName(filename string) string {
+        return strings.TrimSuffix(filename, filepath.Ext(filename))
+}
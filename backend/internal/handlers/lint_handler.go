@@ -0,0 +1,30 @@
+package handlers
+
+import (
+        "historical-events-backend/internal/services"
+        "historical-events-backend/pkg/response"
+        "log"
+        "net/http"
+)
+
+// LintHandler handles data quality report requests
+type LintHandler struct {
+        linter *services.Linter
+}
+
+// NewLintHandler creates a new lint handler
+func NewLintHandler(linter *services.Linter) *LintHandler {
+        return &LintHandler{linter: linter}
+}
+
+// GetReport handles GET /api/lint, the data quality report
+func (h *LintHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+        report, err := h.linter.Run(r.Context())
+        if err != nil {
+                log.Printf("Error running data quality checks: %v", err)
+                response.InternalError(w, "Failed to run data quality checks")
+                return
+        }
+
+        response.Success(w, report)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
         "encoding/json"
+        "errors"
         "historical-events-backend/internal/database/repositories"
         "historical-events-backend/internal/models"
         "historical-events-backend/pkg/response"
@@ -97,6 +98,7 @@ func (h *RegionHandler) GetRegionByID(w http.ResponseWriter, r *http.Request) {
                 TemplateIDs []int `json:"template_ids"`
         }
 
+        setETag(w, region.Version)
         response.Success(w, regionWithTemplates{
                 Region:      *region,
                 TemplateIDs: templateIDs,
@@ -144,6 +146,13 @@ func (h *RegionHandler) CreateRegion(w http.ResponseWriter, r *http.Request) {
                 response.BadRequest(w, "GeoJSON is required")
                 return
         }
+        if !isGeoJSONObject(req.GeoJSON) {
+                response.BadRequest(w, "GeoJSON must be an object with a type")
+                return
+        }
+        if !h.checkGeometry(w, req.GeoJSON) {
+                return
+        }
 
         created, err := h.regionRepo.Create(&req)
         if err != nil {
@@ -163,22 +172,89 @@ func (h *RegionHandler) UpdateRegion(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
+        version, ok := ifMatchVersion(w, r)
+        if !ok {
+                return
+        }
+
         var req models.RegionUpdate
         if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
                 response.BadRequest(w, "Invalid request body")
                 return
         }
+        if req.GeoJSON != nil {
+                if !isGeoJSONObject(*req.GeoJSON) {
+                        response.BadRequest(w, "GeoJSON must be an object with a type")
+                        return
+                }
+                if !h.checkGeometry(w, *req.GeoJSON) {
+                        return
+                }
+        }
 
-        updated, err := h.regionRepo.Update(id, &req)
+        updated, err := h.regionRepo.Update(id, version, &req)
         if err != nil {
+                if errors.Is(err, repositories.ErrVersionConflict) {
+                        h.writeRegionConflict(w, r, id)
+                        return
+                }
+                if err.Error() == "region not found" {
+                        response.NotFound(w, "Region not found")
+                        return
+                }
                 log.Printf("Error updating region %d: %v", id, err)
                 response.InternalError(w, "Failed to update region")
                 return
         }
 
+        setETag(w, updated.Version)
         response.Success(w, updated)
 }
 
+// isGeoJSONObject reports whether raw is a JSON object with a GeoJSON type
+func isGeoJSONObject(raw json.RawMessage) bool {
+        var doc struct {
+                Type string `json:"type"`
+        }
+        if err := json.Unmarshal(raw, &doc); err != nil {
+                return false
+        }
+        return doc.Type != ""
+}
+
+// checkGeometry answers 400 with the PostGIS error when it cannot read geojson
+// as a geometry, which would leave the region without geom and off the map
+func (h *RegionHandler) checkGeometry(w http.ResponseWriter, geojson json.RawMessage) bool {
+        problem, err := h.regionRepo.GeoJSONError(geojson)
+        if err != nil {
+                log.Printf("Error checking region geometry: %v", err)
+                response.InternalError(w, "Failed to check region geometry")
+                return false
+        }
+        if problem != "" {
+                response.BadRequest(w, "Invalid GeoJSON geometry", problem)
+                return false
+        }
+        return true
+}
+
+// writeRegionConflict answers a stale write of region id with 412 and the
+// region as it is now
+func (h *RegionHandler) writeRegionConflict(w http.ResponseWriter, r *http.Request, id int) {
+        current, err := h.regionRepo.GetByID(id)
+        if err != nil {
+                response.NotFound(w, "Region not found")
+                return
+        }
+
+        locale := r.URL.Query().Get("locale")
+        if locale == "" {
+                locale = "en"
+        }
+        current.PopulateLegacyFields(locale)
+        writePreconditionFailed(w, current, current.Version)
+}
+
 func (h *RegionHandler) DeleteRegion(w http.ResponseWriter, r *http.Request) {
         vars := mux.Vars(r)
         id, err := strconv.Atoi(vars["id"])
@@ -187,7 +263,20 @@ func (h *RegionHandler) DeleteRegion(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
-        if err := h.regionRepo.Delete(id); err != nil {
+        version, ok := ifMatchVersion(w, r)
+        if !ok {
+                return
+        }
+
+        if err := h.regionRepo.Delete(id, version); err != nil {
+                if errors.Is(err, repositories.ErrVersionConflict) {
+                        h.writeRegionConflict(w, r, id)
+                        return
+                }
+                if err.Error() == "region not found" {
+                        response.NotFound(w, "Region not found")
+                        return
+                }
                 log.Printf("Error deleting region %d: %v", id, err)
                 response.InternalError(w, "Failed to delete region")
                 return
@@ -196,6 +285,37 @@ func (h *RegionHandler) DeleteRegion(w http.ResponseWriter, r *http.Request) {
         response.Success(w, map[string]string{"message": "Region deleted successfully"})
 }
 
+// RestoreRegion handles POST /api/regions/{id}/restore: the region comes back
+// from the trash with its template links
+func (h *RegionHandler) RestoreRegion(w http.ResponseWriter, r *http.Request) {
+        vars := mux.Vars(r)
+        id, err := strconv.Atoi(vars["id"])
+        if err != nil {
+                response.BadRequest(w, "Invalid region ID")
+                return
+        }
+
+        if err := h.regionRepo.Restore(id); err != nil {
+                writeRestoreError(w, err, "Region")
+                return
+        }
+
+        region, err := h.regionRepo.GetByID(id)
+        if err != nil {
+                log.Printf("Error getting restored region %d: %v", id, err)
+                response.InternalError(w, "Region was restored but could not be read")
+                return
+        }
+
+        locale := r.URL.Query().Get("locale")
+        if locale == "" {
+                locale = "en"
+        }
+        region.PopulateLegacyFields(locale)
+
+        response.Success(w, region, "Region restored successfully")
+}
+
 func (h *RegionHandler) LinkRegionToTemplates(w http.ResponseWriter, r *http.Request) {
         vars := mux.Vars(r)
         regionID, err := strconv.Atoi(vars["id"])
@@ -22,21 +22,30 @@ type Router struct {
         supportHandler  *SupportHandler
         configHandler   *ConfigHandler
         regionHandler   *RegionHandler
+        tileHandler     *TileHandler
+        jobHandler      *JobHandler
+        lintHandler     *LintHandler
+        trashHandler    *TrashHandler
 }
 
 // NewRouter creates a new router with all handlers
-func NewRouter(eventRepo *repositories.EventRepository, templateRepo *repositories.TemplateRepository, tagRepo *repositories.TagRepository, datasetRepo *repositories.DatasetRepository, authService *services.AuthService, supportRepo *repositories.SupportRepository, regionRepo *repositories.RegionRepository) *Router {
+func NewRouter(eventRepo *repositories.EventRepository, templateRepo *repositories.TemplateRepository, tagRepo *repositories.TagRepository, datasetRepo *repositories.DatasetRepository, authService *services.AuthService, supportRepo *repositories.SupportRepository, regionRepo *repositories.RegionRepository, revisionRepo *repositories.RevisionRepository, trashRepo *repositories.TrashRepository, trashRetentionDays int, jobRepo *repositories.JobRepository, jobRunner *services.JobRunner, linter *services.Linter) *Router {
         // Shared cache instance — both EventHandler and TagHandler must invalidate the same cache
         sharedEventCache := cache.NewEventCache()
+        authorizer := NewAuthorizer(eventRepo, datasetRepo)
         return &Router{
-                eventHandler:    NewEventHandler(eventRepo, tagRepo, datasetRepo, sharedEventCache),
+                eventHandler:    NewEventHandler(eventRepo, tagRepo, datasetRepo, regionRepo, revisionRepo, sharedEventCache, jobRunner, authorizer),
                 templateHandler: NewTemplateHandler(templateRepo),
-                tagHandler:      NewTagHandler(tagRepo, sharedEventCache),
+                tagHandler:      NewTagHandler(tagRepo, revisionRepo, sharedEventCache, authorizer),
                 authHandler:     NewAuthHandler(authService),
-                datasetHandler:  NewDatasetHandler(datasetRepo, eventRepo),
+                datasetHandler:  NewDatasetHandler(datasetRepo, eventRepo, regionRepo, revisionRepo, sharedEventCache, authorizer),
                 supportHandler:  NewSupportHandler(supportRepo),
                 configHandler:   NewConfigHandler(),
                 regionHandler:   NewRegionHandler(regionRepo),
+                tileHandler:     NewTileHandler(eventRepo, regionRepo),
+                jobHandler:      NewJobHandler(jobRepo, jobRunner),
+                lintHandler:     NewLintHandler(linter),
+                trashHandler:    NewTrashHandler(trashRepo, trashRetentionDays),
         }
 }
 
@@ -69,17 +78,37 @@ func (router *Router) SetupRoutes() http.Handler {
         // Anonymous session tracking (no auth required)
         api.HandleFunc("/session/anonymous-heartbeat", router.authHandler.AnonymousSessionHeartbeat).Methods("POST", "OPTIONS")
         
-        // Event routes (public read, auth required for create/update/delete)
+        // Event routes (public read of published events; writes by the permissions in authorization.go).
+        // {id} only matches digits so named sub-routes such as /events/bbox are reachable.
         api.HandleFunc("/events", router.authHandler.OptionalAuthMiddleware(router.eventHandler.GetAllEvents)).Methods("GET", "OPTIONS")
-        api.HandleFunc("/events", router.authHandler.AuthMiddleware(router.eventHandler.CreateEvent)).Methods("POST", "OPTIONS")
-        api.HandleFunc("/events/import", router.authHandler.RequireAccessLevel(models.AccessLevelAdmin)(router.eventHandler.ImportEvents)).Methods("POST", "OPTIONS")
-        api.HandleFunc("/events/{id}", router.authHandler.OptionalAuthMiddleware(router.eventHandler.GetEventByID)).Methods("GET", "OPTIONS")
-        api.HandleFunc("/events/{id}", router.authHandler.RequireAccessLevel(models.AccessLevelAdmin)(router.eventHandler.UpdateEvent)).Methods("PUT", "OPTIONS")
-        api.HandleFunc("/events/{id}", router.authHandler.RequireAccessLevel(models.AccessLevelAdmin)(router.eventHandler.DeleteEvent)).Methods("DELETE", "OPTIONS")
+        api.HandleFunc("/events", router.authHandler.RequirePermission(PermissionCreateEvent)(router.eventHandler.CreateEvent)).Methods("POST", "OPTIONS")
+        api.HandleFunc("/events/import", router.authHandler.RequirePermission(PermissionImportDataset)(router.eventHandler.ImportEvents)).Methods("POST", "OPTIONS")
+        api.HandleFunc("/events/{id:[0-9]+}", router.authHandler.OptionalAuthMiddleware(router.eventHandler.GetEventByID)).Methods("GET", "OPTIONS")
+        api.HandleFunc("/events/{id:[0-9]+}", router.authHandler.RequirePermission(PermissionEditEvent)(router.eventHandler.UpdateEvent)).Methods("PUT", "OPTIONS")
+        api.HandleFunc("/events/{id:[0-9]+}", router.authHandler.RequirePermission(PermissionDeleteEvent)(router.eventHandler.DeleteEvent)).Methods("DELETE", "OPTIONS")
+        api.HandleFunc("/events/{id:[0-9]+}/restore", router.authHandler.RequirePermission(PermissionRestoreEvent)(router.eventHandler.RestoreEvent)).Methods("POST", "OPTIONS")
+        api.HandleFunc("/events/{id:[0-9]+}/history", router.authHandler.RequirePermission(PermissionEventHistory)(router.eventHandler.GetEventHistory)).Methods("GET", "OPTIONS")
+        api.HandleFunc("/events/{id:[0-9]+}/revert/{rev:[0-9]+}", router.authHandler.RequirePermission(PermissionEventHistory)(router.eventHandler.RevertEvent)).Methods("POST", "OPTIONS")
         
         // Spatial query routes
         api.HandleFunc("/events/bbox", router.eventHandler.GetEventsInBBox).Methods("GET", "OPTIONS")
         api.HandleFunc("/events/radius", router.eventHandler.GetEventsInRadius).Methods("GET", "OPTIONS")
+        api.HandleFunc("/events/clusters", router.eventHandler.GetEventClusters).Methods("GET", "OPTIONS")
+        
+        // Full-text search
+        api.HandleFunc("/events/search", router.eventHandler.SearchEvents).Methods("GET", "OPTIONS")
+        
+        // Duplicate detection and merging
+        api.HandleFunc("/events/duplicates", router.authHandler.RequirePermission(PermissionMergeEvents)(router.eventHandler.GetDuplicates)).Methods("GET", "OPTIONS")
+        api.HandleFunc("/events/merge", router.authHandler.RequirePermission(PermissionMergeEvents)(router.eventHandler.MergeEvents)).Methods("POST", "OPTIONS")
+        
+        // Moderation queue (editors): events and edits of published events submitted by users wait there until approved or rejected
+        api.HandleFunc("/moderation/queue", router.authHandler.RequirePermission(PermissionReviewEvents)(router.eventHandler.GetModerationQueue)).Methods("GET", "OPTIONS")
+        api.HandleFunc("/moderation/events/{id:[0-9]+}/approve", router.authHandler.RequirePermission(PermissionReviewEvents)(router.eventHandler.ApproveEvent)).Methods("POST", "OPTIONS")
+        api.HandleFunc("/moderation/events/{id:[0-9]+}/reject", router.authHandler.RequirePermission(PermissionReviewEvents)(router.eventHandler.RejectEvent)).Methods("POST", "OPTIONS")
+        api.HandleFunc("/moderation/edits", router.authHandler.RequirePermission(PermissionReviewEvents)(router.eventHandler.GetPendingEdits)).Methods("GET", "OPTIONS")
+        api.HandleFunc("/moderation/edits/{id:[0-9]+}/approve", router.authHandler.RequirePermission(PermissionReviewEvents)(router.eventHandler.ApproveEdit)).Methods("POST", "OPTIONS")
+        api.HandleFunc("/moderation/edits/{id:[0-9]+}/reject", router.authHandler.RequirePermission(PermissionReviewEvents)(router.eventHandler.RejectEdit)).Methods("POST", "OPTIONS")
         
         // Template routes (read public, write requires admin)
         api.HandleFunc("/date-template-groups", router.templateHandler.GetAllGroups).Methods("GET", "OPTIONS")
@@ -100,14 +129,28 @@ func (router *Router) SetupRoutes() http.Handler {
         api.HandleFunc("/tags/{id}", router.tagHandler.GetTagByID).Methods("GET", "OPTIONS")
         api.HandleFunc("/tags/{id}", router.authHandler.RequireAccessLevel(models.AccessLevelEditor)(router.tagHandler.UpdateTag)).Methods("PUT", "OPTIONS")
         api.HandleFunc("/tags/{id}", router.authHandler.RequireAccessLevel(models.AccessLevelEditor)(router.tagHandler.DeleteTag)).Methods("DELETE", "OPTIONS")
+        api.HandleFunc("/tags/{id}/restore", router.authHandler.RequireAccessLevel(models.AccessLevelEditor)(router.tagHandler.RestoreTag)).Methods("POST", "OPTIONS")
+        
+        // Dataset routes (editors; changing and deleting needs ownership or admin)
+        api.HandleFunc("/datasets", router.authHandler.RequirePermission(PermissionViewDatasets)(router.datasetHandler.GetAllDatasets)).Methods("GET", "OPTIONS")
+        api.HandleFunc("/datasets", router.authHandler.RequirePermission(PermissionImportDataset)(router.datasetHandler.CreateDataset)).Methods("POST", "OPTIONS")
+        api.HandleFunc("/datasets/{id}", router.authHandler.RequirePermission(PermissionViewDatasets)(router.datasetHandler.GetDatasetByID)).Methods("GET", "OPTIONS")
+        api.HandleFunc("/datasets/{id}/export", router.authHandler.RequirePermission(PermissionViewDatasets)(router.datasetHandler.ExportDataset)).Methods("GET", "OPTIONS")
+        api.HandleFunc("/datasets/{id}/reimport", router.authHandler.RequirePermission(PermissionChangeDataset)(router.eventHandler.ReimportDataset)).Methods("POST", "OPTIONS")
+        api.HandleFunc("/datasets/{id}/reset-modified", router.authHandler.RequirePermission(PermissionChangeDataset)(router.datasetHandler.ResetModifiedFlag)).Methods("POST", "OPTIONS")
+        api.HandleFunc("/datasets/{id}", router.authHandler.RequirePermission(PermissionDeleteDataset)(router.datasetHandler.DeleteDataset)).Methods("DELETE", "OPTIONS")
+        api.HandleFunc("/datasets/{id}/restore", router.authHandler.RequirePermission(PermissionRestoreDataset)(router.datasetHandler.RestoreDataset)).Methods("POST", "OPTIONS")
         
-        // Dataset routes (admin only)
-        api.HandleFunc("/datasets", router.authHandler.RequireAccessLevel(models.AccessLevelAdmin)(router.datasetHandler.GetAllDatasets)).Methods("GET", "OPTIONS")
-        api.HandleFunc("/datasets", router.authHandler.RequireAccessLevel(models.AccessLevelAdmin)(router.datasetHandler.CreateDataset)).Methods("POST", "OPTIONS")
-        api.HandleFunc("/datasets/{id}", router.authHandler.RequireAccessLevel(models.AccessLevelAdmin)(router.datasetHandler.GetDatasetByID)).Methods("GET", "OPTIONS")
-        api.HandleFunc("/datasets/{id}/export", router.authHandler.RequireAccessLevel(models.AccessLevelAdmin)(router.datasetHandler.ExportDataset)).Methods("GET", "OPTIONS")
-        api.HandleFunc("/datasets/{id}/reset-modified", router.authHandler.RequireAccessLevel(models.AccessLevelAdmin)(router.datasetHandler.ResetModifiedFlag)).Methods("POST", "OPTIONS")
-        api.HandleFunc("/datasets/{id}", router.authHandler.RequireAccessLevel(models.AccessLevelAdmin)(router.datasetHandler.DeleteDataset)).Methods("DELETE", "OPTIONS")
+        // Background import job routes (each job to the user who started it, and admins)
+        api.HandleFunc("/jobs", router.authHandler.RequirePermission(PermissionManageJob)(router.jobHandler.GetJobs)).Methods("GET", "OPTIONS")
+        api.HandleFunc("/jobs/{id:[0-9]+}", router.authHandler.RequirePermission(PermissionManageJob)(router.jobHandler.GetJob)).Methods("GET", "OPTIONS")
+        api.HandleFunc("/jobs/{id:[0-9]+}/cancel", router.authHandler.RequirePermission(PermissionManageJob)(router.jobHandler.CancelJob)).Methods("POST", "OPTIONS")
+        
+        // Data quality report (admin only)
+        api.HandleFunc("/lint", router.authHandler.RequireAccessLevel(models.AccessLevelAdmin)(router.lintHandler.GetReport)).Methods("GET", "OPTIONS")
+        
+        // Trash bin (admin only); restoring is under each resource
+        api.HandleFunc("/trash", router.authHandler.RequireAccessLevel(models.AccessLevelAdmin)(router.trashHandler.GetTrash)).Methods("GET", "OPTIONS")
         
         // User management routes (super users only)
         api.HandleFunc("/users", router.authHandler.RequireAccessLevel(models.AccessLevelSuper)(router.authHandler.GetAllUsers)).Methods("GET", "OPTIONS")
@@ -115,10 +158,10 @@ func (router *Router) SetupRoutes() http.Handler {
         api.HandleFunc("/users/{id}", router.authHandler.RequireAccessLevel(models.AccessLevelSuper)(router.authHandler.UpdateUser)).Methods("PUT", "OPTIONS")
         api.HandleFunc("/users/{id}", router.authHandler.RequireAccessLevel(models.AccessLevelSuper)(router.authHandler.DeleteUser)).Methods("DELETE", "OPTIONS")
         
-        // Event-Tag relationship routes (requires editor/admin)
-        api.HandleFunc("/events/{event_id}/tags/{tag_id}", router.authHandler.RequireAccessLevel(models.AccessLevelEditor)(router.tagHandler.AddTagToEvent)).Methods("POST", "OPTIONS")
-        api.HandleFunc("/events/{event_id}/tags/{tag_id}", router.authHandler.RequireAccessLevel(models.AccessLevelEditor)(router.tagHandler.RemoveTagFromEvent)).Methods("DELETE", "OPTIONS")
-        api.HandleFunc("/events/{event_id}/tags", router.authHandler.RequireAccessLevel(models.AccessLevelEditor)(router.tagHandler.SetEventTags)).Methods("PUT", "OPTIONS")
+        // Event-Tag relationship routes (own events for users, any event for editors)
+        api.HandleFunc("/events/{event_id}/tags/{tag_id}", router.authHandler.RequirePermission(PermissionTagEvent)(router.tagHandler.AddTagToEvent)).Methods("POST", "OPTIONS")
+        api.HandleFunc("/events/{event_id}/tags/{tag_id}", router.authHandler.RequirePermission(PermissionTagEvent)(router.tagHandler.RemoveTagFromEvent)).Methods("DELETE", "OPTIONS")
+        api.HandleFunc("/events/{event_id}/tags", router.authHandler.RequirePermission(PermissionTagEvent)(router.tagHandler.SetEventTags)).Methods("PUT", "OPTIONS")
         
         // Support credentials routes (public read, admin for create/update/delete)
         api.HandleFunc("/support", router.supportHandler.GetSupportCredentials).Methods("GET", "OPTIONS")
@@ -133,9 +176,14 @@ func (router *Router) SetupRoutes() http.Handler {
         api.HandleFunc("/regions/{id}", router.authHandler.RequireAccessLevel(models.AccessLevelEditor)(router.regionHandler.GetRegionByID)).Methods("GET", "OPTIONS")
         api.HandleFunc("/regions/{id}", router.authHandler.RequireAccessLevel(models.AccessLevelEditor)(router.regionHandler.UpdateRegion)).Methods("PUT", "OPTIONS")
         api.HandleFunc("/regions/{id}", router.authHandler.RequireAccessLevel(models.AccessLevelEditor)(router.regionHandler.DeleteRegion)).Methods("DELETE", "OPTIONS")
+        api.HandleFunc("/regions/{id}/restore", router.authHandler.RequireAccessLevel(models.AccessLevelEditor)(router.regionHandler.RestoreRegion)).Methods("POST", "OPTIONS")
         api.HandleFunc("/regions/{id}/templates", router.authHandler.RequireAccessLevel(models.AccessLevelEditor)(router.regionHandler.LinkRegionToTemplates)).Methods("POST", "OPTIONS")
         api.HandleFunc("/regions/{id}/templates/{templateId}", router.authHandler.RequireAccessLevel(models.AccessLevelEditor)(router.regionHandler.UnlinkRegionFromTemplate)).Methods("DELETE", "OPTIONS")
         
+        // Vector tile routes (public)
+        api.HandleFunc("/tiles/events/{z:[0-9]+}/{x:[0-9]+}/{y:[0-9]+}.mvt", router.tileHandler.GetEventTile).Methods("GET", "OPTIONS")
+        api.HandleFunc("/tiles/regions/{z:[0-9]+}/{x:[0-9]+}/{y:[0-9]+}.mvt", router.tileHandler.GetRegionTile).Methods("GET", "OPTIONS")
+        
         // Public config route (contact email, etc.)
         api.HandleFunc("/config", router.configHandler.GetPublicConfig).Methods("GET", "OPTIONS")
         
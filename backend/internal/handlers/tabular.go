@@ -0,0 +1,329 @@
+package handlers
+
+import (
+        "bytes"
+        "encoding/csv"
+        "encoding/json"
+        "fmt"
+        "historical-events-backend/internal/models"
+        "historical-events-backend/pkg/xlsx"
+        "io"
+        "net/http"
+        "path/filepath"
+        "sort"
+        "strconv"
+        "strings"
+)
+
+// Spreadsheet formats for imports and exports
+const (
+        formatCSV  = "csv"
+        formatXLSX = "xlsx"
+)
+
+const csvContentType = "text/csv"
+
+// maxTabularUpload limits the size of CSV/XLSX import uploads
+const maxTabularUpload = 16 << 20
+
+// utf8BOM makes Excel open exported CSV files as UTF-8 (Cyrillic names)
+const utf8BOM = "\ufeff"
+
+// tabularColumns are the columns of CSV/XLSX exports, in order. They use the
+// field names of the JSON dataset format, which is also what imports expect
+// by default, so exports round-trip.
+var tabularColumns = []string{
+        "name", "name_ru", "description", "description_ru",
+        "date", "era", "calendar", "precision", "circa",
+        "earliest", "earliest_era", "latest", "latest_era", "end_date", "end_era",
+        "latitude", "longitude", "type", "tags", "source", "external_id",
+}
+
+// tabularImportFields are the fields a column mapping can target: the export
+// columns plus the explicit English name and description
+var tabularImportFields = append([]string{"name_en", "description_en"}, tabularColumns...)
+
+// tagSeparator separates tag names within a cell
+const tagSeparator = ";"
+
+// parseTabularImport reads a CSV or XLSX upload into an import request. The
+// multipart form carries the file, an optional format (csv or xlsx, taken
+// from the file extension otherwise) and an optional JSON mapping from import
+// field to column header, e.g. {"name_en": "Title", "date": "Date"}. Fields
+// without a mapping are read from the column named like the field. Rows that
+// cannot be converted are returned as import errors.
+func parseTabularImport(r *http.Request) (importRequest, []models.ImportIssue, error) {
+        if err := r.ParseMultipartForm(maxTabularUpload); err != nil {
+                return importRequest{}, nil, fmt.Errorf("Invalid multipart upload: %v", err)
+        }
+
+        file, header, err := r.FormFile("file")
+        if err != nil {
+                return importRequest{}, nil, fmt.Errorf("Missing file in upload")
+        }
+        defer file.Close()
+
+        format := strings.ToLower(r.FormValue("format"))
+        if format == "" {
+                format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+        }
+
+        var rows [][]string
+        switch format {
+        case formatCSV:
+                rows, err = readCSV(file, r.FormValue("delimiter"))
+        case formatXLSX:
+                rows, err = xlsx.Read(file, header.Size)
+        default:
+                return importRequest{}, nil, fmt.Errorf("Unsupported import format '%s' (valid: csv, xlsx)", format)
+        }
+        if err != nil {
+                return importRequest{}, nil, fmt.Errorf("Failed to read %s file: %v", format, err)
+        }
+        if len(rows) == 0 {
+                return importRequest{}, nil, fmt.Errorf("The uploaded file is empty")
+        }
+
+        mapping := map[string]string{}
+        if value := r.FormValue("mapping"); value != "" {
+                if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+                        return importRequest{}, nil, fmt.Errorf("Invalid mapping, expected a JSON object of field to column name")
+                }
+        }
+
+        columns, err := mapColumns(rows[0], mapping)
+        if err != nil {
+                return importRequest{}, nil, err
+        }
+
+        req := importRequest{Filename: header.Filename}
+        _, req.precisionColumn = columns["precision"]
+        var issues []models.ImportIssue
+        for i, row := range rows[1:] {
+                if isBlankRow(row) {
+                        continue
+                }
+                // Row numbers as shown by spreadsheet programs: the header is row 1
+                event, issue := tabularRowToEvent(row, columns)
+                if issue != nil {
+                        issue.Row = i + 2
+                        issues = append(issues, *issue)
+                        continue
+                }
+                event.Row = i + 2
+                req.Events = append(req.Events, event)
+        }
+        return req, issues, nil
+}
+
+// readCSV reads all records of a CSV file. Without an explicit delimiter,
+// ";" is used when the header line has more semicolons than commas, as in
+// files saved by Excel in many European locales.
+func readCSV(file io.Reader, delimiter string) ([][]string, error) {
+        data, err := io.ReadAll(file)
+        if err != nil {
+                return nil, err
+        }
+        data = bytes.TrimPrefix(data, []byte(utf8BOM))
+
+        comma := ','
+        switch {
+        case delimiter == "tab" || delimiter == "\t":
+                comma = '\t'
+        case len(delimiter) == 1:
+                comma = rune(delimiter[0])
+        case delimiter == "":
+                firstLine := data
+                if i := bytes.IndexByte(data, '\n'); i >= 0 {
+                        firstLine = data[:i]
+                }
+                if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+                        comma = ';'
+                }
+        default:
+                return nil, fmt.Errorf("invalid delimiter '%s'", delimiter)
+        }
+
+        reader := csv.NewReader(bytes.NewReader(data))
+        reader.Comma = comma
+        reader.FieldsPerRecord = -1
+        reader.TrimLeadingSpace = true
+        return reader.ReadAll()
+}
+
+// mapColumns resolves the column index of every import field from the header
+// row. Header names match case-insensitively.
+func mapColumns(header []string, mapping map[string]string) (map[string]int, error) {
+        known := make(map[string]bool, len(tabularImportFields))
+        for _, field := range tabularImportFields {
+                known[field] = true
+        }
+        for field := range mapping {
+                if !known[field] {
+                        return nil, fmt.Errorf("Unknown field '%s' in mapping (valid: %s)", field, strings.Join(tabularImportFields, ", "))
+                }
+        }
+
+        index := make(map[string]int, len(header))
+        for i, name := range header {
+                key := strings.ToLower(strings.TrimSpace(name))
+                if _, exists := index[key]; !exists {
+                        index[key] = i
+                }
+        }
+
+        columns := make(map[string]int)
+        var missing []string
+        for _, field := range tabularImportFields {
+                name, mapped := mapping[field]
+                if !mapped {
+                        name = field
+                }
+                if i, ok := index[strings.ToLower(strings.TrimSpace(name))]; ok {
+                        columns[field] = i
+                } else if mapped {
+                        missing = append(missing, fmt.Sprintf("'%s' (for %s)", name, field))
+                }
+        }
+        if len(missing) > 0 {
+                sort.Strings(missing)
+                return nil, fmt.Errorf("Mapped columns not found in header: %s", strings.Join(missing, ", "))
+        }
+
+        for _, field := range []string{"date", "latitude", "longitude", "type"} {
+                if _, ok := columns[field]; !ok {
+                        return nil, fmt.Errorf("Missing required column '%s' (add it to the header or the mapping)", field)
+                }
+        }
+        _, hasName := columns["name"]
+        _, hasNameEn := columns["name_en"]
+        if !hasName && !hasNameEn {
+                return nil, fmt.Errorf("Missing required column 'name' or 'name_en' (add it to the header or the mapping)")
+        }
+        return columns, nil
+}
+
+// tabularRowToEvent converts one spreadsheet row into an import event. Cells
+// that cannot be converted are returned as an import error without a row.
+func tabularRowToEvent(row []string, columns map[string]int) (importEvent, *models.ImportIssue) {
+        cell := func(field string) string {
+                i, ok := columns[field]
+                if !ok || i >= len(row) {
+                        return ""
+                }
+                return strings.TrimSpace(row[i])
+        }
+
+        event := importEvent{
+                Name:          cell("name"),
+                NameEN:        cell("name_en"),
+                NameRU:        cell("name_ru"),
+                Description:   cell("description"),
+                DescriptionEN: cell("description_en"),
+                DescriptionRU: cell("description_ru"),
+                Date:          cell("date"),
+                Era:           strings.ToUpper(cell("era")),
+                Calendar:      strings.ToLower(cell("calendar")),
+                Precision:     strings.ToLower(cell("precision")),
+                Earliest:      cell("earliest"),
+                EarliestEra:   strings.ToUpper(cell("earliest_era")),
+                Latest:        cell("latest"),
+                LatestEra:     strings.ToUpper(cell("latest_era")),
+                EndDate:       cell("end_date"),
+                EndEra:        strings.ToUpper(cell("end_era")),
+                Type:          strings.ToLower(cell("type")),
+                Source:        cell("source"),
+                ExternalID:    cell("external_id"),
+        }
+        switch strings.ToLower(cell("circa")) {
+        case "", "false", "no", "0":
+        case "true", "yes", "1", "x":
+                event.Circa = true
+        default:
+                return importEvent{}, tabularIssue("circa", models.ImportInvalidValue, fmt.Sprintf("invalid circa '%s' (use true or false)", cell("circa")))
+        }
+
+        var err error
+        if event.Latitude, err = parseCellFloat(cell("latitude")); err != nil {
+                return importEvent{}, tabularIssue("latitude", models.ImportInvalidLatitude, fmt.Sprintf("invalid latitude '%s'", cell("latitude")))
+        }
+        if event.Longitude, err = parseCellFloat(cell("longitude")); err != nil {
+                return importEvent{}, tabularIssue("longitude", models.ImportInvalidLongitude, fmt.Sprintf("invalid longitude '%s'", cell("longitude")))
+        }
+
+        for _, tag := range strings.Split(cell("tags"), tagSeparator) {
+                if tag = strings.TrimSpace(tag); tag != "" {
+                        event.Tags = append(event.Tags, tag)
+                }
+        }
+        return event, nil
+}
+
+func tabularIssue(field, code, message string) *models.ImportIssue {
+        return &models.ImportIssue{Field: field, Code: code, Severity: models.ImportSeverityError, Message: message}
+}
+
+// parseCellFloat parses a coordinate, accepting a decimal comma
+func parseCellFloat(value string) (float64, error) {
+        return strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+}
+
+func isBlankRow(row []string) bool {
+        for _, value := range row {
+                if strings.TrimSpace(value) != "" {
+                        return false
+                }
+        }
+        return true
+}
+
+// tabularRows converts events into a header row plus one row per event, with
+// the same values as the JSON dataset export
+func tabularRows(events []models.HistoricalEvent) [][]string {
+        rows := make([][]string, 0, len(events)+1)
+        rows = append(rows, tabularColumns)
+        for _, event := range events {
+                values := datasetExportEvent(event)
+                row := make([]string, len(tabularColumns))
+                for i, column := range tabularColumns {
+                        switch v := values[column].(type) {
+                        case string:
+                                row[i] = v
+                        case float64:
+                                row[i] = strconv.FormatFloat(v, 'f', -1, 64)
+                        case bool:
+                                row[i] = strconv.FormatBool(v)
+                        case []string:
+                                row[i] = strings.Join(v, tagSeparator)
+                        }
+                }
+                rows = append(rows, row)
+        }
+        return rows
+}
+
+// writeTabular sends events as a CSV or XLSX download. name is the download
+// file name without extension.
+func writeTabular(w http.ResponseWriter, format, name string, events []models.HistoricalEvent) error {
+        rows := tabularRows(events)
+
+        var buf bytes.Buffer
+        contentType := xlsx.ContentType
+        if format == formatCSV {
+                contentType = csvContentType + "; charset=utf-8"
+                buf.WriteString(utf8BOM)
+                writer := csv.NewWriter(&buf)
+                if err := writer.WriteAll(rows); err != nil {
+                        return fmt.Errorf("failed to write CSV: %w", err)
+                }
+        } else if err := xlsx.Write(&buf, "Events", rows); err != nil {
+                return fmt.Errorf("failed to write XLSX: %w", err)
+        }
+
+        w.Header().Set("Content-Type", contentType)
+        w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", name, format))
+        w.Header().Set("Cache-Control", "no-cache")
+        w.WriteHeader(http.StatusOK)
+        w.Write(buf.Bytes())
+        return nil
+}
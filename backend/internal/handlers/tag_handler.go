@@ -1,11 +1,14 @@
 package handlers
 
 import (
+        "database/sql"
         "encoding/json"
+        "errors"
         "historical-events-backend/internal/database/repositories"
         "historical-events-backend/internal/models"
         "historical-events-backend/pkg/cache"
         "historical-events-backend/pkg/response"
+        "log"
         "net/http"
         "strconv"
 
@@ -14,15 +17,19 @@ import (
 
 // TagHandler handles HTTP requests for tags
 type TagHandler struct {
-        tagRepo    *repositories.TagRepository
-        eventCache *cache.EventCache
+        tagRepo      *repositories.TagRepository
+        revisionRepo *repositories.RevisionRepository
+        eventCache   *cache.EventCache
+        authorizer   *Authorizer
 }
 
 // NewTagHandler creates a new TagHandler
-func NewTagHandler(tagRepo *repositories.TagRepository, eventCache *cache.EventCache) *TagHandler {
+func NewTagHandler(tagRepo *repositories.TagRepository, revisionRepo *repositories.RevisionRepository, eventCache *cache.EventCache, authorizer *Authorizer) *TagHandler {
         return &TagHandler{
-                tagRepo:    tagRepo,
-                eventCache: eventCache,
+                tagRepo:      tagRepo,
+                revisionRepo: revisionRepo,
+                eventCache:   eventCache,
+                authorizer:   authorizer,
         }
 }
 
@@ -52,6 +59,7 @@ func (h *TagHandler) GetTagByID(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
+        setETag(w, tag.Version)
         response.Success(w, tag)
 }
 
@@ -85,6 +93,11 @@ func (h *TagHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
+        version, ok := ifMatchVersion(w, r)
+        if !ok {
+                return
+        }
+
         var req models.UpdateTagRequest
         if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
                 response.BadRequest(w, "Invalid JSON payload")
@@ -125,17 +138,38 @@ func (h *TagHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
                 existingTag.Weight = *req.Weight
         }
 
-        // Update tag in database
+        // Update tag in database, unless it changed since the client read it
+        existingTag.Version = version
         updatedTag, err := h.tagRepo.UpdateTag(id, existingTag)
+        if errors.Is(err, repositories.ErrVersionConflict) {
+                h.writeTagConflict(w, id)
+                return
+        }
+        if err == sql.ErrNoRows {
+                response.NotFound(w, "Tag not found")
+                return
+        }
         if err != nil {
                 response.InternalError(w, "Failed to update tag")
                 return
         }
 
         h.eventCache.Invalidate()
+        setETag(w, updatedTag.Version)
         response.Success(w, updatedTag)
 }
 
+// writeTagConflict answers a stale write of tag id with 412 and the tag as it
+// is now
+func (h *TagHandler) writeTagConflict(w http.ResponseWriter, id int) {
+        current, err := h.tagRepo.GetTagByID(id)
+        if err != nil {
+                response.NotFound(w, "Tag not found")
+                return
+        }
+        writePreconditionFailed(w, current, current.Version)
+}
+
 // DeleteTag handles DELETE /api/tags/{id}
 func (h *TagHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
         vars := mux.Vars(r)
@@ -145,16 +179,96 @@ func (h *TagHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
-        err = h.tagRepo.DeleteTag(id)
+        version, ok := ifMatchVersion(w, r)
+        if !ok {
+                return
+        }
+
+        tx, err := h.tagRepo.Begin()
         if err != nil {
                 response.InternalError(w, "Failed to delete tag")
                 return
         }
+        defer tx.Rollback()
+
+        // The events lose the tag: record their new state
+        eventIDs, err := h.tagRepo.GetEventIDsByTagTx(tx, id)
+        if err != nil {
+                log.Printf("Error getting events of tag %d: %v", id, err)
+                response.InternalError(w, "Failed to delete tag")
+                return
+        }
+        if err := h.tagRepo.DeleteTagTx(tx, id, version); err != nil {
+                if errors.Is(err, repositories.ErrVersionConflict) {
+                        h.writeTagConflict(w, id)
+                        return
+                }
+                if err == sql.ErrNoRows {
+                        response.NotFound(w, "Tag not found")
+                        return
+                }
+                response.InternalError(w, "Failed to delete tag")
+                return
+        }
+        if err := h.revisionRepo.RecordManyTx(tx, eventIDs, models.RevisionTags, userIDFromContext(r.Context())); err != nil {
+                log.Printf("Error recording event revisions: %v", err)
+                response.InternalError(w, "Failed to delete tag")
+                return
+        }
+        if err := tx.Commit(); err != nil {
+                response.InternalError(w, "Failed to delete tag")
+                return
+        }
 
         h.eventCache.Invalidate()
         response.Success(w, map[string]string{"message": "Tag deleted successfully"})
 }
 
+// RestoreTag handles POST /api/tags/{id}/restore: the tag comes back from the
+// trash, attached to the events it had
+func (h *TagHandler) RestoreTag(w http.ResponseWriter, r *http.Request) {
+        vars := mux.Vars(r)
+        id, err := strconv.Atoi(vars["id"])
+        if err != nil {
+                response.BadRequest(w, "Invalid tag ID")
+                return
+        }
+
+        tx, err := h.tagRepo.Begin()
+        if err != nil {
+                response.InternalError(w, "Failed to restore tag")
+                return
+        }
+        defer tx.Rollback()
+
+        // The events get the tag back: record their new state
+        err = h.tagRepo.RestoreTagTx(tx, id)
+        var eventIDs []int
+        if err == nil {
+                eventIDs, err = h.tagRepo.GetEventIDsByTagTx(tx, id)
+        }
+        if err == nil {
+                err = h.revisionRepo.RecordManyTx(tx, eventIDs, models.RevisionTags, userIDFromContext(r.Context()))
+        }
+        if err == nil {
+                err = tx.Commit()
+        }
+        if err != nil {
+                writeRestoreError(w, err, "Tag")
+                return
+        }
+
+        h.eventCache.Invalidate()
+
+        tag, err := h.tagRepo.GetTagByID(id)
+        if err != nil {
+                log.Printf("Error getting restored tag %d: %v", id, err)
+                response.InternalError(w, "Tag was restored but could not be read")
+                return
+        }
+        response.Success(w, tag, "Tag restored successfully")
+}
+
 // AddTagToEvent handles POST /api/events/{event_id}/tags/{tag_id}
 func (h *TagHandler) AddTagToEvent(w http.ResponseWriter, r *http.Request) {
         vars := mux.Vars(r)
@@ -169,10 +283,15 @@ func (h *TagHandler) AddTagToEvent(w http.ResponseWriter, r *http.Request) {
                 response.BadRequest(w, "Invalid tag ID")
                 return
         }
+        if !h.authorizer.Event(w, r, PermissionTagEvent, eventID) {
+                return
+        }
 
-        err = h.tagRepo.AddTagToEvent(eventID, tagID)
+        err = h.changeEventTags(r, eventID, func(tx *sql.Tx) error {
+                return h.tagRepo.AddTagToEventTx(tx, eventID, tagID)
+        })
         if err != nil {
-                response.InternalError(w, "Failed to add tag to event")
+                writeEventTagsError(w, err, "Failed to add tag to event")
                 return
         }
 
@@ -194,10 +313,15 @@ func (h *TagHandler) RemoveTagFromEvent(w http.ResponseWriter, r *http.Request)
                 response.BadRequest(w, "Invalid tag ID")
                 return
         }
+        if !h.authorizer.Event(w, r, PermissionTagEvent, eventID) {
+                return
+        }
 
-        err = h.tagRepo.RemoveTagFromEvent(eventID, tagID)
+        err = h.changeEventTags(r, eventID, func(tx *sql.Tx) error {
+                return h.tagRepo.RemoveTagFromEventTx(tx, eventID, tagID)
+        })
         if err != nil {
-                response.InternalError(w, "Failed to remove tag from event")
+                writeEventTagsError(w, err, "Failed to remove tag from event")
                 return
         }
 
@@ -221,15 +345,48 @@ func (h *TagHandler) SetEventTags(w http.ResponseWriter, r *http.Request) {
                 response.BadRequest(w, "Invalid JSON payload")
                 return
         }
+        if !h.authorizer.Event(w, r, PermissionTagEvent, eventID) {
+                return
+        }
 
         // Allow unlimited tags per event (display will be limited on frontend)
 
-        err = h.tagRepo.SetEventTags(eventID, req.TagIDs)
+        err = h.changeEventTags(r, eventID, func(tx *sql.Tx) error {
+                return h.tagRepo.SetEventTagsTx(tx, eventID, req.TagIDs)
+        })
         if err != nil {
-                response.InternalError(w, "Failed to set event tags")
+                writeEventTagsError(w, err, "Failed to set event tags")
                 return
         }
 
         h.eventCache.Invalidate()
         response.Success(w, map[string]string{"message": "Event tags updated successfully"})
+}
+
+// changeEventTags runs change and records the event's new tags as a revision,
+// in one transaction
+func (h *TagHandler) changeEventTags(r *http.Request, eventID int, change func(tx *sql.Tx) error) error {
+        tx, err := h.tagRepo.Begin()
+        if err != nil {
+                return err
+        }
+        defer tx.Rollback()
+
+        if err := change(tx); err != nil {
+                return err
+        }
+        if err := h.revisionRepo.RecordTx(tx, eventID, models.RevisionTags, userIDFromContext(r.Context())); err != nil {
+                return err
+        }
+        return tx.Commit()
+}
+
+// writeEventTagsError responds to a failed changeEventTags
+func writeEventTagsError(w http.ResponseWriter, err error, message string) {
+        if err.Error() == "event not found" {
+                response.NotFound(w, "Event not found")
+                return
+        }
+        log.Printf("%s: %v", message, err)
+        response.InternalError(w, message)
 }
\ No newline at end of file
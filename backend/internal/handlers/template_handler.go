@@ -2,6 +2,7 @@ package handlers
 
 import (
         "encoding/json"
+        "errors"
         "historical-events-backend/internal/database/repositories"
         "historical-events-backend/internal/models"
         "historical-events-backend/pkg/response"
@@ -211,7 +212,11 @@ func (h *TemplateHandler) GetGroupByID(w http.ResponseWriter, r *http.Request) {
 func (h *TemplateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
         var template models.DateTemplate
         if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
-                response.BadRequest(w, "Invalid request body")
+                response.BadRequest(w, "Invalid request body", err.Error())
+                return
+        }
+        if err := template.Validate(); err != nil {
+                response.BadRequest(w, err.Error())
                 return
         }
         
@@ -236,22 +241,58 @@ func (h *TemplateHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request)
                 return
         }
         
+        version, ok := ifMatchVersion(w, r)
+        if !ok {
+                return
+        }
+        
         var template models.DateTemplate
         if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
-                response.BadRequest(w, "Invalid request body")
+                response.BadRequest(w, "Invalid request body", err.Error())
+                return
+        }
+        if err := template.Validate(); err != nil {
+                response.BadRequest(w, err.Error())
                 return
         }
         template.ID = id
+        template.Version = version
         
         if err := h.templateRepo.UpdateTemplate(&template); err != nil {
+                if errors.Is(err, repositories.ErrVersionConflict) {
+                        h.writeTemplateConflict(w, r, id)
+                        return
+                }
+                if err.Error() == "template not found" {
+                        response.NotFound(w, "Template not found")
+                        return
+                }
                 log.Printf("Error updating template: %v", err)
                 response.InternalError(w, "Failed to update template")
                 return
         }
         
+        setETag(w, template.Version)
         response.Success(w, template)
 }
 
+// writeTemplateConflict answers a stale write of template id with 412 and the
+// template as it is now
+func (h *TemplateHandler) writeTemplateConflict(w http.ResponseWriter, r *http.Request, id int) {
+        current, err := h.templateRepo.GetTemplateByID(id)
+        if err != nil {
+                response.NotFound(w, "Template not found")
+                return
+        }
+        
+        locale := r.URL.Query().Get("locale")
+        if locale == "" {
+                locale = "en"
+        }
+        current.PopulateLegacyFields(locale)
+        writePreconditionFailed(w, current, current.Version)
+}
+
 // DeleteTemplate handles DELETE /api/date-templates/single/{id}
 func (h *TemplateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
         vars := mux.Vars(r)
@@ -263,7 +304,20 @@ func (h *TemplateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request)
                 return
         }
         
-        if err := h.templateRepo.DeleteTemplate(id); err != nil {
+        version, ok := ifMatchVersion(w, r)
+        if !ok {
+                return
+        }
+        
+        if err := h.templateRepo.DeleteTemplate(id, version); err != nil {
+                if errors.Is(err, repositories.ErrVersionConflict) {
+                        h.writeTemplateConflict(w, r, id)
+                        return
+                }
+                if err.Error() == "template not found" {
+                        response.NotFound(w, "Template not found")
+                        return
+                }
                 log.Printf("Error deleting template: %v", err)
                 response.InternalError(w, "Failed to delete template")
                 return
@@ -296,5 +350,6 @@ func (h *TemplateHandler) GetTemplateByID(w http.ResponseWriter, r *http.Request
         }
         
         template.PopulateLegacyFields(locale)
+        setETag(w, template.Version)
         response.Success(w, template)
 }
\ No newline at end of file
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+        "fmt"
+        "historical-events-backend/internal/database/repositories"
+        "historical-events-backend/pkg/response"
+        "log"
+        "net/http"
+        "strconv"
+
+        "github.com/gorilla/mux"
+)
+
+// tileContentType is the media type of Mapbox Vector Tiles
+const tileContentType = "application/vnd.mapbox-vector-tile"
+
+// tileCacheControl lets browsers and the proxy reuse tiles for a few minutes;
+// edits show up on the map once the cached tiles expire.
+const tileCacheControl = "public, max-age=300"
+
+// TileHandler serves vector tiles for the map
+type TileHandler struct {
+        eventRepo  *repositories.EventRepository
+        regionRepo *repositories.RegionRepository
+}
+
+// NewTileHandler creates a new tile handler
+func NewTileHandler(eventRepo *repositories.EventRepository, regionRepo *repositories.RegionRepository) *TileHandler {
+        return &TileHandler{
+                eventRepo:  eventRepo,
+                regionRepo: regionRepo,
+        }
+}
+
+// GetEventTile handles GET /api/tiles/events/{z}/{x}/{y}.mvt. Accepts the same
+// filters as GET /api/events (from, to, tags, tag_mode, lens, dataset) and locale.
+func (h *TileHandler) GetEventTile(w http.ResponseWriter, r *http.Request) {
+        z, x, y, err := parseTileCoordinates(mux.Vars(r))
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+
+        query := r.URL.Query()
+        locale := query.Get("locale")
+        if locale == "" {
+                locale = "en"
+        }
+
+        filter, err := parseEventFilter(query)
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+
+        tile, err := h.eventRepo.GetTile(z, x, y, filter, locale)
+        if err != nil {
+                log.Printf("Error rendering event tile %d/%d/%d: %v", z, x, y, err)
+                response.InternalError(w, "Failed to render event tile")
+                return
+        }
+
+        writeTile(w, tile)
+}
+
+// GetRegionTile handles GET /api/tiles/regions/{z}/{x}/{y}.mvt with optional
+// template_id and locale query parameters
+func (h *TileHandler) GetRegionTile(w http.ResponseWriter, r *http.Request) {
+        z, x, y, err := parseTileCoordinates(mux.Vars(r))
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+
+        query := r.URL.Query()
+        locale := query.Get("locale")
+        if locale == "" {
+                locale = "en"
+        }
+
+        templateID, err := parseOptionalID(query, "template_id")
+        if err != nil {
+                response.BadRequest(w, err.Error())
+                return
+        }
+
+        tile, err := h.regionRepo.GetTile(z, x, y, templateID, locale)
+        if err != nil {
+                log.Printf("Error rendering region tile %d/%d/%d: %v", z, x, y, err)
+                response.InternalError(w, "Failed to render region tile")
+                return
+        }
+
+        writeTile(w, tile)
+}
+
+// parseTileCoordinates validates the z/x/y path variables of a tile request
+func parseTileCoordinates(vars map[string]string) (z, x, y int, err error) {
+        z, err = strconv.Atoi(vars["z"])
+        if err != nil || z < 0 || z > maxZoom {
+                return 0, 0, 0, fmt.Errorf("Invalid tile zoom (0-%d)", maxZoom)
+        }
+
+        size := 1 << z
+        x, err = strconv.Atoi(vars["x"])
+        if err != nil || x < 0 || x >= size {
+                return 0, 0, 0, fmt.Errorf("Invalid tile x coordinate (0-%d at zoom %d)", size-1, z)
+        }
+        y, err = strconv.Atoi(vars["y"])
+        if err != nil || y < 0 || y >= size {
+                return 0, 0, 0, fmt.Errorf("Invalid tile y coordinate (0-%d at zoom %d)", size-1, z)
+        }
+        return z, x, y, nil
+}
+
+// writeTile sends a rendered tile; empty tiles are answered with 204 No Content
+func writeTile(w http.ResponseWriter, tile []byte) {
+        w.Header().Set("Cache-Control", tileCacheControl)
+        if len(tile) == 0 {
+                w.WriteHeader(http.StatusNoContent)
+                return
+        }
+        w.Header().Set("Content-Type", tileContentType)
+        w.Header().Set("Content-Length", strconv.Itoa(len(tile)))
+        w.WriteHeader(http.StatusOK)
+        w.Write(tile)
+}
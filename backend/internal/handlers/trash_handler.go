@@ -0,0 +1,53 @@
+package handlers
+
+import (
+        "errors"
+        "historical-events-backend/internal/database/repositories"
+        "historical-events-backend/pkg/response"
+        "log"
+        "net/http"
+        "strings"
+)
+
+// TrashHandler handles requests for the trash bin. Restoring is handled by
+// the handler of each kind of row.
+type TrashHandler struct {
+        trashRepo     *repositories.TrashRepository
+        retentionDays int
+}
+
+// NewTrashHandler creates a new trash handler; retentionDays is how long
+// deleted rows are kept before they are purged (0 keeps them forever)
+func NewTrashHandler(trashRepo *repositories.TrashRepository, retentionDays int) *TrashHandler {
+        return &TrashHandler{trashRepo: trashRepo, retentionDays: retentionDays}
+}
+
+// GetTrash handles GET /api/trash, the deleted events, tags, regions and
+// datasets with the date they will be purged
+func (h *TrashHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+        trash, err := h.trashRepo.List()
+        if err != nil {
+                log.Printf("Error listing trash: %v", err)
+                response.InternalError(w, "Failed to list trash")
+                return
+        }
+
+        trash.SetRetention(h.retentionDays)
+        response.Success(w, trash)
+}
+
+// writeRestoreError answers a failed restore of an event, tag, region or
+// dataset; kind is the capitalised name used in the messages
+func writeRestoreError(w http.ResponseWriter, err error, kind string) {
+        switch {
+        case errors.Is(err, repositories.ErrNotInTrash):
+                response.NotFound(w, kind+" not found in trash")
+        case errors.Is(err, repositories.ErrDatasetInTrash):
+                response.Error(w, http.StatusConflict, "The event's dataset is in the trash", "restore the dataset to restore its events")
+        case errors.Is(err, repositories.ErrRestoreConflict):
+                response.Error(w, http.StatusConflict, kind+" conflicts with an existing one", err.Error())
+        default:
+                log.Printf("Error restoring %s: %v", strings.ToLower(kind), err)
+                response.InternalError(w, "Failed to restore "+strings.ToLower(kind))
+        }
+}
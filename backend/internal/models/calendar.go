@@ -0,0 +1,132 @@
+package models
+
+// Calendars an event date can be expressed in. Dates are stored and returned
+// in the event's own calendar; ordering uses the calendar-independent Julian
+// Day Number.
+const (
+	CalendarGregorian = "gregorian"
+	CalendarJulian    = "julian"
+)
+
+// gregorianReform is the first day of the Gregorian calendar (15 October 1582).
+// Earlier dates in our datasets are Julian by convention.
+var gregorianReform = HistoricDate{Year: 1582, Month: 10, Day: 15, Era: EraAD, Calendar: CalendarGregorian}
+
+// calendarShiftCycles shifts years by whole 400-year cycles (which have the
+// same length every time in both calendars) so that the day number formulas
+// below only ever divide positive numbers. 100 cycles cover 40,000 years.
+const calendarShiftCycles = 100
+
+// IsValidCalendar reports whether c is a supported calendar
+func IsValidCalendar(c string) bool {
+	return c == CalendarGregorian || c == CalendarJulian
+}
+
+// DefaultCalendar returns the calendar a date is assumed to be in when none
+// is given: Julian before the Gregorian reform of 1582, Gregorian after.
+func DefaultCalendar(d HistoricDate) string {
+	g := d
+	g.Calendar = CalendarGregorian
+	if g.Before(gregorianReform) {
+		return CalendarJulian
+	}
+	return CalendarGregorian
+}
+
+// DayNumber returns the Julian Day Number of the date, counting days
+// continuously across calendars and eras. Two dates in different calendars
+// describe the same day exactly when their day numbers are equal.
+func (d HistoricDate) DayNumber() int {
+	return dayNumber(d.AstronomicalYearNumber(), d.Month, d.Day, d.Calendar)
+}
+
+// In converts the date to the given calendar
+func (d HistoricDate) In(calendar string) HistoricDate {
+	if d.calendar() == calendar {
+		return d
+	}
+	return HistoricDateFromDayNumber(d.DayNumber(), calendar)
+}
+
+// HistoricDateFromDayNumber returns the date in the given calendar for a
+// Julian Day Number
+func HistoricDateFromDayNumber(jdn int, calendar string) HistoricDate {
+	year, month, day := civilFromDayNumber(jdn, calendar)
+	d := HistoricDate{Year: year, Month: month, Day: day, Era: EraAD, Calendar: calendar}
+	if year < 1 {
+		d.Year, d.Era = 1-year, EraBC
+	}
+	return d
+}
+
+// isLeapYear reports whether the astronomical year has a 29 February in the
+// calendar: every fourth year in the Julian calendar, except the centuries
+// not divisible by 400 in the Gregorian one. Year 0 (1 BC) is a leap year in
+// both.
+func isLeapYear(year int, calendar string) bool {
+	if calendar == CalendarJulian {
+		return year%4 == 0
+	}
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// daysInMonth returns the number of days in a month of the astronomical year
+// in the calendar
+func daysInMonth(year, month int, calendar string) int {
+	switch month {
+	case 2:
+		if isLeapYear(year, calendar) {
+			return 29
+		}
+		return 28
+	case 4, 6, 9, 11:
+		return 30
+	}
+	return 31
+}
+
+// calendar returns the date's calendar, treating an unset one as Gregorian
+func (d HistoricDate) calendar() string {
+	if d.Calendar == "" {
+		return CalendarGregorian
+	}
+	return d.Calendar
+}
+
+// dayNumber converts an astronomical year, month and day to a Julian Day
+// Number (Fliegel & Van Flandern). Mirrored by historic_day_number in SQL.
+func dayNumber(year, month, day int, calendar string) int {
+	year += calendarShiftCycles * 400
+	a := (14 - month) / 12
+	y := year + 4800 - a
+	m := month + 12*a - 3
+
+	jdn := day + (153*m+2)/5 + 365*y + y/4 - 32083
+	cycle := 146100
+	if calendar != CalendarJulian {
+		jdn += -y/100 + y/400 + 38
+		cycle = 146097
+	}
+	return jdn - calendarShiftCycles*cycle
+}
+
+// civilFromDayNumber is the inverse of dayNumber
+func civilFromDayNumber(jdn int, calendar string) (year, month, day int) {
+	var b, c int
+	if calendar == CalendarJulian {
+		c = jdn + calendarShiftCycles*146100 + 32082
+	} else {
+		a := jdn + calendarShiftCycles*146097 + 32044
+		b = (4*a + 3) / 146097
+		c = a - 146097*b/4
+	}
+
+	d := (4*c + 3) / 1461
+	e := c - 1461*d/4
+	m := (5*e + 2) / 153
+
+	day = e - (153*m+2)/5 + 1
+	month = m + 3 - 12*(m/10)
+	year = 100*b + d - 4800 + m/10 - calendarShiftCycles*400
+	return year, month, day
+}
@@ -0,0 +1,131 @@
+package models
+
+import "testing"
+
+func TestDayNumber(t *testing.T) {
+	tests := []struct {
+		date HistoricDate
+		jdn  int
+	}{
+		{HistoricDate{Year: 4713, Month: 1, Day: 1, Era: EraBC, Calendar: CalendarJulian}, 0},
+		{HistoricDate{Year: 4714, Month: 11, Day: 24, Era: EraBC, Calendar: CalendarGregorian}, 0},
+		{HistoricDate{Year: 4713, Month: 1, Day: 1, Era: EraBC, Calendar: CalendarGregorian}, 38},
+		{HistoricDate{Year: 9500, Month: 1, Day: 1, Era: EraBC, Calendar: CalendarJulian}, -1748451},
+		{HistoricDate{Year: 44, Month: 3, Day: 15, Era: EraBC, Calendar: CalendarJulian}, 1705426},
+		{HistoricDate{Year: 1, Month: 12, Day: 31, Era: EraBC, Calendar: CalendarJulian}, 1721423},
+		{HistoricDate{Year: 1, Month: 1, Day: 1, Era: EraAD, Calendar: CalendarJulian}, 1721424},
+		{HistoricDate{Year: 1, Month: 1, Day: 1, Era: EraAD, Calendar: CalendarGregorian}, 1721426},
+		{HistoricDate{Year: 1300, Month: 2, Day: 29, Era: EraAD, Calendar: CalendarJulian}, 2195942},
+		{HistoricDate{Year: 1582, Month: 10, Day: 4, Era: EraAD, Calendar: CalendarJulian}, 2299160},
+		{HistoricDate{Year: 1582, Month: 10, Day: 15, Era: EraAD, Calendar: CalendarGregorian}, 2299161},
+		{HistoricDate{Year: 1858, Month: 11, Day: 17, Era: EraAD, Calendar: CalendarGregorian}, 2400001},
+		{HistoricDate{Year: 2000, Month: 1, Day: 1, Era: EraAD, Calendar: CalendarGregorian}, 2451545},
+		// An empty calendar is Gregorian
+		{HistoricDate{Year: 2000, Month: 1, Day: 1, Era: EraAD}, 2451545},
+	}
+
+	for _, tt := range tests {
+		if got := tt.date.DayNumber(); got != tt.jdn {
+			t.Errorf("%+v.DayNumber() = %d, want %d", tt.date, got, tt.jdn)
+		}
+
+		want := tt.date
+		if want.Calendar == "" {
+			want.Calendar = CalendarGregorian
+		}
+		if got := HistoricDateFromDayNumber(tt.jdn, want.Calendar); got != want {
+			t.Errorf("HistoricDateFromDayNumber(%d, %q) = %+v, want %+v", tt.jdn, want.Calendar, got, want)
+		}
+	}
+}
+
+func TestDayNumberRoundTrip(t *testing.T) {
+	// Every day from 10,000 BC to 2,100 AD converts back to itself, and
+	// consecutive day numbers are consecutive days
+	first := HistoricDate{Year: 10000, Month: 1, Day: 1, Era: EraBC}.DayNumber()
+	last := HistoricDate{Year: 2100, Month: 12, Day: 31, Era: EraAD}.DayNumber()
+
+	for _, calendar := range []string{CalendarJulian, CalendarGregorian} {
+		prev := HistoricDateFromDayNumber(first-1, calendar)
+		for jdn := first; jdn <= last; jdn++ {
+			d := HistoricDateFromDayNumber(jdn, calendar)
+			if err := d.Validate(); err != nil {
+				t.Fatalf("HistoricDateFromDayNumber(%d, %q) = %v: %v", jdn, calendar, d, err)
+			}
+			if got := d.DayNumber(); got != jdn {
+				t.Fatalf("HistoricDateFromDayNumber(%d, %q) = %v, which has day number %d", jdn, calendar, d, got)
+			}
+			if !prev.Before(d) {
+				t.Fatalf("%v (%s) is not before %v", prev, calendar, d)
+			}
+			prev = d
+		}
+	}
+}
+
+func TestCalendarConversion(t *testing.T) {
+	tests := []struct {
+		julian, gregorian HistoricDate
+	}{
+		{
+			HistoricDate{Year: 1582, Month: 10, Day: 4, Era: EraAD, Calendar: CalendarJulian},
+			HistoricDate{Year: 1582, Month: 10, Day: 14, Era: EraAD, Calendar: CalendarGregorian},
+		},
+		{
+			HistoricDate{Year: 1582, Month: 10, Day: 5, Era: EraAD, Calendar: CalendarJulian},
+			HistoricDate{Year: 1582, Month: 10, Day: 15, Era: EraAD, Calendar: CalendarGregorian},
+		},
+		{
+			HistoricDate{Year: 1917, Month: 10, Day: 25, Era: EraAD, Calendar: CalendarJulian},
+			HistoricDate{Year: 1917, Month: 11, Day: 7, Era: EraAD, Calendar: CalendarGregorian},
+		},
+		// A Julian leap day the Gregorian calendar does not have
+		{
+			HistoricDate{Year: 1300, Month: 2, Day: 29, Era: EraAD, Calendar: CalendarJulian},
+			HistoricDate{Year: 1300, Month: 3, Day: 8, Era: EraAD, Calendar: CalendarGregorian},
+		},
+		// Across the era boundary: the calendars are two days apart in the 1st century
+		{
+			HistoricDate{Year: 1, Month: 1, Day: 1, Era: EraAD, Calendar: CalendarJulian},
+			HistoricDate{Year: 1, Month: 12, Day: 30, Era: EraBC, Calendar: CalendarGregorian},
+		},
+		{
+			HistoricDate{Year: 44, Month: 3, Day: 15, Era: EraBC, Calendar: CalendarJulian},
+			HistoricDate{Year: 44, Month: 3, Day: 13, Era: EraBC, Calendar: CalendarGregorian},
+		},
+		{
+			HistoricDate{Year: 4713, Month: 1, Day: 1, Era: EraBC, Calendar: CalendarJulian},
+			HistoricDate{Year: 4714, Month: 11, Day: 24, Era: EraBC, Calendar: CalendarGregorian},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.julian.In(CalendarGregorian); got != tt.gregorian {
+			t.Errorf("%v.In(gregorian) = %v, want %v", tt.julian, got, tt.gregorian)
+		}
+		if got := tt.gregorian.In(CalendarJulian); got != tt.julian {
+			t.Errorf("%v.In(julian) = %v, want %v", tt.gregorian, got, tt.julian)
+		}
+		if got := tt.julian.In(CalendarJulian); got != tt.julian {
+			t.Errorf("%v.In(julian) = %v, want it unchanged", tt.julian, got)
+		}
+	}
+}
+
+func TestDefaultCalendar(t *testing.T) {
+	tests := []struct {
+		date HistoricDate
+		want string
+	}{
+		{HistoricDate{Year: 44, Month: 3, Day: 15, Era: EraBC}, CalendarJulian},
+		{HistoricDate{Year: 1582, Month: 10, Day: 14, Era: EraAD}, CalendarJulian},
+		{HistoricDate{Year: 1582, Month: 10, Day: 15, Era: EraAD}, CalendarGregorian},
+		{HistoricDate{Year: 2000, Month: 1, Day: 1, Era: EraAD}, CalendarGregorian},
+	}
+
+	for _, tt := range tests {
+		if got := DefaultCalendar(tt.date); got != tt.want {
+			t.Errorf("DefaultCalendar(%v) = %q, want %q", tt.date, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package models
+
+import "strconv"
+
+// Date precisions, from most to least precise. The precision says how much of
+// an event date is meaningful; the remaining parts are placeholders.
+const (
+	DatePrecisionDay        = "day"
+	DatePrecisionMonth      = "month"
+	DatePrecisionYear       = "year"
+	DatePrecisionDecade     = "decade"
+	DatePrecisionCentury    = "century"
+	DatePrecisionMillennium = "millennium"
+)
+
+// IsValidDatePrecision reports whether p is one of the supported precisions
+func IsValidDatePrecision(p string) bool {
+	switch p {
+	case DatePrecisionDay, DatePrecisionMonth, DatePrecisionYear,
+		DatePrecisionDecade, DatePrecisionCentury, DatePrecisionMillennium:
+		return true
+	}
+	return false
+}
+
+// Period returns the first and last day covered by the date at the given
+// precision, e.g. 01.01.0500 BC - 31.12.0401 BC for the 5th century BC.
+// Mirrors historic_period_bounds in SQL.
+func (d HistoricDate) Period(precision string) (HistoricDate, HistoricDate) {
+	first, last := d, d
+	switch precision {
+	case DatePrecisionDay, "":
+		return first, last
+	case DatePrecisionMonth:
+		first.Day = 1
+		last.Day = daysInMonth(d.AstronomicalYearNumber(), d.Month, d.calendar())
+		return first, last
+	}
+
+	y := d.Year
+	firstYear, lastYear := y, y
+	switch precision {
+	case DatePrecisionDecade:
+		firstYear, lastYear = y-y%10, y-y%10+9
+		if firstYear < 1 {
+			firstYear = 1
+		}
+	case DatePrecisionCentury:
+		firstYear = (y-1)/100*100 + 1
+		lastYear = firstYear + 99
+	case DatePrecisionMillennium:
+		firstYear = (y-1)/1000*1000 + 1
+		lastYear = firstYear + 999
+	}
+
+	// BC years count down, so the period starts in its highest-numbered year
+	if d.Era == EraBC {
+		firstYear, lastYear = lastYear, firstYear
+	}
+	first.Year, first.Month, first.Day = firstYear, 1, 1
+	last.Year, last.Month, last.Day = lastYear, 12, 31
+	return first, last
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
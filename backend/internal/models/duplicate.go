@@ -0,0 +1,127 @@
+package models
+
+import (
+	"math"
+	"sort"
+)
+
+// DuplicateOptions are the thresholds of duplicate event detection
+type DuplicateOptions struct {
+	MinSimilarity float64 // Trigram similarity of the names in either locale, 0..1
+	MaxYears      float64 // Largest difference of the astronomical years
+	MaxDistanceKm float64 // Largest distance between the events
+	DatasetID     *int    // Only pairs with at least one event of this dataset
+	CrossDataset  bool    // Only pairs of events from different datasets
+	Limit         int     // Largest number of pairs considered
+}
+
+// DefaultDuplicateOptions returns the thresholds used when a request does
+// not set them
+func DefaultDuplicateOptions() DuplicateOptions {
+	return DuplicateOptions{MinSimilarity: 0.6, MaxYears: 1, MaxDistanceKm: 50, Limit: 1000}
+}
+
+// Score combines the name similarity, date difference and distance of two
+// events into a 0..1 duplicate score; names weigh most
+func (o DuplicateOptions) Score(similarity, years, km float64) float64 {
+	score := 0.6*similarity + 0.25*closeness(years, o.MaxYears) + 0.15*closeness(km, o.MaxDistanceKm)
+	return math.Round(score*1000) / 1000
+}
+
+// closeness maps a difference within [0, max] to 1..0
+func closeness(value, max float64) float64 {
+	if max <= 0 {
+		if value == 0 {
+			return 1
+		}
+		return 0
+	}
+	return math.Max(0, 1-value/max)
+}
+
+// DuplicatePair is two events that probably describe the same thing
+type DuplicatePair struct {
+	EventID        int     `json:"event_id"`
+	OtherID        int     `json:"other_id"`
+	NameSimilarity float64 `json:"name_similarity"`
+	YearsApart     float64 `json:"years_apart"`
+	DistanceKm     float64 `json:"distance_km"`
+	Score          float64 `json:"score"`
+}
+
+// DuplicateGroup is a set of events linked by duplicate pairs. Its score is
+// the best score of its pairs.
+type DuplicateGroup struct {
+	Score    float64           `json:"score"`
+	EventIDs []int             `json:"event_ids"`
+	Events   []HistoricalEvent `json:"events,omitempty"`
+	Pairs    []DuplicatePair   `json:"pairs"`
+}
+
+// GroupDuplicates joins pairs that share an event into groups, best score
+// first
+func GroupDuplicates(pairs []DuplicatePair) []DuplicateGroup {
+	parent := make(map[int]int)
+	var find func(id int) int
+	find = func(id int) int {
+		if p, ok := parent[id]; ok && p != id {
+			parent[id] = find(p)
+			return parent[id]
+		}
+		parent[id] = id
+		return id
+	}
+	for _, pair := range pairs {
+		a, b := find(pair.EventID), find(pair.OtherID)
+		if a != b {
+			parent[b] = a
+		}
+	}
+
+	byRoot := make(map[int]*DuplicateGroup)
+	var roots []int
+	for _, pair := range pairs {
+		root := find(pair.EventID)
+		group, ok := byRoot[root]
+		if !ok {
+			group = &DuplicateGroup{}
+			byRoot[root] = group
+			roots = append(roots, root)
+		}
+		group.Pairs = append(group.Pairs, pair)
+		group.Score = math.Max(group.Score, pair.Score)
+	}
+
+	groups := make([]DuplicateGroup, 0, len(roots))
+	for _, root := range roots {
+		group := byRoot[root]
+		seen := make(map[int]bool)
+		for _, pair := range group.Pairs {
+			for _, id := range []int{pair.EventID, pair.OtherID} {
+				if !seen[id] {
+					seen[id] = true
+					group.EventIDs = append(group.EventIDs, id)
+				}
+			}
+		}
+		sort.Ints(group.EventIDs)
+		groups = append(groups, *group)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Score > groups[j].Score
+	})
+	return groups
+}
+
+// MergeEventsRequest merges duplicate events into a canonical one
+type MergeEventsRequest struct {
+	CanonicalID  int   `json:"canonical_id"`
+	DuplicateIDs []int `json:"duplicate_ids"`
+}
+
+// MergeEventsResult is the canonical event after a merge
+type MergeEventsResult struct {
+	Event        *HistoricalEvent `json:"event"`
+	MergedIDs    []int            `json:"merged_ids"`
+	OtherSources []string         `json:"other_sources,omitempty"` // Sources of merged events that differ from the kept one, appended to its descriptions
+}
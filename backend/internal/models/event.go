@@ -3,7 +3,6 @@ package models
 import (
         "encoding/json"
         "fmt"
-        "strings"
         "time"
 )
 
@@ -18,17 +17,39 @@ type HistoricalEvent struct {
         DescriptionRu *string   `json:"description_ru,omitempty"` // Russian description
         Latitude      float64   `json:"latitude"`
         Longitude     float64   `json:"longitude"`
-        EventDate     time.Time `json:"-"` // Don't auto-marshal this field
-        Era           string    `json:"era"`
+        EventDate     HistoricDate `json:"-"` // Marshalled as event_date + era
+        DatePrecision string    `json:"date_precision"` // day, month, year, decade, century or millennium
+        Circa         bool      `json:"circa"`          // Date is approximate
+        EarliestDate  *HistoricDate `json:"-"` // Optional lower uncertainty bound (date_earliest + date_earliest_era)
+        LatestDate    *HistoricDate `json:"-"` // Optional upper uncertainty bound (date_latest + date_latest_era)
+        EndDate       *HistoricDate `json:"-"` // Optional end of an event with a duration (end_date + end_era)
         LensType      string    `json:"lens_type"`
         Source        *string   `json:"source,omitempty"` // Optional HTTP/HTTPS link to source
         DisplayDate   string    `json:"display_date,omitempty"`
+        EndDisplayDate string   `json:"end_display_date,omitempty"`
         DatasetID     *int      `json:"dataset_id,omitempty"`
+        ExternalID    *string   `json:"external_id,omitempty"` // Stable key from the dataset file, unique within the dataset
         CreatedBy     *int      `json:"created_by"`  // User ID who created this event
         UpdatedBy     *int      `json:"updated_by"`  // User ID who last updated this event
         CreatedAt     time.Time `json:"created_at"`  // When event was created
         UpdatedAt     time.Time `json:"updated_at"`  // When event was last updated
+        Version       int       `json:"version"`     // Row version, incremented on every update; served as the ETag
+        Status        string    `json:"status"`      // draft, pending, published or rejected
+        ReviewNote    *string   `json:"review_note,omitempty"` // Note of the last approval or rejection
+        ReviewedBy    *int      `json:"reviewed_by,omitempty"` // User ID of the last reviewer
+        ReviewedAt    *time.Time `json:"reviewed_at,omitempty"`
         Tags          []Tag     `json:"tags,omitempty"`
+        Distance      *float64  `json:"distance_meters,omitempty"` // Great-circle distance from the query point (radius search only)
+}
+
+// EventSearchResult is a single full-text search hit with its rank and
+// highlighted snippets (matches wrapped in <mark></mark>)
+type EventSearchResult struct {
+        Event              HistoricalEvent `json:"event"`
+        Rank               float64         `json:"rank"`
+        MatchedLocale      string          `json:"matched_locale"` // Locale the snippets were taken from
+        NameSnippet        string          `json:"name_snippet"`
+        DescriptionSnippet string          `json:"description_snippet"`
 }
 
 // GetNameForLocale returns the name for the specified locale
@@ -68,21 +89,45 @@ func (e HistoricalEvent) MarshalJSON() ([]byte, error) {
         type Alias HistoricalEvent
         
         // For all dates, use standard ISO format
-        // The era field will indicate BC/AD
-        eventDateStr := e.EventDate.Format("2006-01-02T15:04:05Z07:00")
+        // The era fields will indicate BC/AD
+        earliest, earliestEra := timestampAndEra(e.EarliestDate)
+        latest, latestEra := timestampAndEra(e.LatestDate)
+        end, endEra := timestampAndEra(e.EndDate)
         
         return json.Marshal(&struct {
-                EventDate string `json:"event_date"`
+                EventDate    string  `json:"event_date"`
+                Era          string  `json:"era"`
+                Calendar     string  `json:"calendar"`
+                DayNumber    int     `json:"day_number"` // Julian Day Number, comparable across calendars
+                EarliestDate *string `json:"date_earliest,omitempty"`
+                EarliestEra  *string `json:"date_earliest_era,omitempty"`
+                LatestDate   *string `json:"date_latest,omitempty"`
+                LatestEra    *string `json:"date_latest_era,omitempty"`
+                EndDate      *string `json:"end_date,omitempty"`
+                EndEra       *string `json:"end_era,omitempty"`
                 *Alias
         }{
-                EventDate: eventDateStr,
-                Alias:     (*Alias)(&e),
+                EventDate:    e.EventDate.Timestamp(),
+                Era:          e.EventDate.Era,
+                Calendar:     e.EventDate.Calendar,
+                DayNumber:    e.EventDate.DayNumber(),
+                EarliestDate: earliest,
+                EarliestEra:  earliestEra,
+                LatestDate:   latest,
+                LatestEra:    latestEra,
+                EndDate:      end,
+                EndEra:       endEra,
+                Alias:        (*Alias)(&e),
         })
 }
 
-// formatBCDate formats BC dates in a safe string format
-func formatBCDate(year int, month time.Month, day int) string {
-        return fmt.Sprintf("%04d-%02d-%02dT00:00:00Z", year, int(month), day)
+// timestampAndEra splits an optional date into its JSON timestamp and era
+func timestampAndEra(d *HistoricDate) (*string, *string) {
+        if d == nil {
+                return nil, nil
+        }
+        timestamp, era := d.Timestamp(), d.Era
+        return &timestamp, &era
 }
 
 // CreateEventRequest represents the request payload for creating an event
@@ -97,39 +142,104 @@ type CreateEventRequest struct {
         Longitude     float64 `json:"longitude" validate:"required,min=-180,max=180"`
         EventDate     string  `json:"event_date" validate:"required"` // Changed to string to handle BC dates
         Era           string  `json:"era"`
+        Calendar      string  `json:"calendar,omitempty"`          // julian or gregorian; defaults to Julian before 1582, Gregorian after
+        DatePrecision string  `json:"date_precision,omitempty"`    // Defaults to "day"
+        Circa         bool    `json:"circa,omitempty"`
+        DateEarliest    string `json:"date_earliest,omitempty"`     // Optional lower bound, same format as event_date
+        DateEarliestEra string `json:"date_earliest_era,omitempty"` // Defaults to Era
+        DateLatest      string `json:"date_latest,omitempty"`       // Optional upper bound, same format as event_date
+        DateLatestEra   string `json:"date_latest_era,omitempty"`   // Defaults to Era
+        EndDate       string  `json:"end_date,omitempty"` // Optional end of an event with a duration, same format as event_date
+        EndEra        string  `json:"end_era,omitempty"`  // Defaults to Era
         LensType      string  `json:"lens_type" validate:"required"`
         Source        *string `json:"source,omitempty"` // Optional HTTP/HTTPS link to source
         DatasetID     *int    `json:"dataset_id,omitempty"`
         TagIDs        []int   `json:"tag_ids,omitempty"`
+        Status        string  `json:"status,omitempty"` // "draft" saves without submitting; otherwise published, or pending review for users who cannot publish
+}
+
+// ParseEventDate parses the event date string handling BC dates properly.
+// An era suffix on the date (" BC") wins over the era field. The date is in
+// the requested calendar, or the conventional one for its period.
+func (req *CreateEventRequest) ParseEventDate() (HistoricDate, error) {
+        d, err := ParseHistoricDate(req.EventDate, req.Era)
+        if err != nil {
+                return d, err
+        }
+        
+        d.Calendar = req.Calendar
+        if d.Calendar == "" {
+                d.Calendar = DefaultCalendar(d)
+        }
+        if !IsValidCalendar(d.Calendar) {
+                return HistoricDate{}, fmt.Errorf("unknown calendar %q (valid: julian, gregorian)", d.Calendar)
+        }
+        if err := d.Validate(); err != nil {
+                return HistoricDate{}, err
+        }
+        return d, nil
+}
+
+// parseOptionalDate parses one of the optional dates of the request; its era
+// defaults to the event era and it is in the event's calendar
+func parseOptionalDate(field, value, era string, event HistoricDate) (*HistoricDate, error) {
+        if value == "" {
+                return nil, nil
+        }
+        if era == "" {
+                era = event.Era
+        }
+        if era != EraBC && era != EraAD {
+                return nil, fmt.Errorf("invalid era %q for %s, expected BC or AD", era, field)
+        }
+        
+        d, err := ParseHistoricDate(value, era)
+        if err != nil {
+                return nil, fmt.Errorf("invalid %s: %v", field, err)
+        }
+        d.Calendar = event.Calendar
+        if err := d.Validate(); err != nil {
+                return nil, fmt.Errorf("invalid %s: %v", field, err)
+        }
+        return &d, nil
 }
 
-// ParseEventDate parses the event date string handling BC dates properly
-func (req *CreateEventRequest) ParseEventDate() (time.Time, error) {
-        // Clean the date string - remove any " BC" or " AD" suffix that might be present
-        dateStr := strings.TrimSuffix(strings.TrimSuffix(req.EventDate, " BC"), " AD")
+// parseDateBounds parses the optional uncertainty bounds and checks that the
+// earliest bound does not come after the latest one
+func (req *CreateEventRequest) parseDateBounds(event HistoricDate) (*HistoricDate, *HistoricDate, error) {
+        earliest, err := parseOptionalDate("date_earliest", req.DateEarliest, req.DateEarliestEra, event)
+        if err != nil {
+                return nil, nil, err
+        }
+        latest, err := parseOptionalDate("date_latest", req.DateLatest, req.DateLatestEra, event)
+        if err != nil {
+                return nil, nil, err
+        }
         
-        // Handle both simple date format and ISO timestamp format
-        // Try ISO timestamp format first (from map creation): "1992-02-15T00:00:00.000Z"
-        if t, err := time.Parse(time.RFC3339, dateStr); err == nil {
-                return t, nil
+        if earliest != nil && latest != nil && earliest.After(*latest) {
+                return nil, nil, fmt.Errorf("date_earliest must not be later than date_latest")
         }
         
-        // Try simple date format (from admin panel): "1992-02-15"
-        if t, err := time.Parse("2006-01-02", dateStr); err == nil {
-                return t, nil
+        return earliest, latest, nil
+}
+
+// parseEndDate parses the optional end date and checks that it does not come
+// before the start date
+func (req *CreateEventRequest) parseEndDate(start HistoricDate) (*HistoricDate, error) {
+        end, err := parseOptionalDate("end_date", req.EndDate, req.EndEra, start)
+        if err != nil {
+                return nil, err
+        }
+        
+        if end != nil && end.Before(start) {
+                return nil, fmt.Errorf("end_date must not be earlier than event_date")
         }
         
-        // If both fail, return error
-        return time.Time{}, fmt.Errorf("parsing time %q: invalid date format, expected YYYY-MM-DD or ISO timestamp", dateStr)
+        return end, nil
 }
 
 // ToHistoricalEvent converts CreateEventRequest to HistoricalEvent
 func (req *CreateEventRequest) ToHistoricalEvent(createdBy int) (*HistoricalEvent, error) {
-        era := req.Era
-        if era == "" {
-                era = "AD"
-        }
-        
         // Parse the event date string
         eventDate, err := req.ParseEventDate()
         if err != nil {
@@ -137,6 +247,24 @@ func (req *CreateEventRequest) ToHistoricalEvent(createdBy int) (*HistoricalEven
                 return nil, fmt.Errorf("invalid event date: %v", err)
         }
         
+        precision := req.DatePrecision
+        if precision == "" {
+                precision = DatePrecisionDay
+        }
+        if !IsValidDatePrecision(precision) {
+                return nil, fmt.Errorf("invalid date_precision %q (valid: day, month, year, decade, century, millennium)", precision)
+        }
+        
+        earliest, latest, err := req.parseDateBounds(eventDate)
+        if err != nil {
+                return nil, err
+        }
+        
+        endDate, err := req.parseEndDate(eventDate)
+        if err != nil {
+                return nil, err
+        }
+        
         // Handle locale-specific fields - if not provided, use legacy fields as default
         nameEn := req.NameEn
         if nameEn == "" {
@@ -170,7 +298,11 @@ func (req *CreateEventRequest) ToHistoricalEvent(createdBy int) (*HistoricalEven
                 Latitude:      req.Latitude,
                 Longitude:     req.Longitude,
                 EventDate:     eventDate,
-                Era:           era,
+                DatePrecision: precision,
+                Circa:         req.Circa,
+                EarliestDate:  earliest,
+                LatestDate:    latest,
+                EndDate:       endDate,
                 LensType:      req.LensType,
                 Source:        req.Source,
                 DatasetID:     req.DatasetID,
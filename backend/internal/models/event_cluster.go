@@ -0,0 +1,14 @@
+package models
+
+// EventCluster is a group of nearby events returned by the clusters endpoint.
+// The position is the centroid of its events; the breakdowns drive the
+// pie-chart cluster icons on the map.
+type EventCluster struct {
+	Latitude   float64        `json:"latitude"`
+	Longitude  float64        `json:"longitude"`
+	Count      int            `json:"count"`
+	Bounds     [4]float64     `json:"bounds"` // min_lng, min_lat, max_lng, max_lat of the events, for zoom-to-cluster
+	LensCounts map[string]int `json:"lens_counts"`
+	TagCounts  map[int]int    `json:"tag_counts"`       // Events per tag ID
+	EventID    *int           `json:"event_id,omitempty"` // Set when the cluster holds a single event
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded or
+// does not belong to the requested sort order and filter
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EventCursor marks a position in a keyset-paginated event listing: the sort
+// key and ID of the last row returned. It is handed to clients as an opaque
+// string and echoes the sort order and filter it was issued for, so a cursor
+// cannot silently be replayed against a different listing.
+type EventCursor struct {
+	SortField     string `json:"s"`
+	SortDirection string `json:"o"`
+	Filter        string `json:"f,omitempty"`
+	Key           string `json:"k"`
+	ID            int    `json:"id"`
+}
+
+// Encode returns the opaque, URL-safe representation of the cursor
+func (c EventCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeEventCursor parses an opaque cursor produced by Encode
+func DecodeEventCursor(value string) (*EventCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor EventCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID < 1 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// Matches reports whether the cursor was issued for the given listing
+func (c EventCursor) Matches(sortField, sortDirection string, filter EventFilter) bool {
+	return c.SortField == sortField && c.SortDirection == sortDirection && c.Filter == filter.CacheKey()
+}
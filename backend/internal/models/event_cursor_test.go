@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestEventCursorRoundTrip(t *testing.T) {
+	from := -43.5
+	cursors := []EventCursor{
+		{SortField: "date", SortDirection: "asc", Key: "-43.19892473118279", ID: 12},
+		{SortField: "name", SortDirection: "desc", Key: "Battle of Actium", ID: 1},
+		{SortField: "date", SortDirection: "desc", Filter: EventFilter{From: &from, TagIDs: []int{3, 1}, TagMode: TagModeAny}.CacheKey(), Key: "1453.4", ID: 7},
+		{SortField: "name", SortDirection: "asc", Key: "Взятие Константинополя & \"quotes\"", ID: 99},
+	}
+
+	for _, c := range cursors {
+		encoded := c.Encode()
+		got, err := DecodeEventCursor(encoded)
+		if err != nil {
+			t.Fatalf("DecodeEventCursor(%q): %v", encoded, err)
+		}
+		if *got != c {
+			t.Errorf("DecodeEventCursor(Encode(%+v)) = %+v", c, *got)
+		}
+	}
+}
+
+func TestDecodeEventCursorInvalid(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []string{
+		"",
+		"not base64!",
+		encode("not json"),
+		encode(`{"s":"date","o":"asc","k":"1"}`),
+		encode(`{"s":"date","o":"asc","k":"1","id":0}`),
+		encode(`{"s":"date","o":"asc","k":"1","id":-4}`),
+		encode(`{"s":"date","o":"asc","k":"1","id":"4"}`),
+	}
+
+	for _, value := range tests {
+		if _, err := DecodeEventCursor(value); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeEventCursor(%q) error = %v, want ErrInvalidCursor", value, err)
+		}
+	}
+}
+
+func TestEventCursorMatches(t *testing.T) {
+	from := 100.0
+	filter := EventFilter{From: &from, TagIDs: []int{2, 5}, TagMode: TagModeAll}
+	cursor := EventCursor{SortField: "date", SortDirection: "asc", Filter: filter.CacheKey(), Key: "120", ID: 3}
+
+	tests := []struct {
+		sortField, sortDirection string
+		filter                   EventFilter
+		want                     bool
+	}{
+		{"date", "asc", filter, true},
+		// Tag order does not change the listing
+		{"date", "asc", EventFilter{From: &from, TagIDs: []int{5, 2}, TagMode: TagModeAll}, true},
+		{"date", "desc", filter, false},
+		{"name", "asc", filter, false},
+		{"date", "asc", EventFilter{From: &from, TagIDs: []int{2, 5}, TagMode: TagModeAny}, false},
+		{"date", "asc", EventFilter{}, false},
+	}
+
+	for _, tt := range tests {
+		if got := cursor.Matches(tt.sortField, tt.sortDirection, tt.filter); got != tt.want {
+			t.Errorf("Matches(%q, %q, %+v) = %v, want %v", tt.sortField, tt.sortDirection, tt.filter, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Tag matching modes for EventFilter.TagMode
+const (
+	TagModeAny = "any"
+	TagModeAll = "all"
+)
+
+// EventFilter holds the server-side filters accepted by the events list endpoints.
+// Date bounds are expressed as astronomical years, matching the
+// astronomical_year column of the events_with_display_dates view.
+// Without Statuses only published events match.
+type EventFilter struct {
+	From       *float64
+	To         *float64
+	TagIDs     []int
+	TagMode    string
+	LensTypes  []string
+	DatasetIDs []int
+	Statuses   []string
+}
+
+// IsEmpty reports whether the filter restricts nothing beyond the default of
+// published events
+func (f EventFilter) IsEmpty() bool {
+	return f.From == nil && f.To == nil && len(f.TagIDs) == 0 &&
+		len(f.LensTypes) == 0 && len(f.DatasetIDs) == 0 && len(f.Statuses) == 0
+}
+
+// CacheKey returns a canonical string for the filter, suitable for keying caches.
+// Two filters that select the same events produce the same key.
+func (f EventFilter) CacheKey() string {
+	if f.IsEmpty() {
+		return ""
+	}
+
+	var parts []string
+	if f.From != nil {
+		parts = append(parts, "from="+strconv.FormatFloat(*f.From, 'f', -1, 64))
+	}
+	if f.To != nil {
+		parts = append(parts, "to="+strconv.FormatFloat(*f.To, 'f', -1, 64))
+	}
+	if len(f.TagIDs) > 0 {
+		parts = append(parts, "tags="+joinSortedInts(f.TagIDs), "tag_mode="+f.TagMode)
+	}
+	if len(f.LensTypes) > 0 {
+		lensTypes := append([]string(nil), f.LensTypes...)
+		sort.Strings(lensTypes)
+		parts = append(parts, "lens="+strings.Join(lensTypes, ","))
+	}
+	if len(f.DatasetIDs) > 0 {
+		parts = append(parts, "dataset="+joinSortedInts(f.DatasetIDs))
+	}
+	if len(f.Statuses) > 0 {
+		statuses := append([]string(nil), f.Statuses...)
+		sort.Strings(statuses)
+		parts = append(parts, "status="+strings.Join(statuses, ","))
+	}
+	return strings.Join(parts, "&")
+}
+
+// ParseDateBound parses an era-aware range bound such as "0500-BC",
+// "0044-03-15-BC" or "1453-AD" into an astronomical year. The era suffix is
+// optional and defaults to AD. When only a year (or year and month) is given,
+// the bound is widened to cover the whole period: lower bounds resolve to its
+// earliest instant and upper bounds to its latest.
+func ParseDateBound(value string, upper bool) (float64, error) {
+	s, era := splitEra(strings.TrimSpace(value), EraAD)
+
+	fields := strings.Split(s, "-")
+	if len(fields) == 0 || len(fields) > 3 || fields[0] == "" {
+		return 0, fmt.Errorf("invalid date bound %q, expected YYYY[-MM[-DD]][-BC|-AD]", value)
+	}
+
+	numbers := make([]int, len(fields))
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid date bound %q, expected YYYY[-MM[-DD]][-BC|-AD]", value)
+		}
+		numbers[i] = n
+	}
+
+	year := numbers[0]
+	if year == 0 {
+		return 0, fmt.Errorf("invalid date bound %q: there is no year 0", value)
+	}
+
+	// Lower bounds resolve to the first day of the period, upper bounds to its
+	// last day; months run forward in both eras
+	d := HistoricDate{Year: year, Month: 1, Day: 1, Era: era}
+	if upper {
+		d.Month, d.Day = 12, 31
+	}
+	if len(numbers) > 1 {
+		d.Month, d.Day = numbers[1], 1
+		if d.Month < 1 || d.Month > 12 {
+			return 0, fmt.Errorf("invalid month in date bound %q", value)
+		}
+	}
+	if len(numbers) > 2 {
+		d.Day = numbers[2]
+	}
+
+	// Bounds follow the dataset convention: Julian before 1582, Gregorian after
+	d.Calendar = DefaultCalendar(d)
+	if upper && len(numbers) == 2 {
+		d.Day = daysInMonth(d.AstronomicalYearNumber(), d.Month, d.Calendar)
+	}
+	if err := d.Validate(); err != nil {
+		return 0, fmt.Errorf("invalid day in date bound %q", value)
+	}
+	return d.AstronomicalYear(), nil
+}
+
+func joinSortedInts(values []int) string {
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	strs := make([]string, len(sorted))
+	for i, v := range sorted {
+		strs[i] = strconv.Itoa(v)
+	}
+	return strings.Join(strs, ",")
+}
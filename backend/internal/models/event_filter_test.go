@@ -0,0 +1,91 @@
+package models
+
+import "testing"
+
+func TestParseDateBound(t *testing.T) {
+	date := func(year, month, day int, era, calendar string) float64 {
+		return HistoricDate{Year: year, Month: month, Day: day, Era: era, Calendar: calendar}.AstronomicalYear()
+	}
+
+	tests := []struct {
+		value   string
+		upper   bool
+		want    float64
+		wantErr bool
+	}{
+		{value: "1453", want: date(1453, 1, 1, EraAD, CalendarJulian)},
+		{value: "1453-AD", upper: true, want: date(1453, 12, 31, EraAD, CalendarJulian)},
+		{value: "0500-BC", want: date(500, 1, 1, EraBC, CalendarJulian)},
+		{value: "0500-BC", upper: true, want: date(500, 12, 31, EraBC, CalendarJulian)},
+		{value: "0500 bc", want: date(500, 1, 1, EraBC, CalendarJulian)},
+		{value: "0044-03-15-BC", want: date(44, 3, 15, EraBC, CalendarJulian)},
+		{value: "0044-03-15-BC", upper: true, want: date(44, 3, 15, EraBC, CalendarJulian)},
+		{value: "0044-03-BC", want: date(44, 3, 1, EraBC, CalendarJulian)},
+		{value: "0044-03-BC", upper: true, want: date(44, 3, 31, EraBC, CalendarJulian)},
+		{value: "1969-07-20", want: date(1969, 7, 20, EraAD, CalendarGregorian)},
+		// Upper month bounds end on the month's last day in its calendar
+		{value: "1300-04", upper: true, want: date(1300, 4, 30, EraAD, CalendarJulian)},
+		{value: "1300-02", upper: true, want: date(1300, 2, 29, EraAD, CalendarJulian)},
+		{value: "1900-02", upper: true, want: date(1900, 2, 28, EraAD, CalendarGregorian)},
+		{value: "2000-02", upper: true, want: date(2000, 2, 29, EraAD, CalendarGregorian)},
+
+		{value: "", wantErr: true},
+		{value: "abc", wantErr: true},
+		{value: "-0044", wantErr: true},
+		{value: "0000", wantErr: true},
+		{value: "0000-BC", wantErr: true},
+		{value: "2000-13", wantErr: true},
+		{value: "2000-00", wantErr: true},
+		{value: "2000-04-31", wantErr: true},
+		{value: "1900-02-29", wantErr: true},
+		{value: "2000-01-01-01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDateBound(tt.value, tt.upper)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseDateBound(%q, %v) = %v, want error", tt.value, tt.upper, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDateBound(%q, %v): %v", tt.value, tt.upper, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDateBound(%q, %v) = %v, want %v", tt.value, tt.upper, got, tt.want)
+		}
+	}
+}
+
+func TestParseDateBoundAcrossEras(t *testing.T) {
+	// Each bound lies strictly before the next: the last day of 1 BC comes
+	// right before the first day of 1 AD
+	bounds := []struct {
+		value string
+		upper bool
+	}{
+		{"0002-BC", false},
+		{"0002-BC", true},
+		{"0001-BC", false},
+		{"0001-12-BC", false},
+		{"0001-BC", true},
+		{"0001-AD", false},
+		{"0001-01-AD", true},
+		{"0001-AD", true},
+		{"0002", false},
+	}
+
+	var prev float64
+	for i, b := range bounds {
+		got, err := ParseDateBound(b.value, b.upper)
+		if err != nil {
+			t.Fatalf("ParseDateBound(%q, %v): %v", b.value, b.upper, err)
+		}
+		if i > 0 && got <= prev {
+			t.Errorf("ParseDateBound(%q, %v) = %v, not after the previous bound %v", b.value, b.upper, got, prev)
+		}
+		prev = got
+	}
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// Event revision actions
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionTags    = "tags"    // Tags attached or removed
+	RevisionRevert  = "revert"  // Restored from an earlier revision
+	RevisionRestore = "restore" // Taken out of the trash
+	RevisionApprove = "approve" // Published from the moderation queue
+	RevisionReject  = "reject"  // Rejected from the moderation queue
+)
+
+// SnapshotTag is a tag of an event snapshot
+type SnapshotTag struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// EventSnapshot is the full editable state of an event at one revision.
+// Dates are stored with their era ("0044-03-15 BC") in the event's calendar.
+type EventSnapshot struct {
+	Name          string        `json:"name"`
+	Description   string        `json:"description"`
+	NameEn        string        `json:"name_en"`
+	NameRu        string        `json:"name_ru"`
+	DescriptionEn *string       `json:"description_en"`
+	DescriptionRu *string       `json:"description_ru"`
+	Latitude      float64       `json:"latitude"`
+	Longitude     float64       `json:"longitude"`
+	EventDate     HistoricDate  `json:"event_date"`
+	Calendar      string        `json:"calendar"`
+	DatePrecision string        `json:"date_precision"`
+	Circa         bool          `json:"circa"`
+	EarliestDate  *HistoricDate `json:"date_earliest"`
+	LatestDate    *HistoricDate `json:"date_latest"`
+	EndDate       *HistoricDate `json:"end_date"`
+	LensType      string        `json:"lens_type"`
+	Source        *string       `json:"source"`
+	DatasetID     *int          `json:"dataset_id"`
+	ExternalID    *string       `json:"external_id"`
+	Tags          []SnapshotTag `json:"tags"`
+}
+
+// NewEventSnapshot captures the editable state of an event
+func NewEventSnapshot(e *HistoricalEvent) EventSnapshot {
+	tags := make([]SnapshotTag, 0, len(e.Tags))
+	for _, tag := range e.Tags {
+		tags = append(tags, SnapshotTag{ID: tag.ID, Name: tag.Name})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].ID < tags[j].ID })
+
+	return EventSnapshot{
+		Name:          e.Name,
+		Description:   e.Description,
+		NameEn:        e.NameEn,
+		NameRu:        e.NameRu,
+		DescriptionEn: e.DescriptionEn,
+		DescriptionRu: e.DescriptionRu,
+		Latitude:      e.Latitude,
+		Longitude:     e.Longitude,
+		EventDate:     e.EventDate,
+		Calendar:      e.EventDate.Calendar,
+		DatePrecision: e.DatePrecision,
+		Circa:         e.Circa,
+		EarliestDate:  e.EarliestDate,
+		LatestDate:    e.LatestDate,
+		EndDate:       e.EndDate,
+		LensType:      e.LensType,
+		Source:        e.Source,
+		DatasetID:     e.DatasetID,
+		ExternalID:    e.ExternalID,
+		Tags:          tags,
+	}
+}
+
+// Apply sets the fields of e to the snapshot; the ID, authorship and
+// timestamps are kept. Tags are left alone, see TagIDs.
+func (s EventSnapshot) Apply(e *HistoricalEvent) {
+	e.Name = s.Name
+	e.Description = s.Description
+	e.NameEn = s.NameEn
+	e.NameRu = s.NameRu
+	e.DescriptionEn = s.DescriptionEn
+	e.DescriptionRu = s.DescriptionRu
+	e.Latitude = s.Latitude
+	e.Longitude = s.Longitude
+	e.EventDate = s.EventDate
+	e.EventDate.Calendar = s.Calendar
+	e.DatePrecision = s.DatePrecision
+	e.Circa = s.Circa
+	e.EarliestDate = inCalendar(s.EarliestDate, s.Calendar)
+	e.LatestDate = inCalendar(s.LatestDate, s.Calendar)
+	e.EndDate = inCalendar(s.EndDate, s.Calendar)
+	e.LensType = s.LensType
+	e.Source = s.Source
+	e.DatasetID = s.DatasetID
+	e.ExternalID = s.ExternalID
+}
+
+// inCalendar returns a copy of an optional date in the given calendar
+func inCalendar(d *HistoricDate, calendar string) *HistoricDate {
+	if d == nil {
+		return nil
+	}
+	date := *d
+	date.Calendar = calendar
+	return &date
+}
+
+// TagIDs returns the IDs of the snapshot's tags
+func (s EventSnapshot) TagIDs() []int {
+	ids := make([]int, len(s.Tags))
+	for i, tag := range s.Tags {
+		ids[i] = tag.ID
+	}
+	return ids
+}
+
+// FieldChange is one field that differs between two revisions, with its
+// JSON values; Old is null for the first revision
+type FieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old"`
+	New   json.RawMessage `json:"new"`
+}
+
+// Diff lists the fields that differ from prev, in field name order. A nil
+// prev (the first revision) reports every field that is set.
+func (s EventSnapshot) Diff(prev *EventSnapshot) ([]FieldChange, error) {
+	current, err := snapshotFields(&s)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := snapshotFields(prev)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	changes := []FieldChange{}
+	for _, name := range names {
+		old, ok := previous[name]
+		if !ok {
+			old = json.RawMessage("null")
+		}
+		if !bytes.Equal(old, current[name]) {
+			changes = append(changes, FieldChange{Field: name, Old: old, New: current[name]})
+		}
+	}
+	return changes, nil
+}
+
+// snapshotFields splits a snapshot into its JSON fields; nil has none
+func snapshotFields(s *EventSnapshot) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if s == nil {
+		return fields, nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// EventRevision is one recorded change of an event. Changes compares the
+// snapshot with the revision before it.
+type EventRevision struct {
+	ID         int           `json:"id"`
+	EventID    int           `json:"event_id"`
+	Action     string        `json:"action"`
+	Snapshot   EventSnapshot `json:"snapshot"`
+	AuthorID   *int          `json:"author_id"`
+	AuthorName string        `json:"author_name,omitempty"`
+	RevertedTo *int          `json:"reverted_to,omitempty"` // Revision a revert restored
+	CreatedAt  time.Time     `json:"created_at"`
+	Changes    []FieldChange `json:"changes"`
+}
+
+// EventEdit is an edit of a published event by a user who cannot publish. It
+// waits for review while the published event stays as it is; approving it
+// applies Snapshot, as long as the event is still at BaseVersion. Changes
+// compares the snapshot with the published event.
+type EventEdit struct {
+	ID          int           `json:"id"`
+	EventID     int           `json:"event_id"`
+	BaseVersion int           `json:"base_version"` // Version of the event the edit was made against
+	Snapshot    EventSnapshot `json:"snapshot"`
+	AuthorID    *int          `json:"author_id"`
+	AuthorName  string        `json:"author_name,omitempty"`
+	Status      string        `json:"status"` // pending, then published or rejected
+	ReviewNote  *string       `json:"review_note,omitempty"`
+	ReviewedBy  *int          `json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time    `json:"reviewed_at,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	Changes     []FieldChange `json:"changes"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// Event moderation statuses. Only published events are served on the public
+// read paths; events submitted by users who cannot publish wait as pending
+// until an editor approves or rejects them.
+const (
+	EventStatusDraft     = "draft"   // Saved by its author, not submitted yet
+	EventStatusPending   = "pending" // In the moderation queue
+	EventStatusPublished = "published"
+	EventStatusRejected  = "rejected" // Turned down by a reviewer, see the review note
+)
+
+// EventStatuses lists every event status
+var EventStatuses = []string{EventStatusDraft, EventStatusPending, EventStatusPublished, EventStatusRejected}
+
+// IsValidEventStatus reports whether s is one of the event statuses
+func IsValidEventStatus(s string) bool {
+	switch s {
+	case EventStatusDraft, EventStatusPending, EventStatusPublished, EventStatusRejected:
+		return true
+	}
+	return false
+}
+
+// ReviewRequest is the payload for approving or rejecting a pending event
+type ReviewRequest struct {
+	Note string `json:"note"` // Shown to the author; required when rejecting
+}
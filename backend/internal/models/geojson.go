@@ -0,0 +1,74 @@
+package models
+
+// GeoJSON types for exporting events as RFC 7946 FeatureCollections, the
+// format GIS tools such as QGIS load directly.
+
+// FeatureCollection is a GeoJSON FeatureCollection
+type FeatureCollection struct {
+	Type     string    `json:"type"` // Always "FeatureCollection"
+	Features []Feature `json:"features"`
+}
+
+// Feature is a GeoJSON Feature
+type Feature struct {
+	Type       string                 `json:"type"` // Always "Feature"
+	ID         int                    `json:"id"`
+	Geometry   PointGeometry          `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// PointGeometry is a GeoJSON Point. Coordinates are [longitude, latitude]
+// in WGS 84, as RFC 7946 requires.
+type PointGeometry struct {
+	Type        string     `json:"type"` // Always "Point"
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// NewEventFeatureCollection converts events into a FeatureCollection whose
+// properties carry the name and description in the given locale
+func NewEventFeatureCollection(events []HistoricalEvent, locale string) FeatureCollection {
+	features := make([]Feature, len(events))
+	for i, event := range events {
+		features[i] = event.Feature(locale)
+	}
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+// Feature converts the event into a GeoJSON Point feature
+func (e HistoricalEvent) Feature(locale string) Feature {
+	tagNames := make([]string, len(e.Tags))
+	for i, tag := range e.Tags {
+		tagNames[i] = tag.Name
+	}
+
+	properties := map[string]interface{}{
+		"id":           e.ID,
+		"name":         e.GetNameForLocale(locale),
+		"description":  e.GetDescriptionForLocale(locale),
+		"event_date":   e.EventDate.Timestamp(),
+		"display_date": e.DisplayDate,
+		"era":          e.EventDate.Era,
+		"lens_type":    e.LensType,
+		"tags":         tagNames,
+		"source":       e.Source,
+	}
+	if e.EndDisplayDate != "" {
+		properties["end_display_date"] = e.EndDisplayDate
+	}
+	if e.DatasetID != nil {
+		properties["dataset_id"] = *e.DatasetID
+	}
+	if e.Distance != nil {
+		properties["distance_meters"] = *e.Distance
+	}
+
+	return Feature{
+		Type: "Feature",
+		ID:   e.ID,
+		Geometry: PointGeometry{
+			Type:        "Point",
+			Coordinates: [2]float64{e.Longitude, e.Latitude},
+		},
+		Properties: properties,
+	}
+}
@@ -0,0 +1,335 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Eras of a HistoricDate
+const (
+	EraBC = "BC"
+	EraAD = "AD"
+)
+
+// HistoricDate is a calendar date with an era: a positive year plus "BC" or
+// "AD". There is no year 0; 1 BC is followed by 1 AD. The events table stores
+// it as its Julian Day Number (see Value), which every day of either calendar
+// has, however far back.
+//
+// All parsing, formatting and ordering of event dates goes through this type.
+// Within a year, months and days always run forward, so 15.03.44 BC comes
+// before 01.12.44 BC. Year, Month and Day are in the date's own Calendar
+// (Gregorian when empty); ordering converts to a common calendar first.
+type HistoricDate struct {
+	Year     int
+	Month    int
+	Day      int
+	Era      string
+	Calendar string
+}
+
+// ParseHistoricDate parses a date sent by clients. Accepted forms are
+// "YYYY-MM-DD", ISO timestamps such as "1992-02-15T00:00:00.000Z" (the time
+// part is ignored) and "DD.MM.YYYY", each optionally followed by " BC"/" AD"
+// or "-BC"/"-AD". A leading "-" on an ISO date also means BC ("-0044-03-15").
+// Without an explicit era, defaultEra is used.
+func ParseHistoricDate(value, defaultEra string) (HistoricDate, error) {
+	s, era := splitEra(strings.TrimSpace(value), defaultEra)
+
+	if i := strings.IndexByte(s, 'T'); i >= 0 {
+		s = s[:i]
+	}
+
+	var fields []string
+	var order [3]int // positions of year, month and day in fields
+	if strings.Contains(s, ".") {
+		fields = strings.Split(s, ".")
+		order = [3]int{2, 1, 0}
+	} else {
+		if strings.HasPrefix(s, "-") {
+			s = s[1:]
+			era = EraBC
+		}
+		fields = strings.Split(s, "-")
+		order = [3]int{0, 1, 2}
+	}
+	if len(fields) != 3 {
+		return HistoricDate{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or DD.MM.YYYY", value)
+	}
+
+	var numbers [3]int
+	for i, idx := range order {
+		n, err := strconv.Atoi(fields[idx])
+		if err != nil {
+			return HistoricDate{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or DD.MM.YYYY", value)
+		}
+		numbers[i] = n
+	}
+
+	d := HistoricDate{Year: numbers[0], Month: numbers[1], Day: numbers[2], Era: era}
+	if err := d.Validate(); err != nil {
+		return HistoricDate{}, fmt.Errorf("invalid date %q: %v", value, err)
+	}
+	return d, nil
+}
+
+// ParseDatasetDate parses a date in the dataset import format, which allows
+// "DD.MM.YYYY", "MM.YYYY" or "YYYY". Missing parts are set to 1, and the
+// precision implied by the format is returned alongside the date.
+func ParseDatasetDate(value, era string) (HistoricDate, string, error) {
+	if era == "" {
+		era = EraAD
+	}
+	parts := strings.Split(strings.TrimSpace(value), ".")
+
+	d := HistoricDate{Month: 1, Day: 1, Era: era}
+	var precision string
+	var err error
+	switch len(parts) {
+	case 3:
+		precision = DatePrecisionDay
+		if d.Day, err = strconv.Atoi(parts[0]); err != nil {
+			break
+		}
+		if d.Month, err = strconv.Atoi(parts[1]); err != nil {
+			break
+		}
+		d.Year, err = strconv.Atoi(parts[2])
+	case 2:
+		precision = DatePrecisionMonth
+		if d.Month, err = strconv.Atoi(parts[0]); err != nil {
+			break
+		}
+		d.Year, err = strconv.Atoi(parts[1])
+	case 1:
+		precision = DatePrecisionYear
+		d.Year, err = strconv.Atoi(parts[0])
+	default:
+		err = fmt.Errorf("too many components")
+	}
+	if err != nil {
+		return HistoricDate{}, "", fmt.Errorf("invalid date %q, expected DD.MM.YYYY, MM.YYYY or YYYY", value)
+	}
+
+	if err := d.Validate(); err != nil {
+		return HistoricDate{}, "", fmt.Errorf("invalid date %q: %v", value, err)
+	}
+	return d, precision, nil
+}
+
+// splitEra strips a trailing era marker and returns it, or defaultEra
+func splitEra(s, defaultEra string) (string, string) {
+	upper := strings.ToUpper(s)
+	for _, era := range []string{EraBC, EraAD} {
+		if strings.HasSuffix(upper, " "+era) || strings.HasSuffix(upper, "-"+era) {
+			return strings.TrimSpace(s[:len(s)-3]), era
+		}
+	}
+	if defaultEra == "" {
+		defaultEra = EraAD
+	}
+	return s, defaultEra
+}
+
+// Validate checks that the date exists: a positive year, a valid era and a
+// day that exists in the month in the date's calendar, so 29.02.1300 is valid
+// in the Julian calendar but not in the Gregorian one. Without a calendar the
+// day only has to exist in one of them; parsers validate again once the
+// calendar is known.
+func (d HistoricDate) Validate() error {
+	if d.Era != EraBC && d.Era != EraAD {
+		return fmt.Errorf("era must be BC or AD, got %q", d.Era)
+	}
+	if d.Year < 1 {
+		return fmt.Errorf("year must be 1 or later (there is no year 0)")
+	}
+	if d.Month < 1 || d.Month > 12 {
+		return fmt.Errorf("month must be between 1 and 12")
+	}
+
+	// The Julian calendar has every leap day the Gregorian one has
+	calendar := d.Calendar
+	if calendar == "" {
+		calendar = CalendarJulian
+	}
+	if d.Day < 1 || d.Day > daysInMonth(d.AstronomicalYearNumber(), d.Month, calendar) {
+		if d.Calendar != "" {
+			return fmt.Errorf("day %d does not exist in month %d of %d %s in the %s calendar", d.Day, d.Month, d.Year, d.Era, d.Calendar)
+		}
+		return fmt.Errorf("day %d does not exist in month %d", d.Day, d.Month)
+	}
+	return nil
+}
+
+// IsZero reports whether the date is unset
+func (d HistoricDate) IsZero() bool {
+	return d.Year == 0 && d.Month == 0 && d.Day == 0
+}
+
+// AstronomicalYearNumber returns the signed year with a year 0: 1 BC is 0,
+// 44 BC is -43
+func (d HistoricDate) AstronomicalYearNumber() int {
+	if d.Era == EraBC {
+		return 1 - d.Year
+	}
+	return d.Year
+}
+
+// Key returns an exact ordering key: dates compare chronologically by
+// comparing their keys, whatever calendar they are in
+func (d HistoricDate) Key() int64 {
+	g := d.In(CalendarGregorian)
+	return int64(g.AstronomicalYearNumber())*10000 + int64(g.Month)*100 + int64(g.Day)
+}
+
+// AstronomicalYear returns the fractional astronomical year of the date in
+// the Gregorian calendar, the astronomical_year column of
+// events_with_display_dates (historic_astronomical_year in SQL). Each day maps
+// to a distinct value within [year, year+1), laid out as if every month had
+// 31 days, so the ordering is exact.
+func (d HistoricDate) AstronomicalYear() float64 {
+	g := d.In(CalendarGregorian)
+	return float64(g.AstronomicalYearNumber()) + float64((g.Month-1)*31+g.Day-1)/372.0
+}
+
+// Compare returns -1, 0 or +1 as d is before, equal to or after other
+func (d HistoricDate) Compare(other HistoricDate) int {
+	a, b := d.Key(), other.Key()
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Before reports whether d is chronologically before other
+func (d HistoricDate) Before(other HistoricDate) bool {
+	return d.Key() < other.Key()
+}
+
+// After reports whether d is chronologically after other
+func (d HistoricDate) After(other HistoricDate) bool {
+	return d.Key() > other.Key()
+}
+
+// ISO returns the date part as "YYYY-MM-DD" with the positive year
+func (d HistoricDate) ISO() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// Timestamp returns the date as the midnight UTC timestamp the API has
+// always used for event_date, e.g. "0044-03-15T00:00:00Z"
+func (d HistoricDate) Timestamp() string {
+	return d.ISO() + "T00:00:00Z"
+}
+
+// String returns the date with its era, e.g. "0044-03-15 BC"
+func (d HistoricDate) String() string {
+	return d.ISO() + " " + d.Era
+}
+
+// Display renders the date the way the events_with_display_dates view does
+// (historic_display_date): only the meaningful part of the date is shown,
+// e.g. "15.03.44 BC", "44 BC", "5th century BC", prefixed with "c. " when circa.
+func (d HistoricDate) Display(precision string, circa bool) string {
+	year := d.Year
+
+	var label string
+	switch precision {
+	case DatePrecisionMonth:
+		label = fmt.Sprintf("%02d.%d", d.Month, year)
+	case DatePrecisionYear:
+		label = strconv.Itoa(year)
+	case DatePrecisionDecade:
+		label = fmt.Sprintf("%ds", year-year%10)
+	case DatePrecisionCentury:
+		label = ordinal((year-1)/100+1) + " century"
+	case DatePrecisionMillennium:
+		label = ordinal((year-1)/1000+1) + " millennium"
+	default:
+		label = fmt.Sprintf("%02d.%02d.%d", d.Day, d.Month, year)
+	}
+
+	if d.Era == EraBC {
+		label += " BC"
+	} else {
+		label += " AD"
+	}
+	if circa {
+		label = "c. " + label
+	}
+	return label
+}
+
+// DatasetString is the inverse of ParseDatasetDate: it writes only the
+// parts of the date that the precision makes meaningful.
+func (d HistoricDate) DatasetString(precision string) string {
+	switch precision {
+	case DatePrecisionDay, "":
+		return fmt.Sprintf("%02d.%02d.%04d", d.Day, d.Month, d.Year)
+	case DatePrecisionMonth:
+		return fmt.Sprintf("%02d.%04d", d.Month, d.Year)
+	default:
+		return fmt.Sprintf("%04d", d.Year)
+	}
+}
+
+// MarshalJSON encodes the date as "YYYY-MM-DD BC" / "YYYY-MM-DD AD"
+func (d HistoricDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON accepts any format understood by ParseHistoricDate
+func (d *HistoricDate) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseHistoricDate(s, EraAD)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value stores the date as its Julian Day Number. The day number is the same
+// whatever the calendar, so the calendar column says how to read it back;
+// without a calendar the date is in the conventional one for its period.
+// PostgreSQL DATE columns cannot hold Julian-only leap days such as
+// 29.02.1300 or dates before 4714 BC, day numbers can.
+func (d HistoricDate) Value() (driver.Value, error) {
+	if d.Calendar == "" {
+		d.Calendar = DefaultCalendar(d)
+	}
+	return int64(d.DayNumber()), nil
+}
+
+// Scan reads a day number column. The calendar is not part of the column, so
+// the date comes out in the calendar already set on d, or Gregorian; convert
+// it with In once the calendar column has been read.
+func (d *HistoricDate) Scan(src interface{}) error {
+	var jdn int64
+	switch v := src.(type) {
+	case int64:
+		jdn = v
+	case []byte:
+		n, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return fmt.Errorf("cannot scan %q into HistoricDate", v)
+		}
+		jdn = n
+	case nil:
+		return fmt.Errorf("cannot scan NULL into HistoricDate")
+	default:
+		return fmt.Errorf("cannot scan %T into HistoricDate", src)
+	}
+
+	*d = HistoricDateFromDayNumber(int(jdn), d.calendar())
+	return nil
+}
@@ -0,0 +1,314 @@
+package models
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestParseHistoricDate(t *testing.T) {
+	tests := []struct {
+		value      string
+		defaultEra string
+		want       HistoricDate
+		wantErr    bool
+	}{
+		{value: "1992-02-15", want: HistoricDate{Year: 1992, Month: 2, Day: 15, Era: EraAD}},
+		{value: "1992-02-15T00:00:00.000Z", want: HistoricDate{Year: 1992, Month: 2, Day: 15, Era: EraAD}},
+		{value: "15.03.0044", want: HistoricDate{Year: 44, Month: 3, Day: 15, Era: EraAD}},
+		{value: "15.03.44 BC", want: HistoricDate{Year: 44, Month: 3, Day: 15, Era: EraBC}},
+		{value: "0044-03-15-BC", want: HistoricDate{Year: 44, Month: 3, Day: 15, Era: EraBC}},
+		{value: "0044-03-15 bc", want: HistoricDate{Year: 44, Month: 3, Day: 15, Era: EraBC}},
+		{value: "-0044-03-15", want: HistoricDate{Year: 44, Month: 3, Day: 15, Era: EraBC}},
+		{value: "0044-03-15", defaultEra: EraBC, want: HistoricDate{Year: 44, Month: 3, Day: 15, Era: EraBC}},
+		{value: "0044-03-15 AD", defaultEra: EraBC, want: HistoricDate{Year: 44, Month: 3, Day: 15, Era: EraAD}},
+		{value: "  2000-02-29  ", want: HistoricDate{Year: 2000, Month: 2, Day: 29, Era: EraAD}},
+		// Without a calendar a Julian-only leap day is accepted
+		{value: "1300-02-29", want: HistoricDate{Year: 1300, Month: 2, Day: 29, Era: EraAD}},
+
+		{value: "", wantErr: true},
+		{value: "2023-02", wantErr: true},
+		{value: "2023-02-01-05", wantErr: true},
+		{value: "abcd-01-01", wantErr: true},
+		{value: "0000-01-01", wantErr: true},
+		{value: "2023-13-01", wantErr: true},
+		{value: "2023-00-10", wantErr: true},
+		{value: "2023-02-29", wantErr: true},
+		{value: "2023-04-31", wantErr: true},
+		{value: "31.04.2023", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseHistoricDate(tt.value, tt.defaultEra)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseHistoricDate(%q) = %v, want error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseHistoricDate(%q): %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseHistoricDate(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestHistoricDateValidateCalendar(t *testing.T) {
+	tests := []struct {
+		date    HistoricDate
+		wantErr bool
+	}{
+		{date: HistoricDate{Year: 1300, Month: 2, Day: 29, Era: EraAD, Calendar: CalendarJulian}},
+		{date: HistoricDate{Year: 1300, Month: 2, Day: 29, Era: EraAD, Calendar: CalendarGregorian}, wantErr: true},
+		{date: HistoricDate{Year: 2000, Month: 2, Day: 29, Era: EraAD, Calendar: CalendarGregorian}},
+		{date: HistoricDate{Year: 1900, Month: 2, Day: 29, Era: EraAD, Calendar: CalendarGregorian}, wantErr: true},
+		// 1 BC and 5 BC are astronomical years 0 and -4, leap in both calendars
+		{date: HistoricDate{Year: 1, Month: 2, Day: 29, Era: EraBC, Calendar: CalendarJulian}},
+		{date: HistoricDate{Year: 5, Month: 2, Day: 29, Era: EraBC, Calendar: CalendarGregorian}},
+		{date: HistoricDate{Year: 4, Month: 2, Day: 29, Era: EraBC, Calendar: CalendarJulian}, wantErr: true},
+		// 101 BC is astronomical -100: Julian leap year, Gregorian common year
+		{date: HistoricDate{Year: 101, Month: 2, Day: 29, Era: EraBC, Calendar: CalendarJulian}},
+		{date: HistoricDate{Year: 101, Month: 2, Day: 29, Era: EraBC, Calendar: CalendarGregorian}, wantErr: true},
+		{date: HistoricDate{Year: 2023, Month: 6, Day: 31, Era: EraAD}, wantErr: true},
+		{date: HistoricDate{Year: 2023, Month: 6, Day: 1, Era: "CE"}, wantErr: true},
+		{date: HistoricDate{Year: 0, Month: 6, Day: 1, Era: EraAD}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := tt.date.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%+v.Validate() = %v, want error %v", tt.date, err, tt.wantErr)
+		}
+	}
+}
+
+func TestHistoricDateKey(t *testing.T) {
+	tests := []struct {
+		date HistoricDate
+		want int64
+	}{
+		{HistoricDate{Year: 2000, Month: 1, Day: 1, Era: EraAD, Calendar: CalendarGregorian}, 20000101},
+		{HistoricDate{Year: 1, Month: 1, Day: 1, Era: EraAD, Calendar: CalendarGregorian}, 10101},
+		{HistoricDate{Year: 1, Month: 12, Day: 31, Era: EraBC, Calendar: CalendarGregorian}, 1231},
+		{HistoricDate{Year: 2, Month: 1, Day: 1, Era: EraBC, Calendar: CalendarGregorian}, -9899},
+		// An empty calendar is Gregorian
+		{HistoricDate{Year: 1, Month: 12, Day: 31, Era: EraBC}, 1231},
+		// Julian dates are keyed by their Gregorian equivalent
+		{HistoricDate{Year: 1582, Month: 10, Day: 4, Era: EraAD, Calendar: CalendarJulian}, 15821014},
+		{HistoricDate{Year: 1, Month: 1, Day: 1, Era: EraAD, Calendar: CalendarJulian}, 1230},
+	}
+
+	for _, tt := range tests {
+		if got := tt.date.Key(); got != tt.want {
+			t.Errorf("%+v.Key() = %d, want %d", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestHistoricDateCompare(t *testing.T) {
+	tests := []struct {
+		a, b HistoricDate
+		want int
+	}{
+		{
+			HistoricDate{Year: 44, Month: 3, Day: 15, Era: EraBC},
+			HistoricDate{Year: 44, Month: 12, Day: 1, Era: EraBC},
+			-1,
+		},
+		{
+			HistoricDate{Year: 45, Month: 12, Day: 31, Era: EraBC},
+			HistoricDate{Year: 44, Month: 1, Day: 1, Era: EraBC},
+			-1,
+		},
+		{
+			HistoricDate{Year: 1, Month: 12, Day: 31, Era: EraBC},
+			HistoricDate{Year: 1, Month: 1, Day: 1, Era: EraAD},
+			-1,
+		},
+		{
+			HistoricDate{Year: 1, Month: 1, Day: 1, Era: EraAD},
+			HistoricDate{Year: 1, Month: 12, Day: 31, Era: EraBC},
+			1,
+		},
+		{
+			HistoricDate{Year: 2000, Month: 5, Day: 5, Era: EraAD},
+			HistoricDate{Year: 2000, Month: 5, Day: 5, Era: EraAD},
+			0,
+		},
+		// The same day in both calendars
+		{
+			HistoricDate{Year: 1582, Month: 10, Day: 4, Era: EraAD, Calendar: CalendarJulian},
+			HistoricDate{Year: 1582, Month: 10, Day: 14, Era: EraAD, Calendar: CalendarGregorian},
+			0,
+		},
+		// 10 October Julian is 20 October Gregorian
+		{
+			HistoricDate{Year: 1582, Month: 10, Day: 10, Era: EraAD, Calendar: CalendarJulian},
+			HistoricDate{Year: 1582, Month: 10, Day: 15, Era: EraAD, Calendar: CalendarGregorian},
+			1,
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.a.Compare(tt.b); got != tt.want {
+			t.Errorf("%v.Compare(%v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := tt.a.Before(tt.b); got != (tt.want < 0) {
+			t.Errorf("%v.Before(%v) = %v", tt.a, tt.b, got)
+		}
+		if got := tt.a.After(tt.b); got != (tt.want > 0) {
+			t.Errorf("%v.After(%v) = %v", tt.a, tt.b, got)
+		}
+	}
+}
+
+func TestHistoricDateOrderingAcrossEras(t *testing.T) {
+	want := []HistoricDate{
+		{Year: 9500, Month: 1, Day: 1, Era: EraBC, Calendar: CalendarJulian},
+		{Year: 753, Month: 4, Day: 21, Era: EraBC, Calendar: CalendarJulian},
+		{Year: 44, Month: 3, Day: 15, Era: EraBC, Calendar: CalendarJulian},
+		{Year: 44, Month: 12, Day: 1, Era: EraBC, Calendar: CalendarJulian},
+		{Year: 2, Month: 12, Day: 31, Era: EraBC, Calendar: CalendarJulian},
+		{Year: 1, Month: 1, Day: 1, Era: EraBC, Calendar: CalendarJulian},
+		{Year: 1, Month: 12, Day: 31, Era: EraBC, Calendar: CalendarJulian},
+		{Year: 1, Month: 1, Day: 1, Era: EraAD, Calendar: CalendarJulian},
+		{Year: 1, Month: 12, Day: 31, Era: EraAD, Calendar: CalendarJulian},
+		{Year: 2, Month: 1, Day: 1, Era: EraAD, Calendar: CalendarJulian},
+		{Year: 1582, Month: 10, Day: 4, Era: EraAD, Calendar: CalendarJulian},
+		{Year: 1582, Month: 10, Day: 15, Era: EraAD, Calendar: CalendarGregorian},
+		{Year: 1969, Month: 7, Day: 20, Era: EraAD, Calendar: CalendarGregorian},
+	}
+
+	// Reverse, then sort back with each ordering the package offers
+	dates := make([]HistoricDate, len(want))
+	for i, d := range want {
+		dates[len(want)-1-i] = d
+	}
+
+	orderings := map[string]func(a, b HistoricDate) bool{
+		"Compare":          func(a, b HistoricDate) bool { return a.Compare(b) < 0 },
+		"Key":              func(a, b HistoricDate) bool { return a.Key() < b.Key() },
+		"DayNumber":        func(a, b HistoricDate) bool { return a.DayNumber() < b.DayNumber() },
+		"AstronomicalYear": func(a, b HistoricDate) bool { return a.AstronomicalYear() < b.AstronomicalYear() },
+	}
+	for name, less := range orderings {
+		got := append([]HistoricDate(nil), dates...)
+		sort.Slice(got, func(i, j int) bool { return less(got[i], got[j]) })
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("ordering by %s: position %d is %v, want %v", name, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestHistoricDateJSON(t *testing.T) {
+	tests := []struct {
+		date HistoricDate
+		json string
+	}{
+		{HistoricDate{Year: 44, Month: 3, Day: 15, Era: EraBC}, `"0044-03-15 BC"`},
+		{HistoricDate{Year: 1, Month: 1, Day: 1, Era: EraAD}, `"0001-01-01 AD"`},
+		{HistoricDate{Year: 9500, Month: 6, Day: 30, Era: EraBC}, `"9500-06-30 BC"`},
+		{HistoricDate{Year: 2024, Month: 2, Day: 29, Era: EraAD}, `"2024-02-29 AD"`},
+	}
+
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.date)
+		if err != nil {
+			t.Fatalf("Marshal(%+v): %v", tt.date, err)
+		}
+		if string(data) != tt.json {
+			t.Errorf("Marshal(%+v) = %s, want %s", tt.date, data, tt.json)
+		}
+
+		var got HistoricDate
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", data, err)
+		}
+		if got != tt.date {
+			t.Errorf("Unmarshal(%s) = %+v, want %+v", data, got, tt.date)
+		}
+	}
+
+	var d HistoricDate
+	for _, bad := range []string{`123`, `"not a date"`, `"2023-02-30"`} {
+		if err := json.Unmarshal([]byte(bad), &d); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestHistoricDateValueScan(t *testing.T) {
+	tests := []struct {
+		date HistoricDate
+		jdn  int64
+	}{
+		{HistoricDate{Year: 2000, Month: 1, Day: 1, Era: EraAD, Calendar: CalendarGregorian}, 2451545},
+		{HistoricDate{Year: 1582, Month: 10, Day: 15, Era: EraAD, Calendar: CalendarGregorian}, 2299161},
+		{HistoricDate{Year: 1582, Month: 10, Day: 4, Era: EraAD, Calendar: CalendarJulian}, 2299160},
+		{HistoricDate{Year: 4713, Month: 1, Day: 1, Era: EraBC, Calendar: CalendarJulian}, 0},
+		// Julian-only leap day and a date before the start of the day count
+		{HistoricDate{Year: 1300, Month: 2, Day: 29, Era: EraAD, Calendar: CalendarJulian}, 2195942},
+		{HistoricDate{Year: 9500, Month: 1, Day: 1, Era: EraBC, Calendar: CalendarJulian}, -1748451},
+	}
+
+	for _, tt := range tests {
+		v, err := tt.date.Value()
+		if err != nil {
+			t.Fatalf("%+v.Value(): %v", tt.date, err)
+		}
+		if v != tt.jdn {
+			t.Errorf("%+v.Value() = %v, want %d", tt.date, v, tt.jdn)
+		}
+
+		for _, src := range []interface{}{v, []byte(strconv.FormatInt(tt.jdn, 10))} {
+			got := HistoricDate{Calendar: tt.date.Calendar}
+			if err := got.Scan(src); err != nil {
+				t.Fatalf("Scan(%v): %v", src, err)
+			}
+			if got != tt.date {
+				t.Errorf("Scan(%v) = %+v, want %+v", src, got, tt.date)
+			}
+		}
+	}
+}
+
+func TestHistoricDateValueDefaultCalendar(t *testing.T) {
+	// Without a calendar, dates before the reform are stored as Julian
+	d := HistoricDate{Year: 44, Month: 3, Day: 15, Era: EraBC}
+	v, err := d.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	julian := d
+	julian.Calendar = CalendarJulian
+	if v != int64(julian.DayNumber()) {
+		t.Errorf("Value() = %v, want the Julian day number %d", v, julian.DayNumber())
+	}
+
+	// Scanned without a calendar the date comes out Gregorian
+	var got HistoricDate
+	if err := got.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if got.Calendar != CalendarGregorian {
+		t.Errorf("Scan calendar = %q, want %q", got.Calendar, CalendarGregorian)
+	}
+	if got.In(CalendarJulian) != julian {
+		t.Errorf("Scan(%v).In(julian) = %+v, want %+v", v, got.In(CalendarJulian), julian)
+	}
+}
+
+func TestHistoricDateScanErrors(t *testing.T) {
+	for _, src := range []interface{}{nil, "2451545", []byte("x"), 1.5} {
+		var d HistoricDate
+		if err := d.Scan(src); err == nil {
+			t.Errorf("Scan(%#v) succeeded, want error", src)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Import job kinds
+const (
+	ImportJobImport   = "import"
+	ImportJobReimport = "reimport"
+)
+
+// Import job statuses
+const (
+	ImportJobQueued    = "queued"
+	ImportJobRunning   = "running"
+	ImportJobCompleted = "completed"
+	ImportJobFailed    = "failed"
+	ImportJobCancelled = "cancelled"
+)
+
+// ImportJob is an import running in the background. Progress counts the
+// events written so far out of ProgressTotal, the valid rows of the file.
+type ImportJob struct {
+	ID            int             `json:"id"`
+	Kind          string          `json:"kind"`
+	Status        string          `json:"status"`
+	DatasetID     *int            `json:"dataset_id,omitempty"` // Re-imported dataset, or the created one once done
+	Filename      string          `json:"filename,omitempty"`
+	TotalRows     int             `json:"total_rows"`
+	Progress      int             `json:"progress"`
+	ProgressTotal int             `json:"progress_total"`
+	Result        json.RawMessage `json:"result,omitempty"` // Response body the import would have returned synchronously
+	Error         *string         `json:"error,omitempty"`
+	CreatedBy     *int            `json:"created_by,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	StartedAt     *time.Time      `json:"started_at,omitempty"`
+	FinishedAt    *time.Time      `json:"finished_at,omitempty"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// Finished reports whether the job has stopped running
+func (j ImportJob) Finished() bool {
+	return j.Status == ImportJobCompleted || j.Status == ImportJobFailed || j.Status == ImportJobCancelled
+}
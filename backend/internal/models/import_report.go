@@ -0,0 +1,109 @@
+package models
+
+// Severities of import issues. Errors keep a row from being imported;
+// warnings are about data that was dropped or adjusted on an imported row.
+const (
+	ImportSeverityError   = "error"
+	ImportSeverityWarning = "warning"
+)
+
+// Import issue codes
+const (
+	ImportMissingName         = "missing_name"
+	ImportMissingType         = "missing_type"
+	ImportInvalidType         = "invalid_type"
+	ImportInvalidDate         = "invalid_date"
+	ImportInvalidLatitude     = "invalid_latitude"
+	ImportInvalidLongitude    = "invalid_longitude"
+	ImportInvalidValue        = "invalid_value"
+	ImportInvalidOptional     = "invalid_optional_date" // Warning: an end date or bound was ignored
+	ImportEndBeforeStart      = "end_before_start"      // Warning: the end date was ignored
+	ImportCreateFailed        = "create_failed"
+	ImportTagFailed           = "tag_failed"
+	ImportDuplicateExternalID = "duplicate_external_id"
+)
+
+// ImportIssue is a problem found in one row of an import
+type ImportIssue struct {
+	Row      int    `json:"row"`             // 1-based event index, or spreadsheet row number for CSV/XLSX
+	Field    string `json:"field,omitempty"` // Import field the issue is about
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// ImportDuplicate flags a row that probably describes an event that already
+// exists, or another row of the same import
+type ImportDuplicate struct {
+	Row         int     `json:"row"`
+	EventID     int     `json:"event_id,omitempty"`     // Existing event
+	DuplicateOf int     `json:"duplicate_of,omitempty"` // Earlier row of the same import
+	Name        string  `json:"name"`
+	DisplayDate string  `json:"display_date"`
+	Reason      string  `json:"reason"`
+	Score       float64 `json:"score,omitempty"` // Duplicate score of a fuzzy match
+}
+
+// ImportReport describes what an import did, or would do in a dry run
+type ImportReport struct {
+	DryRun        bool              `json:"dry_run"`
+	Mode          string            `json:"mode"` // Error policy: strict or lenient
+	TotalRows     int               `json:"total_rows"`
+	ValidRows     int               `json:"valid_rows"`
+	ImportedCount int               `json:"imported_count"`
+	ErrorCount    int               `json:"error_count"`
+	WarningCount  int               `json:"warning_count"`
+	Issues        []ImportIssue     `json:"issues"`
+	NewTags       []string          `json:"new_tags"` // Tags that do not exist yet and are (or would be) created
+	Duplicates    []ImportDuplicate `json:"duplicates"`
+}
+
+// NewImportReport creates an empty report
+func NewImportReport(totalRows int, dryRun bool) *ImportReport {
+	return &ImportReport{
+		DryRun:     dryRun,
+		TotalRows:  totalRows,
+		Issues:     []ImportIssue{},
+		NewTags:    []string{},
+		Duplicates: []ImportDuplicate{},
+	}
+}
+
+// AddError records an error that keeps the row from being imported
+func (r *ImportReport) AddError(row int, field, code, message string) {
+	r.Issues = append(r.Issues, ImportIssue{Row: row, Field: field, Code: code, Severity: ImportSeverityError, Message: message})
+	r.ErrorCount++
+}
+
+// AddWarning records a problem on a row that is still imported
+func (r *ImportReport) AddWarning(row int, field, code, message string) {
+	r.Issues = append(r.Issues, ImportIssue{Row: row, Field: field, Code: code, Severity: ImportSeverityWarning, Message: message})
+	r.WarningCount++
+}
+
+// Re-import actions
+const (
+	ReimportCreated   = "created"
+	ReimportUpdated   = "updated"
+	ReimportUnchanged = "unchanged"
+	ReimportRemoved   = "removed"
+)
+
+// ReimportChange is an event a dataset re-import creates, updates or removes
+type ReimportChange struct {
+	Row     int    `json:"row,omitempty"`      // Import row; 0 for removed events
+	EventID int    `json:"event_id,omitempty"` // 0 for events a dry run would create
+	Action  string `json:"action"`
+	Name    string `json:"name"`
+}
+
+// ReimportSummary counts what a dataset re-import did, or would do in a dry
+// run. Changes lists every created, updated and removed event; unchanged
+// events are only counted.
+type ReimportSummary struct {
+	Created   int              `json:"created"`
+	Updated   int              `json:"updated"`
+	Unchanged int              `json:"unchanged"`
+	Removed   int              `json:"removed"`
+	Changes   []ReimportChange `json:"changes"`
+}
@@ -0,0 +1,296 @@
+package models
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// KML types for exporting events and regions to Google Earth. Events become
+// placemarks with TimeStamp/TimeSpan elements so the time slider works, styled
+// by their highest-weighted tag; regions become polygons.
+
+// KMLNamespace is the OGC KML 2.2 namespace
+const KMLNamespace = "http://www.opengis.net/kml/2.2"
+
+// kmlEventIcon is the pushpin tinted with the tag colour
+const kmlEventIcon = "http://maps.google.com/mapfiles/kml/paddle/wht-blank.png"
+
+// KML is the root of a KML document
+type KML struct {
+	XMLName  xml.Name    `xml:"kml"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Document KMLDocument `xml:"Document"`
+}
+
+// KMLDocument holds the shared styles and the folders of placemarks
+type KMLDocument struct {
+	Name        string      `xml:"name"`
+	Description string      `xml:"description,omitempty"`
+	Styles      []KMLStyle  `xml:"Style"`
+	Folders     []KMLFolder `xml:"Folder"`
+}
+
+// KMLStyle is a shared style referenced by placemarks via styleUrl
+type KMLStyle struct {
+	ID           string           `xml:"id,attr"`
+	IconStyle    *KMLIconStyle    `xml:"IconStyle,omitempty"`
+	LabelStyle   *KMLColorStyle   `xml:"LabelStyle,omitempty"`
+	LineStyle    *KMLLineStyle    `xml:"LineStyle,omitempty"`
+	PolyStyle    *KMLColorStyle   `xml:"PolyStyle,omitempty"`
+	BalloonStyle *KMLBalloonStyle `xml:"BalloonStyle,omitempty"`
+}
+
+// KMLIconStyle tints a placemark icon
+type KMLIconStyle struct {
+	Color string  `xml:"color"`
+	Icon  KMLIcon `xml:"Icon"`
+}
+
+// KMLIcon references an icon image
+type KMLIcon struct {
+	Href string `xml:"href"`
+}
+
+// KMLColorStyle is a LabelStyle or PolyStyle
+type KMLColorStyle struct {
+	Color string `xml:"color"`
+}
+
+// KMLLineStyle styles outlines
+type KMLLineStyle struct {
+	Color string  `xml:"color"`
+	Width float32 `xml:"width"`
+}
+
+// KMLBalloonStyle sets the template of the placemark popup
+type KMLBalloonStyle struct {
+	Text string `xml:"text"`
+}
+
+// KMLFolder groups placemarks
+type KMLFolder struct {
+	Name       string         `xml:"name"`
+	Placemarks []KMLPlacemark `xml:"Placemark"`
+}
+
+// KMLPlacemark is an event (Point) or a region (raw KML geometry)
+type KMLPlacemark struct {
+	ID          string        `xml:"id,attr,omitempty"`
+	Name        string        `xml:"name"`
+	Description string        `xml:"description,omitempty"`
+	TimeStamp   *KMLTimeStamp `xml:"TimeStamp,omitempty"`
+	TimeSpan    *KMLTimeSpan  `xml:"TimeSpan,omitempty"`
+	StyleURL    string        `xml:"styleUrl,omitempty"`
+	Point       *KMLPoint     `xml:"Point,omitempty"`
+	Geometry    string        `xml:",innerxml"` // Region geometry already rendered as KML (PostGIS ST_AsKML)
+}
+
+// KMLTimeStamp places a placemark at a single moment
+type KMLTimeStamp struct {
+	When string `xml:"when"`
+}
+
+// KMLTimeSpan places a placemark over a period
+type KMLTimeSpan struct {
+	Begin string `xml:"begin"`
+	End   string `xml:"end"`
+}
+
+// KMLPoint holds "longitude,latitude" coordinates
+type KMLPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// NewKML creates an empty KML document with a default style for untagged events
+func NewKML(name, description string) *KML {
+	return &KML{
+		Xmlns: KMLNamespace,
+		Document: KMLDocument{
+			Name:        name,
+			Description: description,
+			Styles: []KMLStyle{{
+				ID:           "event",
+				IconStyle:    &KMLIconStyle{Color: "ffffffff", Icon: KMLIcon{Href: kmlEventIcon}},
+				BalloonStyle: &KMLBalloonStyle{Text: "<h3>$[name]</h3>$[description]"},
+			}},
+		},
+	}
+}
+
+// AddEvents adds the events as a folder of placemarks, with names and
+// descriptions in the given locale, and a style for every tag that is the
+// leading tag of an event
+func (k *KML) AddEvents(folder string, events []HistoricalEvent, locale string) {
+	styled := make(map[string]bool)
+	for _, style := range k.Document.Styles {
+		styled[style.ID] = true
+	}
+
+	placemarks := make([]KMLPlacemark, len(events))
+	for i, event := range events {
+		placemark := KMLPlacemark{
+			ID:          "event-" + strconv.Itoa(event.ID),
+			Name:        event.GetNameForLocale(locale),
+			Description: kmlEventDescription(event, locale),
+			StyleURL:    "#event",
+			Point: &KMLPoint{
+				Coordinates: strconv.FormatFloat(event.Longitude, 'f', -1, 64) + "," + strconv.FormatFloat(event.Latitude, 'f', -1, 64),
+			},
+		}
+		placemark.TimeStamp, placemark.TimeSpan = kmlEventTime(event)
+
+		// Tags are ordered by weight, so the first one represents the event
+		if len(event.Tags) > 0 {
+			tag := event.Tags[0]
+			style := kmlTagStyle(tag)
+			if !styled[style.ID] {
+				k.Document.Styles = append(k.Document.Styles, style)
+				styled[style.ID] = true
+			}
+			placemark.StyleURL = "#" + style.ID
+			if tag.Emoji != nil && *tag.Emoji != "" {
+				placemark.Name = *tag.Emoji + " " + placemark.Name
+			}
+		}
+
+		placemarks[i] = placemark
+	}
+
+	k.Document.Folders = append(k.Document.Folders, KMLFolder{Name: folder, Placemarks: placemarks})
+}
+
+// AddRegions adds regions as a folder of polygons. geometries maps region IDs
+// to their geometry rendered as KML; regions without one are skipped.
+func (k *KML) AddRegions(folder string, regions []Region, geometries map[int]string, locale string) {
+	var placemarks []KMLPlacemark
+	for _, region := range regions {
+		geometry, ok := geometries[region.ID]
+		if !ok {
+			continue
+		}
+
+		styleID := "region-" + strconv.Itoa(region.ID)
+		k.Document.Styles = append(k.Document.Styles, KMLStyle{
+			ID:        styleID,
+			LineStyle: &KMLLineStyle{Color: KMLColor(region.BorderColor, 0.8), Width: region.BorderWidth},
+			PolyStyle: &KMLColorStyle{Color: KMLColor(region.Color, float64(region.FillOpacity))},
+		})
+
+		placemarks = append(placemarks, KMLPlacemark{
+			ID:          styleID,
+			Name:        region.GetNameForLocale(locale),
+			Description: region.GetDescriptionForLocale(locale),
+			StyleURL:    "#" + styleID,
+			Geometry:    geometry,
+		})
+	}
+
+	k.Document.Folders = append(k.Document.Folders, KMLFolder{Name: folder, Placemarks: placemarks})
+}
+
+// kmlTagStyle builds the style of events led by a tag: the pin takes the tag
+// colour, the label its border colour, and the balloon shows its emoji
+func kmlTagStyle(tag Tag) KMLStyle {
+	labelColor := tag.Color
+	if tag.BorderColor != nil && *tag.BorderColor != "" {
+		labelColor = *tag.BorderColor
+	}
+
+	balloon := "<h3>$[name]</h3>$[description]"
+	if tag.Emoji != nil && *tag.Emoji != "" {
+		balloon = "<p>" + *tag.Emoji + " " + html.EscapeString(tag.Name) + "</p>" + balloon
+	}
+
+	return KMLStyle{
+		ID:           "tag-" + strconv.Itoa(tag.ID),
+		IconStyle:    &KMLIconStyle{Color: KMLColor(tag.Color, 1), Icon: KMLIcon{Href: kmlEventIcon}},
+		LabelStyle:   &KMLColorStyle{Color: KMLColor(labelColor, 1)},
+		BalloonStyle: &KMLBalloonStyle{Text: balloon},
+	}
+}
+
+// kmlEventDescription is the balloon body: the description, the display date
+// and the source link
+func kmlEventDescription(event HistoricalEvent, locale string) string {
+	parts := []string{event.DisplayDate}
+	if event.EndDisplayDate != "" {
+		parts[0] += " – " + event.EndDisplayDate
+	}
+	if description := event.GetDescriptionForLocale(locale); description != "" {
+		parts = append(parts, html.EscapeString(description))
+	}
+	if event.Source != nil && *event.Source != "" {
+		source := html.EscapeString(*event.Source)
+		parts = append(parts, fmt.Sprintf(`<a href="%s">%s</a>`, source, source))
+	}
+	return strings.Join(parts, "<br/>")
+}
+
+// kmlEventTime returns a TimeStamp for events that cover a single day, month
+// or year, and a TimeSpan for longer periods and events with an end date
+func kmlEventTime(event HistoricalEvent) (*KMLTimeStamp, *KMLTimeSpan) {
+	if event.EndDate == nil {
+		switch event.DatePrecision {
+		case DatePrecisionDay, "":
+			return &KMLTimeStamp{When: KMLTime(event.EventDate, DatePrecisionDay)}, nil
+		case DatePrecisionMonth, DatePrecisionYear:
+			return &KMLTimeStamp{When: KMLTime(event.EventDate, event.DatePrecision)}, nil
+		}
+	}
+
+	begin, last := event.EventDate.Period(event.DatePrecision)
+	if event.EndDate != nil {
+		_, last = event.EndDate.Period(event.DatePrecision)
+	}
+	return nil, &KMLTimeSpan{
+		Begin: KMLTime(begin, DatePrecisionDay),
+		End:   KMLTime(last, DatePrecisionDay),
+	}
+}
+
+// KMLTime formats a date as a KML time value (XML Schema dateTime subset):
+// "YYYY", "YYYY-MM" or "YYYY-MM-DD" by precision. Years are astronomical, so
+// 44 BC is "-0043". Day values are converted to the proleptic Gregorian
+// calendar used by Google Earth's time slider.
+func KMLTime(d HistoricDate, precision string) string {
+	if precision == DatePrecisionDay {
+		d = d.In(CalendarGregorian)
+	}
+
+	year := d.AstronomicalYearNumber()
+	sign := ""
+	if year < 0 {
+		sign, year = "-", -year
+	}
+
+	switch precision {
+	case DatePrecisionYear:
+		return fmt.Sprintf("%s%04d", sign, year)
+	case DatePrecisionMonth:
+		return fmt.Sprintf("%s%04d-%02d", sign, year, d.Month)
+	default:
+		return fmt.Sprintf("%s%04d-%02d-%02d", sign, year, d.Month, d.Day)
+	}
+}
+
+// KMLColor converts a CSS "#rrggbb" colour and an opacity between 0 and 1 to
+// KML's "aabbggrr". Invalid colours fall back to white.
+func KMLColor(hex string, opacity float64) string {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) != 6 {
+		hex = "ffffff"
+	}
+	if _, err := strconv.ParseUint(hex, 16, 32); err != nil {
+		hex = "ffffff"
+	}
+	if opacity < 0 {
+		opacity = 0
+	} else if opacity > 1 {
+		opacity = 1
+	}
+	alpha := int(opacity*255 + 0.5)
+	return strings.ToLower(fmt.Sprintf("%02x%s%s%s", alpha, hex[4:6], hex[2:4], hex[0:2]))
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Lint finding severities
+const (
+	LintSeverityError   = "error"
+	LintSeverityWarning = "warning"
+	LintSeverityInfo    = "info"
+)
+
+// Lint finding codes
+const (
+	LintNullIsland            = "null_island"              // Event at latitude 0, longitude 0
+	LintOutsideRegion         = "outside_region"           // Event outside the region named by one of its tags
+	LintMissingTranslation    = "missing_translation"      // Russian name or description missing
+	LintIdenticalDescriptions = "identical_descriptions"   // Same description in both locales
+	LintInvalidSource         = "invalid_source"           // Source is not an http(s) URL
+	LintUnusedTag             = "unused_tag"               // Tag attached to no event
+	LintTemplateRange         = "template_start_after_end" // Template starts after it ends
+	LintInvalidRegionGeometry = "invalid_region_geometry"  // Region GeoJSON missing, invalid or self-intersecting
+)
+
+// Kinds of records a lint finding points to
+const (
+	LintEvent    = "event"
+	LintTag      = "tag"
+	LintTemplate = "template"
+	LintRegion   = "region"
+)
+
+// LintFinding is one data quality problem of a record. Link is the API path
+// of the record.
+type LintFinding struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Kind     string `json:"kind"`
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Message  string `json:"message"`
+	Link     string `json:"link"`
+}
+
+// LintReport is the result of a data quality check
+type LintReport struct {
+	GeneratedAt  time.Time      `json:"generated_at"`
+	ErrorCount   int            `json:"error_count"`
+	WarningCount int            `json:"warning_count"`
+	InfoCount    int            `json:"info_count"`
+	CodeCounts   map[string]int `json:"code_counts"`
+	Findings     []LintFinding  `json:"findings"`
+}
+
+// NewLintReport creates an empty report
+func NewLintReport() *LintReport {
+	return &LintReport{GeneratedAt: time.Now(), CodeCounts: map[string]int{}, Findings: []LintFinding{}}
+}
+
+// Add records a finding and sets its link
+func (r *LintReport) Add(f LintFinding) {
+	f.Link = LintLink(f.Kind, f.ID)
+	switch f.Severity {
+	case LintSeverityError:
+		r.ErrorCount++
+	case LintSeverityWarning:
+		r.WarningCount++
+	default:
+		r.InfoCount++
+	}
+	r.CodeCounts[f.Code]++
+	r.Findings = append(r.Findings, f)
+}
+
+// LintLink returns the API path of a record
+func LintLink(kind string, id int) string {
+	switch kind {
+	case LintTag:
+		return fmt.Sprintf("/api/tags/%d", id)
+	case LintTemplate:
+		return fmt.Sprintf("/api/date-templates/single/%d", id)
+	case LintRegion:
+		return fmt.Sprintf("/api/regions/%d", id)
+	}
+	return fmt.Sprintf("/api/events/%d", id)
+}
@@ -20,6 +20,7 @@ type Region struct {
 	BorderWidth   float32          `json:"border_width"`
 	CreatedAt     time.Time        `json:"created_at"`
 	UpdatedAt     time.Time        `json:"updated_at"`
+	Version       int              `json:"version"` // Row version, served as the ETag
 }
 
 func (r *Region) GetNameForLocale(locale string) string {
@@ -15,6 +15,7 @@ type Tag struct {
         EventCount  int       `json:"event_count"`
         CreatedAt   time.Time `json:"created_at"`
         UpdatedAt   time.Time `json:"updated_at"`
+        Version     int       `json:"version,omitempty"` // Row version, served as the ETag; unset on tags embedded in events
 }
 
 // CreateTagRequest represents the request payload for creating a tag
@@ -1,5 +1,10 @@
 package models
 
+import (
+        "encoding/json"
+        "fmt"
+)
+
 // DateTemplateGroup represents a group of date templates
 type DateTemplateGroup struct {
         ID             int    `json:"id"`
@@ -34,7 +39,9 @@ func (g *DateTemplateGroup) PopulateLegacyFields(locale string) {
         g.Description = g.GetDescriptionForLocale(locale)
 }
 
-// DateTemplate represents a date range template with display formatting
+// DateTemplate represents a date range template with display formatting.
+// Both dates are in the template's calendar and stored as day numbers, like
+// event dates.
 type DateTemplate struct {
         ID               int    `json:"id"`
         GroupID          int    `json:"group_id"`
@@ -47,13 +54,12 @@ type DateTemplate struct {
         NameRu           string `json:"name_ru"`
         DescriptionEn    string `json:"description_en"`
         DescriptionRu    string `json:"description_ru"`
-        StartDate        string `json:"start_date"`
-        StartEra         string `json:"start_era"`
-        EndDate          string `json:"end_date"`
-        EndEra           string `json:"end_era"`
+        StartDate        HistoricDate `json:"-"` // Marshalled as start_date + start_era
+        EndDate          HistoricDate `json:"-"` // Marshalled as end_date + end_era
         DisplayOrder     int    `json:"display_order"`
         StartDisplayDate string `json:"start_display_date"`
         EndDisplayDate   string `json:"end_display_date"`
+        Version          int    `json:"version"` // Row version, served as the ETag
 }
 
 // GetNameForLocale returns the name in the specified locale
@@ -85,4 +91,89 @@ func (t *DateTemplate) PopulateLegacyFields(locale string) {
         t.Name = t.GetNameForLocale(locale)
         t.Description = t.GetDescriptionForLocale(locale)
         t.GroupName = t.GetGroupNameForLocale(locale)
-}
\ No newline at end of file
+}
+
+// Validate checks that the template does not start after it ends. Dates are
+// compared to the day, so 10.05.1200 – 02.03.1200 is an inverted range.
+func (t *DateTemplate) Validate() error {
+        if t.StartDate.After(t.EndDate) {
+                return fmt.Errorf("start_date must not be later than end_date")
+        }
+        return nil
+}
+
+// templateDates is the JSON form of the template dates: the same timestamp,
+// era and calendar fields events use
+type templateDates struct {
+        StartDate string `json:"start_date"`
+        StartEra  string `json:"start_era"`
+        EndDate   string `json:"end_date"`
+        EndEra    string `json:"end_era"`
+        Calendar  string `json:"calendar"`
+}
+
+// MarshalJSON writes the dates as timestamps with their eras, like events
+func (t DateTemplate) MarshalJSON() ([]byte, error) {
+        type Alias DateTemplate
+        
+        return json.Marshal(&struct {
+                templateDates
+                StartDayNumber int `json:"start_day_number"`
+                EndDayNumber   int `json:"end_day_number"`
+                *Alias
+        }{
+                templateDates: templateDates{
+                        StartDate: t.StartDate.Timestamp(),
+                        StartEra:  t.StartDate.Era,
+                        EndDate:   t.EndDate.Timestamp(),
+                        EndEra:    t.EndDate.Era,
+                        Calendar:  t.StartDate.Calendar,
+                },
+                StartDayNumber: t.StartDate.DayNumber(),
+                EndDayNumber:   t.EndDate.DayNumber(),
+                Alias:          (*Alias)(&t),
+        })
+}
+
+// UnmarshalJSON reads the dates in any format ParseHistoricDate accepts. An
+// era suffix on a date wins over its era field; both dates are in the
+// requested calendar, or the conventional one for the start date.
+func (t *DateTemplate) UnmarshalJSON(data []byte) error {
+        type Alias DateTemplate
+        
+        aux := struct {
+                templateDates
+                *Alias
+        }{Alias: (*Alias)(t)}
+        if err := json.Unmarshal(data, &aux); err != nil {
+                return err
+        }
+        
+        dates := aux.templateDates
+        start, err := ParseHistoricDate(dates.StartDate, dates.StartEra)
+        if err != nil {
+                return fmt.Errorf("invalid start_date: %v", err)
+        }
+        end, err := ParseHistoricDate(dates.EndDate, dates.EndEra)
+        if err != nil {
+                return fmt.Errorf("invalid end_date: %v", err)
+        }
+        
+        calendar := dates.Calendar
+        if calendar == "" {
+                calendar = DefaultCalendar(start)
+        }
+        if !IsValidCalendar(calendar) {
+                return fmt.Errorf("unknown calendar %q (valid: julian, gregorian)", calendar)
+        }
+        start.Calendar, end.Calendar = calendar, calendar
+        if err := start.Validate(); err != nil {
+                return fmt.Errorf("invalid start_date: %v", err)
+        }
+        if err := end.Validate(); err != nil {
+                return fmt.Errorf("invalid end_date: %v", err)
+        }
+        
+        t.StartDate, t.EndDate = start, end
+        return nil
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDateTemplateJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		body       string
+		start, end HistoricDate
+	}{
+		{
+			`{"name_en": "Rome", "start_date": "0753-04-21", "start_era": "BC", "end_date": "0476-09-04", "end_era": "AD"}`,
+			HistoricDate{Year: 753, Month: 4, Day: 21, Era: EraBC, Calendar: CalendarJulian},
+			HistoricDate{Year: 476, Month: 9, Day: 4, Era: EraAD, Calendar: CalendarJulian},
+		},
+		// The timestamps the API returns, and a Julian-only leap day
+		{
+			`{"start_date": "1300-02-29T00:00:00Z", "start_era": "AD", "end_date": "1300-03-01T00:00:00Z", "end_era": "AD"}`,
+			HistoricDate{Year: 1300, Month: 2, Day: 29, Era: EraAD, Calendar: CalendarJulian},
+			HistoricDate{Year: 1300, Month: 3, Day: 1, Era: EraAD, Calendar: CalendarJulian},
+		},
+		// An explicit calendar applies to both dates
+		{
+			`{"start_date": "1917-11-07", "end_date": "1991-12-26", "calendar": "gregorian"}`,
+			HistoricDate{Year: 1917, Month: 11, Day: 7, Era: EraAD, Calendar: CalendarGregorian},
+			HistoricDate{Year: 1991, Month: 12, Day: 26, Era: EraAD, Calendar: CalendarGregorian},
+		},
+	}
+
+	for _, tt := range tests {
+		var template DateTemplate
+		if err := json.Unmarshal([]byte(tt.body), &template); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", tt.body, err)
+		}
+		if template.StartDate != tt.start || template.EndDate != tt.end {
+			t.Errorf("Unmarshal(%s) dates = %v, %v, want %v, %v", tt.body, template.StartDate, template.EndDate, tt.start, tt.end)
+		}
+
+		data, err := json.Marshal(template)
+		if err != nil {
+			t.Fatalf("Marshal(%+v): %v", template, err)
+		}
+		var again DateTemplate
+		if err := json.Unmarshal(data, &again); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", data, err)
+		}
+		if again.StartDate != tt.start || again.EndDate != tt.end {
+			t.Errorf("Unmarshal(%s) dates = %v, %v, want %v, %v", data, again.StartDate, again.EndDate, tt.start, tt.end)
+		}
+
+		var fields map[string]interface{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			t.Fatal(err)
+		}
+		if got := fields["start_day_number"]; got != float64(tt.start.DayNumber()) {
+			t.Errorf("Marshal(%+v) start_day_number = %v, want %d", template, got, tt.start.DayNumber())
+		}
+	}
+}
+
+func TestDateTemplateJSONInvalid(t *testing.T) {
+	tests := []string{
+		`{"start_date": "", "end_date": "0476-09-04"}`,
+		`{"start_date": "0753-04-21", "end_date": "0476-13-04"}`,
+		`{"start_date": "1900-02-29", "end_date": "1901-01-01"}`,
+		`{"start_date": "0753-04-21", "end_date": "0476-09-04", "calendar": "lunar"}`,
+	}
+
+	for _, body := range tests {
+		var template DateTemplate
+		if err := json.Unmarshal([]byte(body), &template); err == nil {
+			t.Errorf("Unmarshal(%s) = %+v, want error", body, template)
+		}
+	}
+}
+
+func TestDateTemplateValidate(t *testing.T) {
+	date := func(year, month, day int, era string) HistoricDate {
+		return HistoricDate{Year: year, Month: month, Day: day, Era: era, Calendar: CalendarJulian}
+	}
+
+	tests := []struct {
+		name       string
+		start, end HistoricDate
+		wantErr    bool
+	}{
+		{"ordered years", date(753, 4, 21, EraBC), date(476, 9, 4, EraAD), false},
+		{"single day", date(1200, 5, 10, EraAD), date(1200, 5, 10, EraAD), false},
+		{"ordered within a year", date(1200, 3, 2, EraAD), date(1200, 5, 10, EraAD), false},
+		// The same year, so comparing years alone would miss it
+		{"inverted within a year", date(1200, 5, 10, EraAD), date(1200, 3, 2, EraAD), true},
+		{"inverted within a BC year", date(44, 3, 15, EraBC), date(44, 1, 1, EraBC), true},
+		{"inverted years", date(476, 9, 4, EraAD), date(753, 4, 21, EraBC), true},
+	}
+
+	for _, tt := range tests {
+		template := DateTemplate{StartDate: tt.start, EndDate: tt.end}
+		if err := template.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// TrashItem is a soft-deleted event, tag, region or dataset. PurgeAt is when
+// the trash purge removes it for good; it is unset when purging is disabled.
+type TrashItem struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	DatasetID  *int       `json:"dataset_id,omitempty"`  // Events: the dataset they belong to
+	EventCount int        `json:"event_count,omitempty"` // Datasets: events deleted together with the dataset
+	DeletedAt  time.Time  `json:"deleted_at"`
+	PurgeAt    *time.Time `json:"purge_at,omitempty"`
+}
+
+// Trash lists the soft-deleted rows, most recently deleted first. Events
+// deleted together with their dataset are not listed on their own: they come
+// back when the dataset is restored.
+type Trash struct {
+	RetentionDays int         `json:"retention_days"` // 0 when purging is disabled
+	Events        []TrashItem `json:"events"`
+	Tags          []TrashItem `json:"tags"`
+	Regions       []TrashItem `json:"regions"`
+	Datasets      []TrashItem `json:"datasets"`
+}
+
+// SetRetention sets the retention period and the purge dates of the items;
+// a retention of zero or less means nothing is purged
+func (t *Trash) SetRetention(days int) {
+	if days <= 0 {
+		return
+	}
+	t.RetentionDays = days
+	for _, items := range [][]TrashItem{t.Events, t.Tags, t.Regions, t.Datasets} {
+		for i := range items {
+			purgeAt := items[i].DeletedAt.AddDate(0, 0, days)
+			items[i].PurgeAt = &purgeAt
+		}
+	}
+}
+
+// PurgeResult counts the rows a trash purge removed
+type PurgeResult struct {
+	Events   int64 `json:"events"`
+	Tags     int64 `json:"tags"`
+	Regions  int64 `json:"regions"`
+	Datasets int64 `json:"datasets"`
+}
+
+// Total is the number of rows removed
+func (p PurgeResult) Total() int64 {
+	return p.Events + p.Tags + p.Regions + p.Datasets
+}
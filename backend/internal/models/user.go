@@ -15,6 +15,24 @@ const (
         AccessLevelSuper AccessLevel = "super"
 )
 
+// accessLevelRanks orders the access levels; each level has the permissions
+// of the levels below it
+var accessLevelRanks = map[AccessLevel]int{
+        AccessLevelGuest:  0,
+        AccessLevelUser:   1,
+        AccessLevelEditor: 2,
+        AccessLevelAdmin:  3,
+        AccessLevelSuper:  4,
+}
+
+// AtLeast checks if the access level is required or above. Unknown levels
+// never qualify.
+func (l AccessLevel) AtLeast(required AccessLevel) bool {
+        rank, ok := accessLevelRanks[l]
+        requiredRank, requiredOK := accessLevelRanks[required]
+        return ok && requiredOK && rank >= requiredRank
+}
+
 // User represents a user in the system
 type User struct {
         ID           int         `json:"id"`
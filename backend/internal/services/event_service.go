@@ -55,5 +55,5 @@ func (s *EventService) GetEventsInBoundingBox(minLat, minLng, maxLat, maxLng flo
                 }
         }
         
-        return s.eventRepo.GetInBoundingBox(minLat, minLng, maxLat, maxLng)
+        return s.eventRepo.GetInBoundingBox(minLat, minLng, maxLat, maxLng, models.EventFilter{})
 }
\ No newline at end of file
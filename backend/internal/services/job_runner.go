@@ -0,0 +1,173 @@
+package services
+
+import (
+        "context"
+        "errors"
+        "fmt"
+        "historical-events-backend/internal/database/repositories"
+        "historical-events-backend/internal/models"
+        "log"
+        "sync"
+        "time"
+)
+
+// progressInterval limits how often job progress is written to the database
+const progressInterval = time.Second
+
+// ErrShuttingDown is returned by Submit once the server is stopping
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// JobFunc runs a job. It calls progress with the number of events written so
+// far and the number to write, and returns the result to store on the job.
+// A non-nil error fails the job; the result is stored either way.
+type JobFunc func(ctx context.Context, progress func(done, total int)) (result interface{}, datasetID *int, err error)
+
+// JobRunner runs import jobs in the background and records their status and
+// progress. Like MetricsCollector it is started with the server's context:
+// when that is cancelled, running jobs are cancelled (their transactions roll
+// back), marked failed, and Start returns once they have stopped.
+type JobRunner struct {
+        jobRepo *repositories.JobRepository
+        logger  *log.Logger
+
+        mu       sync.Mutex
+        running  map[int]context.CancelFunc
+        stopping bool
+        wg       sync.WaitGroup
+}
+
+func NewJobRunner(jobRepo *repositories.JobRepository, logger *log.Logger) *JobRunner {
+        return &JobRunner{
+                jobRepo: jobRepo,
+                logger:  logger,
+                running: make(map[int]context.CancelFunc),
+        }
+}
+
+// FailInterrupted marks the jobs a previous run left unfinished as failed.
+// Call it before the server accepts requests: afterwards it would also fail
+// the jobs submitted by this run.
+func (j *JobRunner) FailInterrupted() {
+        if count, err := j.jobRepo.FailUnfinished("interrupted by a server restart"); err != nil {
+                j.logger.Printf("Failed to clean up interrupted jobs: %v", err)
+        } else if count > 0 {
+                j.logger.Printf("Marked %d interrupted jobs as failed", count)
+        }
+}
+
+// Start waits for ctx to be cancelled and shuts the running jobs down
+func (j *JobRunner) Start(ctx context.Context) {
+        j.logger.Println("Starting import job runner...")
+        
+        <-ctx.Done()
+        
+        j.mu.Lock()
+        j.stopping = true
+        for _, cancel := range j.running {
+                cancel()
+        }
+        j.mu.Unlock()
+        
+        j.wg.Wait()
+        j.logger.Println("Import job runner stopped")
+}
+
+// Submit records a queued job and runs it in the background
+func (j *JobRunner) Submit(job *models.ImportJob, run JobFunc) (*models.ImportJob, error) {
+        j.mu.Lock()
+        defer j.mu.Unlock()
+        if j.stopping {
+                return nil, ErrShuttingDown
+        }
+        
+        created, err := j.jobRepo.Create(job)
+        if err != nil {
+                return nil, err
+        }
+        
+        ctx, cancel := context.WithCancel(context.Background())
+        j.running[created.ID] = cancel
+        j.wg.Add(1)
+        go func() {
+                defer j.wg.Done()
+                defer cancel()
+                j.run(ctx, created.ID, run)
+        }()
+        
+        return created, nil
+}
+
+// Cancel stops a running job. It returns false when the job is not running.
+func (j *JobRunner) Cancel(id int) bool {
+        j.mu.Lock()
+        defer j.mu.Unlock()
+        
+        cancel, ok := j.running[id]
+        if ok {
+                cancel()
+        }
+        return ok
+}
+
+func (j *JobRunner) run(ctx context.Context, id int, run JobFunc) {
+        defer func() {
+                j.mu.Lock()
+                delete(j.running, id)
+                j.mu.Unlock()
+        }()
+        
+        if err := j.jobRepo.MarkRunning(id); err != nil {
+                j.logger.Printf("Job %d: %v", id, err)
+        }
+        
+        var done, total int
+        var lastWrite time.Time
+        progress := func(d, t int) {
+                done, total = d, t
+                if time.Since(lastWrite) < progressInterval && d < t {
+                        return
+                }
+                lastWrite = time.Now()
+                if err := j.jobRepo.UpdateProgress(id, d, t); err != nil {
+                        j.logger.Printf("Job %d: %v", id, err)
+                }
+        }
+        
+        result, datasetID, err := j.execute(ctx, run, progress)
+        
+        status := models.ImportJobCompleted
+        var message *string
+        if err != nil {
+                status = models.ImportJobFailed
+                text := err.Error()
+                if ctx.Err() != nil {
+                        j.mu.Lock()
+                        stopping := j.stopping
+                        j.mu.Unlock()
+                        if stopping {
+                                text = "interrupted by server shutdown, nothing was imported"
+                        } else {
+                                status = models.ImportJobCancelled
+                                text = "cancelled, nothing was imported"
+                        }
+                }
+                message = &text
+        }
+        
+        if err := j.jobRepo.Finish(id, status, datasetID, done, total, result, message); err != nil {
+                j.logger.Printf("Job %d: %v", id, err)
+        }
+        j.logger.Printf("Job %d %s", id, status)
+}
+
+// execute runs the job, turning a panic into a job failure so that it cannot
+// take the server down
+func (j *JobRunner) execute(ctx context.Context, run JobFunc, progress func(done, total int)) (result interface{}, datasetID *int, err error) {
+        defer func() {
+                if r := recover(); r != nil {
+                        j.logger.Printf("Job panicked: %v", r)
+                        err = fmt.Errorf("internal error")
+                }
+        }()
+        return run(ctx, progress)
+}
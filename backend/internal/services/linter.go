@@ -0,0 +1,60 @@
+package services
+
+import (
+        "context"
+        "fmt"
+        "historical-events-backend/internal/database/repositories"
+        "historical-events-backend/internal/models"
+        "net/url"
+        "strings"
+)
+
+// Linter produces data quality reports over events, tags, templates and
+// regions
+type Linter struct {
+        lintRepo *repositories.LintRepository
+}
+
+// NewLinter creates a new linter
+func NewLinter(lintRepo *repositories.LintRepository) *Linter {
+        return &Linter{lintRepo: lintRepo}
+}
+
+// Run checks the whole database and returns the findings, grouped by check
+func (l *Linter) Run(ctx context.Context) (*models.LintReport, error) {
+        report := models.NewLintReport()
+        if err := l.lintRepo.Check(ctx, report); err != nil {
+                return nil, err
+        }
+
+        sources, err := l.lintRepo.GetEventSources(ctx)
+        if err != nil {
+                return nil, err
+        }
+        for _, source := range sources {
+                if problem := sourceProblem(source.Source); problem != "" {
+                        report.Add(models.LintFinding{Severity: models.LintSeverityError, Code: models.LintInvalidSource, Kind: models.LintEvent,
+                                ID: source.EventID, Name: source.Name, Message: fmt.Sprintf("source %q %s", source.Source, problem)})
+                }
+        }
+        return report, nil
+}
+
+// sourceProblem describes why source is not an absolute http(s) URL, or
+// returns "" when it is one
+func sourceProblem(source string) string {
+        if strings.TrimSpace(source) != source || strings.ContainsAny(source, " \t\n") {
+                return "contains whitespace"
+        }
+        u, err := url.Parse(source)
+        if err != nil {
+                return "is not a valid URL"
+        }
+        if u.Scheme != "http" && u.Scheme != "https" {
+                return "is not an http or https link"
+        }
+        if u.Host == "" {
+                return "has no host"
+        }
+        return ""
+}
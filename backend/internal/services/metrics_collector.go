@@ -63,7 +63,7 @@ func (m *MetricsCollector) safeCount(query string, args ...interface{}) float64
 }
 
 func (m *MetricsCollector) collectEventCount() {
-        metrics.EventsTotal.Set(m.safeCount("SELECT COUNT(*) FROM events"))
+        metrics.EventsTotal.Set(m.safeCount("SELECT COUNT(*) FROM events WHERE deleted_at IS NULL"))
 }
 
 func (m *MetricsCollector) collectUserCount() {
@@ -71,11 +71,11 @@ func (m *MetricsCollector) collectUserCount() {
 }
 
 func (m *MetricsCollector) collectTagCount() {
-        metrics.TagsTotal.Set(m.safeCount("SELECT COUNT(*) FROM tags"))
+        metrics.TagsTotal.Set(m.safeCount("SELECT COUNT(*) FROM tags WHERE deleted_at IS NULL"))
 }
 
 func (m *MetricsCollector) collectDatasetCount() {
-        metrics.DatasetsTotal.Set(m.safeCount("SELECT COUNT(*) FROM event_datasets"))
+        metrics.DatasetsTotal.Set(m.safeCount("SELECT COUNT(*) FROM event_datasets WHERE deleted_at IS NULL"))
 }
 
 func (m *MetricsCollector) collectTemplateCount() {
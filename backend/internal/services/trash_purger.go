@@ -0,0 +1,66 @@
+package services
+
+import (
+        "context"
+        "historical-events-backend/internal/database/repositories"
+        "log"
+        "time"
+)
+
+// trashPurgeInterval is how often the trash is checked for expired rows
+const trashPurgeInterval = time.Hour
+
+// TrashPurger permanently removes deleted events, tags, regions and datasets
+// once they have been in the trash longer than the retention period. Like
+// MetricsCollector it runs until the server's context is cancelled.
+type TrashPurger struct {
+        trashRepo *repositories.TrashRepository
+        retention time.Duration
+        logger    *log.Logger
+}
+
+// NewTrashPurger creates a purger keeping deleted rows for retentionDays
+func NewTrashPurger(trashRepo *repositories.TrashRepository, retentionDays int, logger *log.Logger) *TrashPurger {
+        return &TrashPurger{
+                trashRepo: trashRepo,
+                retention: time.Duration(retentionDays) * 24 * time.Hour,
+                logger:    logger,
+        }
+}
+
+// Start purges the trash now and then every hour until ctx is cancelled. It
+// returns at once when the retention is zero or less, which disables purging.
+func (p *TrashPurger) Start(ctx context.Context) {
+        if p.retention <= 0 {
+                p.logger.Println("Trash purge disabled")
+                return
+        }
+        p.logger.Printf("Starting trash purger (retention %s)...", p.retention)
+        
+        p.purge()
+        
+        ticker := time.NewTicker(trashPurgeInterval)
+        defer ticker.Stop()
+        
+        for {
+                select {
+                case <-ctx.Done():
+                        p.logger.Println("Trash purger stopped")
+                        return
+                case <-ticker.C:
+                        p.purge()
+                }
+        }
+}
+
+func (p *TrashPurger) purge() {
+        result, err := p.trashRepo.Purge(p.retention)
+        if err != nil {
+                p.logger.Printf("Failed to purge trash: %v", err)
+                return
+        }
+        if result.Total() > 0 {
+                p.logger.Printf("Purged %d events, %d tags, %d regions and %d datasets from the trash",
+                        result.Events, result.Tags, result.Regions, result.Datasets)
+        }
+}
@@ -21,6 +21,14 @@ func main() {
         cfg := config.Load()
         log.Println("Configuration loaded successfully")
 
+        // Run a maintenance command instead of the server, e.g. "lint"
+        if len(os.Args) > 1 {
+                if err := runCommand(cfg, os.Args[1:]); err != nil {
+                        log.Fatal(err)
+                }
+                return
+        }
+
         // Initialize database connection
         db, err := database.NewConnection(&cfg.Database)
         if err != nil {
@@ -36,6 +44,10 @@ func main() {
         datasetRepo := repositories.NewDatasetRepository(db.DB)
         supportRepo := repositories.NewSupportRepository(db.DB)
         regionRepo := repositories.NewRegionRepository(db.DB)
+        jobRepo := repositories.NewJobRepository(db.DB)
+        lintRepo := repositories.NewLintRepository(db.DB)
+        revisionRepo := repositories.NewRevisionRepository(db.DB)
+        trashRepo := repositories.NewTrashRepository(db.DB)
         log.Println("Repositories initialized successfully")
 
         // Initialize services
@@ -44,6 +56,7 @@ func main() {
                 jwtSecret = "your-secret-key-change-in-production" // Default for development
         }
         authService := services.NewAuthService(userRepo, jwtSecret)
+        linter := services.NewLinter(lintRepo)
         log.Println("Services initialized successfully")
 
         // Initialize metrics collector service
@@ -58,8 +71,25 @@ func main() {
                 metricsCollector.Start(ctx)
         }()
 
+        // Purge rows that have been in the trash longer than the retention period
+        trashPurger := services.NewTrashPurger(trashRepo, cfg.Trash.RetentionDays, log.New(os.Stdout, "[Trash] ", log.LstdFlags))
+        wg.Add(1)
+        go func() {
+                defer wg.Done()
+                trashPurger.Start(ctx)
+        }()
+
+        // Run background import jobs; on shutdown they are cancelled and marked failed
+        jobRunner := services.NewJobRunner(jobRepo, log.New(os.Stdout, "[Jobs] ", log.LstdFlags))
+        jobRunner.FailInterrupted()
+        wg.Add(1)
+        go func() {
+                defer wg.Done()
+                jobRunner.Start(ctx)
+        }()
+
         // Initialize router with all handlers
-        router := handlers.NewRouter(eventRepo, templateRepo, tagRepo, datasetRepo, authService, supportRepo, regionRepo)
+        router := handlers.NewRouter(eventRepo, templateRepo, tagRepo, datasetRepo, authService, supportRepo, regionRepo, revisionRepo, trashRepo, cfg.Trash.RetentionDays, jobRepo, jobRunner, linter)
         
         // Setup routes
         httpHandler := router.SetupRoutes()
@@ -0,0 +1,34 @@
+-- +goose Up
+-- Add a PostGIS geography column for spatial queries (bounding box, radius).
+-- latitude/longitude stay the source of truth; a trigger keeps location in sync
+-- so every write path (create, update, import, raw SQL) is covered.
+
+CREATE EXTENSION IF NOT EXISTS postgis;
+
+ALTER TABLE events ADD COLUMN location geography(Point, 4326);
+
+UPDATE events
+SET location = ST_SetSRID(ST_MakePoint(longitude::double precision, latitude::double precision), 4326)::geography;
+
+ALTER TABLE events ALTER COLUMN location SET NOT NULL;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION events_sync_location() RETURNS trigger AS $$
+BEGIN
+    NEW.location := ST_SetSRID(ST_MakePoint(NEW.longitude::double precision, NEW.latitude::double precision), 4326)::geography;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER trg_events_sync_location
+    BEFORE INSERT OR UPDATE OF latitude, longitude ON events
+    FOR EACH ROW EXECUTE FUNCTION events_sync_location();
+
+CREATE INDEX idx_events_location_gist ON events USING GIST (location);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_events_location_gist;
+DROP TRIGGER IF EXISTS trg_events_sync_location ON events;
+DROP FUNCTION IF EXISTS events_sync_location();
+ALTER TABLE events DROP COLUMN IF EXISTS location;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- Full-text search over event names and descriptions.
+-- One generated tsvector per locale so each is stemmed with the matching
+-- text search configuration; names weigh more than descriptions.
+
+ALTER TABLE events ADD COLUMN search_en tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english'::regconfig, COALESCE(name_en, '')), 'A') ||
+    setweight(to_tsvector('english'::regconfig, COALESCE(description_en, '')), 'B')
+) STORED;
+
+ALTER TABLE events ADD COLUMN search_ru tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian'::regconfig, COALESCE(name_ru, '')), 'A') ||
+    setweight(to_tsvector('russian'::regconfig, COALESCE(description_ru, '')), 'B')
+) STORED;
+
+CREATE INDEX idx_events_search_en ON events USING GIN (search_en);
+CREATE INDEX idx_events_search_ru ON events USING GIN (search_ru);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_events_search_ru;
+DROP INDEX IF EXISTS idx_events_search_en;
+ALTER TABLE events DROP COLUMN IF EXISTS search_ru;
+ALTER TABLE events DROP COLUMN IF EXISTS search_en;
@@ -0,0 +1,275 @@
+-- +goose Up
+-- Date precision and uncertainty for events.
+--   date_precision  how much of event_date is meaningful (day .. millennium)
+--   date_circa      the date is approximate ("c. 500 BC")
+--   date_earliest / date_latest (+ era)  optional explicit uncertainty bounds
+-- display_date now only shows the meaningful part of the date, and the view
+-- exposes astronomical_earliest/astronomical_latest for range filtering.
+
+ALTER TABLE events ADD COLUMN date_precision VARCHAR(10) NOT NULL DEFAULT 'day'
+    CHECK (date_precision IN ('day', 'month', 'year', 'decade', 'century', 'millennium'));
+ALTER TABLE events ADD COLUMN date_circa BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE events ADD COLUMN date_earliest DATE;
+ALTER TABLE events ADD COLUMN date_earliest_era VARCHAR(2) CHECK (date_earliest_era IN ('BC', 'AD'));
+ALTER TABLE events ADD COLUMN date_latest DATE;
+ALTER TABLE events ADD COLUMN date_latest_era VARCHAR(2) CHECK (date_latest_era IN ('BC', 'AD'));
+
+-- Dataset imports padded year-only dates to 1 January; treat those as year precision
+UPDATE events SET date_precision = 'year'
+WHERE EXTRACT(MONTH FROM event_date) = 1 AND EXTRACT(DAY FROM event_date) = 1;
+
+-- +goose StatementBegin
+-- Same fractional astronomical year as migration 008 (BC months run backwards)
+CREATE OR REPLACE FUNCTION historic_astronomical_year(d DATE, era TEXT) RETURNS NUMERIC AS $$
+    SELECT CASE
+        WHEN era = 'BC' THEN
+            EXTRACT(YEAR FROM d) * -1 + 1
+              - EXTRACT(MONTH FROM d) / 12.0
+              - EXTRACT(DAY   FROM d) / 365.0
+        ELSE
+            EXTRACT(YEAR FROM d)
+              + EXTRACT(MONTH FROM d) / 12.0
+              + EXTRACT(DAY   FROM d) / 365.0
+    END
+$$ LANGUAGE sql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Astronomical range covered by a date at the given precision.
+-- Decades are the "1230s", centuries and millennia start at year 1.
+CREATE OR REPLACE FUNCTION historic_period_bounds(d DATE, era TEXT, date_precision TEXT,
+                                                  OUT earliest NUMERIC, OUT latest NUMERIC) AS $$
+DECLARE
+    y INTEGER := EXTRACT(YEAR FROM d);
+    first_year INTEGER;
+    last_year INTEGER;
+    a NUMERIC;
+    b NUMERIC;
+BEGIN
+    IF date_precision = 'day' THEN
+        earliest := historic_astronomical_year(d, era);
+        latest := earliest;
+        RETURN;
+    END IF;
+
+    IF date_precision = 'month' THEN
+        a := historic_astronomical_year(date_trunc('month', d)::DATE, era);
+        b := historic_astronomical_year((date_trunc('month', d) + INTERVAL '1 month - 1 day')::DATE, era);
+    ELSE
+        CASE date_precision
+            WHEN 'decade' THEN
+                first_year := GREATEST(y - y % 10, 1);
+                last_year := y - y % 10 + 9;
+            WHEN 'century' THEN
+                first_year := ((y - 1) / 100) * 100 + 1;
+                last_year := first_year + 99;
+            WHEN 'millennium' THEN
+                first_year := ((y - 1) / 1000) * 1000 + 1;
+                last_year := first_year + 999;
+            ELSE
+                first_year := y;
+                last_year := y;
+        END CASE;
+        a := historic_astronomical_year(make_date(first_year, 1, 1), era);
+        b := historic_astronomical_year(make_date(last_year, 12, 31), era);
+    END IF;
+
+    -- BC periods run backwards, so order the corners explicitly
+    earliest := LEAST(a, b);
+    latest := GREATEST(a, b);
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION historic_ordinal(n INTEGER) RETURNS TEXT AS $$
+    SELECT n::TEXT || CASE
+        WHEN n % 100 IN (11, 12, 13) THEN 'th'
+        WHEN n % 10 = 1 THEN 'st'
+        WHEN n % 10 = 2 THEN 'nd'
+        WHEN n % 10 = 3 THEN 'rd'
+        ELSE 'th'
+    END
+$$ LANGUAGE sql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Display string honouring precision: "15.03.44 BC", "03.44 BC", "44 BC",
+-- "1230s BC", "5th century BC", "2nd millennium BC", prefixed "c. " when circa
+CREATE OR REPLACE FUNCTION historic_display_date(d DATE, era TEXT, date_precision TEXT, circa BOOLEAN) RETURNS TEXT AS $$
+DECLARE
+    y INTEGER := EXTRACT(YEAR FROM d);
+    label TEXT;
+BEGIN
+    CASE date_precision
+        WHEN 'month' THEN
+            label := CONCAT(LPAD(EXTRACT(MONTH FROM d)::TEXT, 2, '0'), '.', y::TEXT);
+        WHEN 'year' THEN
+            label := y::TEXT;
+        WHEN 'decade' THEN
+            label := CONCAT((y - y % 10)::TEXT, 's');
+        WHEN 'century' THEN
+            label := historic_ordinal((y - 1) / 100 + 1) || ' century';
+        WHEN 'millennium' THEN
+            label := historic_ordinal((y - 1) / 1000 + 1) || ' millennium';
+        ELSE
+            label := CONCAT(LPAD(EXTRACT(DAY   FROM d)::TEXT, 2, '0'), '.',
+                            LPAD(EXTRACT(MONTH FROM d)::TEXT, 2, '0'), '.',
+                            y::TEXT);
+    END CASE;
+
+    label := CONCAT(label, ' ', CASE WHEN era = 'BC' THEN 'BC' ELSE 'AD' END);
+    IF circa THEN
+        label := 'c. ' || label;
+    END IF;
+    RETURN label;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+DROP VIEW IF EXISTS events_with_display_dates;
+
+CREATE VIEW events_with_display_dates AS
+SELECT
+  e.id,
+  e.name,
+  e.description,
+  e.latitude,
+  e.longitude,
+  e.event_date,
+  e.era,
+  e.lens_type,
+  e.created_at,
+  e.updated_at,
+  e.created_by,
+  e.updated_by,
+  e.dataset_id,
+  e.source,
+  e.name_en,
+  e.name_ru,
+  e.description_en,
+  e.description_ru,
+  e.date_precision,
+  e.date_circa,
+  e.date_earliest,
+  e.date_earliest_era,
+  e.date_latest,
+  e.date_latest_era,
+  historic_display_date(e.event_date, e.era, e.date_precision, e.date_circa) AS display_date,
+  -- Imprecise dates sort by the middle of the period they cover
+  CASE
+    WHEN e.date_precision = 'day' THEN historic_astronomical_year(e.event_date, e.era)
+    ELSE (pb.earliest + pb.latest) / 2
+  END AS astronomical_year,
+  -- Explicit uncertainty bounds win over the precision-derived period
+  COALESCE(historic_astronomical_year(e.date_earliest, COALESCE(e.date_earliest_era, e.era)), pb.earliest) AS astronomical_earliest,
+  COALESCE(historic_astronomical_year(e.date_latest,   COALESCE(e.date_latest_era,   e.era)), pb.latest)   AS astronomical_latest,
+  COALESCE(
+    JSON_AGG(
+      JSON_BUILD_OBJECT(
+        'id',           t.id,
+        'name',         t.name,
+        'description',  t.description,
+        'color',        t.color,
+        'border_color', t.border_color,
+        'key_color',    t.key_color,
+        'emoji',        t.emoji,
+        'weight',       t.weight
+      ) ORDER BY t.weight DESC, t.name
+    ) FILTER (WHERE t.id IS NOT NULL),
+    '[]'::json
+  ) AS tags
+FROM events e
+CROSS JOIN LATERAL historic_period_bounds(e.event_date, e.era, e.date_precision) pb
+LEFT JOIN event_tags et ON et.event_id = e.id
+LEFT JOIN tags       t  ON t.id = et.tag_id
+GROUP BY
+  e.id, e.name, e.description, e.latitude, e.longitude,
+  e.event_date, e.era, e.lens_type, e.source, e.dataset_id,
+  e.created_at, e.updated_at, e.created_by, e.updated_by,
+  e.name_en, e.name_ru, e.description_en, e.description_ru,
+  e.date_precision, e.date_circa, e.date_earliest, e.date_earliest_era,
+  e.date_latest, e.date_latest_era, pb.earliest, pb.latest
+ORDER BY astronomical_year;
+
+-- +goose Down
+-- Restore the 023 view
+
+DROP VIEW IF EXISTS events_with_display_dates;
+
+CREATE VIEW events_with_display_dates AS
+SELECT
+  e.id,
+  e.name,
+  e.description,
+  e.latitude,
+  e.longitude,
+  e.event_date,
+  e.era,
+  e.lens_type,
+  e.created_at,
+  e.updated_at,
+  e.created_by,
+  e.updated_by,
+  e.dataset_id,
+  e.source,
+  e.name_en,
+  e.name_ru,
+  e.description_en,
+  e.description_ru,
+  CASE
+    WHEN e.era = 'BC' THEN
+      CONCAT(LPAD(EXTRACT(DAY   FROM e.event_date)::TEXT, 2, '0'), '.',
+             LPAD(EXTRACT(MONTH FROM e.event_date)::TEXT, 2, '0'), '.',
+             EXTRACT(YEAR FROM e.event_date)::TEXT, ' BC')
+    ELSE
+      CONCAT(LPAD(EXTRACT(DAY   FROM e.event_date)::TEXT, 2, '0'), '.',
+             LPAD(EXTRACT(MONTH FROM e.event_date)::TEXT, 2, '0'), '.',
+             EXTRACT(YEAR FROM e.event_date)::TEXT, ' AD')
+  END AS display_date,
+  CASE
+    WHEN e.era = 'BC' THEN
+      EXTRACT(YEAR FROM e.event_date) * -1 + 1
+        - EXTRACT(MONTH FROM e.event_date) / 12.0
+        - EXTRACT(DAY   FROM e.event_date) / 365.0
+    ELSE
+      EXTRACT(YEAR FROM e.event_date)
+        + EXTRACT(MONTH FROM e.event_date) / 12.0
+        + EXTRACT(DAY   FROM e.event_date) / 365.0
+  END AS astronomical_year,
+  COALESCE(
+    JSON_AGG(
+      JSON_BUILD_OBJECT(
+        'id',           t.id,
+        'name',         t.name,
+        'description',  t.description,
+        'color',        t.color,
+        'border_color', t.border_color,
+        'key_color',    t.key_color,
+        'emoji',        t.emoji,
+        'weight',       t.weight
+      ) ORDER BY t.weight DESC, t.name
+    ) FILTER (WHERE t.id IS NOT NULL),
+    '[]'::json
+  ) AS tags
+FROM events e
+LEFT JOIN event_tags et ON et.event_id = e.id
+LEFT JOIN tags       t  ON t.id = et.tag_id
+GROUP BY
+  e.id, e.name, e.description, e.latitude, e.longitude,
+  e.event_date, e.era, e.lens_type, e.source, e.dataset_id,
+  e.created_at, e.updated_at, e.created_by, e.updated_by,
+  e.name_en, e.name_ru, e.description_en, e.description_ru
+ORDER BY astronomical_year;
+
+DROP FUNCTION IF EXISTS historic_display_date(DATE, TEXT, TEXT, BOOLEAN);
+DROP FUNCTION IF EXISTS historic_ordinal(INTEGER);
+DROP FUNCTION IF EXISTS historic_period_bounds(DATE, TEXT, TEXT);
+DROP FUNCTION IF EXISTS historic_astronomical_year(DATE, TEXT);
+
+ALTER TABLE events DROP COLUMN IF EXISTS date_latest_era;
+ALTER TABLE events DROP COLUMN IF EXISTS date_latest;
+ALTER TABLE events DROP COLUMN IF EXISTS date_earliest_era;
+ALTER TABLE events DROP COLUMN IF EXISTS date_earliest;
+ALTER TABLE events DROP COLUMN IF EXISTS date_circa;
+ALTER TABLE events DROP COLUMN IF EXISTS date_precision;
@@ -0,0 +1,156 @@
+-- +goose Up
+-- Optional end date for events that last (wars, reigns, constructions).
+-- end_date/end_era share the event's date_precision; the view exposes
+-- end_display_date and widens astronomical_latest to the end of the period,
+-- so range filters include events that overlap the selected range.
+
+ALTER TABLE events ADD COLUMN end_date DATE;
+ALTER TABLE events ADD COLUMN end_era VARCHAR(2) CHECK (end_era IN ('BC', 'AD'));
+
+DROP VIEW IF EXISTS events_with_display_dates;
+
+CREATE VIEW events_with_display_dates AS
+SELECT
+  e.id,
+  e.name,
+  e.description,
+  e.latitude,
+  e.longitude,
+  e.event_date,
+  e.era,
+  e.lens_type,
+  e.created_at,
+  e.updated_at,
+  e.created_by,
+  e.updated_by,
+  e.dataset_id,
+  e.source,
+  e.name_en,
+  e.name_ru,
+  e.description_en,
+  e.description_ru,
+  e.date_precision,
+  e.date_circa,
+  e.date_earliest,
+  e.date_earliest_era,
+  e.date_latest,
+  e.date_latest_era,
+  e.end_date,
+  e.end_era,
+  historic_display_date(e.event_date, e.era, e.date_precision, e.date_circa) AS display_date,
+  CASE
+    WHEN e.end_date IS NOT NULL THEN historic_display_date(e.end_date, COALESCE(e.end_era, e.era), e.date_precision, e.date_circa)
+  END AS end_display_date,
+  -- Imprecise dates sort by the middle of the period they cover
+  CASE
+    WHEN e.date_precision = 'day' THEN historic_astronomical_year(e.event_date, e.era)
+    ELSE (pb.earliest + pb.latest) / 2
+  END AS astronomical_year,
+  -- Explicit uncertainty bounds win over the precision-derived period
+  COALESCE(historic_astronomical_year(e.date_earliest, COALESCE(e.date_earliest_era, e.era)), pb.earliest) AS astronomical_earliest,
+  -- Events with a duration extend to the end of their end date's period
+  -- (GREATEST ignores NULLs, so events without an end date are unchanged)
+  GREATEST(
+    COALESCE(historic_astronomical_year(e.date_latest, COALESCE(e.date_latest_era, e.era)), pb.latest),
+    pe.latest
+  ) AS astronomical_latest,
+  COALESCE(
+    JSON_AGG(
+      JSON_BUILD_OBJECT(
+        'id',           t.id,
+        'name',         t.name,
+        'description',  t.description,
+        'color',        t.color,
+        'border_color', t.border_color,
+        'key_color',    t.key_color,
+        'emoji',        t.emoji,
+        'weight',       t.weight
+      ) ORDER BY t.weight DESC, t.name
+    ) FILTER (WHERE t.id IS NOT NULL),
+    '[]'::json
+  ) AS tags
+FROM events e
+CROSS JOIN LATERAL historic_period_bounds(e.event_date, e.era, e.date_precision) pb
+LEFT JOIN LATERAL historic_period_bounds(e.end_date, COALESCE(e.end_era, e.era), e.date_precision) pe ON e.end_date IS NOT NULL
+LEFT JOIN event_tags et ON et.event_id = e.id
+LEFT JOIN tags       t  ON t.id = et.tag_id
+GROUP BY
+  e.id, e.name, e.description, e.latitude, e.longitude,
+  e.event_date, e.era, e.lens_type, e.source, e.dataset_id,
+  e.created_at, e.updated_at, e.created_by, e.updated_by,
+  e.name_en, e.name_ru, e.description_en, e.description_ru,
+  e.date_precision, e.date_circa, e.date_earliest, e.date_earliest_era,
+  e.date_latest, e.date_latest_era, e.end_date, e.end_era,
+  pb.earliest, pb.latest, pe.latest
+ORDER BY astronomical_year;
+
+-- +goose Down
+-- Restore the 026 view
+
+DROP VIEW IF EXISTS events_with_display_dates;
+
+CREATE VIEW events_with_display_dates AS
+SELECT
+  e.id,
+  e.name,
+  e.description,
+  e.latitude,
+  e.longitude,
+  e.event_date,
+  e.era,
+  e.lens_type,
+  e.created_at,
+  e.updated_at,
+  e.created_by,
+  e.updated_by,
+  e.dataset_id,
+  e.source,
+  e.name_en,
+  e.name_ru,
+  e.description_en,
+  e.description_ru,
+  e.date_precision,
+  e.date_circa,
+  e.date_earliest,
+  e.date_earliest_era,
+  e.date_latest,
+  e.date_latest_era,
+  historic_display_date(e.event_date, e.era, e.date_precision, e.date_circa) AS display_date,
+  -- Imprecise dates sort by the middle of the period they cover
+  CASE
+    WHEN e.date_precision = 'day' THEN historic_astronomical_year(e.event_date, e.era)
+    ELSE (pb.earliest + pb.latest) / 2
+  END AS astronomical_year,
+  -- Explicit uncertainty bounds win over the precision-derived period
+  COALESCE(historic_astronomical_year(e.date_earliest, COALESCE(e.date_earliest_era, e.era)), pb.earliest) AS astronomical_earliest,
+  COALESCE(historic_astronomical_year(e.date_latest,   COALESCE(e.date_latest_era,   e.era)), pb.latest)   AS astronomical_latest,
+  COALESCE(
+    JSON_AGG(
+      JSON_BUILD_OBJECT(
+        'id',           t.id,
+        'name',         t.name,
+        'description',  t.description,
+        'color',        t.color,
+        'border_color', t.border_color,
+        'key_color',    t.key_color,
+        'emoji',        t.emoji,
+        'weight',       t.weight
+      ) ORDER BY t.weight DESC, t.name
+    ) FILTER (WHERE t.id IS NOT NULL),
+    '[]'::json
+  ) AS tags
+FROM events e
+CROSS JOIN LATERAL historic_period_bounds(e.event_date, e.era, e.date_precision) pb
+LEFT JOIN event_tags et ON et.event_id = e.id
+LEFT JOIN tags       t  ON t.id = et.tag_id
+GROUP BY
+  e.id, e.name, e.description, e.latitude, e.longitude,
+  e.event_date, e.era, e.lens_type, e.source, e.dataset_id,
+  e.created_at, e.updated_at, e.created_by, e.updated_by,
+  e.name_en, e.name_ru, e.description_en, e.description_ru,
+  e.date_precision, e.date_circa, e.date_earliest, e.date_earliest_era,
+  e.date_latest, e.date_latest_era, pb.earliest, pb.latest
+ORDER BY astronomical_year;
+
+ALTER TABLE events DROP COLUMN IF EXISTS end_era;
+ALTER TABLE events DROP COLUMN IF EXISTS end_date;
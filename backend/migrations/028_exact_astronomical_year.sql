@@ -0,0 +1,133 @@
+-- +goose Up
+-- Exact, chronological astronomical_year (mirrors models.HistoricDate).
+-- The previous formula added month/12 + day/365, which is approximate, and
+-- subtracted it for BC dates so that January sorted after December within a
+-- BC year (migration 008). Months and days run forward in every era: 15 March
+-- 44 BC is before 1 December 44 BC. Each day now maps to a distinct value in
+-- [year, year + 1), laid out as if every month had 31 days.
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION historic_astronomical_year(d DATE, era TEXT) RETURNS NUMERIC AS $$
+    SELECT CASE
+        WHEN era = 'BC' THEN 1 - EXTRACT(YEAR FROM d)
+        ELSE EXTRACT(YEAR FROM d)
+    END
+    + ((EXTRACT(MONTH FROM d) - 1) * 31 + EXTRACT(DAY FROM d) - 1) / 372.0
+$$ LANGUAGE sql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Astronomical range covered by a date at the given precision.
+-- Decades are the "1230s", centuries and millennia start at year 1.
+-- Years are stored positive, so a BC period starts on 1 January of its
+-- largest year and ends on 31 December of its smallest.
+CREATE OR REPLACE FUNCTION historic_period_bounds(d DATE, era TEXT, date_precision TEXT,
+                                                  OUT earliest NUMERIC, OUT latest NUMERIC) AS $$
+DECLARE
+    y INTEGER := EXTRACT(YEAR FROM d);
+    first_year INTEGER;
+    last_year INTEGER;
+BEGIN
+    IF date_precision = 'day' THEN
+        earliest := historic_astronomical_year(d, era);
+        latest := earliest;
+        RETURN;
+    END IF;
+
+    IF date_precision = 'month' THEN
+        earliest := historic_astronomical_year(date_trunc('month', d)::DATE, era);
+        latest := historic_astronomical_year((date_trunc('month', d) + INTERVAL '1 month - 1 day')::DATE, era);
+        RETURN;
+    END IF;
+
+    CASE date_precision
+        WHEN 'decade' THEN
+            first_year := GREATEST(y - y % 10, 1);
+            last_year := y - y % 10 + 9;
+        WHEN 'century' THEN
+            first_year := ((y - 1) / 100) * 100 + 1;
+            last_year := first_year + 99;
+        WHEN 'millennium' THEN
+            first_year := ((y - 1) / 1000) * 1000 + 1;
+            last_year := first_year + 999;
+        ELSE
+            first_year := y;
+            last_year := y;
+    END CASE;
+
+    IF era = 'BC' THEN
+        earliest := historic_astronomical_year(make_date(last_year, 1, 1), era);
+        latest := historic_astronomical_year(make_date(first_year, 12, 31), era);
+    ELSE
+        earliest := historic_astronomical_year(make_date(first_year, 1, 1), era);
+        latest := historic_astronomical_year(make_date(last_year, 12, 31), era);
+    END IF;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- Restore the 026 functions
+
+-- +goose StatementBegin
+-- Same fractional astronomical year as migration 008 (BC months run backwards)
+CREATE OR REPLACE FUNCTION historic_astronomical_year(d DATE, era TEXT) RETURNS NUMERIC AS $$
+    SELECT CASE
+        WHEN era = 'BC' THEN
+            EXTRACT(YEAR FROM d) * -1 + 1
+              - EXTRACT(MONTH FROM d) / 12.0
+              - EXTRACT(DAY   FROM d) / 365.0
+        ELSE
+            EXTRACT(YEAR FROM d)
+              + EXTRACT(MONTH FROM d) / 12.0
+              + EXTRACT(DAY   FROM d) / 365.0
+    END
+$$ LANGUAGE sql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Astronomical range covered by a date at the given precision.
+-- Decades are the "1230s", centuries and millennia start at year 1.
+CREATE OR REPLACE FUNCTION historic_period_bounds(d DATE, era TEXT, date_precision TEXT,
+                                                  OUT earliest NUMERIC, OUT latest NUMERIC) AS $$
+DECLARE
+    y INTEGER := EXTRACT(YEAR FROM d);
+    first_year INTEGER;
+    last_year INTEGER;
+    a NUMERIC;
+    b NUMERIC;
+BEGIN
+    IF date_precision = 'day' THEN
+        earliest := historic_astronomical_year(d, era);
+        latest := earliest;
+        RETURN;
+    END IF;
+
+    IF date_precision = 'month' THEN
+        a := historic_astronomical_year(date_trunc('month', d)::DATE, era);
+        b := historic_astronomical_year((date_trunc('month', d) + INTERVAL '1 month - 1 day')::DATE, era);
+    ELSE
+        CASE date_precision
+            WHEN 'decade' THEN
+                first_year := GREATEST(y - y % 10, 1);
+                last_year := y - y % 10 + 9;
+            WHEN 'century' THEN
+                first_year := ((y - 1) / 100) * 100 + 1;
+                last_year := first_year + 99;
+            WHEN 'millennium' THEN
+                first_year := ((y - 1) / 1000) * 1000 + 1;
+                last_year := first_year + 999;
+            ELSE
+                first_year := y;
+                last_year := y;
+        END CASE;
+        a := historic_astronomical_year(make_date(first_year, 1, 1), era);
+        b := historic_astronomical_year(make_date(last_year, 12, 31), era);
+    END IF;
+
+    -- BC periods run backwards, so order the corners explicitly
+    earliest := LEAST(a, b);
+    latest := GREATEST(a, b);
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
@@ -0,0 +1,277 @@
+-- +goose Up
+-- Calendar awareness. Each event date is stored in its own calendar (julian
+-- or gregorian); by convention dates before the 1582 reform are Julian.
+-- Ordering and range filtering convert through the Julian Day Number, so a
+-- Byzantine (Julian) and a Western (Gregorian) date of the same day compare
+-- equal. Mirrors models.HistoricDate / models/calendar.go.
+
+ALTER TABLE events ADD COLUMN calendar VARCHAR(16) NOT NULL DEFAULT 'gregorian'
+    CHECK (calendar IN ('julian', 'gregorian'));
+
+UPDATE events SET calendar = 'julian'
+WHERE era = 'BC' OR event_date < DATE '1582-10-15';
+
+-- +goose StatementBegin
+-- Julian Day Number of a date in the given calendar (Fliegel & Van Flandern).
+-- Years are shifted by 100 whole 400-year cycles so the integer divisions
+-- only see positive numbers.
+CREATE OR REPLACE FUNCTION historic_day_number(d DATE, era TEXT, calendar TEXT) RETURNS INTEGER AS $$
+DECLARE
+    astro INTEGER := CASE WHEN era = 'BC' THEN 1 - EXTRACT(YEAR FROM d)::INTEGER ELSE EXTRACT(YEAR FROM d)::INTEGER END;
+    mon INTEGER := EXTRACT(MONTH FROM d);
+    dy INTEGER := EXTRACT(DAY FROM d);
+    a INTEGER := (14 - mon) / 12;
+    y INTEGER := astro + 40000 + 4800 - a;
+    m INTEGER := mon + 12 * a - 3;
+    jdn INTEGER;
+BEGIN
+    jdn := dy + (153 * m + 2) / 5 + 365 * y + y / 4 - 32083;
+    IF calendar = 'julian' THEN
+        RETURN jdn - 100 * 146100;
+    END IF;
+    RETURN jdn - y / 100 + y / 400 + 38 - 100 * 146097;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Astronomical year (see migration 028) of a date in the given calendar,
+-- taken from its Gregorian equivalent
+CREATE OR REPLACE FUNCTION historic_astronomical_year(d DATE, era TEXT, calendar TEXT) RETURNS NUMERIC AS $$
+DECLARE
+    a INTEGER;
+    b INTEGER;
+    c INTEGER;
+    dd INTEGER;
+    e INTEGER;
+    m INTEGER;
+BEGIN
+    IF calendar IS DISTINCT FROM 'julian' THEN
+        RETURN historic_astronomical_year(d, era);
+    END IF;
+
+    -- Julian Day Number back to a proleptic Gregorian date
+    a := historic_day_number(d, era, calendar) + 100 * 146097 + 32044;
+    b := (4 * a + 3) / 146097;
+    c := a - 146097 * b / 4;
+    dd := (4 * c + 3) / 1461;
+    e := c - 1461 * dd / 4;
+    m := (5 * e + 2) / 153;
+
+    RETURN (100 * b + dd - 4800 + m / 10 - 40000)
+         + ((m + 3 - 12 * (m / 10) - 1) * 31 + (e - (153 * m + 2) / 5 + 1) - 1) / 372.0;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Calendar-aware historic_period_bounds (see migration 028)
+CREATE OR REPLACE FUNCTION historic_period_bounds(d DATE, era TEXT, date_precision TEXT, calendar TEXT,
+                                                  OUT earliest NUMERIC, OUT latest NUMERIC) AS $$
+DECLARE
+    y INTEGER := EXTRACT(YEAR FROM d);
+    first_year INTEGER;
+    last_year INTEGER;
+BEGIN
+    IF date_precision = 'day' THEN
+        earliest := historic_astronomical_year(d, era, calendar);
+        latest := earliest;
+        RETURN;
+    END IF;
+
+    IF date_precision = 'month' THEN
+        earliest := historic_astronomical_year(date_trunc('month', d)::DATE, era, calendar);
+        latest := historic_astronomical_year((date_trunc('month', d) + INTERVAL '1 month - 1 day')::DATE, era, calendar);
+        RETURN;
+    END IF;
+
+    CASE date_precision
+        WHEN 'decade' THEN
+            first_year := GREATEST(y - y % 10, 1);
+            last_year := y - y % 10 + 9;
+        WHEN 'century' THEN
+            first_year := ((y - 1) / 100) * 100 + 1;
+            last_year := first_year + 99;
+        WHEN 'millennium' THEN
+            first_year := ((y - 1) / 1000) * 1000 + 1;
+            last_year := first_year + 999;
+        ELSE
+            first_year := y;
+            last_year := y;
+    END CASE;
+
+    IF era = 'BC' THEN
+        earliest := historic_astronomical_year(make_date(last_year, 1, 1), era, calendar);
+        latest := historic_astronomical_year(make_date(first_year, 12, 31), era, calendar);
+    ELSE
+        earliest := historic_astronomical_year(make_date(first_year, 1, 1), era, calendar);
+        latest := historic_astronomical_year(make_date(last_year, 12, 31), era, calendar);
+    END IF;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+DROP VIEW IF EXISTS events_with_display_dates;
+
+CREATE VIEW events_with_display_dates AS
+SELECT
+  e.id,
+  e.name,
+  e.description,
+  e.latitude,
+  e.longitude,
+  e.event_date,
+  e.era,
+  e.lens_type,
+  e.created_at,
+  e.updated_at,
+  e.created_by,
+  e.updated_by,
+  e.dataset_id,
+  e.source,
+  e.name_en,
+  e.name_ru,
+  e.description_en,
+  e.description_ru,
+  e.date_precision,
+  e.date_circa,
+  e.date_earliest,
+  e.date_earliest_era,
+  e.date_latest,
+  e.date_latest_era,
+  e.end_date,
+  e.end_era,
+  e.calendar,
+  historic_day_number(e.event_date, e.era, e.calendar) AS day_number,
+  historic_display_date(e.event_date, e.era, e.date_precision, e.date_circa) AS display_date,
+  CASE
+    WHEN e.end_date IS NOT NULL THEN historic_display_date(e.end_date, COALESCE(e.end_era, e.era), e.date_precision, e.date_circa)
+  END AS end_display_date,
+  -- Imprecise dates sort by the middle of the period they cover
+  CASE
+    WHEN e.date_precision = 'day' THEN historic_astronomical_year(e.event_date, e.era, e.calendar)
+    ELSE (pb.earliest + pb.latest) / 2
+  END AS astronomical_year,
+  -- Explicit uncertainty bounds win over the precision-derived period
+  COALESCE(historic_astronomical_year(e.date_earliest, COALESCE(e.date_earliest_era, e.era), e.calendar), pb.earliest) AS astronomical_earliest,
+  -- Events with a duration extend to the end of their end date's period
+  -- (GREATEST ignores NULLs, so events without an end date are unchanged)
+  GREATEST(
+    COALESCE(historic_astronomical_year(e.date_latest, COALESCE(e.date_latest_era, e.era), e.calendar), pb.latest),
+    pe.latest
+  ) AS astronomical_latest,
+  COALESCE(
+    JSON_AGG(
+      JSON_BUILD_OBJECT(
+        'id',           t.id,
+        'name',         t.name,
+        'description',  t.description,
+        'color',        t.color,
+        'border_color', t.border_color,
+        'key_color',    t.key_color,
+        'emoji',        t.emoji,
+        'weight',       t.weight
+      ) ORDER BY t.weight DESC, t.name
+    ) FILTER (WHERE t.id IS NOT NULL),
+    '[]'::json
+  ) AS tags
+FROM events e
+CROSS JOIN LATERAL historic_period_bounds(e.event_date, e.era, e.date_precision, e.calendar) pb
+LEFT JOIN LATERAL historic_period_bounds(e.end_date, COALESCE(e.end_era, e.era), e.date_precision, e.calendar) pe ON e.end_date IS NOT NULL
+LEFT JOIN event_tags et ON et.event_id = e.id
+LEFT JOIN tags       t  ON t.id = et.tag_id
+GROUP BY
+  e.id, e.name, e.description, e.latitude, e.longitude,
+  e.event_date, e.era, e.lens_type, e.source, e.dataset_id,
+  e.created_at, e.updated_at, e.created_by, e.updated_by,
+  e.name_en, e.name_ru, e.description_en, e.description_ru,
+  e.date_precision, e.date_circa, e.date_earliest, e.date_earliest_era,
+  e.date_latest, e.date_latest_era, e.end_date, e.end_era, e.calendar,
+  pb.earliest, pb.latest, pe.latest
+ORDER BY astronomical_year;
+
+-- +goose Down
+-- Restore the 027 view
+
+DROP VIEW IF EXISTS events_with_display_dates;
+
+CREATE VIEW events_with_display_dates AS
+SELECT
+  e.id,
+  e.name,
+  e.description,
+  e.latitude,
+  e.longitude,
+  e.event_date,
+  e.era,
+  e.lens_type,
+  e.created_at,
+  e.updated_at,
+  e.created_by,
+  e.updated_by,
+  e.dataset_id,
+  e.source,
+  e.name_en,
+  e.name_ru,
+  e.description_en,
+  e.description_ru,
+  e.date_precision,
+  e.date_circa,
+  e.date_earliest,
+  e.date_earliest_era,
+  e.date_latest,
+  e.date_latest_era,
+  e.end_date,
+  e.end_era,
+  historic_display_date(e.event_date, e.era, e.date_precision, e.date_circa) AS display_date,
+  CASE
+    WHEN e.end_date IS NOT NULL THEN historic_display_date(e.end_date, COALESCE(e.end_era, e.era), e.date_precision, e.date_circa)
+  END AS end_display_date,
+  -- Imprecise dates sort by the middle of the period they cover
+  CASE
+    WHEN e.date_precision = 'day' THEN historic_astronomical_year(e.event_date, e.era)
+    ELSE (pb.earliest + pb.latest) / 2
+  END AS astronomical_year,
+  -- Explicit uncertainty bounds win over the precision-derived period
+  COALESCE(historic_astronomical_year(e.date_earliest, COALESCE(e.date_earliest_era, e.era)), pb.earliest) AS astronomical_earliest,
+  -- Events with a duration extend to the end of their end date's period
+  -- (GREATEST ignores NULLs, so events without an end date are unchanged)
+  GREATEST(
+    COALESCE(historic_astronomical_year(e.date_latest, COALESCE(e.date_latest_era, e.era)), pb.latest),
+    pe.latest
+  ) AS astronomical_latest,
+  COALESCE(
+    JSON_AGG(
+      JSON_BUILD_OBJECT(
+        'id',           t.id,
+        'name',         t.name,
+        'description',  t.description,
+        'color',        t.color,
+        'border_color', t.border_color,
+        'key_color',    t.key_color,
+        'emoji',        t.emoji,
+        'weight',       t.weight
+      ) ORDER BY t.weight DESC, t.name
+    ) FILTER (WHERE t.id IS NOT NULL),
+    '[]'::json
+  ) AS tags
+FROM events e
+CROSS JOIN LATERAL historic_period_bounds(e.event_date, e.era, e.date_precision) pb
+LEFT JOIN LATERAL historic_period_bounds(e.end_date, COALESCE(e.end_era, e.era), e.date_precision) pe ON e.end_date IS NOT NULL
+LEFT JOIN event_tags et ON et.event_id = e.id
+LEFT JOIN tags       t  ON t.id = et.tag_id
+GROUP BY
+  e.id, e.name, e.description, e.latitude, e.longitude,
+  e.event_date, e.era, e.lens_type, e.source, e.dataset_id,
+  e.created_at, e.updated_at, e.created_by, e.updated_by,
+  e.name_en, e.name_ru, e.description_en, e.description_ru,
+  e.date_precision, e.date_circa, e.date_earliest, e.date_earliest_era,
+  e.date_latest, e.date_latest_era, e.end_date, e.end_era,
+  pb.earliest, pb.latest, pe.latest
+ORDER BY astronomical_year;
+
+DROP FUNCTION IF EXISTS historic_period_bounds(DATE, TEXT, TEXT, TEXT);
+DROP FUNCTION IF EXISTS historic_astronomical_year(DATE, TEXT, TEXT);
+DROP FUNCTION IF EXISTS historic_day_number(DATE, TEXT, TEXT);
+
+ALTER TABLE events DROP COLUMN IF EXISTS calendar;
@@ -0,0 +1,52 @@
+-- +goose Up
+-- Add a PostGIS geometry column to regions for vector tiles.
+-- geojson stays the source of truth (it may be a bare geometry, a Feature or a
+-- FeatureCollection); a trigger keeps geom in sync on every write path.
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION region_geometry(doc JSONB) RETURNS geometry AS $$
+BEGIN
+    IF doc IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    CASE doc->>'type'
+        WHEN 'FeatureCollection' THEN
+            RETURN (
+                SELECT ST_SetSRID(ST_Collect(region_geometry(f)), 4326)
+                FROM jsonb_array_elements(doc->'features') f
+            );
+        WHEN 'Feature' THEN
+            RETURN region_geometry(doc->'geometry');
+        ELSE
+            RETURN ST_SetSRID(ST_GeomFromGeoJSON(doc::text), 4326);
+    END CASE;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+ALTER TABLE regions ADD COLUMN geom geometry(Geometry, 4326);
+
+UPDATE regions SET geom = region_geometry(geojson);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION regions_sync_geom() RETURNS trigger AS $$
+BEGIN
+    NEW.geom := region_geometry(NEW.geojson);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER trg_regions_sync_geom
+    BEFORE INSERT OR UPDATE OF geojson ON regions
+    FOR EACH ROW EXECUTE FUNCTION regions_sync_geom();
+
+CREATE INDEX idx_regions_geom_gist ON regions USING GIST (geom);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_regions_geom_gist;
+DROP TRIGGER IF EXISTS trg_regions_sync_geom ON regions;
+DROP FUNCTION IF EXISTS regions_sync_geom();
+ALTER TABLE regions DROP COLUMN IF EXISTS geom;
+DROP FUNCTION IF EXISTS region_geometry(JSONB);
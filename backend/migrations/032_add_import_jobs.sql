@@ -0,0 +1,28 @@
+-- +goose Up
+-- Background import jobs (POST /events/import?async=true and dataset
+-- re-imports). The uploaded rows are only held in memory, so a job that was
+-- queued or running when the server stopped is marked failed on startup.
+CREATE TABLE import_jobs (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('import', 'reimport')),
+    status VARCHAR(16) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'running', 'completed', 'failed', 'cancelled')),
+    dataset_id INTEGER REFERENCES event_datasets(id) ON DELETE SET NULL,
+    filename VARCHAR(255),
+    total_rows INTEGER NOT NULL DEFAULT 0,
+    progress INTEGER NOT NULL DEFAULT 0,
+    progress_total INTEGER NOT NULL DEFAULT 0,
+    result JSONB,
+    error TEXT,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_import_jobs_created_at ON import_jobs(created_at DESC);
+CREATE INDEX idx_import_jobs_unfinished ON import_jobs(status) WHERE status IN ('queued', 'running');
+
+-- +goose Down
+DROP TABLE IF EXISTS import_jobs;
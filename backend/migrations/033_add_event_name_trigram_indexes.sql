@@ -0,0 +1,13 @@
+-- +goose Up
+-- Trigram indexes for duplicate detection: events whose lower-cased names
+-- are similar in either locale (pg_trgm's % operator) are compared further
+-- by date and distance (see repositories/duplicate_repository.go).
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_events_name_en_trgm ON events USING GIN (lower(name_en) gin_trgm_ops);
+CREATE INDEX idx_events_name_ru_trgm ON events USING GIN (lower(name_ru) gin_trgm_ops);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_events_name_ru_trgm;
+DROP INDEX IF EXISTS idx_events_name_en_trgm;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- History of every event change with a full snapshot of the event after it
+-- (before it, for deletes). event_id has no foreign key so the history of a
+-- deleted event is kept.
+
+CREATE TABLE event_revisions (
+    id SERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL,
+    action VARCHAR(16) NOT NULL,           -- create, update, delete, tags or revert
+    snapshot JSONB NOT NULL,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reverted_to INTEGER REFERENCES event_revisions(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_event_revisions_event ON event_revisions(event_id, id);
+
+-- +goose Down
+DROP TABLE IF EXISTS event_revisions;
@@ -0,0 +1,210 @@
+-- +goose Up
+-- Soft deletion with a trash bin. Deleted rows keep their data and links
+-- (event tags, template regions) and are hidden from every read path until
+-- they are restored or purged after the retention period. Events deleted
+-- together with their dataset share its deleted_at, which is how restoring
+-- the dataset finds them.
+
+ALTER TABLE events         ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE tags           ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE regions        ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE event_datasets ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_events_deleted_at         ON events (deleted_at)         WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_tags_deleted_at           ON tags (deleted_at)           WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_regions_deleted_at        ON regions (deleted_at)        WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_event_datasets_deleted_at ON event_datasets (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Tag names and external IDs only have to be unique among live rows
+ALTER TABLE tags DROP CONSTRAINT IF EXISTS tags_name_key;
+CREATE UNIQUE INDEX idx_tags_name ON tags (name) WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS idx_events_dataset_external_id;
+CREATE UNIQUE INDEX idx_events_dataset_external_id ON events (dataset_id, external_id)
+WHERE external_id IS NOT NULL AND deleted_at IS NULL;
+
+DROP VIEW IF EXISTS events_with_display_dates;
+
+CREATE VIEW events_with_display_dates AS
+SELECT
+  e.id,
+  e.name,
+  e.description,
+  e.latitude,
+  e.longitude,
+  e.event_date,
+  e.era,
+  e.lens_type,
+  e.created_at,
+  e.updated_at,
+  e.created_by,
+  e.updated_by,
+  e.dataset_id,
+  e.source,
+  e.name_en,
+  e.name_ru,
+  e.description_en,
+  e.description_ru,
+  e.date_precision,
+  e.date_circa,
+  e.date_earliest,
+  e.date_earliest_era,
+  e.date_latest,
+  e.date_latest_era,
+  e.end_date,
+  e.end_era,
+  e.calendar,
+  e.external_id,
+  historic_day_number(e.event_date, e.era, e.calendar) AS day_number,
+  historic_display_date(e.event_date, e.era, e.date_precision, e.date_circa) AS display_date,
+  CASE
+    WHEN e.end_date IS NOT NULL THEN historic_display_date(e.end_date, COALESCE(e.end_era, e.era), e.date_precision, e.date_circa)
+  END AS end_display_date,
+  -- Imprecise dates sort by the middle of the period they cover
+  CASE
+    WHEN e.date_precision = 'day' THEN historic_astronomical_year(e.event_date, e.era, e.calendar)
+    ELSE (pb.earliest + pb.latest) / 2
+  END AS astronomical_year,
+  -- Explicit uncertainty bounds win over the precision-derived period
+  COALESCE(historic_astronomical_year(e.date_earliest, COALESCE(e.date_earliest_era, e.era), e.calendar), pb.earliest) AS astronomical_earliest,
+  -- Events with a duration extend to the end of their end date's period
+  -- (GREATEST ignores NULLs, so events without an end date are unchanged)
+  GREATEST(
+    COALESCE(historic_astronomical_year(e.date_latest, COALESCE(e.date_latest_era, e.era), e.calendar), pb.latest),
+    pe.latest
+  ) AS astronomical_latest,
+  COALESCE(
+    JSON_AGG(
+      JSON_BUILD_OBJECT(
+        'id',           t.id,
+        'name',         t.name,
+        'description',  t.description,
+        'color',        t.color,
+        'border_color', t.border_color,
+        'key_color',    t.key_color,
+        'emoji',        t.emoji,
+        'weight',       t.weight
+      ) ORDER BY t.weight DESC, t.name
+    ) FILTER (WHERE t.id IS NOT NULL),
+    '[]'::json
+  ) AS tags
+FROM events e
+CROSS JOIN LATERAL historic_period_bounds(e.event_date, e.era, e.date_precision, e.calendar) pb
+LEFT JOIN LATERAL historic_period_bounds(e.end_date, COALESCE(e.end_era, e.era), e.date_precision, e.calendar) pe ON e.end_date IS NOT NULL
+LEFT JOIN event_tags et ON et.event_id = e.id
+LEFT JOIN tags       t  ON t.id = et.tag_id AND t.deleted_at IS NULL
+WHERE e.deleted_at IS NULL
+GROUP BY
+  e.id, e.name, e.description, e.latitude, e.longitude,
+  e.event_date, e.era, e.lens_type, e.source, e.dataset_id,
+  e.created_at, e.updated_at, e.created_by, e.updated_by,
+  e.name_en, e.name_ru, e.description_en, e.description_ru,
+  e.date_precision, e.date_circa, e.date_earliest, e.date_earliest_era,
+  e.date_latest, e.date_latest_era, e.end_date, e.end_era, e.calendar, e.external_id,
+  pb.earliest, pb.latest, pe.latest
+ORDER BY astronomical_year;
+
+-- +goose Down
+-- Deleted rows are dropped for good, then the 031 view and constraints come back
+
+DROP VIEW IF EXISTS events_with_display_dates;
+
+DELETE FROM events         WHERE deleted_at IS NOT NULL;
+DELETE FROM tags           WHERE deleted_at IS NOT NULL;
+DELETE FROM regions        WHERE deleted_at IS NOT NULL;
+DELETE FROM event_datasets WHERE deleted_at IS NOT NULL;
+
+CREATE VIEW events_with_display_dates AS
+SELECT
+  e.id,
+  e.name,
+  e.description,
+  e.latitude,
+  e.longitude,
+  e.event_date,
+  e.era,
+  e.lens_type,
+  e.created_at,
+  e.updated_at,
+  e.created_by,
+  e.updated_by,
+  e.dataset_id,
+  e.source,
+  e.name_en,
+  e.name_ru,
+  e.description_en,
+  e.description_ru,
+  e.date_precision,
+  e.date_circa,
+  e.date_earliest,
+  e.date_earliest_era,
+  e.date_latest,
+  e.date_latest_era,
+  e.end_date,
+  e.end_era,
+  e.calendar,
+  e.external_id,
+  historic_day_number(e.event_date, e.era, e.calendar) AS day_number,
+  historic_display_date(e.event_date, e.era, e.date_precision, e.date_circa) AS display_date,
+  CASE
+    WHEN e.end_date IS NOT NULL THEN historic_display_date(e.end_date, COALESCE(e.end_era, e.era), e.date_precision, e.date_circa)
+  END AS end_display_date,
+  -- Imprecise dates sort by the middle of the period they cover
+  CASE
+    WHEN e.date_precision = 'day' THEN historic_astronomical_year(e.event_date, e.era, e.calendar)
+    ELSE (pb.earliest + pb.latest) / 2
+  END AS astronomical_year,
+  -- Explicit uncertainty bounds win over the precision-derived period
+  COALESCE(historic_astronomical_year(e.date_earliest, COALESCE(e.date_earliest_era, e.era), e.calendar), pb.earliest) AS astronomical_earliest,
+  -- Events with a duration extend to the end of their end date's period
+  -- (GREATEST ignores NULLs, so events without an end date are unchanged)
+  GREATEST(
+    COALESCE(historic_astronomical_year(e.date_latest, COALESCE(e.date_latest_era, e.era), e.calendar), pb.latest),
+    pe.latest
+  ) AS astronomical_latest,
+  COALESCE(
+    JSON_AGG(
+      JSON_BUILD_OBJECT(
+        'id',           t.id,
+        'name',         t.name,
+        'description',  t.description,
+        'color',        t.color,
+        'border_color', t.border_color,
+        'key_color',    t.key_color,
+        'emoji',        t.emoji,
+        'weight',       t.weight
+      ) ORDER BY t.weight DESC, t.name
+    ) FILTER (WHERE t.id IS NOT NULL),
+    '[]'::json
+  ) AS tags
+FROM events e
+CROSS JOIN LATERAL historic_period_bounds(e.event_date, e.era, e.date_precision, e.calendar) pb
+LEFT JOIN LATERAL historic_period_bounds(e.end_date, COALESCE(e.end_era, e.era), e.date_precision, e.calendar) pe ON e.end_date IS NOT NULL
+LEFT JOIN event_tags et ON et.event_id = e.id
+LEFT JOIN tags       t  ON t.id = et.tag_id
+GROUP BY
+  e.id, e.name, e.description, e.latitude, e.longitude,
+  e.event_date, e.era, e.lens_type, e.source, e.dataset_id,
+  e.created_at, e.updated_at, e.created_by, e.updated_by,
+  e.name_en, e.name_ru, e.description_en, e.description_ru,
+  e.date_precision, e.date_circa, e.date_earliest, e.date_earliest_era,
+  e.date_latest, e.date_latest_era, e.end_date, e.end_era, e.calendar, e.external_id,
+  pb.earliest, pb.latest, pe.latest
+ORDER BY astronomical_year;
+
+DROP INDEX IF EXISTS idx_events_dataset_external_id;
+CREATE UNIQUE INDEX idx_events_dataset_external_id ON events (dataset_id, external_id)
+WHERE external_id IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_tags_name;
+ALTER TABLE tags ADD CONSTRAINT tags_name_key UNIQUE (name);
+
+DROP INDEX IF EXISTS idx_events_deleted_at;
+DROP INDEX IF EXISTS idx_tags_deleted_at;
+DROP INDEX IF EXISTS idx_regions_deleted_at;
+DROP INDEX IF EXISTS idx_event_datasets_deleted_at;
+
+ALTER TABLE events         DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE tags           DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE regions        DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE event_datasets DROP COLUMN IF EXISTS deleted_at;
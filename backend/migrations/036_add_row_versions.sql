@@ -0,0 +1,303 @@
+-- +goose Up
+-- Row versions for optimistic concurrency control. Every update of an event,
+-- tag, template or region increments version; the API returns it as the ETag
+-- and only writes when If-Match still names the current version.
+
+ALTER TABLE events         ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE tags           ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE date_templates ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE regions        ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- New columns go last, so the views can be replaced in place
+
+CREATE OR REPLACE VIEW events_with_display_dates AS
+SELECT
+  e.id,
+  e.name,
+  e.description,
+  e.latitude,
+  e.longitude,
+  e.event_date,
+  e.era,
+  e.lens_type,
+  e.created_at,
+  e.updated_at,
+  e.created_by,
+  e.updated_by,
+  e.dataset_id,
+  e.source,
+  e.name_en,
+  e.name_ru,
+  e.description_en,
+  e.description_ru,
+  e.date_precision,
+  e.date_circa,
+  e.date_earliest,
+  e.date_earliest_era,
+  e.date_latest,
+  e.date_latest_era,
+  e.end_date,
+  e.end_era,
+  e.calendar,
+  e.external_id,
+  historic_day_number(e.event_date, e.era, e.calendar) AS day_number,
+  historic_display_date(e.event_date, e.era, e.date_precision, e.date_circa) AS display_date,
+  CASE
+    WHEN e.end_date IS NOT NULL THEN historic_display_date(e.end_date, COALESCE(e.end_era, e.era), e.date_precision, e.date_circa)
+  END AS end_display_date,
+  -- Imprecise dates sort by the middle of the period they cover
+  CASE
+    WHEN e.date_precision = 'day' THEN historic_astronomical_year(e.event_date, e.era, e.calendar)
+    ELSE (pb.earliest + pb.latest) / 2
+  END AS astronomical_year,
+  -- Explicit uncertainty bounds win over the precision-derived period
+  COALESCE(historic_astronomical_year(e.date_earliest, COALESCE(e.date_earliest_era, e.era), e.calendar), pb.earliest) AS astronomical_earliest,
+  -- Events with a duration extend to the end of their end date's period
+  -- (GREATEST ignores NULLs, so events without an end date are unchanged)
+  GREATEST(
+    COALESCE(historic_astronomical_year(e.date_latest, COALESCE(e.date_latest_era, e.era), e.calendar), pb.latest),
+    pe.latest
+  ) AS astronomical_latest,
+  COALESCE(
+    JSON_AGG(
+      JSON_BUILD_OBJECT(
+        'id',           t.id,
+        'name',         t.name,
+        'description',  t.description,
+        'color',        t.color,
+        'border_color', t.border_color,
+        'key_color',    t.key_color,
+        'emoji',        t.emoji,
+        'weight',       t.weight
+      ) ORDER BY t.weight DESC, t.name
+    ) FILTER (WHERE t.id IS NOT NULL),
+    '[]'::json
+  ) AS tags,
+  e.version
+FROM events e
+CROSS JOIN LATERAL historic_period_bounds(e.event_date, e.era, e.date_precision, e.calendar) pb
+LEFT JOIN LATERAL historic_period_bounds(e.end_date, COALESCE(e.end_era, e.era), e.date_precision, e.calendar) pe ON e.end_date IS NOT NULL
+LEFT JOIN event_tags et ON et.event_id = e.id
+LEFT JOIN tags       t  ON t.id = et.tag_id AND t.deleted_at IS NULL
+WHERE e.deleted_at IS NULL
+GROUP BY
+  e.id, e.name, e.description, e.latitude, e.longitude,
+  e.event_date, e.era, e.lens_type, e.source, e.dataset_id,
+  e.created_at, e.updated_at, e.created_by, e.updated_by,
+  e.name_en, e.name_ru, e.description_en, e.description_ru,
+  e.date_precision, e.date_circa, e.date_earliest, e.date_earliest_era,
+  e.date_latest, e.date_latest_era, e.end_date, e.end_era, e.calendar, e.external_id, e.version,
+  pb.earliest, pb.latest, pe.latest
+ORDER BY astronomical_year;
+
+CREATE OR REPLACE VIEW date_templates_with_display AS
+SELECT 
+    dt.id,
+    dt.group_id,
+    dtg.name as group_name,
+    dt.name,
+    dt.description,
+    dt.name_en,
+    dt.name_ru,
+    dt.description_en,
+    dt.description_ru,
+    dtg.name_en as group_name_en,
+    dtg.name_ru as group_name_ru,
+    dt.start_date,
+    dt.start_era,
+    dt.end_date,
+    dt.end_era,
+    dt.display_order,
+    -- Format display dates  
+    CASE 
+        WHEN dt.start_era = 'BC' THEN 
+            CONCAT(
+                LPAD(EXTRACT(DAY FROM dt.start_date)::TEXT, 2, '0'), '.',
+                LPAD(EXTRACT(MONTH FROM dt.start_date)::TEXT, 2, '0'), '.',
+                EXTRACT(YEAR FROM dt.start_date)::TEXT, ' BC'
+            )
+        ELSE 
+            CONCAT(
+                LPAD(EXTRACT(DAY FROM dt.start_date)::TEXT, 2, '0'), '.',
+                LPAD(EXTRACT(MONTH FROM dt.start_date)::TEXT, 2, '0'), '.',
+                EXTRACT(YEAR FROM dt.start_date)::TEXT, ' AD'
+            )
+    END AS start_display_date,
+    CASE 
+        WHEN dt.end_era = 'BC' THEN 
+            CONCAT(
+                LPAD(EXTRACT(DAY FROM dt.end_date)::TEXT, 2, '0'), '.',
+                LPAD(EXTRACT(MONTH FROM dt.end_date)::TEXT, 2, '0'), '.',
+                EXTRACT(YEAR FROM dt.end_date)::TEXT, ' BC'
+            )
+        ELSE 
+            CONCAT(
+                LPAD(EXTRACT(DAY FROM dt.end_date)::TEXT, 2, '0'), '.',
+                LPAD(EXTRACT(MONTH FROM dt.end_date)::TEXT, 2, '0'), '.',
+                EXTRACT(YEAR FROM dt.end_date)::TEXT, ' AD'
+            )
+    END AS end_display_date,
+    -- Calculate astronomical years for sorting
+    CASE 
+        WHEN dt.start_era = 'BC' THEN (EXTRACT(YEAR FROM dt.start_date) * -1) + 1
+        ELSE EXTRACT(YEAR FROM dt.start_date)
+    END AS start_astronomical_year,
+    CASE 
+        WHEN dt.end_era = 'BC' THEN (EXTRACT(YEAR FROM dt.end_date) * -1) + 1
+        ELSE EXTRACT(YEAR FROM dt.end_date)
+    END AS end_astronomical_year,
+    dt.version
+FROM date_templates dt
+JOIN date_template_groups dtg ON dt.group_id = dtg.id
+ORDER BY dtg.display_order, dt.display_order;
+
+-- +goose Down
+-- Restore the 035 events view and the 005 templates view
+
+DROP VIEW IF EXISTS events_with_display_dates;
+
+CREATE VIEW events_with_display_dates AS
+SELECT
+  e.id,
+  e.name,
+  e.description,
+  e.latitude,
+  e.longitude,
+  e.event_date,
+  e.era,
+  e.lens_type,
+  e.created_at,
+  e.updated_at,
+  e.created_by,
+  e.updated_by,
+  e.dataset_id,
+  e.source,
+  e.name_en,
+  e.name_ru,
+  e.description_en,
+  e.description_ru,
+  e.date_precision,
+  e.date_circa,
+  e.date_earliest,
+  e.date_earliest_era,
+  e.date_latest,
+  e.date_latest_era,
+  e.end_date,
+  e.end_era,
+  e.calendar,
+  e.external_id,
+  historic_day_number(e.event_date, e.era, e.calendar) AS day_number,
+  historic_display_date(e.event_date, e.era, e.date_precision, e.date_circa) AS display_date,
+  CASE
+    WHEN e.end_date IS NOT NULL THEN historic_display_date(e.end_date, COALESCE(e.end_era, e.era), e.date_precision, e.date_circa)
+  END AS end_display_date,
+  -- Imprecise dates sort by the middle of the period they cover
+  CASE
+    WHEN e.date_precision = 'day' THEN historic_astronomical_year(e.event_date, e.era, e.calendar)
+    ELSE (pb.earliest + pb.latest) / 2
+  END AS astronomical_year,
+  -- Explicit uncertainty bounds win over the precision-derived period
+  COALESCE(historic_astronomical_year(e.date_earliest, COALESCE(e.date_earliest_era, e.era), e.calendar), pb.earliest) AS astronomical_earliest,
+  -- Events with a duration extend to the end of their end date's period
+  -- (GREATEST ignores NULLs, so events without an end date are unchanged)
+  GREATEST(
+    COALESCE(historic_astronomical_year(e.date_latest, COALESCE(e.date_latest_era, e.era), e.calendar), pb.latest),
+    pe.latest
+  ) AS astronomical_latest,
+  COALESCE(
+    JSON_AGG(
+      JSON_BUILD_OBJECT(
+        'id',           t.id,
+        'name',         t.name,
+        'description',  t.description,
+        'color',        t.color,
+        'border_color', t.border_color,
+        'key_color',    t.key_color,
+        'emoji',        t.emoji,
+        'weight',       t.weight
+      ) ORDER BY t.weight DESC, t.name
+    ) FILTER (WHERE t.id IS NOT NULL),
+    '[]'::json
+  ) AS tags
+FROM events e
+CROSS JOIN LATERAL historic_period_bounds(e.event_date, e.era, e.date_precision, e.calendar) pb
+LEFT JOIN LATERAL historic_period_bounds(e.end_date, COALESCE(e.end_era, e.era), e.date_precision, e.calendar) pe ON e.end_date IS NOT NULL
+LEFT JOIN event_tags et ON et.event_id = e.id
+LEFT JOIN tags       t  ON t.id = et.tag_id AND t.deleted_at IS NULL
+WHERE e.deleted_at IS NULL
+GROUP BY
+  e.id, e.name, e.description, e.latitude, e.longitude,
+  e.event_date, e.era, e.lens_type, e.source, e.dataset_id,
+  e.created_at, e.updated_at, e.created_by, e.updated_by,
+  e.name_en, e.name_ru, e.description_en, e.description_ru,
+  e.date_precision, e.date_circa, e.date_earliest, e.date_earliest_era,
+  e.date_latest, e.date_latest_era, e.end_date, e.end_era, e.calendar, e.external_id,
+  pb.earliest, pb.latest, pe.latest
+ORDER BY astronomical_year;
+
+DROP VIEW IF EXISTS date_templates_with_display;
+
+CREATE OR REPLACE VIEW date_templates_with_display AS
+SELECT 
+    dt.id,
+    dt.group_id,
+    dtg.name as group_name,
+    dt.name,
+    dt.description,
+    dt.name_en,
+    dt.name_ru,
+    dt.description_en,
+    dt.description_ru,
+    dtg.name_en as group_name_en,
+    dtg.name_ru as group_name_ru,
+    dt.start_date,
+    dt.start_era,
+    dt.end_date,
+    dt.end_era,
+    dt.display_order,
+    -- Format display dates  
+    CASE 
+        WHEN dt.start_era = 'BC' THEN 
+            CONCAT(
+                LPAD(EXTRACT(DAY FROM dt.start_date)::TEXT, 2, '0'), '.',
+                LPAD(EXTRACT(MONTH FROM dt.start_date)::TEXT, 2, '0'), '.',
+                EXTRACT(YEAR FROM dt.start_date)::TEXT, ' BC'
+            )
+        ELSE 
+            CONCAT(
+                LPAD(EXTRACT(DAY FROM dt.start_date)::TEXT, 2, '0'), '.',
+                LPAD(EXTRACT(MONTH FROM dt.start_date)::TEXT, 2, '0'), '.',
+                EXTRACT(YEAR FROM dt.start_date)::TEXT, ' AD'
+            )
+    END AS start_display_date,
+    CASE 
+        WHEN dt.end_era = 'BC' THEN 
+            CONCAT(
+                LPAD(EXTRACT(DAY FROM dt.end_date)::TEXT, 2, '0'), '.',
+                LPAD(EXTRACT(MONTH FROM dt.end_date)::TEXT, 2, '0'), '.',
+                EXTRACT(YEAR FROM dt.end_date)::TEXT, ' BC'
+            )
+        ELSE 
+            CONCAT(
+                LPAD(EXTRACT(DAY FROM dt.end_date)::TEXT, 2, '0'), '.',
+                LPAD(EXTRACT(MONTH FROM dt.end_date)::TEXT, 2, '0'), '.',
+                EXTRACT(YEAR FROM dt.end_date)::TEXT, ' AD'
+            )
+    END AS end_display_date,
+    -- Calculate astronomical years for sorting
+    CASE 
+        WHEN dt.start_era = 'BC' THEN (EXTRACT(YEAR FROM dt.start_date) * -1) + 1
+        ELSE EXTRACT(YEAR FROM dt.start_date)
+    END AS start_astronomical_year,
+    CASE 
+        WHEN dt.end_era = 'BC' THEN (EXTRACT(YEAR FROM dt.end_date) * -1) + 1
+        ELSE EXTRACT(YEAR FROM dt.end_date)
+    END AS end_astronomical_year
+FROM date_templates dt
+JOIN date_template_groups dtg ON dt.group_id = dtg.id
+ORDER BY dtg.display_order, dt.display_order;
+
+ALTER TABLE events         DROP COLUMN IF EXISTS version;
+ALTER TABLE tags           DROP COLUMN IF EXISTS version;
+ALTER TABLE date_templates DROP COLUMN IF EXISTS version;
+ALTER TABLE regions        DROP COLUMN IF EXISTS version;
@@ -0,0 +1,374 @@
+-- +goose Up
+-- Event dates are stored as Julian Day Numbers instead of DATE values. The
+-- DATE columns held the positive year, month and day in the event's own
+-- calendar, which PostgreSQL reads as a Gregorian AD date, so Julian-only
+-- leap days (29.02.1300) and BC leap days (29.02.45 BC) could not be stored,
+-- and a Gregorian-converted DATE would not reach back past 4714 BC. Every day
+-- of either calendar has a day number; the calendar column says how to show
+-- it. Mirrors models.HistoricDate.Value and models/calendar.go.
+
+-- +goose StatementBegin
+-- Julian Day Number of an astronomical year, month and day in the given
+-- calendar; the same formula as historic_day_number(DATE, TEXT, TEXT)
+CREATE OR REPLACE FUNCTION historic_day_number(y INTEGER, m INTEGER, d INTEGER, calendar TEXT) RETURNS INTEGER AS $$
+DECLARE
+    a INTEGER := (14 - m) / 12;
+    yy INTEGER := y + 40000 + 4800 - a;
+    mm INTEGER := m + 12 * a - 3;
+    jdn INTEGER;
+BEGIN
+    jdn := d + (153 * mm + 2) / 5 + 365 * yy + yy / 4 - 32083;
+    IF calendar = 'julian' THEN
+        RETURN jdn - 100 * 146100;
+    END IF;
+    RETURN jdn - yy / 100 + yy / 400 + 38 - 100 * 146097;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Astronomical year, month and day of a Julian Day Number in the given
+-- calendar (the inverse of historic_day_number)
+CREATE OR REPLACE FUNCTION historic_civil_date(day_number INTEGER, calendar TEXT,
+                                               OUT civil_year INTEGER, OUT civil_month INTEGER, OUT civil_day INTEGER) AS $$
+DECLARE
+    a INTEGER;
+    b INTEGER := 0;
+    c INTEGER;
+    dd INTEGER;
+    e INTEGER;
+    m INTEGER;
+BEGIN
+    IF calendar = 'julian' THEN
+        c := day_number + 100 * 146100 + 32082;
+    ELSE
+        a := day_number + 100 * 146097 + 32044;
+        b := (4 * a + 3) / 146097;
+        c := a - 146097 * b / 4;
+    END IF;
+
+    dd := (4 * c + 3) / 1461;
+    e := c - 1461 * dd / 4;
+    m := (5 * e + 2) / 153;
+
+    civil_day := e - (153 * m + 2) / 5 + 1;
+    civil_month := m + 3 - 12 * (m / 10);
+    civil_year := 100 * b + dd - 4800 + m / 10 - 100 * 400;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Astronomical year (see migration 028) of a day number, taken from its
+-- Gregorian date
+CREATE OR REPLACE FUNCTION historic_astronomical_year(day_number INTEGER) RETURNS NUMERIC AS $$
+DECLARE
+    g RECORD;
+BEGIN
+    SELECT * INTO g FROM historic_civil_date(day_number, 'gregorian');
+    RETURN g.civil_year + ((g.civil_month - 1) * 31 + g.civil_day - 1) / 372.0;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- historic_period_bounds (see migration 029) of a day number, with the
+-- period taken in the event's calendar
+CREATE OR REPLACE FUNCTION historic_period_bounds(day_number INTEGER, date_precision TEXT, calendar TEXT,
+                                                  OUT earliest NUMERIC, OUT latest NUMERIC) AS $$
+DECLARE
+    c RECORD;
+    y INTEGER;
+    first_year INTEGER;
+    last_year INTEGER;
+BEGIN
+    IF date_precision = 'day' THEN
+        earliest := historic_astronomical_year(day_number);
+        latest := earliest;
+        RETURN;
+    END IF;
+
+    SELECT * INTO c FROM historic_civil_date(day_number, calendar);
+
+    IF date_precision = 'month' THEN
+        earliest := historic_astronomical_year(historic_day_number(c.civil_year, c.civil_month, 1, calendar));
+        latest := historic_astronomical_year(
+            historic_day_number(c.civil_year + c.civil_month / 12, c.civil_month % 12 + 1, 1, calendar) - 1);
+        RETURN;
+    END IF;
+
+    -- Periods are counted in the years of the era
+    y := CASE WHEN c.civil_year < 1 THEN 1 - c.civil_year ELSE c.civil_year END;
+    CASE date_precision
+        WHEN 'decade' THEN
+            first_year := GREATEST(y - y % 10, 1);
+            last_year := y - y % 10 + 9;
+        WHEN 'century' THEN
+            first_year := ((y - 1) / 100) * 100 + 1;
+            last_year := first_year + 99;
+        WHEN 'millennium' THEN
+            first_year := ((y - 1) / 1000) * 1000 + 1;
+            last_year := first_year + 999;
+        ELSE
+            first_year := y;
+            last_year := y;
+    END CASE;
+
+    IF c.civil_year < 1 THEN
+        earliest := historic_astronomical_year(historic_day_number(1 - last_year, 1, 1, calendar));
+        latest := historic_astronomical_year(historic_day_number(1 - first_year, 12, 31, calendar));
+    ELSE
+        earliest := historic_astronomical_year(historic_day_number(first_year, 1, 1, calendar));
+        latest := historic_astronomical_year(historic_day_number(last_year, 12, 31, calendar));
+    END IF;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- historic_display_date (see migration 026) of a day number, shown in the
+-- event's calendar
+CREATE OR REPLACE FUNCTION historic_display_date(day_number INTEGER, calendar TEXT, date_precision TEXT, circa BOOLEAN) RETURNS TEXT AS $$
+DECLARE
+    c RECORD;
+    y INTEGER;
+    label TEXT;
+BEGIN
+    SELECT * INTO c FROM historic_civil_date(day_number, calendar);
+    y := CASE WHEN c.civil_year < 1 THEN 1 - c.civil_year ELSE c.civil_year END;
+
+    CASE date_precision
+        WHEN 'month' THEN
+            label := CONCAT(LPAD(c.civil_month::TEXT, 2, '0'), '.', y::TEXT);
+        WHEN 'year' THEN
+            label := y::TEXT;
+        WHEN 'decade' THEN
+            label := CONCAT((y - y % 10)::TEXT, 's');
+        WHEN 'century' THEN
+            label := historic_ordinal((y - 1) / 100 + 1) || ' century';
+        WHEN 'millennium' THEN
+            label := historic_ordinal((y - 1) / 1000 + 1) || ' millennium';
+        ELSE
+            label := CONCAT(LPAD(c.civil_day::TEXT, 2, '0'), '.',
+                            LPAD(c.civil_month::TEXT, 2, '0'), '.',
+                            y::TEXT);
+    END CASE;
+
+    label := CONCAT(label, ' ', CASE WHEN c.civil_year < 1 THEN 'BC' ELSE 'AD' END);
+    IF circa THEN
+        label := 'c. ' || label;
+    END IF;
+    RETURN label;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+DROP VIEW IF EXISTS events_with_display_dates;
+
+-- The DATE overloads of the 029 functions read the old columns
+ALTER TABLE events
+    ALTER COLUMN event_date TYPE INTEGER USING historic_day_number(event_date, era, calendar),
+    ALTER COLUMN date_earliest TYPE INTEGER USING historic_day_number(date_earliest, COALESCE(date_earliest_era, era), calendar),
+    ALTER COLUMN date_latest TYPE INTEGER USING historic_day_number(date_latest, COALESCE(date_latest_era, era), calendar),
+    ALTER COLUMN end_date TYPE INTEGER USING historic_day_number(end_date, COALESCE(end_era, era), calendar);
+
+CREATE VIEW events_with_display_dates AS
+SELECT
+  e.id,
+  e.name,
+  e.description,
+  e.latitude,
+  e.longitude,
+  e.event_date,
+  e.era,
+  e.lens_type,
+  e.created_at,
+  e.updated_at,
+  e.created_by,
+  e.updated_by,
+  e.dataset_id,
+  e.source,
+  e.name_en,
+  e.name_ru,
+  e.description_en,
+  e.description_ru,
+  e.date_precision,
+  e.date_circa,
+  e.date_earliest,
+  e.date_earliest_era,
+  e.date_latest,
+  e.date_latest_era,
+  e.end_date,
+  e.end_era,
+  e.calendar,
+  e.external_id,
+  e.event_date AS day_number,
+  historic_display_date(e.event_date, e.calendar, e.date_precision, e.date_circa) AS display_date,
+  CASE
+    WHEN e.end_date IS NOT NULL THEN historic_display_date(e.end_date, e.calendar, e.date_precision, e.date_circa)
+  END AS end_display_date,
+  -- Imprecise dates sort by the middle of the period they cover
+  CASE
+    WHEN e.date_precision = 'day' THEN historic_astronomical_year(e.event_date)
+    ELSE (pb.earliest + pb.latest) / 2
+  END AS astronomical_year,
+  -- Explicit uncertainty bounds win over the precision-derived period
+  COALESCE(historic_astronomical_year(e.date_earliest), pb.earliest) AS astronomical_earliest,
+  -- Events with a duration extend to the end of their end date's period
+  -- (GREATEST ignores NULLs, so events without an end date are unchanged)
+  GREATEST(
+    COALESCE(historic_astronomical_year(e.date_latest), pb.latest),
+    pe.latest
+  ) AS astronomical_latest,
+  COALESCE(
+    JSON_AGG(
+      JSON_BUILD_OBJECT(
+        'id',           t.id,
+        'name',         t.name,
+        'description',  t.description,
+        'color',        t.color,
+        'border_color', t.border_color,
+        'key_color',    t.key_color,
+        'emoji',        t.emoji,
+        'weight',       t.weight
+      ) ORDER BY t.weight DESC, t.name
+    ) FILTER (WHERE t.id IS NOT NULL),
+    '[]'::json
+  ) AS tags,
+  e.version,
+  e.status,
+  e.review_note,
+  e.reviewed_by,
+  e.reviewed_at
+FROM events e
+CROSS JOIN LATERAL historic_period_bounds(e.event_date, e.date_precision, e.calendar) pb
+LEFT JOIN LATERAL historic_period_bounds(e.end_date, e.date_precision, e.calendar) pe ON e.end_date IS NOT NULL
+LEFT JOIN event_tags et ON et.event_id = e.id
+LEFT JOIN tags       t  ON t.id = et.tag_id AND t.deleted_at IS NULL
+WHERE e.deleted_at IS NULL
+GROUP BY
+  e.id, e.name, e.description, e.latitude, e.longitude,
+  e.event_date, e.era, e.lens_type, e.source, e.dataset_id,
+  e.created_at, e.updated_at, e.created_by, e.updated_by,
+  e.name_en, e.name_ru, e.description_en, e.description_ru,
+  e.date_precision, e.date_circa, e.date_earliest, e.date_earliest_era,
+  e.date_latest, e.date_latest_era, e.end_date, e.end_era, e.calendar, e.external_id, e.version,
+  e.status, e.review_note, e.reviewed_by, e.reviewed_at,
+  pb.earliest, pb.latest, pe.latest
+ORDER BY astronomical_year;
+
+-- +goose Down
+-- Back to DATE columns. Days the old storage cannot hold (Julian-only and BC
+-- leap days) move to 1 March.
+
+-- +goose StatementBegin
+CREATE FUNCTION historic_legacy_date(day_number INTEGER, calendar TEXT) RETURNS DATE AS $$
+DECLARE
+    c RECORD;
+BEGIN
+    SELECT * INTO c FROM historic_civil_date(day_number, calendar);
+    RETURN make_date(CASE WHEN c.civil_year < 1 THEN 1 - c.civil_year ELSE c.civil_year END, c.civil_month, 1)
+         + (c.civil_day - 1);
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+DROP VIEW IF EXISTS events_with_display_dates;
+
+ALTER TABLE events
+    ALTER COLUMN event_date TYPE DATE USING historic_legacy_date(event_date, calendar),
+    ALTER COLUMN date_earliest TYPE DATE USING historic_legacy_date(date_earliest, calendar),
+    ALTER COLUMN date_latest TYPE DATE USING historic_legacy_date(date_latest, calendar),
+    ALTER COLUMN end_date TYPE DATE USING historic_legacy_date(end_date, calendar);
+
+CREATE VIEW events_with_display_dates AS
+SELECT
+  e.id,
+  e.name,
+  e.description,
+  e.latitude,
+  e.longitude,
+  e.event_date,
+  e.era,
+  e.lens_type,
+  e.created_at,
+  e.updated_at,
+  e.created_by,
+  e.updated_by,
+  e.dataset_id,
+  e.source,
+  e.name_en,
+  e.name_ru,
+  e.description_en,
+  e.description_ru,
+  e.date_precision,
+  e.date_circa,
+  e.date_earliest,
+  e.date_earliest_era,
+  e.date_latest,
+  e.date_latest_era,
+  e.end_date,
+  e.end_era,
+  e.calendar,
+  e.external_id,
+  historic_day_number(e.event_date, e.era, e.calendar) AS day_number,
+  historic_display_date(e.event_date, e.era, e.date_precision, e.date_circa) AS display_date,
+  CASE
+    WHEN e.end_date IS NOT NULL THEN historic_display_date(e.end_date, COALESCE(e.end_era, e.era), e.date_precision, e.date_circa)
+  END AS end_display_date,
+  -- Imprecise dates sort by the middle of the period they cover
+  CASE
+    WHEN e.date_precision = 'day' THEN historic_astronomical_year(e.event_date, e.era, e.calendar)
+    ELSE (pb.earliest + pb.latest) / 2
+  END AS astronomical_year,
+  -- Explicit uncertainty bounds win over the precision-derived period
+  COALESCE(historic_astronomical_year(e.date_earliest, COALESCE(e.date_earliest_era, e.era), e.calendar), pb.earliest) AS astronomical_earliest,
+  -- Events with a duration extend to the end of their end date's period
+  -- (GREATEST ignores NULLs, so events without an end date are unchanged)
+  GREATEST(
+    COALESCE(historic_astronomical_year(e.date_latest, COALESCE(e.date_latest_era, e.era), e.calendar), pb.latest),
+    pe.latest
+  ) AS astronomical_latest,
+  COALESCE(
+    JSON_AGG(
+      JSON_BUILD_OBJECT(
+        'id',           t.id,
+        'name',         t.name,
+        'description',  t.description,
+        'color',        t.color,
+        'border_color', t.border_color,
+        'key_color',    t.key_color,
+        'emoji',        t.emoji,
+        'weight',       t.weight
+      ) ORDER BY t.weight DESC, t.name
+    ) FILTER (WHERE t.id IS NOT NULL),
+    '[]'::json
+  ) AS tags,
+  e.version,
+  e.status,
+  e.review_note,
+  e.reviewed_by,
+  e.reviewed_at
+FROM events e
+CROSS JOIN LATERAL historic_period_bounds(e.event_date, e.era, e.date_precision, e.calendar) pb
+LEFT JOIN LATERAL historic_period_bounds(e.end_date, COALESCE(e.end_era, e.era), e.date_precision, e.calendar) pe ON e.end_date IS NOT NULL
+LEFT JOIN event_tags et ON et.event_id = e.id
+LEFT JOIN tags       t  ON t.id = et.tag_id AND t.deleted_at IS NULL
+WHERE e.deleted_at IS NULL
+GROUP BY
+  e.id, e.name, e.description, e.latitude, e.longitude,
+  e.event_date, e.era, e.lens_type, e.source, e.dataset_id,
+  e.created_at, e.updated_at, e.created_by, e.updated_by,
+  e.name_en, e.name_ru, e.description_en, e.description_ru,
+  e.date_precision, e.date_circa, e.date_earliest, e.date_earliest_era,
+  e.date_latest, e.date_latest_era, e.end_date, e.end_era, e.calendar, e.external_id, e.version,
+  e.status, e.review_note, e.reviewed_by, e.reviewed_at,
+  pb.earliest, pb.latest, pe.latest
+ORDER BY astronomical_year;
+
+DROP FUNCTION IF EXISTS historic_legacy_date(INTEGER, TEXT);
+DROP FUNCTION IF EXISTS historic_display_date(INTEGER, TEXT, TEXT, BOOLEAN);
+DROP FUNCTION IF EXISTS historic_period_bounds(INTEGER, TEXT, TEXT);
+DROP FUNCTION IF EXISTS historic_astronomical_year(INTEGER);
+DROP FUNCTION IF EXISTS historic_civil_date(INTEGER, TEXT);
+DROP FUNCTION IF EXISTS historic_day_number(INTEGER, INTEGER, INTEGER, TEXT);
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Edits of published events by users who cannot publish. The published event
+-- stays as it is while the edit, a snapshot of the event as its author wants
+-- it (see event_revisions), waits for review; approving it applies the
+-- snapshot. An event has at most one pending edit, a newer one replaces it.
+
+CREATE TABLE event_edits (
+    id SERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    snapshot JSONB NOT NULL,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'published', 'rejected')),
+    review_note TEXT,
+    reviewed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_event_edits_pending ON event_edits (event_id) WHERE status = 'pending';
+
+-- +goose Down
+DROP TABLE IF EXISTS event_edits;
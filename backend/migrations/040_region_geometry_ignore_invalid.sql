@@ -0,0 +1,52 @@
+-- +goose Up
+-- A region whose geojson PostGIS cannot parse is stored without geom (and so
+-- left out of the vector tiles) instead of failing the write.
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION region_geometry(doc JSONB) RETURNS geometry AS $$
+BEGIN
+    IF doc IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    BEGIN
+        CASE doc->>'type'
+            WHEN 'FeatureCollection' THEN
+                RETURN (
+                    SELECT ST_SetSRID(ST_Collect(region_geometry(f)), 4326)
+                    FROM jsonb_array_elements(doc->'features') f
+                );
+            WHEN 'Feature' THEN
+                RETURN region_geometry(doc->'geometry');
+            ELSE
+                RETURN ST_SetSRID(ST_GeomFromGeoJSON(doc::text), 4326);
+        END CASE;
+    EXCEPTION WHEN others THEN
+        RETURN NULL;
+    END;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION region_geometry(doc JSONB) RETURNS geometry AS $$
+BEGIN
+    IF doc IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    CASE doc->>'type'
+        WHEN 'FeatureCollection' THEN
+            RETURN (
+                SELECT ST_SetSRID(ST_Collect(region_geometry(f)), 4326)
+                FROM jsonb_array_elements(doc->'features') f
+            );
+        WHEN 'Feature' THEN
+            RETURN region_geometry(doc->'geometry');
+        ELSE
+            RETURN ST_SetSRID(ST_GeomFromGeoJSON(doc::text), 4326);
+    END CASE;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- The version of the event an edit was made against. Approving an edit whose
+-- event has been saved since would overwrite that save, so it is refused
+-- until the edit is resubmitted against the current version.
+
+ALTER TABLE event_edits ADD COLUMN base_version INTEGER;
+
+-- The versions of edits already queued were not recorded; take the current
+-- version of their event, as approving them did before
+UPDATE event_edits ed
+SET base_version = e.version
+FROM events e
+WHERE e.id = ed.event_id;
+
+ALTER TABLE event_edits ALTER COLUMN base_version SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE event_edits DROP COLUMN IF EXISTS base_version;
@@ -0,0 +1,133 @@
+-- +goose Up
+-- Date template dates move to Julian Day Numbers in the template's calendar,
+-- the same storage as event dates (migrations 029 and 038). The view formats
+-- and orders them with the event date functions, so a template range is
+-- shown like an event date and ordered to the day, not to the year.
+
+ALTER TABLE date_templates ADD COLUMN calendar VARCHAR(16) NOT NULL DEFAULT 'gregorian'
+    CHECK (calendar IN ('julian', 'gregorian'));
+
+UPDATE date_templates SET calendar = 'julian'
+WHERE start_era = 'BC' OR start_date < DATE '1582-10-15';
+
+DROP VIEW IF EXISTS date_templates_with_display;
+
+-- The DATE overload of historic_day_number (029) reads the old columns
+ALTER TABLE date_templates
+    ALTER COLUMN start_date TYPE INTEGER USING historic_day_number(start_date, start_era, calendar),
+    ALTER COLUMN end_date TYPE INTEGER USING historic_day_number(end_date, end_era, calendar);
+
+CREATE VIEW date_templates_with_display AS
+SELECT
+    dt.id,
+    dt.group_id,
+    dtg.name as group_name,
+    dt.name,
+    dt.description,
+    dt.name_en,
+    dt.name_ru,
+    dt.description_en,
+    dt.description_ru,
+    dtg.name_en as group_name_en,
+    dtg.name_ru as group_name_ru,
+    dt.start_date,
+    dt.start_era,
+    dt.end_date,
+    dt.end_era,
+    dt.calendar,
+    dt.display_order,
+    historic_display_date(dt.start_date, dt.calendar, 'day', FALSE) AS start_display_date,
+    historic_display_date(dt.end_date, dt.calendar, 'day', FALSE) AS end_display_date,
+    historic_astronomical_year(dt.start_date) AS start_astronomical_year,
+    historic_astronomical_year(dt.end_date) AS end_astronomical_year,
+    dt.version
+FROM date_templates dt
+JOIN date_template_groups dtg ON dt.group_id = dtg.id
+ORDER BY dtg.display_order, dt.display_order;
+
+-- +goose Down
+-- Back to DATE columns. Days the old storage cannot hold (Julian-only and BC
+-- leap days) move to 1 March, as in the Down of migration 038.
+
+-- +goose StatementBegin
+CREATE FUNCTION historic_legacy_template_date(day_number INTEGER, calendar TEXT) RETURNS DATE AS $$
+DECLARE
+    c RECORD;
+BEGIN
+    SELECT * INTO c FROM historic_civil_date(day_number, calendar);
+    RETURN make_date(CASE WHEN c.civil_year < 1 THEN 1 - c.civil_year ELSE c.civil_year END, c.civil_month, 1)
+         + (c.civil_day - 1);
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+DROP VIEW IF EXISTS date_templates_with_display;
+
+ALTER TABLE date_templates
+    ALTER COLUMN start_date TYPE DATE USING historic_legacy_template_date(start_date, calendar),
+    ALTER COLUMN end_date TYPE DATE USING historic_legacy_template_date(end_date, calendar);
+
+ALTER TABLE date_templates DROP COLUMN IF EXISTS calendar;
+
+DROP FUNCTION IF EXISTS historic_legacy_template_date(INTEGER, TEXT);
+
+CREATE VIEW date_templates_with_display AS
+SELECT
+    dt.id,
+    dt.group_id,
+    dtg.name as group_name,
+    dt.name,
+    dt.description,
+    dt.name_en,
+    dt.name_ru,
+    dt.description_en,
+    dt.description_ru,
+    dtg.name_en as group_name_en,
+    dtg.name_ru as group_name_ru,
+    dt.start_date,
+    dt.start_era,
+    dt.end_date,
+    dt.end_era,
+    dt.display_order,
+    -- Format display dates
+    CASE
+        WHEN dt.start_era = 'BC' THEN
+            CONCAT(
+                LPAD(EXTRACT(DAY FROM dt.start_date)::TEXT, 2, '0'), '.',
+                LPAD(EXTRACT(MONTH FROM dt.start_date)::TEXT, 2, '0'), '.',
+                EXTRACT(YEAR FROM dt.start_date)::TEXT, ' BC'
+            )
+        ELSE
+            CONCAT(
+                LPAD(EXTRACT(DAY FROM dt.start_date)::TEXT, 2, '0'), '.',
+                LPAD(EXTRACT(MONTH FROM dt.start_date)::TEXT, 2, '0'), '.',
+                EXTRACT(YEAR FROM dt.start_date)::TEXT, ' AD'
+            )
+    END AS start_display_date,
+    CASE
+        WHEN dt.end_era = 'BC' THEN
+            CONCAT(
+                LPAD(EXTRACT(DAY FROM dt.end_date)::TEXT, 2, '0'), '.',
+                LPAD(EXTRACT(MONTH FROM dt.end_date)::TEXT, 2, '0'), '.',
+                EXTRACT(YEAR FROM dt.end_date)::TEXT, ' BC'
+            )
+        ELSE
+            CONCAT(
+                LPAD(EXTRACT(DAY FROM dt.end_date)::TEXT, 2, '0'), '.',
+                LPAD(EXTRACT(MONTH FROM dt.end_date)::TEXT, 2, '0'), '.',
+                EXTRACT(YEAR FROM dt.end_date)::TEXT, ' AD'
+            )
+    END AS end_display_date,
+    -- Calculate astronomical years for sorting
+    CASE
+        WHEN dt.start_era = 'BC' THEN (EXTRACT(YEAR FROM dt.start_date) * -1) + 1
+        ELSE EXTRACT(YEAR FROM dt.start_date)
+    END AS start_astronomical_year,
+    CASE
+        WHEN dt.end_era = 'BC' THEN (EXTRACT(YEAR FROM dt.end_date) * -1) + 1
+        ELSE EXTRACT(YEAR FROM dt.end_date)
+    END AS end_astronomical_year,
+    dt.version
+FROM date_templates dt
+JOIN date_template_groups dtg ON dt.group_id = dtg.id
+ORDER BY dtg.display_order, dt.display_order;
@@ -0,0 +1,202 @@
+-- +goose Up
+-- The astronomical years events are filtered and ordered by become stored
+-- columns of events. The view used to compute them with plpgsql functions
+-- on every row, so a date range filter or a page of events ordered by date
+-- had to evaluate and sort every event; stored and indexed, both can use an
+-- index. The expressions are the ones of the view in migration 038.
+
+ALTER TABLE events ADD COLUMN astronomical_year NUMERIC GENERATED ALWAYS AS (
+    -- Imprecise dates sort by the middle of the period they cover
+    CASE
+        WHEN date_precision = 'day' THEN historic_astronomical_year(event_date)
+        ELSE ((historic_period_bounds(event_date, date_precision, calendar)).earliest +
+              (historic_period_bounds(event_date, date_precision, calendar)).latest) / 2
+    END
+) STORED;
+
+-- Explicit uncertainty bounds win over the precision-derived period
+ALTER TABLE events ADD COLUMN astronomical_earliest NUMERIC GENERATED ALWAYS AS (
+    COALESCE(historic_astronomical_year(date_earliest),
+             (historic_period_bounds(event_date, date_precision, calendar)).earliest)
+) STORED;
+
+-- Events with a duration extend to the end of their end date's period
+-- (GREATEST ignores NULLs, so events without an end date are unchanged)
+ALTER TABLE events ADD COLUMN astronomical_latest NUMERIC GENERATED ALWAYS AS (
+    GREATEST(
+        COALESCE(historic_astronomical_year(date_latest),
+                 (historic_period_bounds(event_date, date_precision, calendar)).latest),
+        CASE
+            WHEN end_date IS NOT NULL THEN (historic_period_bounds(end_date, date_precision, calendar)).latest
+        END
+    )
+) STORED;
+
+-- Keyset pages (GetPageAfter) and OFFSET pages order by (sort column, id)
+CREATE INDEX idx_events_astronomical_year ON events(astronomical_year, id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_events_name_id ON events(name, id) WHERE deleted_at IS NULL;
+-- The date range filter: astronomical_latest >= from AND astronomical_earliest <= to
+CREATE INDEX idx_events_astronomical_earliest ON events(astronomical_earliest) WHERE deleted_at IS NULL;
+CREATE INDEX idx_events_astronomical_latest ON events(astronomical_latest) WHERE deleted_at IS NULL;
+
+-- The view reads the stored columns and collects tags in a subquery instead
+-- of grouping, and no longer orders its rows, so the planner can merge it
+-- into the query and push filters, ordering and limits down to the indexes.
+DROP VIEW IF EXISTS events_with_display_dates;
+
+CREATE VIEW events_with_display_dates AS
+SELECT
+  e.id,
+  e.name,
+  e.description,
+  e.latitude,
+  e.longitude,
+  e.event_date,
+  e.era,
+  e.lens_type,
+  e.created_at,
+  e.updated_at,
+  e.created_by,
+  e.updated_by,
+  e.dataset_id,
+  e.source,
+  e.name_en,
+  e.name_ru,
+  e.description_en,
+  e.description_ru,
+  e.date_precision,
+  e.date_circa,
+  e.date_earliest,
+  e.date_earliest_era,
+  e.date_latest,
+  e.date_latest_era,
+  e.end_date,
+  e.end_era,
+  e.calendar,
+  e.external_id,
+  e.event_date AS day_number,
+  historic_display_date(e.event_date, e.calendar, e.date_precision, e.date_circa) AS display_date,
+  CASE
+    WHEN e.end_date IS NOT NULL THEN historic_display_date(e.end_date, e.calendar, e.date_precision, e.date_circa)
+  END AS end_display_date,
+  e.astronomical_year,
+  e.astronomical_earliest,
+  e.astronomical_latest,
+  COALESCE(
+    (SELECT JSON_AGG(
+              JSON_BUILD_OBJECT(
+                'id',           t.id,
+                'name',         t.name,
+                'description',  t.description,
+                'color',        t.color,
+                'border_color', t.border_color,
+                'key_color',    t.key_color,
+                'emoji',        t.emoji,
+                'weight',       t.weight
+              ) ORDER BY t.weight DESC, t.name
+            )
+     FROM event_tags et
+     JOIN tags t ON t.id = et.tag_id AND t.deleted_at IS NULL
+     WHERE et.event_id = e.id),
+    '[]'::json
+  ) AS tags,
+  e.version,
+  e.status,
+  e.review_note,
+  e.reviewed_by,
+  e.reviewed_at
+FROM events e
+WHERE e.deleted_at IS NULL;
+
+-- +goose Down
+DROP VIEW IF EXISTS events_with_display_dates;
+
+DROP INDEX IF EXISTS idx_events_astronomical_latest;
+DROP INDEX IF EXISTS idx_events_astronomical_earliest;
+DROP INDEX IF EXISTS idx_events_name_id;
+DROP INDEX IF EXISTS idx_events_astronomical_year;
+
+ALTER TABLE events DROP COLUMN IF EXISTS astronomical_latest;
+ALTER TABLE events DROP COLUMN IF EXISTS astronomical_earliest;
+ALTER TABLE events DROP COLUMN IF EXISTS astronomical_year;
+
+CREATE VIEW events_with_display_dates AS
+SELECT
+  e.id,
+  e.name,
+  e.description,
+  e.latitude,
+  e.longitude,
+  e.event_date,
+  e.era,
+  e.lens_type,
+  e.created_at,
+  e.updated_at,
+  e.created_by,
+  e.updated_by,
+  e.dataset_id,
+  e.source,
+  e.name_en,
+  e.name_ru,
+  e.description_en,
+  e.description_ru,
+  e.date_precision,
+  e.date_circa,
+  e.date_earliest,
+  e.date_earliest_era,
+  e.date_latest,
+  e.date_latest_era,
+  e.end_date,
+  e.end_era,
+  e.calendar,
+  e.external_id,
+  e.event_date AS day_number,
+  historic_display_date(e.event_date, e.calendar, e.date_precision, e.date_circa) AS display_date,
+  CASE
+    WHEN e.end_date IS NOT NULL THEN historic_display_date(e.end_date, e.calendar, e.date_precision, e.date_circa)
+  END AS end_display_date,
+  CASE
+    WHEN e.date_precision = 'day' THEN historic_astronomical_year(e.event_date)
+    ELSE (pb.earliest + pb.latest) / 2
+  END AS astronomical_year,
+  COALESCE(historic_astronomical_year(e.date_earliest), pb.earliest) AS astronomical_earliest,
+  GREATEST(
+    COALESCE(historic_astronomical_year(e.date_latest), pb.latest),
+    pe.latest
+  ) AS astronomical_latest,
+  COALESCE(
+    JSON_AGG(
+      JSON_BUILD_OBJECT(
+        'id',           t.id,
+        'name',         t.name,
+        'description',  t.description,
+        'color',        t.color,
+        'border_color', t.border_color,
+        'key_color',    t.key_color,
+        'emoji',        t.emoji,
+        'weight',       t.weight
+      ) ORDER BY t.weight DESC, t.name
+    ) FILTER (WHERE t.id IS NOT NULL),
+    '[]'::json
+  ) AS tags,
+  e.version,
+  e.status,
+  e.review_note,
+  e.reviewed_by,
+  e.reviewed_at
+FROM events e
+CROSS JOIN LATERAL historic_period_bounds(e.event_date, e.date_precision, e.calendar) pb
+LEFT JOIN LATERAL historic_period_bounds(e.end_date, e.date_precision, e.calendar) pe ON e.end_date IS NOT NULL
+LEFT JOIN event_tags et ON et.event_id = e.id
+LEFT JOIN tags       t  ON t.id = et.tag_id AND t.deleted_at IS NULL
+WHERE e.deleted_at IS NULL
+GROUP BY
+  e.id, e.name, e.description, e.latitude, e.longitude,
+  e.event_date, e.era, e.lens_type, e.source, e.dataset_id,
+  e.created_at, e.updated_at, e.created_by, e.updated_by,
+  e.name_en, e.name_ru, e.description_en, e.description_ru,
+  e.date_precision, e.date_circa, e.date_earliest, e.date_earliest_era,
+  e.date_latest, e.date_latest_era, e.end_date, e.end_era, e.calendar, e.external_id, e.version,
+  e.status, e.review_note, e.reviewed_by, e.reviewed_at,
+  pb.earliest, pb.latest, pe.latest
+ORDER BY astronomical_year;
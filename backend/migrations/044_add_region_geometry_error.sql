@@ -0,0 +1,36 @@
+-- +goose Up
+-- region_geometry (migration 040) turns GeoJSON that PostGIS cannot parse into
+-- a NULL geom without saying why. region_geometry_error parses the document
+-- the same way and returns the PostGIS error, or NULL when it parses, so
+-- writes can reject such GeoJSON and the lint can report rows stored before.
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION region_geometry_error(doc JSONB) RETURNS TEXT AS $$
+BEGIN
+    IF doc IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    BEGIN
+        CASE doc->>'type'
+            WHEN 'FeatureCollection' THEN
+                RETURN (
+                    SELECT string_agg(problem, '; ')
+                    FROM (SELECT region_geometry_error(f) AS problem FROM jsonb_array_elements(doc->'features') f) problems
+                    WHERE problem IS NOT NULL
+                );
+            WHEN 'Feature' THEN
+                RETURN region_geometry_error(doc->'geometry');
+            ELSE
+                PERFORM ST_GeomFromGeoJSON(doc::text);
+                RETURN NULL;
+        END CASE;
+    EXCEPTION WHEN others THEN
+        RETURN SQLERRM;
+    END;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION IF EXISTS region_geometry_error(JSONB);
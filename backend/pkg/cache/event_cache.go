@@ -12,8 +12,10 @@ type cached_payload struct {
 	expires_at time.Time
 }
 
-// EventCache is a TTL in-memory cache for the full events list, keyed by locale.
-// Call Invalidate() on any write (create/update/delete/import).
+// EventCache is a TTL in-memory cache for the public events list, keyed by
+// locale (plus the canonical filter string for filtered requests). It only
+// ever holds published events.
+// Call Invalidate() on any write (create/update/delete/import/review).
 type EventCache struct {
 	mu      sync.RWMutex
 	entries map[string]cached_payload
@@ -38,11 +40,19 @@ func (c *EventCache) Get(locale string) ([]byte, bool) {
 	return entry.data, true
 }
 
-// Set stores pre-serialised JSON bytes for the given locale.
+// Set stores pre-serialised JSON bytes for the given locale. Expired entries
+// are dropped on the way so filtered keys cannot accumulate between writes.
 func (c *EventCache) Set(locale string, data []byte) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.expires_at) {
+			delete(c.entries, key)
+		}
+	}
+
 	c.entries[locale] = cached_payload{
 		data:       data,
 		expires_at: time.Now().Add(event_cache_ttl),
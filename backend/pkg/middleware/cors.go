@@ -8,7 +8,8 @@ func CORS() func(http.Handler) http.Handler {
                 return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
                         w.Header().Set("Access-Control-Allow-Origin", "*")
                         w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-                        w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+                        w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, If-Match")
+                        w.Header().Set("Access-Control-Expose-Headers", "ETag")
                         w.Header().Set("Access-Control-Allow-Credentials", "false")
                         w.Header().Set("Access-Control-Max-Age", "86400")
                         
@@ -30,6 +30,19 @@ func JSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	}
 }
 
+// GeoJSONContentType is the media type of GeoJSON documents (RFC 7946)
+const GeoJSONContentType = "application/geo+json"
+
+// GeoJSON sends a GeoJSON document as-is, without the data envelope
+func GeoJSON(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", GeoJSONContentType)
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("Error encoding GeoJSON response: %v", err)
+	}
+}
+
 // Success sends a success response
 func Success(w http.ResponseWriter, data interface{}, message ...string) {
 	response := SuccessResponse{Data: data}
@@ -73,4 +86,40 @@ func InternalError(w http.ResponseWriter, message string, details ...string) {
 // NotFound sends a not found response
 func NotFound(w http.ResponseWriter, message string, details ...string) {
 	Error(w, http.StatusNotFound, message, details...)
-}
\ No newline at end of file
+}
+// PreconditionFailedResponse is the body of a 412 response to a stale write,
+// or of a 409 Conflict: the error and the current state of the resource, so
+// the client can merge
+type PreconditionFailedResponse struct {
+	Error   string      `json:"error"`
+	Message string      `json:"message,omitempty"`
+	Code    int         `json:"code"`
+	Current interface{} `json:"current"`
+}
+
+// PreconditionFailed sends a precondition failed response with the current
+// state of the resource
+func PreconditionFailed(w http.ResponseWriter, message string, current interface{}, details ...string) {
+	response := PreconditionFailedResponse{
+		Error:   message,
+		Code:    http.StatusPreconditionFailed,
+		Current: current,
+	}
+	if len(details) > 0 {
+		response.Message = details[0]
+	}
+	JSON(w, http.StatusPreconditionFailed, response)
+}
+
+// Conflict sends a conflict response with the current state of the resource
+func Conflict(w http.ResponseWriter, message string, current interface{}, details ...string) {
+	response := PreconditionFailedResponse{
+		Error:   message,
+		Code:    http.StatusConflict,
+		Current: current,
+	}
+	if len(details) > 0 {
+		response.Message = details[0]
+	}
+	JSON(w, http.StatusConflict, response)
+}
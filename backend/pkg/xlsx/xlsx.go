@@ -0,0 +1,368 @@
+// Package xlsx reads and writes single-sheet Office Open XML spreadsheets
+// using only archive/zip and encoding/xml. It supports what event
+// imports and exports need: cell text, numbers, booleans and dates.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of .xlsx files
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// Write writes rows as the only sheet of a workbook. Every cell is stored as
+// an inline string, so values such as "01.03.0044" are kept exactly as given.
+func Write(w io.Writer, sheetName string, rows [][]string) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", rootRelsXML},
+		{"xl/workbook.xml", fmt.Sprintf(workbookXML, escape(sheetName))},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+	}
+	for _, file := range files {
+		f, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, file.content); err != nil {
+			return err
+		}
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	if err := writeSheet(f, rows); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func writeSheet(w io.Writer, rows [][]string) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, row := range rows {
+		fmt.Fprintf(&b, `<row r="%d">`, i+1)
+		for j, value := range row {
+			if value == "" {
+				continue
+			}
+			fmt.Fprintf(&b, `<c r="%s%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, columnName(j), i+1, escape(value))
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Read returns the cells of the first sheet as text, one slice per row.
+// Missing cells are empty strings and trailing empty cells are dropped.
+// Numbers are formatted without exponent or trailing zeros, and cells
+// formatted as dates are returned as DD.MM.YYYY.
+func Read(r io.ReaderAt, size int64) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not an xlsx file: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var workbook struct {
+		Properties struct {
+			Date1904 string `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeFile(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, fmt.Errorf("workbook has no sheets")
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeFile(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	sheetPath := ""
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[0].RelID {
+			sheetPath = rel.Target
+			if strings.HasPrefix(sheetPath, "/") {
+				sheetPath = strings.TrimPrefix(sheetPath, "/")
+			} else {
+				sheetPath = path.Join("xl", sheetPath)
+			}
+		}
+	}
+	if sheetPath == "" {
+		return nil, fmt.Errorf("first sheet not found in workbook")
+	}
+
+	var shared []string
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []stringItem `xml:"si"`
+		}
+		if err := decodeFile(files, "xl/sharedStrings.xml", &sst); err != nil {
+			return nil, err
+		}
+		shared = make([]string, len(sst.Items))
+		for i, item := range sst.Items {
+			shared[i] = item.String()
+		}
+	}
+
+	var dateStyles []bool
+	if _, ok := files["xl/styles.xml"]; ok {
+		if dateStyles, err = readDateStyles(files); err != nil {
+			return nil, err
+		}
+	}
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if workbook.Properties.Date1904 == "1" || workbook.Properties.Date1904 == "true" {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Index int `xml:"r,attr"`
+			Cells []struct {
+				Ref    string     `xml:"r,attr"`
+				Type   string     `xml:"t,attr"`
+				Style  int        `xml:"s,attr"`
+				Value  string     `xml:"v"`
+				Inline stringItem `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := decodeFile(files, sheetPath, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		index := row.Index - 1
+		if index < len(rows) {
+			index = len(rows)
+		}
+		for len(rows) <= index {
+			rows = append(rows, nil)
+		}
+
+		var cells []string
+		for _, cell := range row.Cells {
+			col := len(cells)
+			if cell.Ref != "" {
+				if col, err = columnIndex(cell.Ref); err != nil {
+					return nil, err
+				}
+			}
+
+			var value string
+			switch cell.Type {
+			case "s":
+				i, err := strconv.Atoi(cell.Value)
+				if err != nil || i < 0 || i >= len(shared) {
+					return nil, fmt.Errorf("cell %s: invalid shared string index %q", cell.Ref, cell.Value)
+				}
+				value = shared[i]
+			case "inlineStr":
+				value = cell.Inline.String()
+			case "b":
+				value = "false"
+				if cell.Value == "1" {
+					value = "true"
+				}
+			case "n", "":
+				value = formatNumber(cell.Value, cell.Style < len(dateStyles) && dateStyles[cell.Style], epoch)
+			default: // str (formula result), e (error), d (ISO date)
+				value = cell.Value
+			}
+
+			for len(cells) < col {
+				cells = append(cells, "")
+			}
+			if col < len(cells) {
+				cells[col] = value
+			} else {
+				cells = append(cells, value)
+			}
+		}
+
+		for len(cells) > 0 && cells[len(cells)-1] == "" {
+			cells = cells[:len(cells)-1]
+		}
+		rows[index] = cells
+	}
+	return rows, nil
+}
+
+// stringItem is rich or plain text in a shared string or inline string
+type stringItem struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (s stringItem) String() string {
+	if len(s.Runs) == 0 {
+		return s.Text
+	}
+	var b strings.Builder
+	for _, run := range s.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// builtinDateFormats are the predefined number format IDs that display dates
+var builtinDateFormats = map[int]bool{14: true, 15: true, 16: true, 17: true, 18: true, 19: true, 20: true, 21: true, 22: true, 45: true, 46: true, 47: true}
+
+// quotedOrBracketed matches literal text and colour/locale sections of a
+// number format, which must be ignored when looking for date placeholders
+var quotedOrBracketed = regexp.MustCompile(`"[^"]*"|\[[^\]]*\]|\\.`)
+
+// readDateStyles reports, for each cell style index, whether it formats
+// numbers as dates
+func readDateStyles(files map[string]*zip.File) ([]bool, error) {
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := decodeFile(files, "xl/styles.xml", &styles); err != nil {
+		return nil, err
+	}
+
+	custom := make(map[int]bool, len(styles.NumFmts))
+	for _, f := range styles.NumFmts {
+		code := strings.ToLower(quotedOrBracketed.ReplaceAllString(f.Code, ""))
+		custom[f.ID] = strings.ContainsAny(code, "dy")
+	}
+
+	dates := make([]bool, len(styles.CellXfs))
+	for i, xf := range styles.CellXfs {
+		dates[i] = builtinDateFormats[xf.NumFmtID] || custom[xf.NumFmtID]
+	}
+	return dates, nil
+}
+
+// formatNumber renders a numeric cell value, converting date serial numbers
+func formatNumber(value string, isDate bool, epoch time.Time) string {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+	if isDate {
+		days := int(math.Floor(f))
+		// The 1900 date system counts a non-existent 29 February 1900
+		if epoch.Year() == 1899 && days < 61 {
+			days++
+		}
+		return epoch.AddDate(0, 0, days).Format("02.01.2006")
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func decodeFile(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("missing %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// columnName converts a zero-based column index to its letters (0 -> A, 26 -> AA)
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// columnIndex returns the zero-based column of a cell reference such as "C12"
+func columnIndex(ref string) (int, error) {
+	col := 0
+	for i, c := range ref {
+		if c >= 'A' && c <= 'Z' {
+			col = col*26 + int(c-'A') + 1
+			continue
+		}
+		if i == 0 {
+			break
+		}
+		return col - 1, nil
+	}
+	return 0, fmt.Errorf("invalid cell reference %q", ref)
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+const contentTypesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+
+const rootRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const workbookXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+const workbookRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
@@ -0,0 +1,148 @@
+package xlsx
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	wide := make([]string, 30)
+	for i := range wide {
+		wide[i] = columnName(i)
+	}
+
+	rows := [][]string{
+		{"name", "date", "era", "latitude", "tags"},
+		{"Ides of March", "15.03.0044", "BC", "41.8953", "rome, assassination"},
+		// Leading zeros, numbers and booleans stay text
+		{"0044", "01.03.0044", "007", "1e3", "true"},
+		// Markup characters, quotes, whitespace and non-Latin text
+		{`<b>Tom & "Jerry"</b>`, "  padded  ", "tab\there", "line\nbreak", "Взятие Константинополя"},
+		// Empty cells in the middle of a row
+		{"", "second", "", "", "fifth"},
+		nil,
+		{"after an empty row"},
+		wide,
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "Events & more", rows); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	assertRows(t, got, rows)
+}
+
+func TestReadExcelFile(t *testing.T) {
+	data, err := os.ReadFile("testdata/events.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	rome := []string{"  Founding of Rome ", "21.04.0753", "BC", "41.8925", "12.4853", "political", "", "", "true"}
+	for len(rome) < 26 {
+		rome = append(rome, "")
+	}
+	rome = append(rome, "Fish & chips <3")
+
+	want := [][]string{
+		{"name", "date", "era", "latitude", "longitude", "type", "tags", "precision", "circa"},
+		// Rich text shared string
+		{"Battle of Austerlitz", "02.12.1805", "AD", "49.128", "16.762", "military", "napoleonic wars, battle"},
+		// Sparse row with a built-in date format and a number format with literal text
+		{"Apollo 11 landing", "20.07.1969", "", "0.67416", "23.47314", "", "", "day"},
+		// Row 4 is not in the file
+		nil,
+		rome,
+		// Custom date format with a time of day, a formula result and an exponent
+		{"Assassination in Sarajevo", "28.06.1914", "AD", "43", "18.4131", "", "", "", "false"},
+		// The first day of the 1900 date system; styled empty cells are dropped
+		{"01.01.1900"},
+	}
+	assertRows(t, got, want)
+}
+
+func TestReadInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "Sheet", [][]string{{"a"}}); err != nil {
+		t.Fatal(err)
+	}
+	valid := buf.Bytes()
+
+	tests := map[string][]byte{
+		"not a zip": []byte("name,date\nRome,21.04.0753\n"),
+		"empty":     {},
+		"truncated": valid[:len(valid)/2],
+	}
+	for name, data := range tests {
+		if _, err := Read(bytes.NewReader(data), int64(len(data))); err == nil {
+			t.Errorf("%s: Read succeeded, want error", name)
+		}
+	}
+}
+
+func TestColumnNames(t *testing.T) {
+	tests := []struct {
+		index int
+		name  string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+		{16383, "XFD"},
+	}
+
+	for _, tt := range tests {
+		if got := columnName(tt.index); got != tt.name {
+			t.Errorf("columnName(%d) = %q, want %q", tt.index, got, tt.name)
+		}
+		got, err := columnIndex(tt.name + "12")
+		if err != nil || got != tt.index {
+			t.Errorf("columnIndex(%q) = %d, %v, want %d", tt.name+"12", got, err, tt.index)
+		}
+	}
+
+	for _, ref := range []string{"", "12", "A", "a1"} {
+		if _, err := columnIndex(ref); err == nil {
+			t.Errorf("columnIndex(%q) succeeded, want error", ref)
+		}
+	}
+}
+
+// assertRows compares rows cell by cell; a nil row equals an empty one
+func assertRows(t *testing.T, got, want [][]string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d rows, want %d:\n%s", len(got), len(want), formatRows(got))
+	}
+	for i := range want {
+		if strings.Join(got[i], "\x00") != strings.Join(want[i], "\x00") || len(got[i]) != len(want[i]) {
+			t.Errorf("row %d = %q, want %q", i+1, got[i], want[i])
+		}
+	}
+}
+
+func formatRows(rows [][]string) string {
+	var b strings.Builder
+	for i, row := range rows {
+		b.WriteString("  ")
+		b.WriteString(strings.Join(row, " | "))
+		if i < len(rows)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
@@ -130,6 +130,8 @@ export function useAuth() {
   const isGuest = computed(() => !isAuthenticated.value)
   const canCreateEvents = computed(() => isAuthenticated.value && user.value && user.value.access_level !== 'guest')
   const canEditEvents = computed(() => isAuthenticated.value && user.value && (user.value.access_level === 'editor' || user.value.access_level === 'admin' || user.value.access_level === 'super'))
+  // Users may edit the events they created; editors and above any event
+  const canEditEvent = (event) => !!(canEditEvents.value || (canCreateEvents.value && event && event.created_by === user.value.id))
   const canAccessAdmin = computed(() => isAuthenticated.value && user.value && (user.value.access_level === 'editor' || user.value.access_level === 'super'))
   const isEditor = computed(() => isAuthenticated.value && user.value && user.value.access_level === 'editor')
   const isAdmin = computed(() => isAuthenticated.value && user.value && (user.value.access_level === 'admin' || user.value.access_level === 'super'))
@@ -155,6 +157,7 @@ export function useAuth() {
     isGuest,
     canCreateEvents,
     canEditEvents,
+    canEditEvent,
     canAccessAdmin,
     isEditor,
     isAdmin,
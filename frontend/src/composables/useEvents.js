@@ -120,6 +120,10 @@ export function useEvents() {
 
   // Handle event creation
   const handleEventCreated = async (newEvent) => {
+    // Events waiting for review are not on the public map yet
+    if (newEvent && newEvent.status && newEvent.status !== 'published') {
+      return
+    }
     // Add the new event to the events array
     if (newEvent && newEvent.id) {
       events.value.push(newEvent)
@@ -135,6 +139,11 @@ export function useEvents() {
 
   // Handle event update (preserves current filter state)
   const handleEventUpdated = async (updatedEvent) => {
+    // An edit sent back for review takes the event off the map
+    if (updatedEvent.status && updatedEvent.status !== 'published') {
+      await handleEventDeleted(updatedEvent.id)
+      return
+    }
     // Update the event in the current events array
     const eventIndex = events.value.findIndex(e => e.id === updatedEvent.id)
     if (eventIndex !== -1) {
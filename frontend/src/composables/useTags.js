@@ -61,9 +61,9 @@ export function useTags() {
   }
 
   // Update an existing tag
-  const updateTag = async (id, tagData) => {
+  const updateTag = async (id, tagData, version) => {
     try {
-      const response = await api.updateTag(id, tagData)
+      const response = await api.updateTag(id, tagData, version)
       if (response && response.data) {
         const index = allTags.value.findIndex(tag => tag.id === id)
         if (index !== -1) {
@@ -78,9 +78,9 @@ export function useTags() {
   }
 
   // Delete a tag
-  const deleteTag = async (id) => {
+  const deleteTag = async (id, version) => {
     try {
-      await api.deleteTag(id)
+      await api.deleteTag(id, version)
       allTags.value = allTags.value.filter(tag => tag.id !== id)
     } catch (error) {
       console.error('Error deleting tag:', error)
@@ -29,6 +29,12 @@ class ApiService {
     return '/api'
   }
 
+  // ifMatch returns the If-Match header for writing a resource read at
+  // version; without a version the write applies to any version
+  ifMatch(version) {
+    return { 'If-Match': version ? `"${version}"` : '*' }
+  }
+
   async makeRequest(endpoint, options = {}) {
     try {
       const url = `${this.baseURL}${endpoint}`
@@ -38,11 +44,11 @@ class ApiService {
       const authHeaders = authService.getHeaders()
       
       const response = await fetch(url, {
+        ...options,
         headers: {
           ...authHeaders,
           ...options.headers,
         },
-        ...options,
       })
 
       if (!response.ok) {
@@ -60,7 +66,10 @@ class ApiService {
           // If we can't parse the error response, use the default message
         }
         
-        throw new Error(errorMessage)
+        const error = new Error(errorMessage)
+        // 412 on a stale write: the body carries the current version
+        error.status = response.status
+        throw error
       }
 
       const responseData = await response.json()
@@ -122,19 +131,20 @@ class ApiService {
     return result
   }
 
-  async updateEvent(eventId, eventData) {
+  async updateEvent(eventId, eventData, version) {
     const endpoint = this.addLocaleToEventUrl(`/events/${eventId}`)
     const result = await this.makeRequest(endpoint, {
       method: 'PUT',
+      headers: this.ifMatch(version),
       body: JSON.stringify(eventData),
     })
     cache_invalidate_events()
     return result
   }
 
-  async deleteEvent(eventId) {
+  async deleteEvent(eventId, version) {
     const endpoint = this.addLocaleToEventUrl(`/events/${eventId}`)
-    const result = await this.makeRequest(endpoint, { method: 'DELETE' })
+    const result = await this.makeRequest(endpoint, { method: 'DELETE', headers: this.ifMatch(version) })
     cache_invalidate_events()
     return result
   }
@@ -144,6 +154,52 @@ class ApiService {
     return this.makeRequest(endpoint)
   }
 
+  async getModerationQueue(page = 1, limit = 10, status = 'pending') {
+    const params = new URLSearchParams({ page, limit, status })
+    return this.makeRequest(this.addLocaleToEventUrl(`/moderation/queue?${params}`))
+  }
+
+  async approveEvent(eventId, version, note = '') {
+    const result = await this.makeRequest(this.addLocaleToEventUrl(`/moderation/events/${eventId}/approve`), {
+      method: 'POST',
+      headers: this.ifMatch(version),
+      body: JSON.stringify({ note }),
+    })
+    cache_invalidate_events()
+    return result
+  }
+
+  async rejectEvent(eventId, version, note) {
+    const result = await this.makeRequest(this.addLocaleToEventUrl(`/moderation/events/${eventId}/reject`), {
+      method: 'POST',
+      headers: this.ifMatch(version),
+      body: JSON.stringify({ note }),
+    })
+    cache_invalidate_events()
+    return result
+  }
+
+  async getPendingEdits(page = 1, limit = 10) {
+    const params = new URLSearchParams({ page, limit })
+    return this.makeRequest(`/moderation/edits?${params}`)
+  }
+
+  async approveEdit(editId, note = '') {
+    const result = await this.makeRequest(this.addLocaleToEventUrl(`/moderation/edits/${editId}/approve`), {
+      method: 'POST',
+      body: JSON.stringify({ note }),
+    })
+    cache_invalidate_events()
+    return result
+  }
+
+  async rejectEdit(editId, note) {
+    return this.makeRequest(`/moderation/edits/${editId}/reject`, {
+      method: 'POST',
+      body: JSON.stringify({ note }),
+    })
+  }
+
   async getEventsInBBox(minLat, minLng, maxLat, maxLng) {
     const params = new URLSearchParams({
       min_lat: minLat,
@@ -188,16 +244,18 @@ class ApiService {
     })
   }
 
-  async updateTemplate(id, templateData) {
+  async updateTemplate(id, templateData, version) {
     return this.makeRequest(`/date-templates/single/${id}`, {
       method: 'PUT',
+      headers: this.ifMatch(version),
       body: JSON.stringify(templateData),
     })
   }
 
-  async deleteTemplate(id) {
+  async deleteTemplate(id, version) {
     return this.makeRequest(`/date-templates/single/${id}`, {
       method: 'DELETE',
+      headers: this.ifMatch(version),
     })
   }
 
@@ -250,9 +308,10 @@ class ApiService {
     return result
   }
 
-  async updateTag(id, tagData) {
+  async updateTag(id, tagData, version) {
     const result = await this.makeRequest(`/tags/${id}`, {
       method: 'PUT',
+      headers: this.ifMatch(version),
       body: JSON.stringify(tagData),
     })
     cache_invalidate('tags')
@@ -260,8 +319,8 @@ class ApiService {
     return result
   }
 
-  async deleteTag(id) {
-    const result = await this.makeRequest(`/tags/${id}`, { method: 'DELETE' })
+  async deleteTag(id, version) {
+    const result = await this.makeRequest(`/tags/${id}`, { method: 'DELETE', headers: this.ifMatch(version) })
     cache_invalidate('tags')
     cache_invalidate_events()
     return result
@@ -379,16 +438,18 @@ class ApiService {
     })
   }
 
-  async updateRegion(id, data) {
+  async updateRegion(id, data, version) {
     return this.makeRequest(`/regions/${id}`, {
       method: 'PUT',
+      headers: this.ifMatch(version),
       body: JSON.stringify(data),
     })
   }
 
-  async deleteRegion(id) {
+  async deleteRegion(id, version) {
     return this.makeRequest(`/regions/${id}`, {
       method: 'DELETE',
+      headers: this.ifMatch(version),
     })
   }
 